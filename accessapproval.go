@@ -0,0 +1,157 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// accessApprovalWorkers is how many resources' Access Approval settings are read at once.
+const accessApprovalWorkers = 8
+
+// enrolledService is a product enrolled in Access Approval, or "all".
+type enrolledService struct {
+	CloudProduct    string `json:"cloudProduct"`
+	EnrollmentLevel string `json:"enrollmentLevel"`
+}
+
+// accessApprovalSettings is what the Access Approval API returns for an org, folder, or project.
+type accessApprovalSettings struct {
+	Name                        string             `json:"name"`
+	EnrolledServices            []*enrolledService `json:"enrolledServices"`
+	NotificationEmails          []string           `json:"notificationEmails"`
+	EnrolledAncestor            bool               `json:"enrolledAncestor"`
+	ActiveKeyVersion            string             `json:"activeKeyVersion"`
+	AncestorHasActiveKeyVersion bool               `json:"ancestorHasActiveKeyVersion"`
+	InvalidKeyVersion           bool               `json:"invalidKeyVersion"`
+}
+
+// accessApprovalResource is an org, folder, or project and its Access Approval settings, nil
+// where they couldn't be read.
+type accessApprovalResource struct {
+	name     string
+	resType  string
+	settings *accessApprovalSettings
+}
+
+// AccessApprovalSettings reads the Access Approval settings of an org, folder, or project. A
+// resource that was never configured has settings without enrolled services.
+func (r *resourceManager) AccessApprovalSettings(name string) (*accessApprovalSettings, error) {
+	settings := &accessApprovalSettings{}
+	err := r.getJSON(fmt.Sprintf("https://accessapproval.googleapis.com/v1/%s/accessApprovalSettings", name), settings)
+	if err != nil {
+		err = classifyError(name, err)
+		if errors.Is(err, ErrNotFound) {
+			return &accessApprovalSettings{Name: name}, nil
+		}
+		return nil, err
+	}
+	return settings, nil
+}
+
+// ResolveAccessApproval reads the Access Approval settings of the org, every folder, and every
+// project rows are on, several at once.
+func (r *resourceManager) ResolveAccessApproval(rows []*Row) {
+	defer timeTrack("Reading Access Approval settings")()
+	r.accessApproval = make([]*accessApprovalResource, 0)
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		if row.Type != "organization" && row.Type != "folder" && row.Type != "project" || seen[row.Name] {
+			continue
+		}
+		seen[row.Name] = true
+		r.accessApproval = append(r.accessApproval, &accessApprovalResource{name: row.Name, resType: row.Type})
+	}
+	var wg sync.WaitGroup
+	work := make(chan *accessApprovalResource)
+	for i := 0; i < accessApprovalWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for resource := range work {
+				settings, err := r.AccessApprovalSettings(resource.name)
+				if err != nil {
+					logerr.Printf("Unable to read Access Approval settings of %s: %v\n", resource.name, err)
+					continue
+				}
+				resource.settings = settings
+			}
+		}()
+	}
+	for _, resource := range r.accessApproval {
+		work <- resource
+	}
+	close(work)
+	wg.Wait()
+	fmt.Printf("Read Access Approval settings of %d resources\n", len(r.accessApproval))
+}
+
+// accessApprovalRecord is a resource's line of the access-approval report. Enrolled tells whether
+// the resource itself enrolls any service; EnrolledServices are written as product:level.
+func accessApprovalRecord(resource *accessApprovalResource) []string {
+	settings := resource.settings
+	if settings == nil {
+		return []string{resource.name, resource.resType, "", "", "", "", ""}
+	}
+	services := make([]string, 0, len(settings.EnrolledServices))
+	for _, s := range settings.EnrolledServices {
+		services = append(services, s.CloudProduct+":"+s.EnrollmentLevel)
+	}
+	keyVersion := settings.ActiveKeyVersion
+	if keyVersion == "" && settings.AncestorHasActiveKeyVersion {
+		keyVersion = "inherited"
+	}
+	if settings.InvalidKeyVersion {
+		keyVersion += " (invalid)"
+	}
+	return []string{
+		resource.name,
+		resource.resType,
+		strconv.FormatBool(len(services) > 0),
+		strconv.FormatBool(settings.EnrolledAncestor),
+		strings.Join(services, " "),
+		strings.Join(settings.NotificationEmails, " "),
+		strings.TrimSpace(keyVersion),
+	}
+}
+
+// accessApprovalReport lists the Access Approval enrollment and settings of the org, its
+// folders, and its projects, in the order they were collected.
+func accessApprovalReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Resource", "Type", "Enrolled", "EnrolledAncestor", "EnrolledServices", "NotificationEmails", "ActiveKeyVersion"}
+	if resman.accessApproval == nil {
+		logerr.Printf("The access-approval report needs --access-approval\n")
+		return header, [][]string{}, nil
+	}
+	records := make([][]string, 0, len(resman.accessApproval))
+	for _, resource := range resman.accessApproval {
+		records = append(records, accessApprovalRecord(resource))
+	}
+	return header, records, nil
+}
+
+func init() {
+	registerEnricher("access-approval", stageAnnotate, func(opts *Options) bool { return opts.AccessApproval },
+		func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+			resman.ResolveAccessApproval(rows)
+			return rows, nil
+		})
+	registerReport("access-approval", accessApprovalReport)
+	registerCollectorPermissions("access-approval", "accessapproval.settings.get")
+}
@@ -0,0 +1,47 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAccessApprovalRecord(t *testing.T) {
+	enrolled := &accessApprovalSettings{
+		Name:               "organizations/1/accessApprovalSettings",
+		EnrolledServices:   []*enrolledService{{CloudProduct: "all", EnrollmentLevel: "BLOCK_ALL"}},
+		NotificationEmails: []string{"sec@example.com", "ops@example.com"},
+		ActiveKeyVersion:   "projects/k/locations/global/keyRings/r/cryptoKeys/c/cryptoKeyVersions/1",
+	}
+	tests := []struct {
+		resource *accessApprovalResource
+		want     []string
+	}{
+		{&accessApprovalResource{name: "organizations/1", resType: "organization", settings: enrolled},
+			[]string{"organizations/1", "organization", "true", "false", "all:BLOCK_ALL", "sec@example.com ops@example.com", enrolled.ActiveKeyVersion}},
+		{&accessApprovalResource{name: "projects/p", resType: "project", settings: &accessApprovalSettings{EnrolledAncestor: true, AncestorHasActiveKeyVersion: true}},
+			[]string{"projects/p", "project", "false", "true", "", "", "inherited"}},
+		{&accessApprovalResource{name: "folders/2", resType: "folder", settings: &accessApprovalSettings{InvalidKeyVersion: true}},
+			[]string{"folders/2", "folder", "false", "false", "", "", "(invalid)"}},
+		{&accessApprovalResource{name: "folders/3", resType: "folder"},
+			[]string{"folders/3", "folder", "", "", "", "", ""}},
+	}
+	for _, tt := range tests {
+		if got := accessApprovalRecord(tt.resource); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("accessApprovalRecord(%s) = %q, want %q", tt.resource.name, got, tt.want)
+		}
+	}
+}
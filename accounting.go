@@ -0,0 +1,126 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var apiCalls = &apiCallCounter{counts: make(map[string]int)}
+
+type apiCallCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+	total  int
+	max    int
+	// requests refused because the budget was spent
+	refused int
+}
+
+// apiMethod names the API method a request calls, e.g. "iam roles.get" or
+// "cloudresourcemanager projects.getIamPolicy", from its host and REST path.
+func apiMethod(req *http.Request) string {
+	service := strings.Split(req.URL.Host, ".")[0]
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) > 1 {
+		// drop the version
+		segments = segments[1:]
+	}
+	last := segments[len(segments)-1]
+	if i := strings.Index(last, ":"); i >= 0 {
+		collection := segments[len(segments)-1][:i]
+		if len(segments) > 1 {
+			collection = segments[len(segments)-2]
+		}
+		return fmt.Sprintf("%s %s.%s", service, collection, last[i+1:])
+	}
+	if len(segments)%2 == 1 {
+		return fmt.Sprintf("%s %s.list", service, last)
+	}
+	return fmt.Sprintf("%s %s.%s", service, segments[len(segments)-2], strings.ToLower(req.Method))
+}
+
+func (c *apiCallCounter) record(req *http.Request) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.max > 0 && c.total >= c.max {
+		c.refused++
+		return errors.New(fmt.Sprintf("API call budget of %d exceeded, aborting", c.max))
+	}
+	c.total++
+	c.counts[apiMethod(req)]++
+	recordSpanApiCall()
+	return nil
+}
+
+// Exceeded returns an error once a request has been refused for going over --max-api-calls,
+// as whatever was collected since is incomplete.
+func (c *apiCallCounter) Exceeded() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.refused == 0 {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("API call budget of %d exceeded, %d requests refused, not writing output", c.max, c.refused))
+}
+
+// Total is the number of requests sent so far.
+func (c *apiCallCounter) Total() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+func (c *apiCallCounter) Print() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.total == 0 {
+		return
+	}
+	methods := make([]string, 0, len(c.counts))
+	for m := range c.counts {
+		methods = append(methods, m)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		if c.counts[methods[i]] != c.counts[methods[j]] {
+			return c.counts[methods[i]] > c.counts[methods[j]]
+		}
+		return methods[i] < methods[j]
+	})
+	fmt.Fprintf(os.Stderr, "API calls: %d\n", c.total)
+	for _, m := range methods {
+		fmt.Fprintf(os.Stderr, "%8d  %s\n", c.counts[m], m)
+	}
+}
+
+// accountingTransport counts every request that goes out, retries included, and refuses
+// requests once the budget is spent.
+type accountingTransport struct {
+	base    http.RoundTripper
+	counter *apiCallCounter
+}
+
+func (t *accountingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.counter.record(req); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
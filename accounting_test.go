@@ -0,0 +1,47 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestApiMethod(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		want   string
+	}{
+		{"GET", "https://iam.googleapis.com/v1/roles/viewer?fields=name", "iam roles.get"},
+		{"GET", "https://iam.googleapis.com/v1/organizations/123/roles/custom", "iam roles.get"},
+		{"POST", "https://cloudresourcemanager.googleapis.com/v1beta1/projects/my-project:getIamPolicy", "cloudresourcemanager projects.getIamPolicy"},
+		{"POST", "https://cloudresourcemanager.googleapis.com/v2beta1/folders/456:getIamPolicy", "cloudresourcemanager folders.getIamPolicy"},
+		{"GET", "https://cloudresourcemanager.googleapis.com/v1beta1/projects?pageSize=500", "cloudresourcemanager projects.list"},
+		{"POST", "https://cloudresourcemanager.googleapis.com/v1/organizations:search", "cloudresourcemanager organizations.search"},
+		{"GET", "https://cloudasset.googleapis.com/v1/projects/p:searchAllIamPolicies?pageSize=500", "cloudasset projects.searchAllIamPolicies"},
+		{"GET", "https://cloudkms.googleapis.com/v1/projects/p/locations/us/keyRings", "cloudkms keyRings.list"},
+		{"DELETE", "https://iam.googleapis.com/v1/projects/p/serviceAccounts/sa@p.iam.gserviceaccount.com", "iam serviceAccounts.delete"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := apiMethod(req); got != tt.want {
+			t.Errorf("apiMethod(%s %s) = %q, want %q", tt.method, tt.url, got, tt.want)
+		}
+	}
+}
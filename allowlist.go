@@ -0,0 +1,163 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const allowlistDateLayout = "2006-01-02"
+
+// AllowlistEntry accepts bindings matching member, role and resource, each a glob pattern
+// (see compileGlob), until the end of the expiry day. Resource matches the canonical or legacy name.
+type AllowlistEntry struct {
+	Member        string `json:"member"`
+	Role          string `json:"role"`
+	Resource      string `json:"resource"`
+	Expires       string `json:"expires,omitempty"`
+	Justification string `json:"justification"`
+
+	expires  time.Time
+	member   *regexp.Regexp
+	role     *regexp.Regexp
+	resource *regexp.Regexp
+}
+
+// compileGlob turns a glob into an anchored regexp. Unlike path.Match, * (and **) match any
+// run of characters including '/', so projects/* covers //cloudresourcemanager.googleapis.com
+// names and roles/* every role; ? matches one character and \ escapes the next one.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			for i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+			}
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 == len(pattern) {
+				return nil, errors.New("trailing \\ in pattern")
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+type allowlist struct {
+	entries []*AllowlistEntry
+}
+
+// loadAllowlist reads a json list of entries; an empty filename gives a nil allowlist that accepts nothing.
+func loadAllowlist(filename string) (*allowlist, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to read allowlist %s: %v", filename, err))
+	}
+	entries := make([]*AllowlistEntry, 0)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to parse allowlist %s: %v", filename, err))
+	}
+	now := time.Now()
+	for i, e := range entries {
+		if e.Member == "" || e.Role == "" || e.Resource == "" {
+			return nil, errors.New(fmt.Sprintf("Allowlist %s entry %d needs a member, role and resource", filename, i+1))
+		}
+		for _, p := range []struct {
+			pattern string
+			re      **regexp.Regexp
+		}{{e.Member, &e.member}, {e.Role, &e.role}, {e.Resource, &e.resource}} {
+			re, err := compileGlob(p.pattern)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Bad pattern %s in allowlist %s: %v", p.pattern, filename, err))
+			}
+			*p.re = re
+		}
+		if e.Justification == "" {
+			return nil, errors.New(fmt.Sprintf("Allowlist %s entry %d needs a justification", filename, i+1))
+		}
+		if e.Expires != "" {
+			expires, err := time.Parse(allowlistDateLayout, e.Expires)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Bad expiry %s in allowlist %s, expected YYYY-MM-DD", e.Expires, filename))
+			}
+			e.expires = expires.AddDate(0, 0, 1)
+			if now.After(e.expires) {
+				fmt.Printf("Allowlist entry for %s %s on %s expired on %s\n", e.Member, e.Role, e.Resource, e.Expires)
+			}
+		}
+	}
+	return &allowlist{entries: entries}, nil
+}
+
+func (e *AllowlistEntry) matches(row *Row, now time.Time) bool {
+	if !e.expires.IsZero() && now.After(e.expires) {
+		return false
+	}
+	if !e.member.MatchString(row.Member) || !e.role.MatchString(row.Role) {
+		return false
+	}
+	for _, name := range []string{row.Name, fullResourceNamePrefix + row.Name, row.Resource} {
+		if e.resource.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed reports whether an unexpired entry accepts the binding.
+func (a *allowlist) Allowed(row *Row) bool {
+	if a == nil {
+		return false
+	}
+	now := time.Now()
+	for _, e := range a.entries {
+		if e.matches(row, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unapproved returns the rows no unexpired entry accepts.
+func (a *allowlist) Unapproved(rows []*Row) []*Row {
+	if a == nil {
+		return rows
+	}
+	unapproved := make([]*Row, 0, len(rows))
+	for _, row := range rows {
+		if !a.Allowed(row) {
+			unapproved = append(unapproved, row)
+		}
+	}
+	return unapproved
+}
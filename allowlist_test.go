@@ -0,0 +1,96 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"projects/app-*", "projects/app-1", true},
+		{"projects/app-*", "projects/db-1", false},
+		{"*/projects/app-*", "//cloudresourcemanager.googleapis.com/projects/app-1", true},
+		{"//storage.googleapis.com/**", "//storage.googleapis.com/projects/_/buckets/b", true},
+		{"roles/*", "roles/editor", true},
+		{"roles/*", "organizations/1/roles/custom", false},
+		{"projects/app-?", "projects/app-1", true},
+		{"projects/app-?", "projects/app-12", false},
+		{"user:*@example.com", "user:a@example.com", true},
+		{"user:*@example.com", "user:a@example.com.evil.org", false},
+		{`projects/a\*`, "projects/a*", true},
+		{`projects/a\*`, "projects/ab", false},
+		{"projects/a.b", "projects/aXb", false},
+	}
+	for _, tt := range tests {
+		re, err := compileGlob(tt.pattern)
+		if err != nil {
+			t.Errorf("compileGlob(%q): %v", tt.pattern, err)
+			continue
+		}
+		if got := re.MatchString(tt.name); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+	if _, err := compileGlob(`trailing\`); err == nil {
+		t.Errorf("compileGlob accepted a trailing backslash")
+	}
+}
+
+func TestAllowlistAllowed(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "allowlist.json")
+	data := `[
+  {"member": "serviceAccount:ci@build-prj.iam.gserviceaccount.com", "role": "roles/editor",
+   "resource": "projects/app-*", "justification": "CI deploys"},
+  {"member": "group:admins@example.com", "role": "*", "resource": "*", "justification": "platform team"},
+  {"member": "user:*@example.com", "role": "roles/viewer", "resource": "*/projects/shared",
+   "expires": "2000-01-01", "justification": "long gone"}
+]`
+	if err := ioutil.WriteFile(filename, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	allow, err := loadAllowlist(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci := "serviceAccount:ci@build-prj.iam.gserviceaccount.com"
+	tests := []struct {
+		row  Row
+		want bool
+	}{
+		{Row{Member: ci, Role: "roles/editor", Name: "projects/app-1", Resource: "App One"}, true},
+		{Row{Member: ci, Role: "roles/owner", Name: "projects/app-1", Resource: "App One"}, false},
+		{Row{Member: ci, Role: "roles/editor", Name: "projects/db-1", Resource: "DB"}, false},
+		{Row{Member: ci, Role: "roles/editor", Name: "projects/db-1", Resource: "projects/app-legacy"}, true},
+		{Row{Member: "group:admins@example.com", Role: "roles/owner", Name: "organizations/1", Resource: "1"}, true},
+		{Row{Member: "user:a@example.com", Role: "roles/viewer", Name: "projects/shared", Resource: "shared"}, false},
+	}
+	for _, tt := range tests {
+		row := tt.row
+		if got := allow.Allowed(&row); got != tt.want {
+			t.Errorf("Allowed(%s %s on %s) = %v, want %v", row.Member, row.Role, row.Name, got, tt.want)
+		}
+	}
+	var none *allowlist
+	if none.Allowed(&Row{Member: ci}) {
+		t.Errorf("a nil allowlist allowed a binding")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/api/googleapi"
+	"net/http"
+)
+
+// Classes of API errors, to test for with errors.Is on the errors of API calls.
+var (
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrQuotaExceeded    = errors.New("quota exceeded")
+	ErrNotFound         = errors.New("not found")
+)
+
+// apiError is an API error of a known class, with the resource it was about.
+type apiError struct {
+	Class    error
+	Resource string
+	Err      error
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Resource, e.Class, e.Err)
+}
+
+func (e *apiError) Unwrap() error {
+	return e.Err
+}
+
+func (e *apiError) Is(target error) bool {
+	return target == e.Class
+}
+
+// errorClass returns the class of an error from the REST helpers or a generated client, or nil
+// when its status doesn't have one. Quota errors come back as 403 from some APIs, told apart by
+// their reason like the throttling transport does.
+func errorClass(err error) error {
+	var e *apiError
+	if errors.As(err, &e) {
+		return e.Class
+	}
+	var code int
+	var body string
+	var h *httpError
+	var g *googleapi.Error
+	switch {
+	case errors.As(err, &h):
+		code, body = h.Code, h.Body
+	case errors.As(err, &g):
+		code, body = g.Code, g.Body
+		for _, item := range g.Errors {
+			body += " " + item.Reason
+		}
+	default:
+		return nil
+	}
+	switch {
+	case code == http.StatusNotFound:
+		return ErrNotFound
+	case code == http.StatusTooManyRequests:
+		return ErrQuotaExceeded
+	case code == http.StatusForbidden && quotaErrorReason.MatchString(body):
+		return ErrQuotaExceeded
+	case code == http.StatusForbidden:
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// classifyError wraps err in an apiError naming resource when it has a class, and returns it
+// unchanged otherwise.
+func classifyError(resource string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *apiError
+	if errors.As(err, &e) {
+		return err
+	}
+	if class := errorClass(err); class != nil {
+		return &apiError{Class: class, Resource: resource, Err: err}
+	}
+	return err
+}
+
+// isNotFound reports whether err is a 404 from an API.
+func isNotFound(err error) bool {
+	return errorClass(err) == ErrNotFound
+}
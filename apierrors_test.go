@@ -0,0 +1,55 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/api/googleapi"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{&httpError{Code: 404}, ErrNotFound},
+		{&httpError{Code: 403, Body: `{"error":{"status":"PERMISSION_DENIED"}}`}, ErrPermissionDenied},
+		{&httpError{Code: 403, Body: `{"error":{"errors":[{"reason":"rateLimitExceeded"}]}}`}, ErrQuotaExceeded},
+		{&httpError{Code: 429}, ErrQuotaExceeded},
+		{&googleapi.Error{Code: 404}, ErrNotFound},
+		{&googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}}}, ErrQuotaExceeded},
+		{&googleapi.Error{Code: 403}, ErrPermissionDenied},
+		{fmt.Errorf("listing: %w", &httpError{Code: 404}), ErrNotFound},
+		{&httpError{Code: 500}, nil},
+		{errors.New("connection reset"), nil},
+	}
+	for _, tt := range tests {
+		err := classifyError("projects/p", tt.err)
+		for _, class := range []error{ErrNotFound, ErrPermissionDenied, ErrQuotaExceeded} {
+			if got := errors.Is(err, class); got != (class == tt.want) {
+				t.Errorf("errors.Is(classifyError(%v), %v) = %v", tt.err, class, got)
+			}
+		}
+		if tt.want == nil && err != tt.err {
+			t.Errorf("classifyError(%v) = %v, want it unchanged", tt.err, err)
+		}
+		var e *apiError
+		if tt.want != nil && (!errors.As(err, &e) || e.Resource != "projects/p") {
+			t.Errorf("classifyError(%v) = %#v, want an apiError for projects/p", tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// stdoutFilename as --file streams the export to stdout.
+const stdoutFilename = "-"
+
+// exportStdout is where an export to stdoutFilename goes; useStdoutForExport points os.Stdout,
+// and so every informational print, at stderr once it has been saved here.
+var exportStdout = os.Stdout
+
+func useStdoutForExport() {
+	exportStdout = os.Stdout
+	os.Stdout = os.Stderr
+}
+
+// atomicFile is written to a temporary file next to its target and only renamed into place by
+// Commit, after it has been flushed, synced and closed, so readers never see a partial file.
+// The temporary file is in the target's own directory because a rename can't cross filesystems,
+// and it is closed before the rename because Windows can't rename open files. Files on Cloud
+// Storage are kept in memory and uploaded by Commit, which is just as atomic.
+type atomicFile struct {
+	*bufio.Writer
+	f        *os.File
+	gcs      *bytes.Buffer
+	filename string
+	done     bool
+	digest   *digestWriter
+	// output files are the export, reports, and stats a run is asked for, see sealOutputs
+	output bool
+}
+
+// digestWriter hashes and counts what goes through an atomicFile.
+type digestWriter struct {
+	h hash.Hash
+	n int64
+}
+
+func (d *digestWriter) Write(p []byte) (int, error) {
+	d.n += int64(len(p))
+	return d.h.Write(p)
+}
+
+// committedFile is the size and sha256 of a file as Commit wrote it.
+type committedFile struct {
+	Bytes  int64
+	Sha256 string
+	Output bool
+}
+
+var committedFiles = struct {
+	sync.Mutex
+	m map[string]*committedFile
+}{m: make(map[string]*committedFile)}
+
+// committed returns what was written to filename by the last Commit of it in this run.
+func committed(filename string) (*committedFile, bool) {
+	committedFiles.Lock()
+	defer committedFiles.Unlock()
+	c, ok := committedFiles.m[filename]
+	return c, ok
+}
+
+func newAtomicFile(w io.Writer, f *os.File, gcs *bytes.Buffer, filename string) *atomicFile {
+	digest := &digestWriter{h: sha256.New()}
+	return &atomicFile{Writer: bufio.NewWriter(io.MultiWriter(w, digest)), f: f, gcs: gcs, filename: filename, digest: digest}
+}
+
+func (a *atomicFile) recordCommit() {
+	committedFiles.Lock()
+	defer committedFiles.Unlock()
+	committedFiles.m[a.filename] = &committedFile{Bytes: a.digest.n, Sha256: hex.EncodeToString(a.digest.h.Sum(nil)),
+		Output: a.output}
+}
+
+// committedOutputs lists the output files committed so far, stdout left out.
+func committedOutputs() []string {
+	committedFiles.Lock()
+	defer committedFiles.Unlock()
+	names := make([]string, 0)
+	for name, c := range committedFiles.m {
+		if c.Output && name != stdoutFilename {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func createAtomic(filename string, perm os.FileMode) (*atomicFile, error) {
+	if filename == stdoutFilename {
+		return newAtomicFile(exportStdout, nil, nil, filename), nil
+	}
+	if isGcsPath(filename) {
+		if _, _, err := splitGcsPath(filename); err != nil {
+			return nil, err
+		}
+		buf := &bytes.Buffer{}
+		return newAtomicFile(buf, nil, buf, filename), nil
+	}
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to create directory for %s: %v", filename, err))
+	}
+	f, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to create %s: %v", filename, err))
+	}
+	if err := f.Chmod(perm); err != nil && !os.IsPermission(err) {
+		// not supported everywhere, e.g. on Windows or some network filesystems
+		logerr.Printf("Unable to set permissions of %s: %v\n", filename, err)
+	}
+	return newAtomicFile(f, f, nil, filename), nil
+}
+
+// createOutput is createAtomic for the files a run is asked to produce, as opposed to caches
+// and snapshots, so they can be checksummed and signed.
+func createOutput(filename string) (*atomicFile, error) {
+	a, err := createAtomic(filename, 0644)
+	if err != nil {
+		return nil, err
+	}
+	a.output = true
+	return a, nil
+}
+
+// Commit moves the file into place. It must be called once all writes succeeded.
+func (a *atomicFile) Commit() error {
+	if a.done {
+		return nil
+	}
+	a.done = true
+	if a.gcs != nil {
+		if err := a.Flush(); err != nil {
+			return errors.New(fmt.Sprintf("Error writing %s: %v", a.filename, err))
+		}
+		if err := uploadToGcs(a.filename, a.gcs.Bytes()); err != nil {
+			return err
+		}
+		a.recordCommit()
+		return nil
+	}
+	if a.f == nil {
+		if err := a.Flush(); err != nil {
+			return errors.New(fmt.Sprintf("Error writing to stdout: %v", err))
+		}
+		a.recordCommit()
+		return nil
+	}
+	tmpname := a.f.Name()
+	if err := a.Flush(); err != nil {
+		a.f.Close()
+		os.Remove(tmpname)
+		return errors.New(fmt.Sprintf("Error writing %s: %v", a.filename, err))
+	}
+	if err := a.f.Sync(); err != nil {
+		a.f.Close()
+		os.Remove(tmpname)
+		return errors.New(fmt.Sprintf("Error syncing %s: %v", a.filename, err))
+	}
+	if err := a.f.Close(); err != nil {
+		os.Remove(tmpname)
+		return errors.New(fmt.Sprintf("Error closing %s: %v", a.filename, err))
+	}
+	if err := os.Rename(tmpname, a.filename); err != nil {
+		os.Remove(tmpname)
+		return errors.New(fmt.Sprintf("Unable to move %s to %s: %v", tmpname, a.filename, err))
+	}
+	a.recordCommit()
+	return nil
+}
+
+// Abort discards the file unless it was committed, so it can be deferred right after createAtomic.
+func (a *atomicFile) Abort() {
+	if a.done || a.f == nil {
+		return
+	}
+	a.done = true
+	a.f.Close()
+	os.Remove(a.f.Name())
+}
+
+// writeFileAtomic is ioutil.WriteFile through an atomicFile.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	a, err := createAtomic(filename, perm)
+	if err != nil {
+		return err
+	}
+	return a.writeAll(data)
+}
+
+// writeOutputFile is writeFileAtomic through createOutput.
+func writeOutputFile(filename string, data []byte) error {
+	a, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	return a.writeAll(data)
+}
+
+func (a *atomicFile) writeAll(data []byte) error {
+	defer a.Abort()
+	if _, err := a.Write(data); err != nil {
+		return errors.New(fmt.Sprintf("Error writing %s: %v", a.filename, err))
+	}
+	return a.Commit()
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "atomic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func dirEntries(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names
+}
+
+func TestAtomicFileCommit(t *testing.T) {
+	dir := filepath.Join(tempDir(t), "out")
+	filename := filepath.Join(dir, "export.csv")
+	a, err := createAtomic(filename, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Abort()
+	a.WriteString("a,b\n")
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("%s exists before Commit", filename)
+	}
+	if err := a.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil || string(data) != "a,b\n" {
+		t.Fatalf("ReadFile = %q, %v, want %q", data, err, "a,b\n")
+	}
+	if names := dirEntries(t, dir); len(names) != 1 {
+		t.Errorf("directory holds %v after Commit, want only export.csv", names)
+	}
+	sum := sha256.Sum256(data)
+	c, ok := committed(filename)
+	if !ok || c.Bytes != int64(len(data)) || c.Sha256 != hex.EncodeToString(sum[:]) || c.Output {
+		t.Errorf("committed(%s) = %+v, %v, want %d bytes with its sha256, not an output", filename, c, ok, len(data))
+	}
+}
+
+func TestAtomicFileAbortKeepsPrevious(t *testing.T) {
+	dir := tempDir(t)
+	filename := filepath.Join(dir, "export.csv")
+	if err := writeOutputFile(filename, []byte("old\n")); err != nil {
+		t.Fatal(err)
+	}
+	a, err := createAtomic(filename, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.WriteString("partial")
+	a.Flush()
+	a.Abort()
+	if err := a.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil || string(data) != "old\n" {
+		t.Errorf("ReadFile after Abort = %q, %v, want %q", data, err, "old\n")
+	}
+	if names := dirEntries(t, dir); len(names) != 1 {
+		t.Errorf("directory holds %v after Abort, want only export.csv", names)
+	}
+	found := false
+	for _, name := range committedOutputs() {
+		found = found || name == filename
+	}
+	if !found {
+		t.Errorf("committedOutputs() = %v, want it to list %s", committedOutputs(), filename)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	v1beta1 "google.golang.org/api/cloudresourcemanager/v1beta1"
+	v2beta1 "google.golang.org/api/cloudresourcemanager/v2beta1"
+	"sort"
+	"strings"
+)
+
+type AuditConfig struct {
+	Service         string            `json:"service,omitempty"`
+	AuditLogConfigs []*AuditLogConfig `json:"auditLogConfigs,omitempty"`
+}
+
+type AuditLogConfig struct {
+	LogType         string   `json:"logType,omitempty"`
+	ExemptedMembers []string `json:"exemptedMembers,omitempty"`
+}
+
+func convertAuditConfigsV1(configs []*v1beta1.AuditConfig) []*AuditConfig {
+	results := make([]*AuditConfig, len(configs))
+	for i, c := range configs {
+		results[i] = &AuditConfig{Service: c.Service}
+		for _, l := range c.AuditLogConfigs {
+			results[i].AuditLogConfigs = append(results[i].AuditLogConfigs,
+				&AuditLogConfig{LogType: l.LogType, ExemptedMembers: l.ExemptedMembers})
+		}
+	}
+	return results
+}
+
+func convertAuditConfigsV2(configs []*v2beta1.AuditConfig) []*AuditConfig {
+	results := make([]*AuditConfig, len(configs))
+	for i, c := range configs {
+		results[i] = &AuditConfig{Service: c.Service}
+		for _, l := range c.AuditLogConfigs {
+			results[i].AuditLogConfigs = append(results[i].AuditLogConfigs,
+				&AuditLogConfig{LogType: l.LogType, ExemptedMembers: l.ExemptedMembers})
+		}
+	}
+	return results
+}
+
+// resourceAuditConfig is an audit config together with the resource whose policy holds it.
+type resourceAuditConfig struct {
+	Resource Row
+	Config   *AuditConfig
+}
+
+func (r *resourceManager) addAuditConfigs(configs []*AuditConfig, base Row) {
+	for _, c := range configs {
+		r.auditConfigs = append(r.auditConfigs, &resourceAuditConfig{Resource: base, Config: c})
+	}
+}
+
+// auditConfigReport lists which log types are enabled for which service on every resource,
+// with the members exempted from each. Resources without audit configs are left out.
+func auditConfigReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	records := make([][]string, 0)
+	for _, rc := range resman.auditConfigs {
+		base := []string{resman.ResourceColumn(&rc.Resource), rc.Resource.Type, rc.Resource.Name, rc.Config.Service}
+		if len(rc.Config.AuditLogConfigs) == 0 {
+			records = append(records, append(base, "", ""))
+			continue
+		}
+		for _, l := range rc.Config.AuditLogConfigs {
+			records = append(records, append(append([]string{}, base...), l.LogType, strings.Join(l.ExemptedMembers, " ")))
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return strings.Join(records[i], ",") < strings.Join(records[j], ",")
+	})
+	return []string{"Resource", "Type", "ResourceName", "Service", "LogType", "ExemptedMembers"}, records, nil
+}
+
+func init() {
+	registerReport("audit-configs", auditConfigReport)
+}
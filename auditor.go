@@ -0,0 +1,92 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var collectorPermissions = make(map[string][]string)
+
+// registerCollectorPermissions records the permissions a collector's API calls need,
+// so auditor-role can print a least-privilege role for the collectors in use.
+func registerCollectorPermissions(collector string, permissions ...string) {
+	collectorPermissions[collector] = append(collectorPermissions[collector], permissions...)
+}
+
+func collectorNames() []string {
+	names := make([]string, 0, len(collectorPermissions))
+	for name := range collectorPermissions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func auditorPermissions(collectors []string) ([]string, error) {
+	unique := make(map[string]bool)
+	for _, c := range collectors {
+		permissions, ok := collectorPermissions[c]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Unknown collector %s, expected one of %s", c, strings.Join(collectorNames(), ", ")))
+		}
+		for _, p := range permissions {
+			unique[p] = true
+		}
+	}
+	permissions := make([]string, 0, len(unique))
+	for p := range unique {
+		permissions = append(permissions, p)
+	}
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+// printAuditorRole prints a custom role definition for `gcloud iam roles create --file`.
+func printAuditorRole(collectorList string) error {
+	collectors := collectorNames()
+	if collectorList != "" {
+		collectors = strings.Split(collectorList, ",")
+	}
+	permissions, err := auditorPermissions(collectors)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("# gcloud iam roles create policygopherAuditor --organization=ORG_ID --file=auditor-role.yaml\n")
+	fmt.Printf("title: policygopher auditor\n")
+	fmt.Printf("description: Read-only access needed by policygopher collectors: %s\n", strings.Join(collectors, ", "))
+	fmt.Printf("stage: GA\n")
+	fmt.Printf("includedPermissions:\n")
+	for _, p := range permissions {
+		fmt.Printf("- %s\n", p)
+	}
+	return nil
+}
+
+func init() {
+	registerCollectorPermissions("core",
+		"resourcemanager.organizations.get",
+		"resourcemanager.organizations.getIamPolicy",
+		"resourcemanager.folders.list",
+		"resourcemanager.folders.getIamPolicy",
+		"resourcemanager.projects.get",
+		"resourcemanager.projects.list",
+		"resourcemanager.projects.getIamPolicy",
+		"iam.roles.get",
+	)
+}
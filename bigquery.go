@@ -0,0 +1,171 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Origin column values: rows from IAM policies, and rows translated from legacy ACLs (see
+// also originGcsAcl).
+const (
+	originIam         = "iam"
+	originBigQueryAcl = "bigquery-acl"
+)
+
+// bigQueryAclRoles are the IAM roles BigQuery itself maps the legacy dataset roles to.
+var bigQueryAclRoles = map[string]string{
+	"OWNER":  "roles/bigquery.dataOwner",
+	"WRITER": "roles/bigquery.dataEditor",
+	"READER": "roles/bigquery.dataViewer",
+}
+
+// bigQueryAccessEntry is one entry of a dataset's access list. Entries granting a view,
+// routine, or dataset access instead of an identity have none of the identity fields.
+type bigQueryAccessEntry struct {
+	Role         string `json:"role"`
+	UserByEmail  string `json:"userByEmail"`
+	GroupByEmail string `json:"groupByEmail"`
+	Domain       string `json:"domain"`
+	SpecialGroup string `json:"specialGroup"`
+	IamMember    string `json:"iamMember"`
+}
+
+type bigQueryDataset struct {
+	DatasetReference struct {
+		ProjectId string `json:"projectId"`
+		DatasetId string `json:"datasetId"`
+	} `json:"datasetReference"`
+	Etag   string                 `json:"etag"`
+	Access []*bigQueryAccessEntry `json:"access"`
+}
+
+type listDatasetsResponse struct {
+	Datasets []struct {
+		DatasetReference struct {
+			DatasetId string `json:"datasetId"`
+		} `json:"datasetReference"`
+	} `json:"datasets"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// aclMember translates an access entry's identity into an IAM member; the special groups
+// become the convenience members IAM uses for the same thing. It returns "" for entries
+// authorizing a view, routine, or dataset.
+func (e *bigQueryAccessEntry) aclMember(projectId string) string {
+	switch {
+	case e.IamMember != "":
+		return e.IamMember
+	case e.UserByEmail != "":
+		if strings.HasSuffix(strings.ToLower(e.UserByEmail), ".gserviceaccount.com") {
+			return "serviceAccount:" + e.UserByEmail
+		}
+		return "user:" + e.UserByEmail
+	case e.GroupByEmail != "":
+		return "group:" + e.GroupByEmail
+	case e.Domain != "":
+		return "domain:" + e.Domain
+	}
+	switch e.SpecialGroup {
+	case "projectOwners":
+		return "projectOwner:" + projectId
+	case "projectWriters":
+		return "projectEditor:" + projectId
+	case "projectReaders":
+		return "projectViewer:" + projectId
+	case "allAuthenticatedUsers", "allUsers":
+		return e.SpecialGroup
+	}
+	return ""
+}
+
+// aclRole is the IAM role of an access entry: newer entries already name one.
+func (e *bigQueryAccessEntry) aclRole() string {
+	if role, ok := bigQueryAclRoles[e.Role]; ok {
+		return role
+	}
+	return e.Role
+}
+
+// aclPolicy turns a dataset's access list into a policy, one binding per entry.
+func (d *bigQueryDataset) aclPolicy() *Policy {
+	policy := &Policy{Etag: d.Etag, raw: d}
+	for _, e := range d.Access {
+		member := e.aclMember(d.DatasetReference.ProjectId)
+		if member == "" || e.Role == "" {
+			continue
+		}
+		policy.Bindings = append(policy.Bindings, &Binding{Role: e.aclRole(), Members: []string{member}})
+	}
+	return policy
+}
+
+// addBigQueryAcls adds the access lists of a project's BigQuery datasets as rows of the roles
+// BigQuery maps them to, with the bigquery-acl origin. Dataset ACLs predate IAM and are still
+// how most datasets are shared, so they'd otherwise be missing from a review.
+func (r *resourceManager) addBigQueryAcls(projectId string, rows *[]*Row) error {
+	base := fmt.Sprintf("https://bigquery.googleapis.com/bigquery/v2/projects/%s/datasets", url.PathEscape(projectId))
+	pageToken := ""
+	for {
+		u := base + "?all=true&maxResults=1000"
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		resp := &listDatasetsResponse{}
+		if err := r.getJSON(u, resp); err != nil {
+			return err
+		}
+		for _, ds := range resp.Datasets {
+			id := ds.DatasetReference.DatasetId
+			dataset := &bigQueryDataset{}
+			if err := r.getJSON(fmt.Sprintf("%s/%s?fields=datasetReference,etag,access", base, url.PathEscape(id)), dataset); err != nil {
+				logerr.Printf("Unable to get dataset %s in project %s: %v\n", id, projectId, err)
+				continue
+			}
+			r.resourcesScanned++
+			r.addPolicy(dataset.aclPolicy(), rows, Row{
+				Resource:    id,
+				Type:        "dataset",
+				Parent:      fmt.Sprintf("projects/%s", projectId),
+				Name:        fmt.Sprintf("//bigquery.googleapis.com/projects/%s/datasets/%s", projectId, id),
+				DisplayName: id,
+				Origin:      originBigQueryAcl,
+			})
+		}
+		if resp.NextPageToken == "" {
+			return nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// originColumn reports whether rows carry the Origin column.
+func (r *resourceManager) originColumn() bool {
+	return r.bigQueryAcls || r.bucketAcls
+}
+
+// RowOrigin is the Origin column of a row.
+func RowOrigin(row *Row) string {
+	if row.Origin == "" {
+		return originIam
+	}
+	return row.Origin
+}
+
+func init() {
+	registerCollectorPermissions("bigquery-acls", "bigquery.datasets.get")
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBigQueryAclPolicy(t *testing.T) {
+	dataset := &bigQueryDataset{Etag: "e1"}
+	dataset.DatasetReference.ProjectId = "analytics"
+	dataset.DatasetReference.DatasetId = "sales"
+	dataset.Access = []*bigQueryAccessEntry{
+		{Role: "OWNER", SpecialGroup: "projectOwners"},
+		{Role: "WRITER", UserByEmail: "etl@analytics.iam.gserviceaccount.com"},
+		{Role: "READER", GroupByEmail: "analysts@example.com"},
+		{Role: "READER", Domain: "example.com"},
+		{Role: "READER", SpecialGroup: "allAuthenticatedUsers"},
+		{Role: "roles/bigquery.metadataViewer", IamMember: "principalSet://iam.googleapis.com/locations/global/workforcePools/p/*"},
+		{Role: "READER", UserByEmail: "alice@example.com"},
+		// an authorized view has no identity
+		{},
+	}
+	want := []*Binding{
+		{Role: "roles/bigquery.dataOwner", Members: []string{"projectOwner:analytics"}},
+		{Role: "roles/bigquery.dataEditor", Members: []string{"serviceAccount:etl@analytics.iam.gserviceaccount.com"}},
+		{Role: "roles/bigquery.dataViewer", Members: []string{"group:analysts@example.com"}},
+		{Role: "roles/bigquery.dataViewer", Members: []string{"domain:example.com"}},
+		{Role: "roles/bigquery.dataViewer", Members: []string{"allAuthenticatedUsers"}},
+		{Role: "roles/bigquery.metadataViewer", Members: []string{"principalSet://iam.googleapis.com/locations/global/workforcePools/p/*"}},
+		{Role: "roles/bigquery.dataViewer", Members: []string{"user:alice@example.com"}},
+	}
+	policy := dataset.aclPolicy()
+	if policy.Etag != "e1" {
+		t.Errorf("Etag = %q, want e1", policy.Etag)
+	}
+	if !reflect.DeepEqual(policy.Bindings, want) {
+		for _, b := range policy.Bindings {
+			t.Logf("%s %v", b.Role, b.Members)
+		}
+		t.Errorf("aclPolicy bindings differ from the expected translation")
+	}
+}
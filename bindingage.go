@@ -0,0 +1,174 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
+	"strings"
+	"time"
+)
+
+// auditLogBatch is how many orgs, folders, and projects one logs query reads at once.
+const auditLogBatch = 50
+
+// auditLogPayload is the part of a SetIamPolicy Admin Activity audit log entry's protoPayload
+// saying who changed the policy and which bindings they added or removed.
+type auditLogPayload struct {
+	MethodName         string `json:"methodName"`
+	ResourceName       string `json:"resourceName"`
+	AuthenticationInfo struct {
+		PrincipalEmail string `json:"principalEmail"`
+	} `json:"authenticationInfo"`
+	ServiceData struct {
+		PolicyDelta struct {
+			BindingDeltas []*bindingDelta `json:"bindingDeltas"`
+		} `json:"policyDelta"`
+	} `json:"serviceData"`
+}
+
+type bindingDelta struct {
+	// ADD or REMOVE
+	Action    string `json:"action"`
+	Role      string `json:"role"`
+	Member    string `json:"member"`
+	Condition *Expr  `json:"condition,omitempty"`
+}
+
+// bindingGrant is when a binding was last added, and by whom.
+type bindingGrant struct {
+	At time.Time
+	By string
+}
+
+// auditResourceName is a resource's name as audit logs write it: without the
+// //service.googleapis.com/ prefix full resource names have.
+func auditResourceName(name string) string {
+	if strings.HasPrefix(name, "//") {
+		if i := strings.Index(name[2:], "/"); i >= 0 {
+			return name[i+3:]
+		}
+	}
+	return name
+}
+
+func bindingGrantKey(resource string, role string, member string, condition *Expr) string {
+	return strings.Join([]string{auditResourceName(resource), role, normalizeMember(member), conditionExpression(condition)}, "\x00")
+}
+
+// addAuditLogEntry records the bindings an entry added, replacing earlier grants of the same
+// binding, so entries read oldest first leave the grant that is in effect now. A binding
+// removed and added again was granted by the later change.
+func addAuditLogEntry(grants map[string]*bindingGrant, timestamp string, payload *auditLogPayload) {
+	at, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return
+	}
+	for _, delta := range payload.ServiceData.PolicyDelta.BindingDeltas {
+		key := bindingGrantKey(payload.ResourceName, delta.Role, delta.Member, delta.Condition)
+		switch delta.Action {
+		case "ADD":
+			grants[key] = &bindingGrant{At: at.UTC(), By: payload.AuthenticationInfo.PrincipalEmail}
+		case "REMOVE":
+			delete(grants, key)
+		}
+	}
+}
+
+// auditLogContainers are the org, folders, and projects whose audit logs hold the policy
+// changes of rows: a resource's changes are logged in its project.
+func (r *resourceManager) auditLogContainers(rows []*Row) []string {
+	containers := make(map[string]bool)
+	for _, row := range rows {
+		switch {
+		case row.Type == "organization" || row.Type == "folder":
+			containers[row.Name] = true
+		case rowProject(row) != "":
+			containers["projects/"+rowProject(row)] = true
+		}
+	}
+	return sortedKeys(containers)
+}
+
+// ResolveBindingGrants reads the last days of SetIamPolicy Admin Activity audit logs of every
+// org, folder, and project rows are in, and sets the GrantedAt and GrantedBy of each binding
+// added in that time. Bindings older than that, or changed by something that doesn't log a
+// policy delta, are left empty.
+func (r *resourceManager) ResolveBindingGrants(rows []*Row, days int) error {
+	defer timeTrack("Reading audit logs")()
+	var clientOptions []option.ClientOption
+	if r.client != nil {
+		clientOptions = append(clientOptions, option.WithHTTPClient(r.client))
+	}
+	service, err := logging.NewService(r.ctx, clientOptions...)
+	if err != nil {
+		return err
+	}
+	since := time.Now().UTC().AddDate(0, 0, -days).Format(time.RFC3339)
+	filter := fmt.Sprintf(`logName:"cloudaudit.googleapis.com%%2Factivity" AND protoPayload.methodName:"SetIamPolicy" AND timestamp>="%s"`, since)
+	grants := make(map[string]*bindingGrant)
+	containers := r.auditLogContainers(rows)
+	entries := 0
+	for start := 0; start < len(containers); start += auditLogBatch {
+		end := start + auditLogBatch
+		if end > len(containers) {
+			end = len(containers)
+		}
+		request := &logging.ListLogEntriesRequest{
+			ResourceNames: containers[start:end],
+			Filter:        filter,
+			OrderBy:       "timestamp asc",
+			PageSize:      1000,
+		}
+		err := service.Entries.List(request).Fields("nextPageToken,entries(timestamp,protoPayload)").
+			Pages(r.ctx, func(page *logging.ListLogEntriesResponse) error {
+				for _, entry := range page.Entries {
+					var payload auditLogPayload
+					if err := json.Unmarshal(entry.ProtoPayload, &payload); err != nil {
+						continue
+					}
+					addAuditLogEntry(grants, entry.Timestamp, &payload)
+					entries++
+				}
+				return nil
+			})
+		if err != nil {
+			logerr.Printf("Unable to read audit logs of %s: %v\n", strings.Join(containers[start:end], ", "), classifyError("audit logs", err))
+		}
+	}
+	found := 0
+	for _, row := range rows {
+		if grant, ok := grants[bindingGrantKey(row.Name, row.Role, row.Member, row.Condition)]; ok {
+			row.GrantedAt, row.GrantedBy = grant.At.Format(time.RFC3339), grant.By
+			found++
+		}
+	}
+	fmt.Printf("Read %d policy changes from the last %d days of audit logs, dating %d of %d bindings\n", entries, days, found, len(rows))
+	return nil
+}
+
+func init() {
+	registerEnricher("binding-age", stageAnnotate, func(opts *Options) bool { return opts.BindingAgeDays > 0 },
+		func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+			if resman.offline {
+				fmt.Printf("Not reading audit logs for --binding-age-days, reading --input\n")
+				return rows, nil
+			}
+			return rows, resman.ResolveBindingGrants(rows, resman.bindingAgeDays)
+		})
+	registerCollectorPermissions("binding-age", "logging.logEntries.list")
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAuditResourceName(t *testing.T) {
+	for name, want := range map[string]string{
+		"projects/p": "projects/p",
+		"//storage.googleapis.com/projects/_/buckets/b":   "projects/_/buckets/b",
+		"//cloudresourcemanager.googleapis.com/folders/1": "folders/1",
+	} {
+		if got := auditResourceName(name); got != want {
+			t.Errorf("auditResourceName(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestAddAuditLogEntry(t *testing.T) {
+	change := func(by string, deltas ...*bindingDelta) *auditLogPayload {
+		p := &auditLogPayload{MethodName: "SetIamPolicy", ResourceName: "projects/p"}
+		p.AuthenticationInfo.PrincipalEmail = by
+		p.ServiceData.PolicyDelta.BindingDeltas = deltas
+		return p
+	}
+	grants := make(map[string]*bindingGrant)
+	addAuditLogEntry(grants, "2020-01-01T00:00:00Z", change("a@example.com",
+		&bindingDelta{Action: "ADD", Role: "roles/viewer", Member: "user:X@example.com"},
+		&bindingDelta{Action: "ADD", Role: "roles/editor", Member: "user:x@example.com"}))
+	addAuditLogEntry(grants, "2020-02-01T00:00:00Z", change("b@example.com",
+		&bindingDelta{Action: "REMOVE", Role: "roles/editor", Member: "user:x@example.com"}))
+	addAuditLogEntry(grants, "2020-03-01T00:00:00.5Z", change("c@example.com",
+		&bindingDelta{Action: "ADD", Role: "roles/owner", Member: "user:x@example.com", Condition: &Expr{Expression: "true"}}))
+	addAuditLogEntry(grants, "not a time", change("d@example.com",
+		&bindingDelta{Action: "ADD", Role: "roles/browser", Member: "user:x@example.com"}))
+
+	want := map[string]*bindingGrant{
+		bindingGrantKey("projects/p", "roles/viewer", "user:x@example.com", nil): {
+			At: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), By: "a@example.com"},
+		bindingGrantKey("//cloudresourcemanager.googleapis.com/projects/p", "roles/owner", "user:x@example.com", &Expr{Expression: "true"}): {
+			At: time.Date(2020, 3, 1, 0, 0, 0, 500000000, time.UTC), By: "c@example.com"},
+	}
+	if !reflect.DeepEqual(grants, want) {
+		t.Errorf("grants = %v, want %v", grants, want)
+	}
+}
+
+func TestAuditLogContainers(t *testing.T) {
+	rows := []*Row{
+		{Type: "organization", Name: "organizations/1"},
+		{Type: "folder", Name: "folders/2"},
+		{Type: "project", Name: "projects/p"},
+		{Type: "bucket", Name: "//storage.googleapis.com/b", Parent: "projects/q"},
+		{Type: "bucket", Name: "//storage.googleapis.com/c"},
+	}
+	got := (&resourceManager{}).auditLogContainers(rows)
+	want := []string{"folders/2", "organizations/1", "projects/p", "projects/q"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("auditLogContainers = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BreakGlassAccount is an emergency account from the config file, with the only bindings it
+// should hold. Without an OrgId it is checked in every org exported.
+type BreakGlassAccount struct {
+	Member   string               `json:"member"`
+	OrgId    string               `json:"orgId,omitempty"`
+	Bindings []*BreakGlassBinding `json:"bindings"`
+}
+
+// BreakGlassBinding is a role on a resource given by canonical name (organizations/123,
+// folders/456, projects/my-project) or, for resources inside projects, full resource name.
+type BreakGlassBinding struct {
+	Role     string `json:"role"`
+	Resource string `json:"resource"`
+}
+
+// checkBreakGlass validates the breakGlass list of a config file.
+func checkBreakGlass(filename string, accounts []*BreakGlassAccount) error {
+	for i, account := range accounts {
+		if !strings.Contains(account.Member, ":") {
+			return errors.New(fmt.Sprintf("Config %s: breakGlass[%d] member %q has no type, e.g. user:", filename, i, account.Member))
+		}
+		for j, b := range account.Bindings {
+			if b.Role == "" || b.Resource == "" {
+				return errors.New(fmt.Sprintf("Config %s: breakGlass[%d].bindings[%d] needs a role and a resource", filename, i, j))
+			}
+		}
+	}
+	return nil
+}
+
+// breakGlassAccounts are the accounts to check in orgId.
+func breakGlassAccounts(accounts []*BreakGlassAccount, orgId string) []*BreakGlassAccount {
+	found := make([]*BreakGlassAccount, 0)
+	for _, account := range accounts {
+		if account.OrgId == "" || account.OrgId == orgId {
+			found = append(found, account)
+		}
+	}
+	return found
+}
+
+// breakGlassFinding is a binding of a break-glass account, and whether it is as expected: ok,
+// missing when the account doesn't hold it, or unexpected when the config doesn't list it.
+type breakGlassFinding struct {
+	Member   string
+	Resource string
+	Role     string
+	Status   string
+}
+
+// BreakGlassFindings compares the bindings the break-glass accounts hold to those the config
+// expects, ordered by member, resource, and role. Conditions aren't compared: a conditional
+// binding counts as held.
+func (r *resourceManager) BreakGlassFindings(rows []*Row) []*breakGlassFinding {
+	findings := make([]*breakGlassFinding, 0)
+	for _, account := range r.breakGlass {
+		member := normalizeMember(account.Member)
+		expected := make(map[string]bool)
+		for _, b := range account.Bindings {
+			expected[b.Resource+" "+b.Role] = true
+		}
+		held := make(map[string]bool)
+		for _, row := range rows {
+			if normalizeMember(row.Member) == member {
+				held[row.Name+" "+row.Role] = true
+			}
+		}
+		for _, key := range sortedKeys(held) {
+			status := "ok"
+			if !expected[key] {
+				status = "unexpected"
+			}
+			findings = append(findings, newBreakGlassFinding(member, key, status))
+		}
+		for _, key := range sortedKeys(expected) {
+			if !held[key] {
+				findings = append(findings, newBreakGlassFinding(member, key, "missing"))
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Member != b.Member {
+			return a.Member < b.Member
+		}
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		return a.Role < b.Role
+	})
+	return findings
+}
+
+func newBreakGlassFinding(member string, key string, status string) *breakGlassFinding {
+	i := strings.LastIndex(key, " ")
+	return &breakGlassFinding{Member: member, Resource: key[:i], Role: key[i+1:], Status: status}
+}
+
+func breakGlassReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Member", "Resource", "Role", "Status"}
+	if resman.breakGlass == nil {
+		logerr.Printf("The break-glass report needs breakGlass accounts in --config\n")
+		return header, [][]string{}, nil
+	}
+	findings := resman.BreakGlassFindings(rows)
+	records := make([][]string, len(findings))
+	for i, f := range findings {
+		records[i] = []string{f.Member, f.Resource, f.Role, f.Status}
+	}
+	return header, records, nil
+}
+
+// breakGlassCondition fails an export where a break-glass account misses an expected binding
+// or holds any other. It is checked whenever the config lists break-glass accounts.
+func breakGlassCondition(rows []*Row, resman *resourceManager) (string, error) {
+	if resman.breakGlass == nil {
+		return "", errors.New("--fail-on break-glass needs breakGlass accounts in --config")
+	}
+	missing, unexpected := 0, 0
+	for _, f := range resman.BreakGlassFindings(rows) {
+		switch f.Status {
+		case "missing":
+			missing++
+		case "unexpected":
+			unexpected++
+		}
+	}
+	if missing == 0 && unexpected == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("break-glass accounts are missing %d bindings and hold %d unexpected ones", missing, unexpected), nil
+}
+
+func init() {
+	registerReport("break-glass", breakGlassReport)
+	registerFailCondition("break-glass", breakGlassCondition)
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBreakGlassFindings(t *testing.T) {
+	accounts := []*BreakGlassAccount{
+		{Member: "user:BreakGlass@example.com", Bindings: []*BreakGlassBinding{
+			{Role: "roles/owner", Resource: "organizations/1"},
+			{Role: "roles/resourcemanager.organizationAdmin", Resource: "organizations/1"},
+		}},
+		{Member: "user:other@example.com", OrgId: "2"},
+	}
+	resman := &resourceManager{orgId: "1", breakGlass: breakGlassAccounts(accounts, "1")}
+	if len(resman.breakGlass) != 1 {
+		t.Fatalf("breakGlassAccounts kept %d accounts for org 1, want 1", len(resman.breakGlass))
+	}
+	rows := []*Row{
+		{Resource: "1", Type: "organization", Name: "organizations/1", Member: "user:breakglass@example.com", Role: "roles/owner"},
+		{Resource: "p", Type: "project", Name: "projects/p", Member: "user:breakglass@example.com", Role: "roles/editor"},
+		{Resource: "p", Type: "project", Name: "projects/p", Member: "user:someone@example.com", Role: "roles/owner"},
+	}
+	_, records, err := breakGlassReport(rows, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"user:breakglass@example.com", "organizations/1", "roles/owner", "ok"},
+		{"user:breakglass@example.com", "organizations/1", "roles/resourcemanager.organizationAdmin", "missing"},
+		{"user:breakglass@example.com", "projects/p", "roles/editor", "unexpected"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+	found, err := breakGlassCondition(rows, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(found, "missing 1 bindings and hold 1 unexpected") {
+		t.Errorf("breakGlassCondition = %q", found)
+	}
+	found, err = breakGlassCondition(rows[:1], &resourceManager{breakGlass: []*BreakGlassAccount{
+		{Member: "user:breakglass@example.com", Bindings: []*BreakGlassBinding{{Role: "roles/owner", Resource: "organizations/1"}}},
+	}})
+	if err != nil || found != "" {
+		t.Errorf("breakGlassCondition on matching bindings = %q, %v", found, err)
+	}
+	if _, err := breakGlassCondition(rows, &resourceManager{}); err == nil {
+		t.Error("breakGlassCondition without accounts in the config didn't fail")
+	}
+}
+
+func TestLoadConfigBreakGlass(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "policygopher.json")
+	write := func(data string) {
+		if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"breakGlass": [{"member": "user:bg@example.com", "bindings": [{"role": "roles/owner", "resource": "organizations/1"}]}]}`)
+	config, err := loadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.BreakGlass) != 1 || config.BreakGlass[0].Bindings[0].Role != "roles/owner" {
+		t.Errorf("breakGlass = %+v", config.BreakGlass)
+	}
+	write(`{"breakGlass": [{"member": "bg@example.com"}]}`)
+	if _, err := loadConfig(filename); err == nil {
+		t.Error("loadConfig accepted a break-glass member without a type")
+	}
+	write(`{"breakGlass": [{"member": "user:bg@example.com", "bindings": [{"role": "roles/owner"}]}]}`)
+	if _, err := loadConfig(filename); err == nil {
+		t.Error("loadConfig accepted a break-glass binding without a resource")
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const originGcsAcl = "gcs-acl"
+
+// gcsAclRoles are the legacy IAM roles Cloud Storage treats bucket ACL roles as.
+var gcsAclRoles = map[string]string{
+	"OWNER":  "roles/storage.legacyBucketOwner",
+	"WRITER": "roles/storage.legacyBucketWriter",
+	"READER": "roles/storage.legacyBucketReader",
+}
+
+// gcsObjectAclRoles are the same for default object ACLs, which only know OWNER and READER.
+var gcsObjectAclRoles = map[string]string{
+	"OWNER":  "roles/storage.legacyObjectOwner",
+	"READER": "roles/storage.legacyObjectReader",
+}
+
+type gcsAclEntry struct {
+	Entity      string `json:"entity"`
+	Role        string `json:"role"`
+	Email       string `json:"email"`
+	Domain      string `json:"domain"`
+	ProjectTeam *struct {
+		ProjectNumber string `json:"projectNumber"`
+		Team          string `json:"team"`
+	} `json:"projectTeam"`
+}
+
+type gcsBucket struct {
+	Name             string `json:"name"`
+	IamConfiguration struct {
+		UniformBucketLevelAccess struct {
+			Enabled bool `json:"enabled"`
+		} `json:"uniformBucketLevelAccess"`
+	} `json:"iamConfiguration"`
+	Acl              []*gcsAclEntry `json:"acl"`
+	DefaultObjectAcl []*gcsAclEntry `json:"defaultObjectAcl"`
+}
+
+type listBucketsResponse struct {
+	Items         []*gcsBucket `json:"items"`
+	NextPageToken string       `json:"nextPageToken"`
+}
+
+// bucketAccess is what the bucket-acls report needs to know about a bucket.
+type bucketAccess struct {
+	Project string
+	Bucket  string
+	Uniform bool
+	// ACL entries other than the project team ones every bucket starts with
+	ExtraAcls int
+}
+
+// projectTeamMembers are the convenience members IAM has for project teams in ACLs.
+var projectTeamMembers = map[string]string{
+	"owners":  "projectOwner:",
+	"editors": "projectEditor:",
+	"viewers": "projectViewer:",
+}
+
+// aclMember translates an ACL entity into an IAM member, or "" when it can't be.
+func (r *resourceManager) gcsAclMember(e *gcsAclEntry) string {
+	switch {
+	case e.Entity == "allUsers" || e.Entity == "allAuthenticatedUsers":
+		return e.Entity
+	case e.ProjectTeam != nil:
+		prefix, ok := projectTeamMembers[e.ProjectTeam.Team]
+		if !ok {
+			return ""
+		}
+		project := e.ProjectTeam.ProjectNumber
+		if id := r.ProjectIdForNumber(project); id != "" {
+			project = id
+		}
+		return prefix + project
+	case strings.HasPrefix(e.Entity, "user-") && e.Email != "":
+		if strings.HasSuffix(strings.ToLower(e.Email), ".gserviceaccount.com") {
+			return "serviceAccount:" + e.Email
+		}
+		return "user:" + e.Email
+	case strings.HasPrefix(e.Entity, "group-") && e.Email != "":
+		return "group:" + e.Email
+	case strings.HasPrefix(e.Entity, "domain-"):
+		return "domain:" + strings.TrimPrefix(e.Entity, "domain-")
+	}
+	return ""
+}
+
+// aclBindings turns ACL entries into bindings of the legacy roles they stand for.
+func (r *resourceManager) gcsAclBindings(entries []*gcsAclEntry, roles map[string]string) []*Binding {
+	bindings := make([]*Binding, 0, len(entries))
+	for _, e := range entries {
+		member := r.gcsAclMember(e)
+		role, ok := roles[e.Role]
+		if member == "" || !ok {
+			continue
+		}
+		bindings = append(bindings, &Binding{Role: role, Members: []string{member}})
+	}
+	return bindings
+}
+
+// addBucketAcls records whether each bucket of a project has uniform bucket-level access, and
+// for those that don't adds their bucket and default object ACLs as rows of the legacy storage
+// roles with the gcs-acl origin. The bucket IAM policies themselves come from --resource-policies.
+func (r *resourceManager) addBucketAcls(projectId string, rows *[]*Row) error {
+	pageToken := ""
+	for {
+		u := fmt.Sprintf("https://storage.googleapis.com/storage/v1/b?project=%s&projection=full&maxResults=1000"+
+			"&fields=nextPageToken,items(name,iamConfiguration/uniformBucketLevelAccess/enabled,acl,defaultObjectAcl)",
+			url.QueryEscape(projectId))
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		resp := &listBucketsResponse{}
+		if err := r.getJSON(u, resp); err != nil {
+			return err
+		}
+		for _, b := range resp.Items {
+			access := &bucketAccess{Project: projectId, Bucket: b.Name, Uniform: b.IamConfiguration.UniformBucketLevelAccess.Enabled}
+			r.bucketAccess = append(r.bucketAccess, access)
+			if access.Uniform {
+				continue
+			}
+			for _, e := range append(append([]*gcsAclEntry{}, b.Acl...), b.DefaultObjectAcl...) {
+				if e.ProjectTeam == nil {
+					access.ExtraAcls++
+				}
+			}
+			bindings := append(r.gcsAclBindings(b.Acl, gcsAclRoles), r.gcsAclBindings(b.DefaultObjectAcl, gcsObjectAclRoles)...)
+			addBindings(bindings, rows, Row{
+				Resource:    b.Name,
+				Type:        "bucket",
+				Parent:      fmt.Sprintf("projects/%s", projectId),
+				Name:        "//storage.googleapis.com/projects/_/buckets/" + b.Name,
+				DisplayName: b.Name,
+				Origin:      originGcsAcl,
+			})
+		}
+		if resp.NextPageToken == "" {
+			return nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// bucketAclsReport lists every bucket with whether uniform bucket-level access is on and, when
+// it isn't, how many ACL entries grant access beyond the project teams: those bypass any review
+// of the bucket's IAM policy. Buckets bypassing IAM come first.
+func bucketAclsReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Project", "Bucket", "UniformBucketLevelAccess", "ExtraAcls", "BypassesIam"}
+	if !resman.bucketAcls {
+		logerr.Printf("The bucket-acls report needs --bucket-acls\n")
+		return header, [][]string{}, nil
+	}
+	buckets := append([]*bucketAccess{}, resman.bucketAccess...)
+	bypasses := func(b *bucketAccess) bool { return !b.Uniform && b.ExtraAcls > 0 }
+	sort.Slice(buckets, func(i, j int) bool {
+		if bypasses(buckets[i]) != bypasses(buckets[j]) {
+			return bypasses(buckets[i])
+		}
+		if buckets[i].Project != buckets[j].Project {
+			return buckets[i].Project < buckets[j].Project
+		}
+		return buckets[i].Bucket < buckets[j].Bucket
+	})
+	records := make([][]string, len(buckets))
+	for i, b := range buckets {
+		records[i] = []string{b.Project, b.Bucket, strconv.FormatBool(b.Uniform), strconv.Itoa(b.ExtraAcls),
+			strconv.FormatBool(bypasses(b))}
+	}
+	return header, records, nil
+}
+
+func init() {
+	registerReport("bucket-acls", bucketAclsReport)
+	registerCollectorPermissions("bucket-acls", "storage.buckets.list", "storage.buckets.get", "storage.buckets.getIamPolicy")
+}
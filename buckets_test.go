@@ -0,0 +1,61 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGcsAclBindings(t *testing.T) {
+	r := &resourceManager{projectIds: map[string]string{"123": "web"}, projectNumbers: map[string]string{"web": "123"}}
+	team := func(n string, t string) *gcsAclEntry {
+		e := &gcsAclEntry{Entity: "project-" + t + "-" + n, Role: "OWNER"}
+		e.ProjectTeam = &struct {
+			ProjectNumber string `json:"projectNumber"`
+			Team          string `json:"team"`
+		}{n, t}
+		return e
+	}
+	entries := []*gcsAclEntry{
+		team("123", "owners"),
+		{Entity: "user-alice@example.com", Email: "alice@example.com", Role: "WRITER"},
+		{Entity: "user-deploy@web.iam.gserviceaccount.com", Email: "deploy@web.iam.gserviceaccount.com", Role: "READER"},
+		{Entity: "group-eng@example.com", Email: "eng@example.com", Role: "READER"},
+		{Entity: "domain-example.com", Domain: "example.com", Role: "READER"},
+		{Entity: "allUsers", Role: "READER"},
+		{Entity: "user-00b4903a97", Role: "READER"},
+		{Entity: "allAuthenticatedUsers", Role: "SOMETHING"},
+	}
+	want := []*Binding{
+		{Role: "roles/storage.legacyBucketOwner", Members: []string{"projectOwner:web"}},
+		{Role: "roles/storage.legacyBucketWriter", Members: []string{"user:alice@example.com"}},
+		{Role: "roles/storage.legacyBucketReader", Members: []string{"serviceAccount:deploy@web.iam.gserviceaccount.com"}},
+		{Role: "roles/storage.legacyBucketReader", Members: []string{"group:eng@example.com"}},
+		{Role: "roles/storage.legacyBucketReader", Members: []string{"domain:example.com"}},
+		{Role: "roles/storage.legacyBucketReader", Members: []string{"allUsers"}},
+	}
+	got := r.gcsAclBindings(entries, gcsAclRoles)
+	if !reflect.DeepEqual(got, want) {
+		for _, b := range got {
+			t.Logf("%s %v", b.Role, b.Members)
+		}
+		t.Errorf("gcsAclBindings differ from the expected translation")
+	}
+	objects := r.gcsAclBindings([]*gcsAclEntry{{Entity: "allUsers", Role: "READER"}, {Entity: "allUsers", Role: "WRITER"}}, gcsObjectAclRoles)
+	if len(objects) != 1 || objects[0].Role != "roles/storage.legacyObjectReader" {
+		t.Errorf("default object ACL bindings = %v, want one legacyObjectReader", objects)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"sort"
+)
+
+// set at build time with
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+//
+// commit and buildDate fall back to the vcs stamp go build embeds when built in a checkout.
+var (
+	version   = "0.0.0"
+	commit    = ""
+	buildDate = ""
+)
+
+// apiModules are the modules whose versions decide which API fields an export can see.
+var apiModules = []string{
+	"google.golang.org/api",
+	"golang.org/x/oauth2",
+	"google.golang.org/grpc",
+}
+
+// buildMetadata says which build of policygopher produced an export, so an audit artifact can
+// be traced back to the exact tool and API library versions it came from.
+type buildMetadata struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"buildDate,omitempty"`
+	GoVersion string `json:"goVersion"`
+	// module path to version of each of apiModules the binary was built with
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+var toolBuild = readBuildMetadata()
+
+func readBuildMetadata() *buildMetadata {
+	meta := &buildMetadata{Version: version, Commit: commit, BuildDate: buildDate, GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return meta
+	}
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && meta.Commit == "":
+			meta.Commit = setting.Value
+		case setting.Key == "vcs.time" && meta.BuildDate == "":
+			meta.BuildDate = setting.Value
+		}
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		for _, path := range apiModules {
+			if dep.Path == path {
+				if meta.Modules == nil {
+					meta.Modules = make(map[string]string)
+				}
+				meta.Modules[path] = dep.Version
+			}
+		}
+	}
+	return meta
+}
+
+// printVersion is what --version prints.
+func printVersion(w io.Writer, name string, meta *buildMetadata) {
+	fmt.Fprintf(w, "%s version %s\n", name, meta.Version)
+	if meta.Commit != "" {
+		fmt.Fprintf(w, "commit:     %s\n", meta.Commit)
+	}
+	if meta.BuildDate != "" {
+		fmt.Fprintf(w, "built:      %s\n", meta.BuildDate)
+	}
+	fmt.Fprintf(w, "go:         %s\n", meta.GoVersion)
+	paths := make([]string, 0, len(meta.Modules))
+	for path := range meta.Modules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(w, "%-24s%s\n", path, meta.Modules[path])
+	}
+}
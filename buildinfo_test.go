@@ -0,0 +1,51 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintVersion(t *testing.T) {
+	meta := &buildMetadata{
+		Version:   "1.2.0",
+		Commit:    "abc123",
+		BuildDate: "2020-01-02T03:04:05Z",
+		GoVersion: "go1.16",
+		Modules:   map[string]string{"google.golang.org/api": "v0.3.2", "golang.org/x/oauth2": "v0.0.0-20190402181905-9f3314589c9a"},
+	}
+	var b bytes.Buffer
+	printVersion(&b, "policygopher", meta)
+	want := `policygopher version 1.2.0
+commit:     abc123
+built:      2020-01-02T03:04:05Z
+go:         go1.16
+golang.org/x/oauth2     v0.0.0-20190402181905-9f3314589c9a
+google.golang.org/api   v0.3.2
+`
+	if b.String() != want {
+		t.Errorf("printVersion:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestPrintVersionUnstamped(t *testing.T) {
+	var b bytes.Buffer
+	printVersion(&b, "policygopher", &buildMetadata{Version: "0.0.0", GoVersion: "go1.16"})
+	want := "policygopher version 0.0.0\ngo:         go1.16\n"
+	if b.String() != want {
+		t.Errorf("printVersion = %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/api/cloudasset/v1"
+	"google.golang.org/api/option"
+	"strings"
+)
+
+// maxPolicyMembers is the most members an IAM policy can hold across all its bindings.
+const maxPolicyMembers = 1500
+
+// policyIncomplete returns why a getIamPolicy response can't be trusted to hold every binding,
+// or "" when it can. The org, folder and project policies are read at version 3, but APIs whose
+// clients can't ask for it return conditional bindings renamed with _withcond_ and no condition.
+// A policy at the member limit, or one that looks cut off (a binding without its role or
+// members, or bindings without the etag every complete policy has), is worth checking against
+// Cloud Asset Inventory too.
+func policyIncomplete(policy *Policy) string {
+	members := 0
+	for _, b := range policy.Bindings {
+		if strings.Contains(b.Role, "_withcond_") {
+			return "conditional bindings were returned without their conditions"
+		}
+		if b.Role == "" || len(b.Members) == 0 {
+			return "a binding has no role or no members, the response looks truncated"
+		}
+		members += len(b.Members)
+	}
+	if members >= maxPolicyMembers {
+		return fmt.Sprintf("%d members, at the policy limit", members)
+	}
+	if len(policy.Bindings) > 0 && policy.Etag == "" {
+		return "bindings came without an etag, the response looks truncated"
+	}
+	return ""
+}
+
+func (r *resourceManager) assetService() (*cloudasset.Service, error) {
+	if r.cai != nil {
+		return r.cai, nil
+	}
+	var clientOptions []option.ClientOption
+	if r.client != nil {
+		clientOptions = append(clientOptions, option.WithHTTPClient(r.client))
+	}
+	service, err := cloudasset.NewService(r.ctx, clientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	r.cai = service
+	return service, nil
+}
+
+// caiPolicy reads the current IAM policy of a resource from Cloud Asset Inventory.
+func (r *resourceManager) caiPolicy(name string) (*Policy, error) {
+	parent := fmt.Sprintf("organizations/%s", r.orgId)
+	if r.standaloneProject != "" {
+		parent = fmt.Sprintf("projects/%s", r.standaloneProject)
+	}
+	service, err := r.assetService()
+	if err != nil {
+		return nil, err
+	}
+	assetName := fullResourceNamePrefix + name
+	if strings.HasPrefix(name, "//") {
+		assetName = name
+	}
+	resp, err := service.V1.BatchGetAssetsHistory(parent).
+		AssetNames(assetName).
+		ContentType("IAM_POLICY").
+		Context(r.ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range resp.Assets {
+		if a.Deleted || a.Asset == nil || a.Asset.Name != assetName || a.Asset.IamPolicy == nil {
+			continue
+		}
+		policy := &Policy{}
+		policy.convertCAI(a.Asset.IamPolicy)
+		return policy, nil
+	}
+	return nil, errors.New(fmt.Sprintf("no IAM policy for %s in Cloud Asset Inventory", assetName))
+}
+
+func (p *Policy) convertCAI(policy *cloudasset.Policy) {
+	p.raw = policy
+	p.Etag = policy.Etag
+	p.Bindings = make([]*Binding, len(policy.Bindings))
+	for i, b := range policy.Bindings {
+		p.Bindings[i] = &Binding{Members: b.Members, Role: b.Role}
+		if b.Condition != nil {
+			p.Bindings[i].Condition = &Expr{
+				Description: b.Condition.Description,
+				Expression:  b.Condition.Expression,
+				Location:    b.Condition.Location,
+				Title:       b.Condition.Title,
+			}
+		}
+	}
+	p.AuditConfigs = make([]*AuditConfig, len(policy.AuditConfigs))
+	for i, c := range policy.AuditConfigs {
+		p.AuditConfigs[i] = &AuditConfig{Service: c.Service}
+		for _, l := range c.AuditLogConfigs {
+			p.AuditConfigs[i].AuditLogConfigs = append(p.AuditConfigs[i].AuditLogConfigs,
+				&AuditLogConfig{LogType: l.LogType, ExemptedMembers: l.ExemptedMembers})
+		}
+	}
+}
+
+// completePolicy swaps a policy that looks incomplete for its Cloud Asset Inventory copy,
+// keeping the original when the fallback fails.
+func (r *resourceManager) completePolicy(name string, policy *Policy) *Policy {
+	reason := policyIncomplete(policy)
+	if reason == "" {
+		return policy
+	}
+	fmt.Printf("Policy of %s may be incomplete (%s), reading it from Cloud Asset Inventory\n", name, reason)
+	cai, err := r.caiPolicy(name)
+	if err != nil {
+		logerr.Printf("Unable to read policy of %s from Cloud Asset Inventory, keeping getIamPolicy's: %v\n", name, err)
+		return policy
+	}
+	return cai
+}
+
+func init() {
+	registerCollectorPermissions("core", "cloudasset.assets.exportIamPolicy")
+}
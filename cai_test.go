@@ -0,0 +1,52 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPolicyIncomplete(t *testing.T) {
+	members := func(n int) []string {
+		list := make([]string, n)
+		for i := range list {
+			list[i] = fmt.Sprintf("user:u%d@example.com", i)
+		}
+		return list
+	}
+	tests := []struct {
+		name       string
+		policy     *Policy
+		incomplete bool
+	}{
+		{"complete", &Policy{Etag: "e", Bindings: []*Binding{{Role: "roles/viewer", Members: members(3)}}}, false},
+		{"empty", &Policy{Etag: "e"}, false},
+		{"conditions dropped", &Policy{Etag: "e", Bindings: []*Binding{{Role: "roles/viewer_withcond_2c8b", Members: members(1)}}}, true},
+		{"below the member limit", &Policy{Etag: "e", Bindings: []*Binding{
+			{Role: "roles/viewer", Members: members(1000)}, {Role: "roles/editor", Members: members(499)}}}, false},
+		{"at the member limit", &Policy{Etag: "e", Bindings: []*Binding{
+			{Role: "roles/viewer", Members: members(1000)}, {Role: "roles/editor", Members: members(500)}}}, true},
+		{"binding without members", &Policy{Etag: "e", Bindings: []*Binding{
+			{Role: "roles/viewer", Members: members(2)}, {Role: "roles/editor"}}}, true},
+		{"binding without role", &Policy{Etag: "e", Bindings: []*Binding{{Members: members(2)}}}, true},
+		{"bindings without etag", &Policy{Bindings: []*Binding{{Role: "roles/viewer", Members: members(2)}}}, true},
+	}
+	for _, tt := range tests {
+		if reason := policyIncomplete(tt.policy); (reason != "") != tt.incomplete {
+			t.Errorf("%s: policyIncomplete = %q, want incomplete %v", tt.name, reason, tt.incomplete)
+		}
+	}
+}
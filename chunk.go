@@ -0,0 +1,122 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// chunkFormats are the formats --max-rows-per-file can split, whose rows stand on their own.
+var chunkFormats = map[string]bool{"csv": true, "json": true, "ndjson": true}
+
+// manifestPart is one numbered part of a chunked export.
+type manifestPart struct {
+	File   string `json:"file"`
+	Rows   int    `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	Sha256 string `json:"sha256"`
+}
+
+// exportManifest lists the parts of an export split by --max-rows-per-file, so a loader can
+// check it has every part and that each is whole before loading them.
+type exportManifest struct {
+	SchemaVersion  int             `json:"schemaVersion"`
+	OrgId          string          `json:"orgId"`
+	Created        time.Time       `json:"created"`
+	Tool           *buildMetadata  `json:"tool"`
+	Format         string          `json:"format"`
+	MaxRowsPerFile int             `json:"maxRowsPerFile"`
+	Rows           int             `json:"rows"`
+	Parts          []*manifestPart `json:"parts"`
+}
+
+// chunkRows splits rows into consecutive parts of at most max output rows, counts[i] being the
+// output rows of rows[i]. A binding is never split, so one with more rows than max is a part of
+// its own.
+func chunkRows(rows []*Row, counts []int, max int) [][]*Row {
+	chunks := make([][]*Row, 0)
+	start, n := 0, 0
+	for i := range rows {
+		if n > 0 && n+counts[i] > max {
+			chunks = append(chunks, rows[start:i])
+			start, n = i, 0
+		}
+		n += counts[i]
+	}
+	if start < len(rows) || len(chunks) == 0 {
+		chunks = append(chunks, rows[start:])
+	}
+	return chunks
+}
+
+// partFilename numbers a part of output: member_role_permissions-00001.csv.
+func partFilename(output string, part int) string {
+	ext := filepath.Ext(output)
+	return fmt.Sprintf("%s-%05d%s", strings.TrimSuffix(output, ext), part, ext)
+}
+
+// manifestFilename is where the manifest of output's parts goes: member_role_permissions.manifest.json.
+func manifestFilename(output string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + ".manifest.json"
+}
+
+// writeChunks renders rows in parts of at most max permission rows each, then the manifest.
+func writeChunks(output string, format string, max int, rows []*Row, resman *resourceManager) error {
+	counts := make([]int, len(rows))
+	for i, row := range rows {
+		permissions, _ := resman.rowPermissions(row)
+		counts[i] = len(permissions)
+	}
+	manifest := &exportManifest{
+		SchemaVersion:  schemaVersion,
+		OrgId:          resman.orgId,
+		Created:        time.Now().UTC(),
+		Tool:           toolBuild,
+		Format:         format,
+		MaxRowsPerFile: max,
+		Parts:          make([]*manifestPart, 0),
+	}
+	for i, chunk := range chunkRows(rows, counts, max) {
+		filename := partFilename(output, i+1)
+		before := resman.permissionRows
+		if err := renderers[format](filename, chunk, resman); err != nil {
+			return err
+		}
+		c, ok := committed(filename)
+		if !ok {
+			return errors.New(fmt.Sprintf("%s wasn't written", filename))
+		}
+		part := &manifestPart{File: path.Base(filepath.ToSlash(filename)), Rows: resman.permissionRows - before,
+			Bytes: c.Bytes, Sha256: c.Sha256}
+		manifest.Parts = append(manifest.Parts, part)
+		manifest.Rows += part.Rows
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error encoding manifest: %v", err))
+	}
+	filename := manifestFilename(output)
+	if err := writeOutputFile(filename, append(data, '\n')); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d rows in %d parts listed in %s\n", manifest.Rows, len(manifest.Parts), filename)
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"google.golang.org/api/iam/v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChunkRows(t *testing.T) {
+	rows := []*Row{{Member: "a"}, {Member: "b"}, {Member: "c"}, {Member: "d"}, {Member: "e"}}
+	tests := []struct {
+		counts []int
+		max    int
+		want   []int
+	}{
+		{[]int{2, 2, 2, 2, 2}, 4, []int{2, 2, 1}},
+		{[]int{1, 5, 1, 1, 1}, 3, []int{1, 1, 3}},
+		{[]int{1, 1, 1, 1, 1}, 10, []int{5}},
+	}
+	for _, test := range tests {
+		chunks := chunkRows(rows, test.counts, test.max)
+		sizes := make([]int, len(chunks))
+		for i, c := range chunks {
+			sizes[i] = len(c)
+		}
+		if len(sizes) != len(test.want) {
+			t.Errorf("chunkRows(%v, %d) sizes = %v, want %v", test.counts, test.max, sizes, test.want)
+			continue
+		}
+		for i := range sizes {
+			if sizes[i] != test.want[i] {
+				t.Errorf("chunkRows(%v, %d) sizes = %v, want %v", test.counts, test.max, sizes, test.want)
+				break
+			}
+		}
+	}
+	if chunks := chunkRows(nil, nil, 10); len(chunks) != 1 || len(chunks[0]) != 0 {
+		t.Errorf("chunkRows() without rows = %v, want one empty part", chunks)
+	}
+}
+
+func TestWriteChunks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chunks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	resman := &resourceManager{orgId: "1", bindingRoles: make(map[string]*iam.Role)}
+	rows := []*Row{
+		{Resource: "p1", Type: "project", Member: "user:x@example.com", Role: "roles/a"},
+		{Resource: "p2", Type: "project", Member: "user:x@example.com", Role: "roles/a"},
+		{Resource: "p3", Type: "project", Member: "user:x@example.com", Role: "roles/a"},
+	}
+	for _, row := range rows {
+		resman.bindingRoles[bindingRoleKey(row)] = &iam.Role{Name: "roles/a", IncludedPermissions: []string{"a.b.get", "a.b.list"}}
+	}
+	output := filepath.Join(dir, "export.ndjson")
+	if err := writeChunks(output, "ndjson", 4, rows, resman); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "export.manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := &exportManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Rows != 6 || len(manifest.Parts) != 2 || manifest.Parts[0].File != "export-00001.ndjson" ||
+		manifest.Parts[0].Rows != 4 || manifest.Parts[1].Rows != 2 {
+		t.Fatalf("manifest = %s", data)
+	}
+	part, err := ioutil.ReadFile(filepath.Join(dir, "export-00002.ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(part)) != manifest.Parts[1].Bytes {
+		t.Errorf("part 2 has %d bytes, manifest says %d", len(part), manifest.Parts[1].Bytes)
+	}
+}
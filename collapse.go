@@ -0,0 +1,107 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// permissions weighing at least this much are named in a collapsed summary
+	notablePermissionWeight = 5
+	maxNotablePermissions   = 3
+)
+
+// collapsePermissions summarizes permissions per service, e.g. "storage: 47 permissions (incl.
+// setIamPolicy)", naming the riskiest ones by weight. A service with a single permission keeps it.
+func collapsePermissions(permissions []string, w *riskWeights) []string {
+	services := make(map[string][]string)
+	for _, p := range permissions {
+		service := p
+		if i := strings.Index(p, "."); i >= 0 {
+			service = p[:i]
+		}
+		services[service] = append(services[service], p)
+	}
+	summaries := make([]string, 0, len(services))
+	for _, service := range sortedKeys(stringKeys(services)) {
+		list := services[service]
+		if len(list) == 1 {
+			summaries = append(summaries, list[0])
+			continue
+		}
+		notable := make([]string, 0)
+		for _, p := range list {
+			if w != nil && w.Weight(p) >= notablePermissionWeight {
+				notable = append(notable, p)
+			}
+		}
+		sort.SliceStable(notable, func(i, j int) bool {
+			if a, b := w.Weight(notable[i]), w.Weight(notable[j]); a != b {
+				return a > b
+			}
+			return notable[i] < notable[j]
+		})
+		summary := fmt.Sprintf("%s: %d permissions", service, len(list))
+		if len(notable) > 0 {
+			if len(notable) > maxNotablePermissions {
+				notable = notable[:maxNotablePermissions]
+			}
+			for i, p := range notable {
+				notable[i] = strings.TrimPrefix(p, service+".")
+			}
+			summary += fmt.Sprintf(" (incl. %s)", strings.Join(notable, ", "))
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func stringKeys(m map[string][]string) map[string]bool {
+	keys := make(map[string]bool, len(m))
+	for k := range m {
+		keys[k] = true
+	}
+	return keys
+}
+
+// outputPermissions returns the permissions a row is written with, sorted, or collapsed per
+// service with --collapse-permissions.
+func (r *resourceManager) outputPermissions(row *Row) []string {
+	permissions, err := r.rowPermissions(row)
+	if err != nil {
+		logerr.Printf("Error getting permissions for %s\n", row.Role)
+	}
+	return permissions
+}
+
+// rowPermissions is outputPermissions without logging, with UNKNOWN for roles that can't be resolved.
+func (r *resourceManager) rowPermissions(row *Row) ([]string, error) {
+	permissions, err := r.GetRolePermissions(row)
+	if err != nil {
+		return []string{"UNKNOWN"}, err
+	}
+	permissions = append([]string{}, permissions...)
+	if r.permissionFilter != nil {
+		permissions = r.permissionFilter.Filter(permissions)
+	}
+	sort.Strings(permissions)
+	if r.collapsePermissions {
+		return collapsePermissions(permissions, r.riskWeights), nil
+	}
+	return permissions, nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapsePermissions(t *testing.T) {
+	w, err := loadRiskWeights("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	permissions := []string{
+		"compute.instances.get",
+		"storage.buckets.get",
+		"storage.buckets.list",
+		"storage.buckets.setIamPolicy",
+		"storage.objects.get",
+		"iam.serviceAccounts.actAs",
+		"iam.serviceAccounts.get",
+		"iam.serviceAccounts.getAccessToken",
+		"iam.serviceAccounts.signBlob",
+		"iam.serviceAccountKeys.create",
+	}
+	want := []string{
+		"compute.instances.get",
+		"iam: 5 permissions (incl. serviceAccountKeys.create, serviceAccounts.actAs, serviceAccounts.getAccessToken)",
+		"storage: 4 permissions (incl. buckets.setIamPolicy)",
+	}
+	if got := collapsePermissions(permissions, w); !reflect.DeepEqual(got, want) {
+		t.Errorf("collapsePermissions() = %q, want %q", got, want)
+	}
+	if got := collapsePermissions([]string{"storage.buckets.get", "storage.buckets.list"}, w); !reflect.DeepEqual(got, []string{"storage: 2 permissions"}) {
+		t.Errorf("collapsePermissions() without notable permissions = %q", got)
+	}
+}
@@ -0,0 +1,376 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// tristate is the value of a condition that can't always be decided from the policy alone:
+// anything depending on the request other than its time is unknown.
+type tristate int
+
+const (
+	unknown tristate = iota
+	isFalse
+	isTrue
+)
+
+func (t tristate) String() string {
+	switch t {
+	case isTrue:
+		return "true"
+	case isFalse:
+		return "false"
+	}
+	return "unknown"
+}
+
+func (t tristate) and(o tristate) tristate {
+	if t == isFalse || o == isFalse {
+		return isFalse
+	}
+	if t == isTrue && o == isTrue {
+		return isTrue
+	}
+	return unknown
+}
+
+func (t tristate) or(o tristate) tristate {
+	if t == isTrue || o == isTrue {
+		return isTrue
+	}
+	if t == isFalse && o == isFalse {
+		return isFalse
+	}
+	return unknown
+}
+
+func (t tristate) not() tristate {
+	switch t {
+	case isTrue:
+		return isFalse
+	case isFalse:
+		return isTrue
+	}
+	return unknown
+}
+
+// conditionEnv is what a condition can be evaluated against: the request time, and the name of
+// the resource when the binding is set on a resource with no descendants.
+type conditionEnv struct {
+	at           time.Time
+	resourceName string
+}
+
+// tokenizeCEL splits a CEL expression into identifiers (dotted paths included), quoted strings
+// with their quotes, numbers, and operators.
+func tokenizeCEL(expr string) ([]string, error) {
+	tokens := make([]string, 0)
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(expr) && expr[j] != c {
+				if expr[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(expr) {
+				return nil, errors.New(fmt.Sprintf("unterminated string in %q", expr))
+			}
+			tokens = append(tokens, expr[i:j+1])
+			i = j + 1
+		case c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9':
+			j := i
+			for j < len(expr) && (expr[j] == '_' || expr[j] == '.' || expr[j] >= 'a' && expr[j] <= 'z' ||
+				expr[j] >= 'A' && expr[j] <= 'Z' || expr[j] >= '0' && expr[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		default:
+			op := expr[i : i+1]
+			if i+1 < len(expr) {
+				switch two := expr[i : i+2]; two {
+				case "&&", "||", "<=", ">=", "==", "!=":
+					op = two
+				}
+			}
+			tokens = append(tokens, op)
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// unquote strips the quotes of a CEL string literal; escapes other than \" and \' are kept.
+func unquote(token string) (string, bool) {
+	if len(token) < 2 || (token[0] != '"' && token[0] != '\'') {
+		return "", false
+	}
+	s := token[1 : len(token)-1]
+	return strings.NewReplacer(`\"`, `"`, `\'`, `'`, `\\`, `\`).Replace(s), true
+}
+
+type celParser struct {
+	tokens []string
+	pos    int
+	env    *conditionEnv
+}
+
+func (p *celParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *celParser) or() tristate {
+	v := p.and()
+	for p.peek() == "||" {
+		p.pos++
+		v = v.or(p.and())
+	}
+	return v
+}
+
+func (p *celParser) and() tristate {
+	v := p.unary()
+	for p.peek() == "&&" {
+		p.pos++
+		v = v.and(p.unary())
+	}
+	return v
+}
+
+func (p *celParser) unary() tristate {
+	switch p.peek() {
+	case "!":
+		p.pos++
+		return p.unary().not()
+	case "(":
+		p.pos++
+		v := p.or()
+		if p.peek() == ")" {
+			p.pos++
+		}
+		return v
+	}
+	return p.atom()
+}
+
+// atom takes the tokens up to the next && or || outside parentheses and evaluates the few
+// forms policygopher understands, which is every form the console's condition builder writes
+// for time and resource name conditions.
+func (p *celParser) atom() tristate {
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		if depth == 0 && (t == "&&" || t == "||" || t == ")") {
+			break
+		}
+		if t == "(" {
+			depth++
+		} else if t == ")" {
+			depth--
+		}
+		p.pos++
+	}
+	return p.env.evalAtom(p.tokens[start:p.pos])
+}
+
+// timestampCall returns the time of the timestamp("...") call in the first four tokens.
+func timestampCall(tokens []string) (time.Time, bool) {
+	if len(tokens) < 4 || tokens[0] != "timestamp" || tokens[1] != "(" || tokens[3] != ")" {
+		return time.Time{}, false
+	}
+	s, ok := unquote(tokens[2])
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func compareTimes(a time.Time, op string, b time.Time) tristate {
+	var v bool
+	switch op {
+	case "<":
+		v = a.Before(b)
+	case "<=":
+		v = !a.After(b)
+	case ">":
+		v = a.After(b)
+	case ">=":
+		v = !a.Before(b)
+	case "==":
+		v = a.Equal(b)
+	case "!=":
+		v = !a.Equal(b)
+	default:
+		return unknown
+	}
+	if v {
+		return isTrue
+	}
+	return isFalse
+}
+
+func (env *conditionEnv) evalAtom(tokens []string) tristate {
+	// request.time < timestamp("2020-01-01T00:00:00Z"), either way round
+	if len(tokens) == 6 && tokens[0] == "request.time" {
+		if t, ok := timestampCall(tokens[2:]); ok {
+			return compareTimes(env.at, tokens[1], t)
+		}
+	}
+	if len(tokens) == 6 && tokens[5] == "request.time" {
+		if t, ok := timestampCall(tokens); ok {
+			return compareTimes(t, tokens[4], env.at)
+		}
+	}
+	// resource.name.startsWith("projects/_/buckets/logs-"), and the other string tests on the name
+	if len(tokens) == 4 && strings.HasPrefix(tokens[0], "resource.name.") && tokens[1] == "(" && tokens[3] == ")" &&
+		env.resourceName != "" {
+		arg, ok := unquote(tokens[2])
+		if !ok {
+			return unknown
+		}
+		var v bool
+		switch strings.TrimPrefix(tokens[0], "resource.name.") {
+		case "startsWith":
+			v = strings.HasPrefix(env.resourceName, arg)
+		case "endsWith":
+			v = strings.HasSuffix(env.resourceName, arg)
+		case "contains":
+			v = strings.Contains(env.resourceName, arg)
+		default:
+			return unknown
+		}
+		if v {
+			return isTrue
+		}
+		return isFalse
+	}
+	if len(tokens) == 1 {
+		switch tokens[0] {
+		case "true":
+			return isTrue
+		case "false":
+			return isFalse
+		}
+	}
+	return unknown
+}
+
+// evaluateCondition decides whether a condition grants access in env, or unknown when that
+// depends on more than the request time and the resource name.
+func evaluateCondition(expr string, env *conditionEnv) tristate {
+	tokens, err := tokenizeCEL(expr)
+	if err != nil {
+		return unknown
+	}
+	p := &celParser{tokens: tokens, env: env}
+	v := p.or()
+	if p.pos != len(tokens) {
+		return unknown
+	}
+	return v
+}
+
+// celResourceName is the resource.name a condition sees for a binding set directly on a
+// resource inside a project, or "" for organizations, folders, and projects, whose bindings
+// also apply to resources below them with other names.
+func celResourceName(row *Row) string {
+	switch row.Type {
+	case "organization", "folder", "project":
+		return ""
+	}
+	name := strings.TrimPrefix(row.Name, "//")
+	if i := strings.Index(name, "/"); i >= 0 && name != row.Name {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// parseConditionTime reads --evaluate-conditions-at: an RFC 3339 timestamp, or now.
+func parseConditionTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if value == "now" {
+		return time.Now().UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New(fmt.Sprintf("Invalid --evaluate-conditions-at %s, expected now or an RFC 3339 timestamp like 2020-01-31T00:00:00Z", value))
+	}
+	return t, nil
+}
+
+// conditional tells whether any binding behind the row has a condition.
+func (row *Row) conditional() bool {
+	if row.Condition != nil {
+		return true
+	}
+	for _, c := range row.merged {
+		if c != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ConditionActive is the ConditionActive column: whether the row's condition holds at the time
+// of --evaluate-conditions-at, empty for bindings without a condition. A row merged by --dedup
+// is active when any of its bindings is, and an unconditional one always is.
+func (r *resourceManager) ConditionActive(row *Row) string {
+	if !row.conditional() {
+		return ""
+	}
+	env := &conditionEnv{at: r.conditionTime, resourceName: celResourceName(row)}
+	active := isFalse
+	for _, c := range append([]*Expr{row.Condition}, row.merged...) {
+		if c == nil {
+			active = active.or(isTrue)
+			continue
+		}
+		active = active.or(evaluateCondition(c.Expression, env))
+	}
+	return active.String()
+}
+
+// printConditionSummary counts the conditional bindings by whether they are active.
+func printConditionSummary(rows []*Row, resman *resourceManager) {
+	counts := make(map[string]int)
+	for _, row := range rows {
+		if row.conditional() {
+			counts[resman.ConditionActive(row)]++
+		}
+	}
+	fmt.Printf("Conditional bindings at %s: %d active, %d inactive, %d unknown\n",
+		resman.conditionTime.Format(time.RFC3339), counts["true"], counts["false"], counts["unknown"])
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	at := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	env := &conditionEnv{at: at, resourceName: "projects/_/buckets/logs-prod"}
+	tests := []struct {
+		expr string
+		want tristate
+	}{
+		{`request.time < timestamp("2025-01-01T00:00:00Z")`, isTrue},
+		{`request.time > timestamp("2025-01-01T00:00:00Z")`, isFalse},
+		{`request.time <= timestamp("2024-06-01T00:00:00Z")`, isTrue},
+		{`timestamp("2024-01-01T00:00:00Z") < request.time`, isTrue},
+		{`!(request.time < timestamp("2025-01-01T00:00:00Z"))`, isFalse},
+		{`resource.name.startsWith("projects/_/buckets/logs-")`, isTrue},
+		{`resource.name.endsWith('-dev')`, isFalse},
+		{`resource.name.contains("logs")`, isTrue},
+		{`request.time < timestamp("2025-01-01T00:00:00Z") && resource.type == "storage.googleapis.com/Bucket"`, unknown},
+		{`request.time > timestamp("2025-01-01T00:00:00Z") && resource.type == "storage.googleapis.com/Bucket"`, isFalse},
+		{`request.time < timestamp("2025-01-01T00:00:00Z") || resource.type == "storage.googleapis.com/Bucket"`, isTrue},
+		{`(request.time > timestamp("2025-01-01T00:00:00Z") || true) && !false`, isTrue},
+		{`api.getAttribute("iam.googleapis.com/modifiedGrantsByRole", []).hasOnly(["roles/viewer"])`, unknown},
+		{`request.time < timestamp("not a time")`, unknown},
+		{`resource.name.startsWith("unterminated)`, unknown},
+	}
+	for _, tt := range tests {
+		if got := evaluateCondition(tt.expr, env); got != tt.want {
+			t.Errorf("evaluateCondition(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateConditionWithoutResourceName(t *testing.T) {
+	env := &conditionEnv{at: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	expr := `resource.name.startsWith("projects/_/buckets/logs-")`
+	if got := evaluateCondition(expr, env); got != unknown {
+		t.Errorf("evaluateCondition(%q) on a project = %s, want unknown", expr, got)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	Orgs []*OrgConfig `json:"orgs,omitempty"`
+	// emergency accounts whose bindings every export verifies, see BreakGlassFindings
+	BreakGlass []*BreakGlassAccount `json:"breakGlass,omitempty"`
+}
+
+// OrgConfig maps an organization to the credentials used to crawl it. Credentials is a service
+// account key file, and Impersonate a service account the base credentials can get tokens for.
+type OrgConfig struct {
+	OrgId       string `json:"orgId"`
+	Name        string `json:"name,omitempty"`
+	Credentials string `json:"credentials,omitempty"`
+	Impersonate string `json:"impersonate,omitempty"`
+}
+
+func loadConfig(filename string) (*Config, error) {
+	config := &Config{}
+	if filename == "" {
+		return config, nil
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error opening %s: %v", filename, err))
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error parsing config %s: %v", filename, err))
+	}
+	for i, org := range config.Orgs {
+		if org.OrgId == "" {
+			return nil, errors.New(fmt.Sprintf("Config %s: orgs[%d] has no orgId", filename, i))
+		}
+	}
+	if err := checkBreakGlass(filename, config.BreakGlass); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (o *OrgConfig) TokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	var base oauth2.TokenSource
+	if o.Credentials != "" {
+		data, err := ioutil.ReadFile(o.Credentials)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Error opening %s: %v", o.Credentials, err))
+		}
+		credentials, err := google.CredentialsFromJSON(ctx, data, cloudPlatformScope)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Error getting credentials from data in %s: %v", o.Credentials, err))
+		}
+		base = credentials.TokenSource
+	} else {
+		ts, err := google.DefaultTokenSource(ctx, cloudPlatformScope)
+		if err != nil {
+			return nil, err
+		}
+		base = ts
+	}
+	if o.Impersonate == "" {
+		return base, nil
+	}
+	return oauth2.ReuseTokenSource(nil, &impersonatedTokenSource{ctx: ctx, base: base, target: o.Impersonate}), nil
+}
+
+type impersonatedTokenSource struct {
+	ctx    context.Context
+	base   oauth2.TokenSource
+	target string
+}
+
+func (s *impersonatedTokenSource) Token() (*oauth2.Token, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"scope":    []string{cloudPlatformScope},
+		"lifetime": "3600s",
+	})
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken", s.target)
+	resp, err := oauth2.NewClient(s.ctx, s.base).Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to impersonate %s: %v", s.target, err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("Unable to impersonate %s: %s", s.target, resp.Status))
+	}
+	var token struct {
+		AccessToken string    `json:"accessToken"`
+		ExpireTime  time.Time `json:"expireTime"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to decode token for %s: %v", s.target, err))
+	}
+	return &oauth2.Token{AccessToken: token.AccessToken, TokenType: "Bearer", Expiry: token.ExpireTime}, nil
+}
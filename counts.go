@@ -0,0 +1,63 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// printBindingCounts prints how many bindings each resource type and each resource has,
+// largest first, for --count-only. They go to the real stdout even with --file -, where
+// os.Stdout has been pointed at stderr, since they are the output of the run.
+func printBindingCounts(rows []*Row, resman *resourceManager) {
+	types := make(map[string]int)
+	resources := make(map[string]int)
+	resourceRows := make(map[string]*Row)
+	for _, row := range rows {
+		types[row.Type]++
+		key := row.Type + "/" + row.Resource
+		resources[key]++
+		resourceRows[key] = row
+	}
+	typeNames := make([]string, 0, len(types))
+	for t := range types {
+		typeNames = append(typeNames, t)
+	}
+	sort.Slice(typeNames, func(i, j int) bool {
+		if types[typeNames[i]] != types[typeNames[j]] {
+			return types[typeNames[i]] > types[typeNames[j]]
+		}
+		return typeNames[i] < typeNames[j]
+	})
+	keys := make([]string, 0, len(resources))
+	for k := range resources {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if resources[keys[i]] != resources[keys[j]] {
+			return resources[keys[i]] > resources[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Fprintf(exportStdout, "%d bindings on %d resources\n", len(rows), len(resources))
+	for _, t := range typeNames {
+		fmt.Fprintf(exportStdout, "%d\t%s\n", types[t], t)
+	}
+	for _, k := range keys {
+		row := resourceRows[k]
+		fmt.Fprintf(exportStdout, "%d\t%s\t%s\n", resources[k], row.Type, resman.ResourceColumn(row))
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// coverageCounts are how many resources of a type were listed, and how many of their policies
+// were read.
+type coverageCounts struct {
+	Type       string
+	Discovered int
+	Read       int
+}
+
+// Percent is the share of discovered resources whose policy was read, 100 when none were.
+func (c *coverageCounts) Percent() float64 {
+	if c.Discovered == 0 {
+		return 100
+	}
+	return float64(c.Read) * 100 / float64(c.Discovered)
+}
+
+// Coverage counts, per resource type, the resources listed and the policies read, with the
+// totals last. A resource was discovered when its policy was read or couldn't be; folders
+// pending deletion and resources deleted while the export ran aren't counted.
+func (r *resourceManager) Coverage() []*coverageCounts {
+	byType := make(map[string]*coverageCounts)
+	types := make(map[string]bool)
+	get := func(resType string) *coverageCounts {
+		if c, ok := byType[resType]; ok {
+			return c
+		}
+		c := &coverageCounts{Type: resType}
+		byType[resType] = c
+		types[resType] = true
+		return c
+	}
+	for resType, read := range r.policiesRead {
+		c := get(resType)
+		c.Read += read
+		c.Discovered += read
+	}
+	for _, u := range r.unreadablePolicies {
+		get(u.Type).Discovered++
+	}
+	total := &coverageCounts{Type: "all"}
+	counts := make([]*coverageCounts, 0, len(byType)+1)
+	for _, resType := range sortedKeys(types) {
+		c := byType[resType]
+		total.Discovered += c.Discovered
+		total.Read += c.Read
+		counts = append(counts, c)
+	}
+	return append(counts, total)
+}
+
+func coverageReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Type", "Discovered", "Read", "Unreadable", "Coverage"}
+	if resman.offline {
+		logerr.Printf("The coverage report needs a live export, not --input\n")
+		return header, [][]string{}, nil
+	}
+	records := make([][]string, 0)
+	for _, c := range resman.Coverage() {
+		records = append(records, []string{c.Type, strconv.Itoa(c.Discovered), strconv.Itoa(c.Read),
+			strconv.Itoa(c.Discovered - c.Read), fmt.Sprintf("%.1f", c.Percent())})
+	}
+	return header, records, nil
+}
+
+func init() {
+	registerReport("coverage", coverageReport)
+}
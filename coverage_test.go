@@ -0,0 +1,52 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCoverageReport(t *testing.T) {
+	resman := &resourceManager{etags: make(map[string]string)}
+	rows := make([]*Row, 0)
+	resman.addPolicy(&Policy{}, &rows, Row{Resource: "1", Type: "organization", Name: "organizations/1"})
+	resman.addPolicy(&Policy{}, &rows, Row{Resource: "a", Type: "project", Name: "projects/a"})
+	resman.addPolicy(&Policy{}, &rows, Row{Resource: "b", Type: "project", Name: "projects/b"})
+	resman.addPolicy(&Policy{}, &rows, Row{Resource: "c", Type: "project", Name: "projects/c"})
+	resman.markUnreadable(Row{Resource: "d", Type: "project", Name: "projects/d"}, errors.New("denied"))
+	resman.markUnreadable(Row{Resource: "2", Type: "folder", Name: "folders/2"}, errors.New("denied"))
+	_, records, err := coverageReport(rows, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"folder", "1", "0", "1", "0.0"},
+		{"organization", "1", "1", "0", "100.0"},
+		{"project", "4", "3", "1", "75.0"},
+		{"all", "6", "4", "2", "66.7"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+}
+
+func TestCoverageEmpty(t *testing.T) {
+	coverage := (&resourceManager{}).Coverage()
+	if len(coverage) != 1 || coverage[0].Type != "all" || coverage[0].Percent() != 100 {
+		t.Errorf("Coverage() = %+v, want only a complete total", coverage)
+	}
+}
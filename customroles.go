@@ -0,0 +1,130 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/api/iam/v1"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// predefinedRoles lists every predefined role with its permissions, or returns those of
+// --roles-file. Listing them needs no permission of its own.
+func (r *resourceManager) predefinedRoles() ([]*iam.Role, error) {
+	if r.predefined != nil {
+		return r.predefined, nil
+	}
+	roles := make([]*iam.Role, 0)
+	err := r.service.Roles.List().View("FULL").PageSize(1000).
+		Fields("nextPageToken,roles(name,title,stage,includedPermissions)").
+		Pages(r.ctx, func(page *iam.ListRolesResponse) error {
+			roles = append(roles, page.Roles...)
+			return nil
+		})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to list predefined roles: %v", err))
+	}
+	return roles, nil
+}
+
+// closestRole picks the predefined role sharing the most permissions with permissions,
+// preferring the one that adds the fewest the custom role doesn't have.
+func closestRole(permissions []string, predefined []*iam.Role) (*iam.Role, int) {
+	has := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		has[p] = true
+	}
+	var best *iam.Role
+	bestShared := 0
+	for _, role := range predefined {
+		if role.Stage == "DEPRECATED" || role.Stage == "DISABLED" {
+			continue
+		}
+		shared := 0
+		for _, p := range role.IncludedPermissions {
+			if has[p] {
+				shared++
+			}
+		}
+		if shared == 0 {
+			continue
+		}
+		if best == nil || shared > bestShared ||
+			shared == bestShared && len(role.IncludedPermissions) < len(best.IncludedPermissions) {
+			best = role
+			bestShared = shared
+		}
+	}
+	return best, bestShared
+}
+
+// customRolesReport compares each custom role bound in the org with its closest predefined
+// role, listing the permissions it adds (Extra) and leaves out (Missing), to see which custom
+// roles could be replaced by a predefined one.
+func customRolesReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"CustomRole", "Bindings", "Permissions", "ClosestRole", "Shared", "Extra", "Missing"}
+	bindings := make(map[string]int)
+	permissions := make(map[string][]string)
+	for _, row := range rows {
+		if strings.HasPrefix(row.Role, "roles/") {
+			continue
+		}
+		bindings[row.Role]++
+		if _, ok := permissions[row.Role]; ok {
+			continue
+		}
+		p, err := resman.GetRolePermissions(row)
+		if err != nil {
+			logerr.Printf("%v\n", err)
+		}
+		permissions[row.Role] = p
+	}
+	if len(bindings) == 0 {
+		return header, [][]string{}, nil
+	}
+	predefined, err := resman.predefinedRoles()
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	records := make([][]string, len(names))
+	for i, name := range names {
+		record := []string{name, strconv.Itoa(bindings[name]), strconv.Itoa(len(permissions[name])), "", "0", "", ""}
+		if closest, shared := closestRole(permissions[name], predefined); closest != nil {
+			extra, missing := diffPermissions(permissions[name], closest.IncludedPermissions)
+			record[3] = closest.Name
+			record[4] = strconv.Itoa(shared)
+			record[5] = strings.Join(extra, " ")
+			record[6] = strings.Join(missing, " ")
+			if resman.collapsePermissions {
+				record[5] = strings.Join(collapsePermissions(extra, resman.riskWeights), "; ")
+				record[6] = strings.Join(collapsePermissions(missing, resman.riskWeights), "; ")
+			}
+		}
+		records[i] = record
+	}
+	return header, records, nil
+}
+
+func init() {
+	registerReport("custom-roles", customRolesReport)
+}
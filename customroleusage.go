@@ -0,0 +1,129 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"google.golang.org/api/iam/v1"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const customRoleFields = "nextPageToken,roles(name,title,stage,includedPermissions)"
+
+// customRoles lists the custom roles defined on the org and on each of projects, which are
+// named projects/<id>. Parents whose roles can't be listed are logged and skipped.
+func (r *resourceManager) customRoles(projects []string) []*iam.Role {
+	var mu sync.Mutex
+	roles := make([]*iam.Role, 0)
+	collect := func(page *iam.ListRolesResponse) error {
+		mu.Lock()
+		roles = append(roles, page.Roles...)
+		mu.Unlock()
+		return nil
+	}
+	if r.standaloneProject == "" {
+		parent := fmt.Sprintf("organizations/%s", r.orgId)
+		err := r.service.Organizations.Roles.List(parent).View("FULL").PageSize(1000).
+			Fields(customRoleFields).Pages(r.ctx, collect)
+		if err != nil {
+			logerr.Printf("Unable to list custom roles of %s: %v\n", parent, err)
+		}
+	}
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < roleResolveWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for project := range work {
+				err := r.service.Projects.Roles.List(project).View("FULL").PageSize(1000).
+					Fields(customRoleFields).Pages(r.ctx, collect)
+				if err != nil {
+					logerr.Printf("Unable to list custom roles of %s: %v\n", project, err)
+				}
+			}
+		}()
+	}
+	for _, project := range projects {
+		work <- project
+	}
+	close(work)
+	wg.Wait()
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles
+}
+
+type customRoleUse struct {
+	bindings int
+	members  map[string]bool
+}
+
+// customRoleUsage counts the bindings and members of each role in rows.
+func customRoleUsage(rows []*Row) map[string]*customRoleUse {
+	usage := make(map[string]*customRoleUse)
+	for _, row := range rows {
+		if strings.HasPrefix(row.Role, "roles/") {
+			continue
+		}
+		use, ok := usage[row.Role]
+		if !ok {
+			use = &customRoleUse{members: make(map[string]bool)}
+			usage[row.Role] = use
+		}
+		use.bindings++
+		use.members[row.Member] = true
+	}
+	return usage
+}
+
+// customRoleUsageRecords lists every defined role with its use, the unused ones first.
+func customRoleUsageRecords(roles []*iam.Role, usage map[string]*customRoleUse) [][]string {
+	records := make([][]string, 0, len(roles))
+	for _, role := range roles {
+		bindings, members := 0, 0
+		if use, ok := usage[role.Name]; ok {
+			bindings, members = use.bindings, len(use.members)
+		}
+		records = append(records, []string{role.Name, role.Title, role.Stage, strconv.Itoa(len(role.IncludedPermissions)),
+			strconv.Itoa(bindings), strconv.Itoa(members), strconv.FormatBool(bindings == 0)})
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i][6] == "true" && records[j][6] != "true"
+	})
+	return records
+}
+
+// customRoleUsageReport lists every custom role defined on the org or its projects with the
+// bindings and members using it. Unused ones are candidates for deletion, though bindings on
+// resources inside projects only count with --resource-policies.
+func customRoleUsageReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"CustomRole", "Title", "Stage", "Permissions", "Bindings", "Members", "Unused"}
+	projects := make(map[string]bool)
+	for _, row := range rows {
+		if row.Type == "project" && strings.HasPrefix(row.Name, "projects/") {
+			projects[row.Name] = true
+		}
+	}
+	roles := resman.customRoles(sortedKeys(projects))
+	return header, customRoleUsageRecords(roles, customRoleUsage(rows)), nil
+}
+
+func init() {
+	registerReport("custom-role-usage", customRoleUsageReport)
+	registerCollectorPermissions("custom-role-usage", "iam.roles.list")
+}
@@ -0,0 +1,47 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"google.golang.org/api/iam/v1"
+	"reflect"
+	"testing"
+)
+
+func TestCustomRoleUsageRecords(t *testing.T) {
+	rows := []*Row{
+		{Member: "user:a@example.com", Role: "organizations/1/roles/auditor"},
+		{Member: "user:b@example.com", Role: "organizations/1/roles/auditor"},
+		{Member: "user:a@example.com", Role: "organizations/1/roles/auditor", Resource: "p"},
+		{Member: "user:a@example.com", Role: "roles/viewer"},
+		{Member: "group:g@example.com", Role: "projects/p/roles/deployer"},
+	}
+	roles := []*iam.Role{
+		{Name: "organizations/1/roles/auditor", Title: "Auditor", IncludedPermissions: []string{"a", "b"}},
+		{Name: "organizations/1/roles/old", Title: "Old", Stage: "DEPRECATED"},
+		{Name: "projects/p/roles/deployer", Title: "Deployer", IncludedPermissions: []string{"c"}},
+		{Name: "projects/q/roles/unused", Title: "Unused"},
+	}
+	got := customRoleUsageRecords(roles, customRoleUsage(rows))
+	want := [][]string{
+		{"organizations/1/roles/old", "Old", "DEPRECATED", "0", "0", "0", "true"},
+		{"projects/q/roles/unused", "Unused", "", "0", "0", "0", "true"},
+		{"organizations/1/roles/auditor", "Auditor", "", "2", "3", "2", "false"},
+		{"projects/p/roles/deployer", "Deployer", "", "1", "1", "1", "false"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("customRoleUsageRecords =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// dedupRows merges rows with the same resource, member and role, which happens when a policy
+// binds a role to a member more than once, e.g. under different conditions. The first row is
+// kept in its place and its Count says how many bindings it stands for; the conditions of the
+// others are kept with it so ConditionActive considers all of them.
+func dedupRows(rows []*Row) []*Row {
+	seen := make(map[string]*Row, len(rows))
+	deduped := make([]*Row, 0, len(rows))
+	for _, row := range rows {
+		key := row.Key()
+		if first, ok := seen[key]; ok {
+			first.Count++
+			first.merged = append(first.merged, row.Condition)
+			continue
+		}
+		row.Count = 1
+		seen[key] = row
+		deduped = append(deduped, row)
+	}
+	return deduped
+}
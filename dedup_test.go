@@ -0,0 +1,63 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedupRows(t *testing.T) {
+	expired := &Expr{Title: "expired", Expression: `request.time < timestamp("2020-01-01T00:00:00Z")`}
+	later := &Expr{Title: "until 2030", Expression: `request.time < timestamp("2030-01-01T00:00:00Z")`}
+	fromIp := &Expr{Title: "office", Expression: `request.auth.access_levels.exists(l, l == "office")`}
+	bucket := Row{Resource: "b", Type: "bucket", Name: "//storage.googleapis.com/projects/_/buckets/b",
+		Member: "user:a@example.com", Role: "roles/storage.objectViewer"}
+	with := func(c *Expr) *Row {
+		row := bucket
+		row.Condition = c
+		return &row
+	}
+	other := bucket
+	other.Role = "roles/storage.admin"
+	resman := &resourceManager{conditionTime: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name       string
+		rows       []*Row
+		wantCounts []int
+		wantActive string
+	}{
+		{"distinct roles", []*Row{with(nil), &other}, []int{1, 1}, ""},
+		{"unconditional wins", []*Row{with(expired), with(nil), &other}, []int{2, 1}, "true"},
+		{"all expired", []*Row{with(expired), with(expired)}, []int{2}, "false"},
+		{"one still valid", []*Row{with(expired), with(later)}, []int{2}, "true"},
+		{"expired and undecidable", []*Row{with(expired), with(fromIp)}, []int{2}, "unknown"},
+	}
+	for _, tt := range tests {
+		deduped := dedupRows(tt.rows)
+		if len(deduped) != len(tt.wantCounts) {
+			t.Errorf("%s: %d rows, want %d", tt.name, len(deduped), len(tt.wantCounts))
+			continue
+		}
+		for i, row := range deduped {
+			if row.Count != tt.wantCounts[i] {
+				t.Errorf("%s: row %d Count = %d, want %d", tt.name, i, row.Count, tt.wantCounts[i])
+			}
+		}
+		if got := resman.ConditionActive(deduped[0]); got != tt.wantActive {
+			t.Errorf("%s: ConditionActive = %q, want %q", tt.name, got, tt.wantActive)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// deployConfig is what the deploy command fills the Terraform configuration in with.
+type deployConfig struct {
+	OrgId       string
+	Project     string
+	Region      string
+	Image       string
+	Bucket      string
+	Dataset     string
+	Schedule    string
+	Reports     string
+	Permissions []string
+}
+
+// deployTemplate runs policygopher as a Cloud Run job on a Cloud Scheduler schedule, configured
+// through its POLICYGOPHER_ environment, exporting ndjson to a bucket that a BigQuery external
+// table reads.
+var deployTemplate = template.Must(template.New("main.tf").Parse(`# Generated by policygopher deploy. Run terraform init && terraform apply in this directory.
+
+provider "google" {
+  project = "{{.Project}}"
+  region  = "{{.Region}}"
+}
+
+resource "google_service_account" "policygopher" {
+  account_id   = "policygopher"
+  display_name = "policygopher scheduled audit"
+}
+
+resource "google_organization_iam_custom_role" "auditor" {
+  org_id      = "{{.OrgId}}"
+  role_id     = "policygopherAuditor"
+  title       = "policygopher auditor"
+  permissions = [
+{{- range .Permissions}}
+    "{{.}}",
+{{- end}}
+  ]
+}
+
+resource "google_organization_iam_member" "auditor" {
+  org_id = "{{.OrgId}}"
+  role   = google_organization_iam_custom_role.auditor.id
+  member = "serviceAccount:${google_service_account.policygopher.email}"
+}
+
+resource "google_storage_bucket" "exports" {
+  name                        = "{{.Bucket}}"
+  location                    = "{{.Region}}"
+  uniform_bucket_level_access = true
+}
+
+resource "google_storage_bucket_iam_member" "writer" {
+  bucket = google_storage_bucket.exports.name
+  role   = "roles/storage.objectAdmin"
+  member = "serviceAccount:${google_service_account.policygopher.email}"
+}
+
+resource "google_cloud_run_v2_job" "policygopher" {
+  name     = "policygopher"
+  location = "{{.Region}}"
+  template {
+    template {
+      service_account = google_service_account.policygopher.email
+      timeout         = "3600s"
+      max_retries     = 1
+      containers {
+        image = "{{.Image}}"
+        env {
+          name  = "POLICYGOPHER_ORG"
+          value = "{{.OrgId}}"
+        }
+        env {
+          name  = "POLICYGOPHER_FORMAT"
+          value = "ndjson"
+        }
+        env {
+          name  = "POLICYGOPHER_FILE"
+          value = "gs://{{.Bucket}}/latest/member_role_permissions.ndjson"
+        }
+{{- if .Reports}}
+        env {
+          name  = "POLICYGOPHER_REPORTS"
+          value = "{{.Reports}}"
+        }
+        env {
+          name  = "POLICYGOPHER_REPORT_DIR"
+          value = "gs://{{.Bucket}}/latest/reports"
+        }
+{{- end}}
+      }
+    }
+  }
+}
+
+resource "google_cloud_run_v2_job_iam_member" "invoker" {
+  name     = google_cloud_run_v2_job.policygopher.name
+  location = google_cloud_run_v2_job.policygopher.location
+  role     = "roles/run.invoker"
+  member   = "serviceAccount:${google_service_account.policygopher.email}"
+}
+
+resource "google_cloud_scheduler_job" "policygopher" {
+  name     = "policygopher"
+  schedule = "{{.Schedule}}"
+  region   = "{{.Region}}"
+  http_target {
+    http_method = "POST"
+    uri         = "https://run.googleapis.com/v2/projects/{{.Project}}/locations/{{.Region}}/jobs/${google_cloud_run_v2_job.policygopher.name}:run"
+    oauth_token {
+      service_account_email = google_service_account.policygopher.email
+    }
+  }
+}
+
+resource "google_bigquery_dataset" "policygopher" {
+  dataset_id = "{{.Dataset}}"
+  location   = "{{.Region}}"
+}
+
+resource "google_bigquery_table" "bindings" {
+  dataset_id          = google_bigquery_dataset.policygopher.dataset_id
+  table_id            = "member_role_permissions"
+  deletion_protection = false
+  external_data_configuration {
+    source_format = "NEWLINE_DELIMITED_JSON"
+    autodetect    = true
+    source_uris   = ["gs://{{.Bucket}}/latest/member_role_permissions.ndjson"]
+  }
+}
+`))
+
+// renderDeploy writes the Terraform configuration of config.
+func renderDeploy(config *deployConfig) ([]byte, error) {
+	missing := make([]string, 0)
+	for name, value := range map[string]string{"--org": config.OrgId, "--host-project": config.Project,
+		"--image": config.Image, "--bucket": config.Bucket} {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, errors.New(fmt.Sprintf("deploy needs %s", strings.Join(sortedKeys(stringSet(missing)), ", ")))
+	}
+	var buf bytes.Buffer
+	if err := deployTemplate.Execute(&buf, config); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func stringSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, s := range list {
+		set[s] = true
+	}
+	return set
+}
+
+// deploy writes main.tf for config into dir, then runs terraform in it with apply.
+func deploy(config *deployConfig, collectorList string, dir string, apply bool) error {
+	collectors := []string{"core"}
+	if collectorList != "" {
+		collectors = strings.Split(collectorList, ",")
+	}
+	permissions, err := auditorPermissions(collectors)
+	if err != nil {
+		return err
+	}
+	config.Permissions = permissions
+	data, err := renderDeploy(config)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.New(fmt.Sprintf("Unable to create %s: %v", dir, err))
+	}
+	filename := filepath.Join(dir, "main.tf")
+	if err := writeFileAtomic(filename, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", filename)
+	if !apply {
+		return nil
+	}
+	for _, args := range [][]string{{"init", "-input=false"}, {"apply", "-input=false"}} {
+		cmd := exec.Command("terraform", args...)
+		cmd.Dir = dir
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return errors.New(fmt.Sprintf("terraform %s failed: %v", args[0], err))
+		}
+	}
+	return nil
+}
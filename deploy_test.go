@@ -0,0 +1,50 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderDeploy(t *testing.T) {
+	config := &deployConfig{OrgId: "123", Project: "audit", Region: "europe-west1", Image: "gcr.io/audit/policygopher",
+		Bucket: "audit-exports", Dataset: "policygopher", Schedule: "0 6 * * *", Reports: "dormant-members",
+		Permissions: []string{"iam.roles.get", "resourcemanager.projects.list"}}
+	data, err := renderDeploy(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf := string(data)
+	for _, want := range []string{
+		`org_id      = "123"`,
+		`"resourcemanager.projects.list",`,
+		`value = "gs://audit-exports/latest/member_role_permissions.ndjson"`,
+		`name  = "POLICYGOPHER_REPORTS"`,
+		`uri         = "https://run.googleapis.com/v2/projects/audit/locations/europe-west1/jobs/${google_cloud_run_v2_job.policygopher.name}:run"`,
+		`source_uris   = ["gs://audit-exports/latest/member_role_permissions.ndjson"]`,
+	} {
+		if !strings.Contains(tf, want) {
+			t.Errorf("main.tf is missing %s", want)
+		}
+	}
+	config.Reports = ""
+	if data, _ := renderDeploy(config); strings.Contains(string(data), "POLICYGOPHER_REPORT_DIR") {
+		t.Errorf("main.tf sets POLICYGOPHER_REPORT_DIR without reports")
+	}
+	if _, err := renderDeploy(&deployConfig{OrgId: "123"}); err == nil || err.Error() != "deploy needs --bucket, --host-project, --image" {
+		t.Errorf("renderDeploy() without settings = %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"google.golang.org/api/iam/v1"
+	"sort"
+)
+
+// roleStage is the launch stage a binding's role is flagged for, DELETED for a deleted custom
+// role, or empty when the role is fine to keep using.
+func roleStage(role *iam.Role) string {
+	switch {
+	case role.Deleted:
+		return "DELETED"
+	case role.Stage == "DEPRECATED" || role.Stage == "DISABLED":
+		return role.Stage
+	}
+	return ""
+}
+
+// deprecatedRolesReport lists bindings of deprecated, disabled, and deleted roles, which
+// Google may stop honouring, so they can be moved to a replacement first.
+func deprecatedRolesReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	flagged := make([]*Row, 0)
+	stages := make(map[*Row]string)
+	for _, row := range rows {
+		role, err := resman.GetRole(row)
+		if err != nil {
+			continue
+		}
+		if stage := roleStage(role); stage != "" {
+			flagged = append(flagged, row)
+			stages[row] = stage
+		}
+	}
+	sort.SliceStable(flagged, func(i, j int) bool {
+		if flagged[i].Role != flagged[j].Role {
+			return flagged[i].Role < flagged[j].Role
+		}
+		if flagged[i].Resource != flagged[j].Resource {
+			return flagged[i].Resource < flagged[j].Resource
+		}
+		return flagged[i].Member < flagged[j].Member
+	})
+	records := make([][]string, len(flagged))
+	for i, row := range flagged {
+		records[i] = []string{resman.ResourceColumn(row), row.Type, row.Member, row.Role, stages[row]}
+	}
+	return []string{"Resource", "Type", "Member", "Role", "Stage"}, records, nil
+}
+
+func init() {
+	registerReport("deprecated-roles", deprecatedRolesReport)
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// diffKey tells bindings apart for diffs by their condition as well as Key, so a binding whose
+// condition was added, changed, or removed shows up as removed and added again.
+func diffKey(row *Row) string {
+	if row.Condition == nil {
+		return row.Key()
+	}
+	return fmt.Sprintf("%s,%s,%s", row.Key(), row.Condition.Title, row.Condition.Expression)
+}
+
+// DiffRows returns the bindings present only in newRows (added) and only in oldRows (removed), sorted by key.
+func DiffRows(oldRows []*Row, newRows []*Row) ([]*Row, []*Row) {
+	oldKeys := make(map[string]*Row, len(oldRows))
+	for _, row := range oldRows {
+		oldKeys[diffKey(row)] = row
+	}
+	newKeys := make(map[string]*Row, len(newRows))
+	for _, row := range newRows {
+		newKeys[diffKey(row)] = row
+	}
+	added := make([]*Row, 0)
+	for k, row := range newKeys {
+		if _, ok := oldKeys[k]; !ok {
+			added = append(added, row)
+		}
+	}
+	removed := make([]*Row, 0)
+	for k, row := range oldKeys {
+		if _, ok := newKeys[k]; !ok {
+			removed = append(removed, row)
+		}
+	}
+	sortRowsByKey(added)
+	sortRowsByKey(removed)
+	return added, removed
+}
+
+func sortRowsByKey(rows []*Row) {
+	sort.Slice(rows, func(i, j int) bool {
+		return diffKey(rows[i]) < diffKey(rows[j])
+	})
+}
+
+// diffLine prints a binding of a diff, with its condition when it has one.
+func diffLine(row *Row) string {
+	if row.Condition == nil {
+		return row.Key()
+	}
+	return fmt.Sprintf("%s if %s", row.Key(), row.Condition.Expression)
+}
@@ -0,0 +1,69 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func rowKeys(rows []*Row) []string {
+	keys := make([]string, len(rows))
+	for i, row := range rows {
+		keys[i] = diffKey(row)
+	}
+	return keys
+}
+
+func TestDiffRows(t *testing.T) {
+	owner := &Row{Resource: "p1", Type: "project", Member: "user:a@example.com", Role: "roles/owner"}
+	viewer := &Row{Resource: "p1", Type: "project", Member: "user:a@example.com", Role: "roles/viewer"}
+	editor := &Row{Resource: "p2", Type: "project", Member: "group:g@example.com", Role: "roles/editor"}
+	until := func(date string) *Expr {
+		return &Expr{Title: "temporary", Expression: fmt.Sprintf(`request.time < timestamp("%sT00:00:00Z")`, date)}
+	}
+	withCondition := func(row *Row, c *Expr) *Row {
+		conditioned := *row
+		conditioned.Condition = c
+		return &conditioned
+	}
+	ownerUntil2025 := withCondition(owner, until("2025-01-01"))
+	ownerUntil2030 := withCondition(owner, until("2030-01-01"))
+	tests := []struct {
+		name        string
+		old, new    []*Row
+		wantAdded   []*Row
+		wantRemoved []*Row
+	}{
+		{"unchanged", []*Row{owner, viewer}, []*Row{viewer, owner}, []*Row{}, []*Row{}},
+		{"added and removed", []*Row{owner, viewer}, []*Row{viewer, editor}, []*Row{editor}, []*Row{owner}},
+		{"from nothing", nil, []*Row{viewer, owner}, []*Row{owner, viewer}, []*Row{}},
+		{"duplicates", []*Row{owner, owner}, []*Row{owner, editor, editor}, []*Row{editor}, []*Row{}},
+		{"condition added", []*Row{owner}, []*Row{ownerUntil2025}, []*Row{ownerUntil2025}, []*Row{owner}},
+		{"condition changed", []*Row{ownerUntil2025}, []*Row{ownerUntil2030}, []*Row{ownerUntil2030}, []*Row{ownerUntil2025}},
+		{"condition removed", []*Row{ownerUntil2030, viewer}, []*Row{owner, viewer}, []*Row{owner}, []*Row{ownerUntil2030}},
+		{"condition unchanged", []*Row{ownerUntil2030}, []*Row{withCondition(owner, until("2030-01-01"))}, []*Row{}, []*Row{}},
+	}
+	for _, tt := range tests {
+		added, removed := DiffRows(tt.old, tt.new)
+		if got, want := rowKeys(added), rowKeys(tt.wantAdded); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: added %v, want %v", tt.name, got, want)
+		}
+		if got, want := rowKeys(removed), rowKeys(tt.wantRemoved); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: removed %v, want %v", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const directoryUserScope = "https://www.googleapis.com/auth/admin.directory.user.readonly"
+
+// newDirectoryClient returns a client for the Admin SDK Directory API. Application default
+// credentials only work for a Workspace admin's own login; a service account needs domain-wide
+// delegation and an admin to act as, given with --admin-subject.
+func newDirectoryClient(ctx context.Context, opts *Options) (*http.Client, error) {
+	var ts oauth2.TokenSource
+	var err error
+	if opts.AdminSubject == "" {
+		if ts, err = google.DefaultTokenSource(ctx, directoryUserScope); err != nil {
+			return nil, err
+		}
+	} else {
+		if opts.CredentialsPath == "" {
+			return nil, errors.New("--admin-subject needs --credentials, a service account key with domain-wide delegation")
+		}
+		data, err := ioutil.ReadFile(opts.CredentialsPath)
+		if err != nil {
+			return nil, err
+		}
+		conf, err := google.JWTConfigFromJSON(data, directoryUserScope)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to use %s for the Directory API: %v", opts.CredentialsPath, err))
+		}
+		conf.Subject = opts.AdminSubject
+		ts = conf.TokenSource(ctx)
+	}
+	return newHTTPClient(ctx, opts, ts)
+}
+
+type directoryUser struct {
+	PrimaryEmail  string   `json:"primaryEmail"`
+	Aliases       []string `json:"aliases"`
+	Suspended     bool     `json:"suspended"`
+	LastLoginTime string   `json:"lastLoginTime"`
+}
+
+type listUsersResponse struct {
+	Users         []*directoryUser `json:"users"`
+	NextPageToken string           `json:"nextPageToken"`
+}
+
+// AnnotateUserStatus looks up every user of the Workspace or Cloud Identity account with one
+// paged list, recording whether they are suspended and when they last logged in. Users from
+// other domains aren't in the directory and stay unknown.
+func (r *resourceManager) AnnotateUserStatus(rows []*Row) error {
+	defer timeTrack("Looking up users")()
+	if r.memberStates == nil {
+		r.memberStates = make(map[string]*memberStatus)
+	}
+	users := 0
+	pageToken := ""
+	for {
+		u := "https://admin.googleapis.com/admin/directory/v1/users?customer=my_customer&maxResults=500&projection=basic" +
+			"&fields=nextPageToken,users(primaryEmail,aliases,suspended,lastLoginTime)"
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		resp := &listUsersResponse{}
+		if err := getJSONWithClient(r.directory, u, resp); err != nil {
+			return err
+		}
+		for _, user := range resp.Users {
+			status := &memberStatus{State: "ACTIVE"}
+			if user.Suspended {
+				status.State = "SUSPENDED"
+			}
+			// users who never logged in have the epoch
+			if t, err := time.Parse(time.RFC3339, user.LastLoginTime); err == nil && t.Unix() > 0 {
+				status.LastActive = t
+			}
+			for _, email := range append([]string{user.PrimaryEmail}, user.Aliases...) {
+				r.memberStates["user:"+strings.ToLower(email)] = status
+			}
+			users++
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+	fmt.Printf("Looked up %d users in the directory\n", users)
+	return nil
+}
+
+// dormantMembersReport lists members that still hold bindings but are suspended, disabled, or
+// deleted, or haven't been active for --dormant-days, riskiest first. Members whose status
+// wasn't looked up aren't listed.
+func dormantMembersReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Member", "MemberState", "MemberLastActive", "Bindings", "MemberRisk"}
+	if !resman.memberStatusColumns() {
+		logerr.Printf("The dormant-members report needs --service-account-status or --user-status\n")
+		return header, [][]string{}, nil
+	}
+	cutoff := time.Now().Add(-time.Duration(resman.dormantDays) * 24 * time.Hour)
+	bindings := make(map[string]int)
+	for _, row := range rows {
+		bindings[row.Member]++
+	}
+	dormant := make([]string, 0)
+	for member := range bindings {
+		state, _ := resman.MemberStatus(member)
+		status, known := resman.memberStates[memberStatusKey(member)]
+		switch {
+		case state == "DELETED" || state == "DISABLED" || state == "SUSPENDED":
+			dormant = append(dormant, member)
+		case known && status.LastActive.Before(cutoff):
+			dormant = append(dormant, member)
+		}
+	}
+	sort.Slice(dormant, func(i, j int) bool {
+		a, b := resman.memberRisk[dormant[i]], resman.memberRisk[dormant[j]]
+		if a != b {
+			return a > b
+		}
+		return dormant[i] < dormant[j]
+	})
+	records := make([][]string, len(dormant))
+	for i, member := range dormant {
+		state, lastActive := resman.MemberStatus(member)
+		records[i] = []string{member, state, lastActive, strconv.Itoa(bindings[member]), strconv.Itoa(resman.memberRisk[member])}
+	}
+	return header, records, nil
+}
+
+func init() {
+	registerReport("dormant-members", dormantMembersReport)
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// highestRolesPerDomain is how many of a domain's riskiest roles the member-domains report lists.
+const highestRolesPerDomain = 3
+
+// memberDomain is the email domain of a member; domain: members are their own domain and
+// allUsers and allAuthenticatedUsers are kept as they are.
+func memberDomain(member string) string {
+	if strings.HasPrefix(member, "domain:") {
+		return strings.TrimPrefix(member, "domain:")
+	}
+	email := memberEmail(member)
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		return strings.ToLower(email[at+1:])
+	}
+	return member
+}
+
+// memberDomainReport groups members by email domain, showing at a glance which outside
+// organizations have any access and how much.
+func memberDomainReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	members := make(map[string]map[string]bool)
+	bindings := make(map[string]int)
+	roleRisk := make(map[string]map[string]int)
+	for _, row := range rows {
+		d := memberDomain(row.Member)
+		if members[d] == nil {
+			members[d] = make(map[string]bool)
+			roleRisk[d] = make(map[string]int)
+		}
+		members[d][row.Member] = true
+		bindings[d]++
+		if risk, ok := roleRisk[d][row.Role]; !ok || row.Risk > risk {
+			roleRisk[d][row.Role] = row.Risk
+		}
+	}
+	domains := make([]string, 0, len(members))
+	for d := range members {
+		domains = append(domains, d)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if bindings[domains[i]] != bindings[domains[j]] {
+			return bindings[domains[i]] > bindings[domains[j]]
+		}
+		return domains[i] < domains[j]
+	})
+	records := make([][]string, len(domains))
+	for i, d := range domains {
+		roles := make([]string, 0, len(roleRisk[d]))
+		for role := range roleRisk[d] {
+			roles = append(roles, role)
+		}
+		sort.Slice(roles, func(a, b int) bool {
+			if roleRisk[d][roles[a]] != roleRisk[d][roles[b]] {
+				return roleRisk[d][roles[a]] > roleRisk[d][roles[b]]
+			}
+			return roles[a] < roles[b]
+		})
+		if len(roles) > highestRolesPerDomain {
+			roles = roles[:highestRolesPerDomain]
+		}
+		records[i] = []string{d, strconv.Itoa(len(members[d])), strconv.Itoa(bindings[d]), strings.Join(roles, " ")}
+	}
+	return []string{"Domain", "Members", "Bindings", "HighestRoles"}, records, nil
+}
+
+func init() {
+	registerReport("member-domains", memberDomainReport)
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"gopkg.in/urfave/cli.v1"
+	"strings"
+)
+
+// envVarPrefix starts the environment variables every global option can also be set with.
+const envVarPrefix = "POLICYGOPHER_"
+
+// flagEnvVar is the environment variable of a flag, e.g. POLICYGOPHER_REPORT_DIR for --report-dir,
+// named after its long name when it has a short one too.
+func flagEnvVar(name string) string {
+	name = strings.TrimSpace(strings.Split(name, ",")[0])
+	return envVarPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// withEnvVars lets every flag be set from its flagEnvVar, for runs like Cloud Run jobs that
+// are configured through their environment rather than arguments. Arguments still win, and a
+// variable the flag already reads, like GOOGLE_APPLICATION_DEFAULT, wins over its flagEnvVar.
+func withEnvVars(flags []cli.Flag) []cli.Flag {
+	for i, flag := range flags {
+		switch f := flag.(type) {
+		case cli.StringFlag:
+			f.EnvVar = appendEnvVar(f.EnvVar, flagEnvVar(f.Name))
+			flags[i] = f
+		case cli.BoolFlag:
+			f.EnvVar = appendEnvVar(f.EnvVar, flagEnvVar(f.Name))
+			flags[i] = f
+		case cli.IntFlag:
+			f.EnvVar = appendEnvVar(f.EnvVar, flagEnvVar(f.Name))
+			flags[i] = f
+		}
+	}
+	return flags
+}
+
+// appendEnvVar adds name to a flag's comma separated EnvVar, which cli reads in order.
+func appendEnvVar(envVar string, name string) string {
+	if envVar == "" {
+		return name
+	}
+	return envVar + "," + name
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"testing"
+)
+
+func TestWithEnvVars(t *testing.T) {
+	flags := withEnvVars([]cli.Flag{
+		cli.StringFlag{Name: "report-dir"},
+		cli.BoolFlag{Name: "dedup"},
+		cli.IntFlag{Name: "top"},
+		cli.StringFlag{Name: "org, o"},
+		cli.StringFlag{Name: "credentials, c", EnvVar: "GOOGLE_APPLICATION_DEFAULT"},
+	})
+	want := []string{"POLICYGOPHER_REPORT_DIR", "POLICYGOPHER_DEDUP", "POLICYGOPHER_TOP", "POLICYGOPHER_ORG",
+		"GOOGLE_APPLICATION_DEFAULT,POLICYGOPHER_CREDENTIALS"}
+	got := []string{flags[0].(cli.StringFlag).EnvVar, flags[1].(cli.BoolFlag).EnvVar, flags[2].(cli.IntFlag).EnvVar,
+		flags[3].(cli.StringFlag).EnvVar, flags[4].(cli.StringFlag).EnvVar}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("flag %d EnvVar = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWithEnvVarsKeepsExistingVariable(t *testing.T) {
+	var credentials string
+	app := cli.NewApp()
+	app.Flags = withEnvVars([]cli.Flag{
+		cli.StringFlag{Name: "credentials, c", EnvVar: "GOOGLE_APPLICATION_DEFAULT", Destination: &credentials},
+	})
+	app.Action = func(c *cli.Context) error { return nil }
+	for _, tt := range []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"GOOGLE_APPLICATION_DEFAULT": "adc.json"}, "adc.json"},
+		{map[string]string{"POLICYGOPHER_CREDENTIALS": "key.json"}, "key.json"},
+		{map[string]string{"GOOGLE_APPLICATION_DEFAULT": "adc.json", "POLICYGOPHER_CREDENTIALS": "key.json"}, "adc.json"},
+	} {
+		credentials = ""
+		os.Unsetenv("GOOGLE_APPLICATION_DEFAULT")
+		os.Unsetenv("POLICYGOPHER_CREDENTIALS")
+		for k, v := range tt.env {
+			os.Setenv(k, v)
+		}
+		if err := app.Run([]string{"policygopher"}); err != nil {
+			t.Fatal(err)
+		}
+		if credentials != tt.want {
+			t.Errorf("with %v, --credentials = %q, want %q", tt.env, credentials, tt.want)
+		}
+	}
+	os.Unsetenv("GOOGLE_APPLICATION_DEFAULT")
+	os.Unsetenv("POLICYGOPHER_CREDENTIALS")
+}
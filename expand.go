@@ -0,0 +1,58 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// roleResolveWorkers is how many roles are looked up at once; the throttling transport
+// still caps requests in flight to the IAM API at --api-concurrency.
+const roleResolveWorkers = 16
+
+// bindingRoleKey identifies the role of a binding: custom roles are looked up on the binding's
+// project or org first, so the same role name can resolve differently per resource.
+func bindingRoleKey(row *Row) string {
+	return fmt.Sprintf("%s/%s/%s", row.Type, row.Resource, row.Role)
+}
+
+// ResolveRoles looks up the role of every distinct binding concurrently, so writing the
+// output afterwards only reads the cache instead of fetching roles one row at a time.
+// Roles that fail to resolve are left for GetRole to report when the row is written.
+func (r *resourceManager) ResolveRoles(rows []*Row) {
+	defer timeTrack("Resolving roles")()
+	unique := make(map[string]*Row)
+	for _, row := range rows {
+		unique[bindingRoleKey(row)] = row
+	}
+	work := make(chan *Row)
+	var wg sync.WaitGroup
+	for i := 0; i < roleResolveWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range work {
+				r.GetRole(row)
+			}
+		}()
+	}
+	for _, row := range unique {
+		work <- row
+	}
+	close(work)
+	wg.Wait()
+	fmt.Printf("Resolved roles of %d distinct bindings\n", len(unique))
+}
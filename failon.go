@@ -0,0 +1,95 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// failConditions are the conditions --fail-on can make an export exit with failOnExitCode on.
+// Each returns what it found in the export, empty when the condition doesn't hold, or an error
+// when the export lacks what the condition needs.
+var failConditions = make(map[string]func(rows []*Row, resman *resourceManager) (string, error))
+
+// failOnExitCode is the exit status of an export that wrote its outputs but met a --fail-on
+// condition, so CI can tell it from an export that failed with 1.
+const failOnExitCode = 2
+
+// failedConditions is what the --fail-on conditions found in every org exported so far.
+var failedConditions []string
+
+func registerFailCondition(name string, condition func(rows []*Row, resman *resourceManager) (string, error)) {
+	failConditions[name] = condition
+}
+
+func failConditionNames() []string {
+	names := make([]string, 0, len(failConditions))
+	for name := range failConditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// failOnError is returned by an export that met --fail-on conditions.
+type failOnError struct {
+	findings []string
+}
+
+func (e *failOnError) Error() string {
+	return "--fail-on: " + strings.Join(e.findings, "; ")
+}
+
+// parseFailOn validates a comma separated list of --fail-on conditions.
+func parseFailOn(list string) ([]string, error) {
+	names := make([]string, 0)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := failConditions[name]; !ok {
+			return nil, errors.New(fmt.Sprintf("Unknown --fail-on %s, expected one of %s", name, strings.Join(failConditionNames(), ", ")))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// checkFailConditions records what the named conditions find in an org's export.
+func checkFailConditions(names []string, rows []*Row, resman *resourceManager) error {
+	for _, name := range names {
+		found, err := failConditions[name](rows, resman)
+		if err != nil {
+			return err
+		}
+		if found != "" {
+			failedConditions = append(failedConditions, fmt.Sprintf("%s: %s", resman.orgId, found))
+		}
+	}
+	return nil
+}
+
+// failOnResult is the error ending an export whose outputs are all written: nil, or the
+// conditions met.
+func failOnResult() error {
+	if len(failedConditions) == 0 {
+		return nil
+	}
+	return &failOnError{findings: failedConditions}
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+)
+
+type fixture struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// fixturePath keys a request by method, URL and body, since getIamPolicy calls are POSTs
+// that differ only by resource in the URL but list calls may carry page tokens in either.
+func fixturePath(dir string, req *http.Request) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.String())
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return filepath.Join(dir, hex.EncodeToString(h.Sum(nil))+".json"), nil
+}
+
+// recordingTransport saves every response as a fixture in dir; later attempts of the same
+// request (retries after quota errors) overwrite earlier ones, so the last answer wins.
+type recordingTransport struct {
+	base http.RoundTripper
+	dir  string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, err := fixturePath(t.dir, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	data, err := json.Marshal(&fixture{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to record fixture: %v", err))
+	}
+	return resp, nil
+}
+
+// replayTransport answers every request from the fixtures in dir and never touches the network.
+type replayTransport struct {
+	dir string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, err := fixturePath(t.dir, req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("No recorded response for %s %s in %s", req.Method, req.URL, t.dir))
+	}
+	f := &fixture{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to decode fixture %s: %v", path, err))
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(f.Body)),
+		ContentLength: int64(len(f.Body)),
+		Request:       req,
+	}, nil
+}
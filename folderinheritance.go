@@ -0,0 +1,165 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// where a binding is set relative to a folder
+const (
+	scopeInherited  = "inherited"
+	scopeDefined    = "defined"
+	scopeDescendant = "descendant"
+)
+
+var scopeOrder = map[string]int{scopeInherited: 0, scopeDefined: 1, scopeDescendant: 2}
+
+// resourceTree is the hierarchy of the folders and projects crawled, and of the resources seen
+// in the rows: a resource's parent and display name. Reading --input there is no crawl, and
+// only resources holding at least one binding are in it, so a folder with an empty policy
+// breaks the chain above the resources below it.
+type resourceTree struct {
+	parents      map[string]string
+	displayNames map[string]string
+}
+
+func newResourceTree(rows []*Row, resman *resourceManager) *resourceTree {
+	t := &resourceTree{parents: make(map[string]string), displayNames: make(map[string]string)}
+	for name, node := range resman.Hierarchy() {
+		if node.Parent != "" {
+			t.parents[name] = node.Parent
+		}
+		if node.DisplayName != "" {
+			t.displayNames[name] = node.DisplayName
+		}
+	}
+	for _, row := range rows {
+		name := graphResourceName(row)
+		if row.Parent != "" {
+			t.parents[name] = row.Parent
+		}
+		if row.DisplayName != "" {
+			t.displayNames[name] = row.DisplayName
+		}
+	}
+	return t
+}
+
+// ancestors returns the resources above name, nearest first.
+func (t *resourceTree) ancestors(name string) []string {
+	above := make([]string, 0)
+	seen := map[string]bool{name: true}
+	for at := t.parents[name]; at != "" && !seen[at]; at = t.parents[at] {
+		seen[at] = true
+		above = append(above, at)
+	}
+	return above
+}
+
+// path is the display names from the top of the tree down to name, joined by " / ".
+func (t *resourceTree) path(name string) string {
+	above := t.ancestors(name)
+	parts := make([]string, 0, len(above)+1)
+	for i := len(above) - 1; i >= 0; i-- {
+		parts = append(parts, t.label(above[i]))
+	}
+	return strings.Join(append(parts, t.label(name)), " / ")
+}
+
+func (t *resourceTree) label(name string) string {
+	if d, ok := t.displayNames[name]; ok {
+		return d
+	}
+	return name
+}
+
+// folderInheritanceReport lists, for every folder, the bindings it inherits from the folders
+// and org above it, those defined on it, and those defined on the folders, projects and
+// resources below it, which together are everything a folder admin's subtree grants.
+func folderInheritanceReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Folder", "Path", "Scope", "Resource", "Member", "Role"}
+	tree := newResourceTree(rows, resman)
+	folders := make(map[string]bool)
+	for _, row := range rows {
+		if row.Type == "folder" {
+			folders[graphResourceName(row)] = true
+		}
+		for _, name := range tree.ancestors(graphResourceName(row)) {
+			if strings.HasPrefix(name, "folders/") {
+				folders[name] = true
+			}
+		}
+	}
+	records := make([][]string, 0)
+	for _, row := range rows {
+		name := graphResourceName(row)
+		above := tree.ancestors(name)
+		if strings.HasPrefix(name, "folders/") {
+			records = append(records, []string{name, "", scopeDefined, name, row.Member, row.Role})
+		}
+		for _, ancestor := range above {
+			if strings.HasPrefix(ancestor, "folders/") {
+				records = append(records, []string{ancestor, "", scopeDescendant, name, row.Member, row.Role})
+			}
+		}
+		if row.Type != "organization" && row.Type != "folder" {
+			continue
+		}
+		// every folder below this one inherits its bindings
+		for folder := range folders {
+			if folder == name {
+				continue
+			}
+			for _, ancestor := range tree.ancestors(folder) {
+				if ancestor == name {
+					records = append(records, []string{folder, "", scopeInherited, name, row.Member, row.Role})
+					break
+				}
+			}
+		}
+	}
+	paths := make(map[string]string)
+	for folder := range folders {
+		paths[folder] = tree.path(folder)
+	}
+	for _, record := range records {
+		record[1] = paths[record[0]]
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a[1] != b[1] {
+			return a[1] < b[1]
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		if a[2] != b[2] {
+			return scopeOrder[a[2]] < scopeOrder[b[2]]
+		}
+		for k := 3; k < len(a); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return header, records, nil
+}
+
+func init() {
+	registerReport("folder-inheritance", folderInheritanceReport)
+}
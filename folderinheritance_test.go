@@ -0,0 +1,82 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFolderInheritanceReport(t *testing.T) {
+	rows := []*Row{
+		{Name: "organizations/1", Type: "organization", DisplayName: "example.com", Member: "group:admins@example.com", Role: "roles/owner"},
+		{Name: "folders/2", Parent: "organizations/1", Type: "folder", DisplayName: "Prod", Member: "group:prod@example.com", Role: "roles/viewer"},
+		{Name: "folders/3", Parent: "folders/2", Type: "folder", DisplayName: "Web", Member: "user:a@example.com", Role: "roles/editor"},
+		{Name: "projects/web", Parent: "folders/3", Type: "project", DisplayName: "web", Member: "user:b@example.com", Role: "roles/viewer"},
+		{Name: "//storage.googleapis.com/projects/_/buckets/logs", Parent: "projects/web", Type: "bucket", Member: "user:c@example.com", Role: "roles/storage.objectViewer"},
+	}
+	_, records, err := folderInheritanceReport(rows, &resourceManager{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(records))
+	for i, r := range records {
+		got[i] = strings.Join(r, ",")
+	}
+	want := []string{
+		"folders/2,example.com / Prod,inherited,organizations/1,group:admins@example.com,roles/owner",
+		"folders/2,example.com / Prod,defined,folders/2,group:prod@example.com,roles/viewer",
+		"folders/2,example.com / Prod,descendant,//storage.googleapis.com/projects/_/buckets/logs,user:c@example.com,roles/storage.objectViewer",
+		"folders/2,example.com / Prod,descendant,folders/3,user:a@example.com,roles/editor",
+		"folders/2,example.com / Prod,descendant,projects/web,user:b@example.com,roles/viewer",
+		"folders/3,example.com / Prod / Web,inherited,folders/2,group:prod@example.com,roles/viewer",
+		"folders/3,example.com / Prod / Web,inherited,organizations/1,group:admins@example.com,roles/owner",
+		"folders/3,example.com / Prod / Web,defined,folders/3,user:a@example.com,roles/editor",
+		"folders/3,example.com / Prod / Web,descendant,//storage.googleapis.com/projects/_/buckets/logs,user:c@example.com,roles/storage.objectViewer",
+		"folders/3,example.com / Prod / Web,descendant,projects/web,user:b@example.com,roles/viewer",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("folderInheritanceReport =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestFolderInheritanceReportFromCollectors(t *testing.T) {
+	resman := newFakeCrm(t, fakeCrmOrg)
+	rows, err := resman.GetAllPolicyRows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, records, err := folderInheritanceReport(*rows, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0)
+	for _, r := range records {
+		if r[0] == "folders/3" {
+			got = append(got, strings.Join(r, ","))
+		}
+	}
+	// Web holds no bindings itself, but is crawled and sits between Prod and project web
+	want := []string{
+		"folders/3,example.com / Prod / Web,inherited,folders/2,group:prod@example.com,roles/viewer",
+		"folders/3,example.com / Prod / Web,inherited,organizations/1,group:admins@example.com,roles/owner",
+		"folders/3,example.com / Prod / Web,descendant,projects/web,user:b@example.com,roles/viewer",
+		"folders/3,example.com / Prod / Web,descendant,projects/web,user:e@example.com,roles/owner",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("folderInheritanceReport of folders/3 =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// folderRollup totals the bindings of a folder and everything below it.
+type folderRollup struct {
+	projects     map[string]bool
+	members      map[string]bool
+	adminMembers map[string]bool
+	bindings     int
+	admin        int
+	risk         int
+}
+
+// folderRollupsReport sums up each folder's subtree: the projects in it, and the bindings,
+// members, and admin-level bindings and members defined on the folder or anywhere below it,
+// so business units can be compared at a glance. Inherited bindings aren't counted; see the
+// folder-inheritance report for those.
+func folderRollupsReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Folder", "Path", "Projects", "Bindings", "Members", "AdminBindings", "AdminMembers", "BindingRisk"}
+	tree := newResourceTree(rows, resman)
+	rollups := make(map[string]*folderRollup)
+	for _, row := range rows {
+		name := graphResourceName(row)
+		subtrees := tree.ancestors(name)
+		if row.Type == "folder" {
+			subtrees = append(subtrees, name)
+		}
+		for _, folder := range subtrees {
+			if !strings.HasPrefix(folder, "folders/") {
+				continue
+			}
+			rollup, ok := rollups[folder]
+			if !ok {
+				rollup = &folderRollup{projects: make(map[string]bool), members: make(map[string]bool),
+					adminMembers: make(map[string]bool)}
+				rollups[folder] = rollup
+			}
+			if project := rowProject(row); project != "" {
+				rollup.projects[project] = true
+			}
+			rollup.members[row.Member] = true
+			rollup.bindings++
+			rollup.risk += row.Risk
+			if isHighRiskRole(row.Role) {
+				rollup.admin++
+				rollup.adminMembers[row.Member] = true
+			}
+		}
+	}
+	records := make([][]string, 0, len(rollups))
+	for folder, r := range rollups {
+		records = append(records, []string{folder, tree.path(folder), strconv.Itoa(len(r.projects)),
+			strconv.Itoa(r.bindings), strconv.Itoa(len(r.members)), strconv.Itoa(r.admin),
+			strconv.Itoa(len(r.adminMembers)), strconv.Itoa(r.risk)})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i][1] != records[j][1] {
+			return records[i][1] < records[j][1]
+		}
+		return records[i][0] < records[j][0]
+	})
+	return header, records, nil
+}
+
+func init() {
+	registerReport("folder-rollups", folderRollupsReport)
+}
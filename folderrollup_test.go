@@ -0,0 +1,65 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFolderRollupsReport(t *testing.T) {
+	rows := []*Row{
+		{Name: "organizations/1", Type: "organization", Member: "user:root@example.com", Role: "roles/owner"},
+		{Name: "folders/2", Parent: "organizations/1", Type: "folder", DisplayName: "Prod", Member: "group:prod@example.com", Role: "roles/viewer"},
+		{Name: "folders/3", Parent: "folders/2", Type: "folder", DisplayName: "Web", Member: "user:a@example.com", Role: "roles/editor", Risk: 5},
+		{Name: "projects/web", Parent: "folders/3", Type: "project", DisplayName: "web", Member: "user:a@example.com", Role: "roles/owner", Risk: 10},
+		{Name: "projects/web", Parent: "folders/3", Type: "project", DisplayName: "web", Member: "user:b@example.com", Role: "roles/viewer"},
+		{Name: "//storage.googleapis.com/projects/_/buckets/logs", Parent: "projects/web", Type: "bucket", Member: "user:c@example.com", Role: "roles/storage.admin"},
+	}
+	header, records, err := folderRollupsReport(rows, &resourceManager{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(header) != 8 {
+		t.Fatalf("header = %v", header)
+	}
+	want := [][]string{
+		{"folders/2", "organizations/1 / Prod", "1", "5", "4", "3", "2", "15"},
+		{"folders/3", "organizations/1 / Prod / Web", "1", "4", "3", "3", "2", "15"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("folderRollupsReport() = %v, want %v", records, want)
+	}
+}
+
+func TestFolderRollupsReportFromCollectors(t *testing.T) {
+	resman := newFakeCrm(t, fakeCrmOrg)
+	rows, err := resman.GetAllPolicyRows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, records, err := folderRollupsReport(*rows, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Prod's subtree reaches project web through Web, which has no bindings of its own
+	want := [][]string{
+		{"folders/2", "example.com / Prod", "1", "3", "3", "1", "1", "0"},
+		{"folders/3", "example.com / Prod / Web", "1", "2", "2", "1", "1", "0"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("folderRollupsReport() = %v, want %v", records, want)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// gcloudConfig is the active gcloud configuration: the named set of properties that
+// `gcloud config set` changes, of which policygopher reads the default project and account.
+type gcloudConfig struct {
+	dir     string
+	Name    string
+	Project string
+	Account string
+}
+
+// gcloudConfigDir is where gcloud keeps its configurations and credentials.
+func gcloudConfigDir(getenv func(string) string) string {
+	if dir := getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(getenv("APPDATA"), "gcloud")
+	}
+	return filepath.Join(getenv("HOME"), ".config", "gcloud")
+}
+
+// parseGcloudProperties reads a gcloud configuration file, an ini file of sections of
+// key = value properties, into properties keyed by section/key.
+func parseGcloudProperties(data string) map[string]string {
+	properties := make(map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+		default:
+			i := strings.IndexAny(line, "=:")
+			if i < 0 {
+				continue
+			}
+			properties[section+"/"+strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	return properties
+}
+
+// loadGcloudConfig reads the active gcloud configuration from dir, with the CLOUDSDK_*
+// environment variables gcloud itself honors taking precedence. It returns nil when gcloud
+// was never set up.
+func loadGcloudConfig(dir string, getenv func(string) string) (*gcloudConfig, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, nil
+	}
+	config := &gcloudConfig{dir: dir, Name: getenv("CLOUDSDK_ACTIVE_CONFIG_NAME")}
+	if config.Name == "" {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "active_config"))
+		config.Name = strings.TrimSpace(string(data))
+		if err != nil || config.Name == "" {
+			config.Name = "default"
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "configurations", "config_"+config.Name))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.New(fmt.Sprintf("Unable to read gcloud configuration %s: %v", config.Name, err))
+	}
+	properties := parseGcloudProperties(string(data))
+	config.Project, config.Account = properties["core/project"], properties["core/account"]
+	if project := getenv("CLOUDSDK_CORE_PROJECT"); project != "" {
+		config.Project = project
+	}
+	if account := getenv("CLOUDSDK_CORE_ACCOUNT"); account != "" {
+		config.Account = account
+	}
+	return config, nil
+}
+
+// activeGcloudConfig is loadGcloudConfig of this user's gcloud, nil when there is none or it
+// can't be read.
+func activeGcloudConfig() *gcloudConfig {
+	config, err := loadGcloudConfig(gcloudConfigDir(os.Getenv), os.Getenv)
+	if err != nil {
+		logerr.Printf("%v\n", err)
+		return nil
+	}
+	return config
+}
+
+// credentialsFile is where gcloud keeps the credentials of the configuration's account, as
+// `gcloud auth login` stored them, usable like a credentials json.
+func (c *gcloudConfig) credentialsFile() string {
+	if c.Account == "" {
+		return ""
+	}
+	return filepath.Join(c.dir, "legacy_credentials", c.Account, "adc.json")
+}
+
+// defaultTokenSource is the token source of the credentials --login stored when there are
+// some, then of application default credentials, and otherwise of the account gcloud is
+// logged in with, so running `gcloud auth login` is enough.
+func defaultTokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	if ts, err := loginTokenSource(ctx); ts != nil || err != nil {
+		return ts, err
+	}
+	ts, err := google.DefaultTokenSource(ctx, cloudPlatformScope)
+	if err == nil {
+		return ts, nil
+	}
+	config := activeGcloudConfig()
+	if config == nil || config.credentialsFile() == "" {
+		return nil, err
+	}
+	data, ferr := ioutil.ReadFile(config.credentialsFile())
+	if ferr != nil {
+		return nil, errors.New(fmt.Sprintf("%v, and gcloud has no credentials for %s: run gcloud auth login", err, config.Account))
+	}
+	credentials, ferr := google.CredentialsFromJSON(ctx, data, cloudPlatformScope)
+	if ferr != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to use gcloud's credentials for %s: %v", config.Account, ferr))
+	}
+	fmt.Printf("Using gcloud credentials of %s from configuration %s\n", config.Account, config.Name)
+	return credentials.TokenSource, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseGcloudProperties(t *testing.T) {
+	properties := parseGcloudProperties(`# comment
+[core]
+account = alice@example.com
+project=my-project
+
+[compute]
+region = us-central1
+`)
+	want := map[string]string{"core/account": "alice@example.com", "core/project": "my-project", "compute/region": "us-central1"}
+	if len(properties) != len(want) {
+		t.Errorf("parseGcloudProperties() = %v, want %v", properties, want)
+	}
+	for k, v := range want {
+		if properties[k] != v {
+			t.Errorf("property %s = %q, want %q", k, properties[k], v)
+		}
+	}
+}
+
+func TestLoadGcloudConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gcloud")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "configurations"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "active_config"), []byte("work\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "configurations", "config_work"), []byte("[core]\nproject = work-project\naccount = a@example.com\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "configurations", "config_home"), []byte("[core]\nproject = home-project\n"), 0644)
+
+	tests := []struct {
+		env     map[string]string
+		name    string
+		project string
+		account string
+	}{
+		{map[string]string{}, "work", "work-project", "a@example.com"},
+		{map[string]string{"CLOUDSDK_ACTIVE_CONFIG_NAME": "home"}, "home", "home-project", ""},
+		{map[string]string{"CLOUDSDK_CORE_PROJECT": "other", "CLOUDSDK_CORE_ACCOUNT": "b@example.com"}, "work", "other", "b@example.com"},
+		{map[string]string{"CLOUDSDK_ACTIVE_CONFIG_NAME": "missing"}, "missing", "", ""},
+	}
+	for _, tt := range tests {
+		config, err := loadGcloudConfig(dir, func(key string) string { return tt.env[key] })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Name != tt.name || config.Project != tt.project || config.Account != tt.account {
+			t.Errorf("loadGcloudConfig(%v) = %+v, want %s %s %s", tt.env, config, tt.name, tt.project, tt.account)
+		}
+	}
+	if config, _ := loadGcloudConfig(dir, func(string) string { return "" }); config.credentialsFile() != filepath.Join(dir, "legacy_credentials", "a@example.com", "adc.json") {
+		t.Errorf("credentialsFile() = %s", config.credentialsFile())
+	}
+	if config, err := loadGcloudConfig(filepath.Join(dir, "none"), func(string) string { return "" }); config != nil || err != nil {
+		t.Errorf("loadGcloudConfig() without gcloud = %v, %v, want nil", config, err)
+	}
+}
+
+func TestGcloudConfigDir(t *testing.T) {
+	env := map[string]string{"HOME": "/home/a", "CLOUDSDK_CONFIG": "/etc/gcloud"}
+	if dir := gcloudConfigDir(func(key string) string { return env[key] }); dir != "/etc/gcloud" {
+		t.Errorf("gcloudConfigDir() = %s, want /etc/gcloud", dir)
+	}
+}
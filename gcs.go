@@ -0,0 +1,73 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// gcsPrefix starts output paths written to Cloud Storage instead of the local disk.
+const gcsPrefix = "gs://"
+
+func isGcsPath(name string) bool {
+	return strings.HasPrefix(name, gcsPrefix)
+}
+
+// splitGcsPath splits gs://bucket/object into its bucket and object.
+func splitGcsPath(name string) (string, string, error) {
+	rest := strings.TrimPrefix(name, gcsPrefix)
+	i := strings.Index(rest, "/")
+	if i <= 0 || i == len(rest)-1 {
+		return "", "", errors.New(fmt.Sprintf("Invalid Cloud Storage path %s, expected gs://<bucket>/<object>", name))
+	}
+	return rest[:i], rest[i+1:], nil
+}
+
+// outputPath joins an output directory and a file name, keeping the gs:// of Cloud Storage
+// directories that filepath.Join would fold.
+func outputPath(dir string, name string) string {
+	if isGcsPath(dir) {
+		return gcsPrefix + path.Join(strings.TrimPrefix(dir, gcsPrefix), name)
+	}
+	return filepath.Join(dir, name)
+}
+
+// uploadToGcs writes data to a gs:// path with the application default credentials, which on
+// Cloud Run and Cloud Functions are those of the service's own service account.
+func uploadToGcs(name string, data []byte) error {
+	bucket, object, err := splitGcsPath(name)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	service, err := storage.NewService(ctx, option.WithScopes(storage.DevstorageReadWriteScope))
+	if err != nil {
+		return err
+	}
+	_, err = service.Objects.Insert(bucket, &storage.Object{Name: object}).
+		Media(bytes.NewReader(data)).Context(ctx).Do()
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to upload %s: %v", name, err))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestOutputPath(t *testing.T) {
+	tests := []struct {
+		dir, name, want string
+	}{
+		{"reports", "custom-roles.csv", "reports/custom-roles.csv"},
+		{"gs://audits/reports", "custom-roles.csv", "gs://audits/reports/custom-roles.csv"},
+		{"gs://audits/", "org_1", "gs://audits/org_1"},
+	}
+	for _, test := range tests {
+		if got := outputPath(test.dir, test.name); got != test.want {
+			t.Errorf("outputPath(%s, %s) = %s, want %s", test.dir, test.name, got, test.want)
+		}
+	}
+}
+
+func TestSplitGcsPath(t *testing.T) {
+	bucket, object, err := splitGcsPath("gs://audits/2020/export.csv")
+	if err != nil || bucket != "audits" || object != "2020/export.csv" {
+		t.Errorf("splitGcsPath() = %s, %s, %v", bucket, object, err)
+	}
+	for _, name := range []string{"gs://audits", "gs://audits/", "gs:///export.csv"} {
+		if _, _, err := splitGcsPath(name); err == nil {
+			t.Errorf("splitGcsPath(%s) didn't fail", name)
+		}
+	}
+}
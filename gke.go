@@ -0,0 +1,268 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GkeCluster holds the IAM-relevant subset of a container.googleapis.com cluster.
+type GkeCluster struct {
+	ProjectId  string `json:"-"`
+	Name       string `json:"name"`
+	Location   string `json:"location"`
+	Endpoint   string `json:"endpoint"`
+	MasterAuth struct {
+		ClusterCaCertificate string `json:"clusterCaCertificate"`
+	} `json:"masterAuth"`
+	LegacyAbac struct {
+		Enabled bool `json:"enabled"`
+	} `json:"legacyAbac"`
+	WorkloadIdentityConfig struct {
+		WorkloadPool      string `json:"workloadPool"`
+		IdentityNamespace string `json:"identityNamespace"`
+	} `json:"workloadIdentityConfig"`
+	NodeConfig struct {
+		ServiceAccount string `json:"serviceAccount"`
+	} `json:"nodeConfig"`
+	NodePools []struct {
+		Name   string `json:"name"`
+		Config struct {
+			ServiceAccount string `json:"serviceAccount"`
+		} `json:"config"`
+	} `json:"nodePools"`
+}
+
+// NodeServiceAccounts lists the distinct service accounts the cluster's node pools run as.
+// NodeConfig only describes the default pool of older clusters.
+func (c *GkeCluster) NodeServiceAccounts() []string {
+	seen := make(map[string]bool)
+	accounts := make([]string, 0)
+	add := func(sa string) {
+		if sa != "" && !seen[sa] {
+			seen[sa] = true
+			accounts = append(accounts, sa)
+		}
+	}
+	for _, pool := range c.NodePools {
+		add(pool.Config.ServiceAccount)
+	}
+	if len(c.NodePools) == 0 {
+		add(c.NodeConfig.ServiceAccount)
+	}
+	sort.Strings(accounts)
+	return accounts
+}
+
+func (c *GkeCluster) WorkloadPool() string {
+	if c.WorkloadIdentityConfig.WorkloadPool != "" {
+		return c.WorkloadIdentityConfig.WorkloadPool
+	}
+	return c.WorkloadIdentityConfig.IdentityNamespace
+}
+
+type rbacBinding struct {
+	Kind     string `json:"-"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	RoleRef struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"roleRef"`
+	Subjects []struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"subjects"`
+}
+
+type rbacBindingList struct {
+	Items []rbacBinding `json:"items"`
+}
+
+func (r *resourceManager) GkeClustersList(projectId string) ([]*GkeCluster, error) {
+	var resp struct {
+		Clusters []*GkeCluster `json:"clusters"`
+	}
+	url := fmt.Sprintf("https://container.googleapis.com/v1/projects/%s/locations/-/clusters", projectId)
+	if err := r.getJSON(url, &resp); err != nil {
+		return nil, err
+	}
+	for _, c := range resp.Clusters {
+		c.ProjectId = projectId
+	}
+	return resp.Clusters, nil
+}
+
+// kubeClient talks to a cluster's master with the same credentials, throttling, accounting and
+// record/replay as every other call, trusting only the cluster CA.
+func kubeClient(ctx context.Context, opts *Options, ts oauth2.TokenSource, cluster *GkeCluster) (*http.Client, error) {
+	ca, err := base64.StdEncoding.DecodeString(cluster.MasterAuth.ClusterCaCertificate)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to decode CA for cluster %s: %v", cluster.Name, err))
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New(fmt.Sprintf("No CA certificate found for cluster %s", cluster.Name))
+	}
+	return newHTTPClientWithBase(ctx, opts, ts, &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}})
+}
+
+// tokenSource returns the token source of the client the resource manager was built with.
+func (r *resourceManager) tokenSource() oauth2.TokenSource {
+	if r.client != nil {
+		if t, ok := r.client.Transport.(*oauth2.Transport); ok {
+			return t.Source
+		}
+	}
+	return nil
+}
+
+func (r *resourceManager) GkeRbacBindings(opts *Options, cluster *GkeCluster) ([]rbacBinding, error) {
+	client, err := kubeClient(r.ctx, opts, r.tokenSource(), cluster)
+	if err != nil {
+		return nil, err
+	}
+	bindings := make([]rbacBinding, 0)
+	for _, kind := range []string{"clusterrolebindings", "rolebindings"} {
+		list := &rbacBindingList{}
+		url := fmt.Sprintf("https://%s/apis/rbac.authorization.k8s.io/v1/%s", cluster.Endpoint, kind)
+		if err := getJSONWithClient(client, url, list); err != nil {
+			return nil, errors.New(fmt.Sprintf("cluster %s: %v", cluster.Name, err))
+		}
+		for _, b := range list.Items {
+			b.Kind = kind
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings, nil
+}
+
+// googleMember maps an RBAC subject to an IAM member string, or "" if it isn't a Google identity.
+func googleMember(kind string, name string) string {
+	if !strings.Contains(name, "@") {
+		return ""
+	}
+	switch kind {
+	case "User":
+		if strings.HasSuffix(name, ".gserviceaccount.com") {
+			return "serviceAccount:" + name
+		}
+		return "user:" + name
+	case "Group":
+		return "group:" + name
+	}
+	return ""
+}
+
+func (r *resourceManager) projectMemberRoles(projectId string) (map[string][]string, error) {
+	policy, err := r.GetIamPolicyForProject(projectId)
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[string][]string)
+	for _, b := range policy.Bindings {
+		for _, m := range b.Members {
+			roles[m] = append(roles[m], b.Role)
+		}
+	}
+	for m := range roles {
+		sort.Strings(roles[m])
+	}
+	return roles, nil
+}
+
+func exportGke(opts *Options, filename string, rbacFilename string) error {
+	ctx := context.Background()
+	resman, err := newResourceManagerFromOptions(ctx, opts, nil)
+	if err != nil {
+		return err
+	}
+	projects, err := resman.ProjectsList()
+	if err != nil {
+		return err
+	}
+	clusterRecords := make([][]string, 0)
+	rbacRecords := make([][]string, 0)
+	for _, p := range projects {
+		clusters, err := resman.GkeClustersList(p.ProjectId)
+		if err != nil {
+			logerr.Printf("Unable to list GKE clusters in project %s: %v\n", p.ProjectId, err)
+			continue
+		}
+		if len(clusters) == 0 {
+			continue
+		}
+		fmt.Printf("Found %d GKE clusters in project %s\n", len(clusters), p.ProjectId)
+		var memberRoles map[string][]string
+		if rbacFilename != "" {
+			if memberRoles, err = resman.projectMemberRoles(p.ProjectId); err != nil {
+				logerr.Printf("Unable to get IAM policy for project %s: %v\n", p.ProjectId, err)
+			}
+		}
+		for _, c := range clusters {
+			clusterRecords = append(clusterRecords, []string{
+				c.ProjectId, c.Name, c.Location,
+				strconv.FormatBool(c.LegacyAbac.Enabled), c.WorkloadPool(), strings.Join(c.NodeServiceAccounts(), " "),
+			})
+			if rbacFilename == "" {
+				continue
+			}
+			bindings, err := resman.GkeRbacBindings(opts, c)
+			if err != nil {
+				logerr.Printf("Unable to list RBAC bindings: %v\n", err)
+				continue
+			}
+			for _, b := range bindings {
+				for _, s := range b.Subjects {
+					member := googleMember(s.Kind, s.Name)
+					if member == "" {
+						continue
+					}
+					rbacRecords = append(rbacRecords, []string{
+						c.ProjectId, c.Name, b.Metadata.Namespace, b.Kind, b.Metadata.Name,
+						fmt.Sprintf("%s/%s", b.RoleRef.Kind, b.RoleRef.Name), member,
+						strings.Join(memberRoles[member], ";"),
+					})
+				}
+			}
+		}
+	}
+	err = writeReport(filename,
+		[]string{"Project", "Cluster", "Location", "LegacyAbac", "WorkloadPool", "NodeServiceAccount"},
+		clusterRecords)
+	if err != nil || rbacFilename == "" {
+		return err
+	}
+	return writeReport(rbacFilename,
+		[]string{"Project", "Cluster", "Namespace", "BindingKind", "Binding", "KubernetesRole", "Member", "ProjectIamRoles"},
+		rbacRecords)
+}
+
+func init() {
+	registerCollectorPermissions("gke", "container.clusters.list", "resourcemanager.projects.getIamPolicy")
+	registerCollectorPermissions("gke-rbac", "container.clusterRoleBindings.list", "container.roleBindings.list")
+}
@@ -1,7 +1,10 @@
 module github.com/glickbot/policygopher
 
 require (
+	github.com/golang/protobuf v1.2.0
+	golang.org/x/net v0.0.0-20190311183353-d8887717615a
 	golang.org/x/oauth2 v0.0.0-20190402181905-9f3314589c9a
 	google.golang.org/api v0.3.2
+	google.golang.org/grpc v1.19.0
 	gopkg.in/urfave/cli.v1 v1.20.0
 )
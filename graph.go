@@ -0,0 +1,102 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var cypherEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+func cypherString(s string) string {
+	return "'" + cypherEscaper.Replace(s) + "'"
+}
+
+func cypherList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, s := range items {
+		quoted[i] = cypherString(s)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// graphResourceName gives every resource the name its children use as their parent.
+func graphResourceName(row *Row) string {
+	if row.Name != "" {
+		return row.Name
+	}
+	switch row.Type {
+	case "organization":
+		return fmt.Sprintf("organizations/%s", row.Resource)
+	case "project":
+		return fmt.Sprintf("projects/%s", row.Resource)
+	}
+	return row.Resource
+}
+
+// writeCypher writes a cypher-shell script loading the rows as a property graph:
+// (Member)-[:MEMBER_OF]->(Binding)-[:GRANTS]->(Role)-[:INCLUDES]->(Permission),
+// (Binding)-[:ON]->(Resource)-[:CHILD_OF]->(Resource).
+func writeCypher(filename string, rows []*Row, resman *resourceManager) error {
+	f, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	defer timeTrack(fmt.Sprintf("Printing Cypher %s", filename))()
+	fmt.Printf("Printing Cypher %s\n", filename)
+	w := f.Writer
+	for _, label := range []string{"Member:id", "Resource:name", "Role:name", "Permission:name", "Binding:id"} {
+		parts := strings.Split(label, ":")
+		fmt.Fprintf(w, "CREATE CONSTRAINT IF NOT EXISTS FOR (n:%s) REQUIRE n.%s IS UNIQUE;\n", parts[0], parts[1])
+	}
+	roles := make(map[string]*Row)
+	for _, row := range rows {
+		resource := graphResourceName(row)
+		binding := fmt.Sprintf("%s|%s", resource, row.Role)
+		fmt.Fprintf(w, "MERGE (m:Member {id: %s}) SET m.class = %s "+
+			"MERGE (res:Resource {name: %s}) SET res.type = %s, res.displayName = %s "+
+			"MERGE (r:Role {name: %s}) "+
+			"MERGE (b:Binding {id: %s}) SET b.risk = %d "+
+			"MERGE (m)-[:MEMBER_OF]->(b) MERGE (b)-[:GRANTS]->(r) MERGE (b)-[:ON]->(res)",
+			cypherString(row.Member), cypherString(memberClass(row.Member)),
+			cypherString(resource), cypherString(row.Type), cypherString(row.DisplayName),
+			cypherString(row.Role), cypherString(binding), row.Risk)
+		if row.Parent != "" {
+			fmt.Fprintf(w, " MERGE (p:Resource {name: %s}) MERGE (res)-[:CHILD_OF]->(p)", cypherString(row.Parent))
+		}
+		fmt.Fprint(w, ";\n")
+		if _, ok := roles[row.Role]; !ok {
+			roles[row.Role] = row
+		}
+	}
+	names := make([]string, 0, len(roles))
+	for name := range roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		permissions, err := resman.GetRolePermissions(roles[name])
+		if err != nil {
+			logerr.Printf("%v\n", err)
+			continue
+		}
+		fmt.Fprintf(w, "MATCH (r:Role {name: %s}) UNWIND %s AS name MERGE (p:Permission {name: name}) MERGE (r)-[:INCLUDES]->(p);\n",
+			cypherString(name), cypherList(permissions))
+	}
+	return f.Commit()
+}
@@ -0,0 +1,161 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "github.com/glickbot/policygopher/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net"
+	"sort"
+)
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. proto/policygopher.proto
+
+type policyGopherServer struct {
+	store *snapshotStore
+	// org whose latest snapshot is served when a request names neither snapshot nor org
+	orgId string
+}
+
+func (s *policyGopherServer) load(orgId string, id string) (*Snapshot, error) {
+	if id == "" {
+		if orgId == "" {
+			orgId = s.orgId
+		}
+		snap, err := s.store.LatestOf(orgId)
+		if err != nil {
+			if errors.Is(err, errSeveralOrgs) {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if snap == nil {
+			return nil, status.Error(codes.NotFound, "no snapshots saved yet")
+		}
+		return snap, nil
+	}
+	// the id comes from the client, so only ids of stored snapshots are opened
+	stored, err := s.store.Has(id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !stored {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("no snapshot %q in the store", id))
+	}
+	snap, err := s.store.Load(id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return snap, nil
+}
+
+func toPolicyRow(row *Row) *pb.PolicyRow {
+	return &pb.PolicyRow{
+		Resource: row.Resource,
+		Type:     row.Type,
+		Member:   row.Member,
+		Role:     row.Role,
+		Parent:   row.Parent,
+		Risk:     int32(row.Risk),
+	}
+}
+
+func toPolicyRows(rows []*Row) []*pb.PolicyRow {
+	converted := make([]*pb.PolicyRow, len(rows))
+	for i, row := range rows {
+		converted[i] = toPolicyRow(row)
+	}
+	return converted
+}
+
+func (s *policyGopherServer) StreamPolicyRows(req *pb.StreamPolicyRowsRequest, stream pb.PolicyGopher_StreamPolicyRowsServer) error {
+	snap, err := s.load(req.OrgId, req.SnapshotId)
+	if err != nil {
+		return err
+	}
+	for _, row := range snap.Rows {
+		if err := stream.Send(toPolicyRow(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *policyGopherServer) GetMemberAccess(ctx context.Context, req *pb.GetMemberAccessRequest) (*pb.GetMemberAccessResponse, error) {
+	if req.Member == "" {
+		return nil, status.Error(codes.InvalidArgument, "member is required")
+	}
+	snap, err := s.load(req.OrgId, req.SnapshotId)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.GetMemberAccessResponse{SnapshotId: snap.Id}
+	permissions := make(map[string]bool)
+	for _, row := range snap.Rows {
+		if row.Member != req.Member {
+			continue
+		}
+		resp.Rows = append(resp.Rows, toPolicyRow(row))
+		for _, p := range snap.Roles[row.Role] {
+			permissions[p] = true
+		}
+	}
+	for p := range permissions {
+		resp.Permissions = append(resp.Permissions, p)
+	}
+	sort.Strings(resp.Permissions)
+	return resp, nil
+}
+
+func (s *policyGopherServer) DiffSnapshots(ctx context.Context, req *pb.DiffSnapshotsRequest) (*pb.DiffSnapshotsResponse, error) {
+	if req.OldId == "" {
+		return nil, status.Error(codes.InvalidArgument, "old_id is required")
+	}
+	oldSnap, err := s.load(req.OrgId, req.OldId)
+	if err != nil {
+		return nil, err
+	}
+	newSnap, err := s.load(req.OrgId, req.NewId)
+	if err != nil {
+		return nil, err
+	}
+	added, removed := DiffRows(oldSnap.Rows, newSnap.Rows)
+	return &pb.DiffSnapshotsResponse{
+		OldId:   oldSnap.Id,
+		NewId:   newSnap.Id,
+		Added:   toPolicyRows(added),
+		Removed: toPolicyRows(removed),
+	}, nil
+}
+
+func serveGrpc(storeDir string, orgId string, listen string) error {
+	store, err := NewSnapshotStore(storeDir)
+	if err != nil {
+		return err
+	}
+	lis, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer()
+	pb.RegisterPolicyGopherServer(server, &policyGopherServer{store: store, orgId: orgId})
+	fmt.Printf("Serving snapshots from %s over gRPC on %s\n", store.dir, lis.Addr())
+	return server.Serve(lis)
+}
@@ -0,0 +1,55 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServerLoadOnlyOpensStoredSnapshots(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewSnapshotStore(filepath.Join(root, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := &Snapshot{Id: "20240102T030405Z-1", OrgId: "1", Rows: []*Row{{Resource: "1", Type: "organization", Member: "user:a@example.com", Role: "roles/viewer"}}}
+	if err := store.Save(snap); err != nil {
+		t.Fatal(err)
+	}
+	// a valid snapshot outside the store, which a crafted id must not reach
+	outside := &snapshotStore{dir: root}
+	if err := outside.Save(&Snapshot{Id: "secret", OrgId: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "secret"+snapshotExt)); err != nil {
+		t.Fatal(err)
+	}
+	server := &policyGopherServer{store: store}
+	if got, err := server.load("", snap.Id); err != nil || got.Id != snap.Id {
+		t.Errorf("load(%s) = %v, %v", snap.Id, got, err)
+	}
+	for _, id := range []string{"../secret", "..", "sub/../../secret", `..\secret`, "missing"} {
+		if _, err := server.load("", id); status.Code(err) != codes.NotFound {
+			t.Errorf("load(%q) error = %v, want NotFound", id, err)
+		}
+	}
+	if _, err := store.Load("../secret"); err == nil {
+		t.Errorf("store.Load opened a snapshot outside the store")
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// permissionHeatmap counts the distinct permissions each member holds in each service, across
+// every binding, to see at a glance who has how much power where.
+type permissionHeatmap struct {
+	Services []string `json:"services"`
+	Members  []string `json:"members"`
+	// Counts[i][j] is the number of Services[j] permissions Members[i] holds
+	Counts [][]int `json:"counts"`
+	Totals []int   `json:"totals"`
+}
+
+// buildPermissionHeatmap makes the heatmap of rows, honoring --service, --permission-resource,
+// and --verb. Members hold their most powerful permissions first.
+func buildPermissionHeatmap(rows []*Row, resman *resourceManager) *permissionHeatmap {
+	held := make(map[string]map[string]map[string]bool)
+	services := make(map[string]bool)
+	for _, row := range rows {
+		permissions, err := resman.GetRolePermissions(row)
+		if err != nil {
+			continue
+		}
+		if resman.permissionFilter != nil {
+			permissions = resman.permissionFilter.Filter(permissions)
+		}
+		for _, p := range permissions {
+			service := parsePermission(p).Service
+			if service == "" {
+				continue
+			}
+			if held[row.Member] == nil {
+				held[row.Member] = make(map[string]map[string]bool)
+			}
+			if held[row.Member][service] == nil {
+				held[row.Member][service] = make(map[string]bool)
+			}
+			held[row.Member][service][p] = true
+			services[service] = true
+		}
+	}
+	heatmap := &permissionHeatmap{Services: sortedKeys(services), Members: make([]string, 0, len(held))}
+	totals := make(map[string]int)
+	for member, byService := range held {
+		heatmap.Members = append(heatmap.Members, member)
+		for _, permissions := range byService {
+			totals[member] += len(permissions)
+		}
+	}
+	sort.Slice(heatmap.Members, func(i, j int) bool {
+		a, b := heatmap.Members[i], heatmap.Members[j]
+		if totals[a] != totals[b] {
+			return totals[a] > totals[b]
+		}
+		return a < b
+	})
+	heatmap.Counts = make([][]int, len(heatmap.Members))
+	heatmap.Totals = make([]int, len(heatmap.Members))
+	for i, member := range heatmap.Members {
+		heatmap.Counts[i] = make([]int, len(heatmap.Services))
+		for j, service := range heatmap.Services {
+			heatmap.Counts[i][j] = len(held[member][service])
+		}
+		heatmap.Totals[i] = totals[member]
+	}
+	return heatmap
+}
+
+// permissionHeatmapReport writes the heatmap as a matrix, a row per member and a column per
+// service, with each member's total last.
+func permissionHeatmapReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	heatmap := buildPermissionHeatmap(rows, resman)
+	header := append(append([]string{"Member", "MemberClass"}, heatmap.Services...), "Total")
+	records := make([][]string, len(heatmap.Members))
+	for i, member := range heatmap.Members {
+		record := []string{member, memberClass(member)}
+		for _, count := range heatmap.Counts[i] {
+			record = append(record, fmt.Sprint(count))
+		}
+		records[i] = append(record, fmt.Sprint(heatmap.Totals[i]))
+	}
+	return header, records, nil
+}
+
+// writePermissionHeatmapJSON writes the same matrix as json, ready for plotting libraries that
+// take the axes and a two-dimensional array.
+func writePermissionHeatmapJSON(filename string, rows []*Row, resman *resourceManager) error {
+	data, err := json.MarshalIndent(buildPermissionHeatmap(rows, resman), "", "  ")
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error encoding permission heatmap: %v", err))
+	}
+	return writeOutputFile(filename, append(data, '\n'))
+}
+
+func init() {
+	registerReport("permission-heatmap", permissionHeatmapReport)
+	registerReportJSON("permission-heatmap", writePermissionHeatmapJSON)
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"google.golang.org/api/iam/v1"
+	"reflect"
+	"testing"
+)
+
+func heatmapRows() ([]*Row, *resourceManager) {
+	resman := &resourceManager{bindingRoles: make(map[string]*iam.Role)}
+	rows := []*Row{
+		{Resource: "p", Type: "project", Name: "projects/p", Member: "user:a@example.com", Role: "roles/storage.admin"},
+		{Resource: "q", Type: "project", Name: "projects/q", Member: "user:a@example.com", Role: "roles/storage.objectViewer"},
+		{Resource: "p", Type: "project", Name: "projects/p", Member: "user:b@example.com", Role: "roles/compute.viewer"},
+	}
+	permissions := map[string][]string{
+		"roles/storage.admin":        {"storage.buckets.get", "storage.objects.get", "storage.objects.delete"},
+		"roles/storage.objectViewer": {"storage.objects.get", "storage.objects.list"},
+		"roles/compute.viewer":       {"compute.instances.get", "storage.buckets.get"},
+	}
+	for _, row := range rows {
+		resman.bindingRoles[bindingRoleKey(row)] = &iam.Role{Name: row.Role, IncludedPermissions: permissions[row.Role]}
+	}
+	return rows, resman
+}
+
+func TestPermissionHeatmap(t *testing.T) {
+	rows, resman := heatmapRows()
+	heatmap := buildPermissionHeatmap(rows, resman)
+	want := &permissionHeatmap{
+		Services: []string{"compute", "storage"},
+		Members:  []string{"user:a@example.com", "user:b@example.com"},
+		Counts:   [][]int{{0, 4}, {1, 1}},
+		Totals:   []int{4, 2},
+	}
+	if !reflect.DeepEqual(heatmap, want) {
+		t.Errorf("heatmap = %+v, want %+v", heatmap, want)
+	}
+
+	header, records, err := permissionHeatmapReport(rows, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Member", "MemberClass", "compute", "storage", "Total"}; !reflect.DeepEqual(header, want) {
+		t.Errorf("header = %v, want %v", header, want)
+	}
+	wantRecords := [][]string{
+		{"user:a@example.com", "customer", "0", "4", "4"},
+		{"user:b@example.com", "customer", "1", "1", "2"},
+	}
+	if !reflect.DeepEqual(records, wantRecords) {
+		t.Errorf("records = %v, want %v", records, wantRecords)
+	}
+}
+
+func TestPermissionHeatmapFiltered(t *testing.T) {
+	rows, resman := heatmapRows()
+	resman.permissionFilter = newPermissionFilter("compute", "", "")
+	heatmap := buildPermissionHeatmap(rows, resman)
+	if !reflect.DeepEqual(heatmap.Services, []string{"compute"}) || !reflect.DeepEqual(heatmap.Members, []string{"user:b@example.com"}) {
+		t.Errorf("heatmap = %+v, want only compute and user:b@example.com", heatmap)
+	}
+}
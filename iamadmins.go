@@ -0,0 +1,104 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// iamAdminReach is how far a binding's IAM admin power goes, by the type of resource it is on:
+// a policy set on the org, a folder, or a project is inherited by everything below it.
+var iamAdminReach = map[string]string{
+	"organization": "organization",
+	"folder":       "folder and below",
+	"project":      "project and below",
+}
+
+// iamAdminReachOrder lists the widest reach first; any other resource only reaches itself.
+var iamAdminReachOrder = map[string]int{
+	"organization":      0,
+	"folder and below":  1,
+	"project and below": 2,
+	"resource":          3,
+}
+
+// isAdminRole matches roles/*.admin, e.g. roles/storage.admin: predefined roles administering
+// a whole service, policies included.
+func isAdminRole(role string) bool {
+	return strings.HasPrefix(role, "roles/") && strings.HasSuffix(role, ".admin")
+}
+
+// setIamPolicyPermissions are the permissions among permissions that change an IAM policy.
+func setIamPolicyPermissions(permissions []string) []string {
+	found := make([]string, 0)
+	for _, p := range permissions {
+		if strings.HasSuffix(p, ".setIamPolicy") {
+			found = append(found, p)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// iamAdminsReport lists every binding that lets its member change IAM policies, through a
+// *.setIamPolicy permission or a roles/*.admin role, with how far that reaches. Whoever can set
+// a policy can grant themselves anything else within that reach, so these are the members the
+// rest of the export's trust rests on. The widest reach comes first.
+func iamAdminsReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Member", "MemberClass", "Resource", "Type", "ResourceName", "Role", "AdminRole", "SetIamPolicy",
+		"Reach", "Conditional"}
+	type iamAdmin struct {
+		row   *Row
+		set   []string
+		reach string
+	}
+	admins := make([]*iamAdmin, 0)
+	for _, row := range rows {
+		// a role that can't be resolved is still matched by name
+		permissions, _ := resman.GetRolePermissions(row)
+		set := setIamPolicyPermissions(permissions)
+		if len(set) == 0 && !isAdminRole(row.Role) {
+			continue
+		}
+		reach, ok := iamAdminReach[row.Type]
+		if !ok {
+			reach = "resource"
+		}
+		admins = append(admins, &iamAdmin{row: row, set: set, reach: reach})
+	}
+	sort.SliceStable(admins, func(i, j int) bool {
+		a, b := admins[i], admins[j]
+		if a.reach != b.reach {
+			return iamAdminReachOrder[a.reach] < iamAdminReachOrder[b.reach]
+		}
+		if a.row.Member != b.row.Member {
+			return a.row.Member < b.row.Member
+		}
+		return a.row.Key() < b.row.Key()
+	})
+	records := make([][]string, len(admins))
+	for i, a := range admins {
+		records[i] = []string{a.row.Member, memberClass(a.row.Member), resman.ResourceColumn(a.row), a.row.Type, a.row.Name,
+			a.row.Role, strconv.FormatBool(isAdminRole(a.row.Role)), strings.Join(a.set, " "), a.reach,
+			strconv.FormatBool(a.row.Condition != nil)}
+	}
+	return header, records, nil
+}
+
+func init() {
+	registerReport("iam-admins", iamAdminsReport)
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"google.golang.org/api/iam/v1"
+	"reflect"
+	"testing"
+)
+
+func TestIamAdminsReport(t *testing.T) {
+	resman := &resourceManager{bindingRoles: make(map[string]*iam.Role)}
+	rows := []*Row{
+		{Resource: "p", Type: "project", Name: "projects/p", Member: "user:b@example.com", Role: "roles/resourcemanager.projectIamAdmin"},
+		{Resource: "b", Type: "bucket", Name: "//storage.googleapis.com/b", Member: "user:c@example.com", Role: "roles/storage.admin"},
+		{Resource: "1", Type: "organization", Name: "organizations/1", Member: "user:z@example.com", Role: "roles/owner",
+			Condition: &Expr{Expression: "true"}},
+		{Resource: "p", Type: "project", Name: "projects/p", Member: "user:a@example.com", Role: "roles/viewer"},
+	}
+	permissions := map[string][]string{
+		"roles/resourcemanager.projectIamAdmin": {"resourcemanager.projects.setIamPolicy", "resourcemanager.projects.getIamPolicy"},
+		"roles/storage.admin":                   {"storage.buckets.setIamPolicy", "storage.objects.setIamPolicy", "storage.buckets.get"},
+		"roles/owner":                           {"resourcemanager.organizations.setIamPolicy", "iam.serviceAccounts.setIamPolicy"},
+		"roles/viewer":                          {"resourcemanager.projects.get"},
+	}
+	for _, row := range rows {
+		resman.bindingRoles[bindingRoleKey(row)] = &iam.Role{Name: row.Role, IncludedPermissions: permissions[row.Role]}
+	}
+	_, records, err := iamAdminsReport(rows, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"user:z@example.com", "customer", "1", "organization", "organizations/1", "roles/owner", "false",
+			"iam.serviceAccounts.setIamPolicy resourcemanager.organizations.setIamPolicy", "organization", "true"},
+		{"user:b@example.com", "customer", "p", "project", "projects/p", "roles/resourcemanager.projectIamAdmin", "false",
+			"resourcemanager.projects.setIamPolicy", "project and below", "false"},
+		{"user:c@example.com", "customer", "//storage.googleapis.com/b", "bucket", "//storage.googleapis.com/b", "roles/storage.admin", "true",
+			"storage.buckets.setIamPolicy storage.objects.setIamPolicy", "resource", "false"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %v\nwant %v", records, want)
+	}
+}
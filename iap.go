@@ -0,0 +1,116 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"google.golang.org/api/compute/v1"
+)
+
+// iapResource is a resource IAP keeps its own IAM policy on, below projects/<number>/.
+type iapResource struct {
+	path        string
+	resource    string
+	resType     string
+	displayName string
+}
+
+// getIapPolicy reads the IAM policy IAP keeps on a resource, with conditions.
+func (r *resourceManager) getIapPolicy(path string) (*Policy, error) {
+	var raw json.RawMessage
+	body := map[string]interface{}{"options": map[string]int{"requestedPolicyVersion": 3}}
+	if err := r.postJSON(fmt.Sprintf("https://iap.googleapis.com/v1/%s:getIamPolicy", path), body, &raw); err != nil {
+		return nil, err
+	}
+	policy := &Policy{}
+	if err := json.Unmarshal(raw, policy); err != nil {
+		return nil, err
+	}
+	policy.raw = raw
+	return policy, nil
+}
+
+// iapResources lists what IAP protects in a project: all web apps and all TCP forwarding
+// tunnels at the project level, the App Engine app, and each backend service with IAP enabled.
+func (r *resourceManager) iapResources(projectId string, number string) []*iapResource {
+	base := fmt.Sprintf("projects/%s", number)
+	resources := []*iapResource{
+		{path: base + "/iap_web", resource: projectId, resType: "iap_web", displayName: projectId},
+		{path: base + "/iap_tunnel", resource: projectId, resType: "iap_tunnel", displayName: projectId},
+		{path: fmt.Sprintf("%s/iap_web/appengine-%s", base, projectId), resource: projectId, resType: "iap_appengine", displayName: projectId},
+	}
+	service, err := r.computeService()
+	if err != nil {
+		logerr.Printf("%v\n", err)
+		return resources
+	}
+	err = service.BackendServices.List(projectId).Fields("nextPageToken,items(id,name,iap/enabled)").
+		Pages(r.ctx, func(page *compute.BackendServiceList) error {
+			for _, bs := range page.Items {
+				if bs.Iap == nil || !bs.Iap.Enabled {
+					continue
+				}
+				resources = append(resources, &iapResource{
+					path:        fmt.Sprintf("%s/iap_web/compute/services/%d", base, bs.Id),
+					resource:    bs.Name,
+					resType:     "iap_backend_service",
+					displayName: bs.Name,
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		logerr.Printf("Unable to list backend services of project %s: %v\n", projectId, err)
+	}
+	return resources
+}
+
+// addIapPolicies adds the policies of a project's IAP-protected apps and tunnels, where
+// roles/iap.httpsResourceAccessor and roles/iap.tunnelResourceAccessor decide who gets through.
+// Projects without an App Engine app answer 404 for it, which isn't an error.
+func (r *resourceManager) addIapPolicies(projectId string, rows *[]*Row) {
+	number := r.ProjectNumberForId(projectId)
+	if number == "" {
+		logerr.Printf("Unable to find the number of project %s for its IAP policies\n", projectId)
+		return
+	}
+	for _, res := range r.iapResources(projectId, number) {
+		policy, err := r.getIapPolicy(res.path)
+		if err != nil {
+			if !isNotFound(err) {
+				logerr.Printf("Unable to get IAP policy of %s: %v\n", res.path, err)
+			}
+			continue
+		}
+		r.resourcesScanned++
+		r.addPolicy(policy, rows, Row{
+			Resource:    res.resource,
+			Type:        res.resType,
+			Parent:      fmt.Sprintf("projects/%s", projectId),
+			Name:        "//iap.googleapis.com/" + res.path,
+			DisplayName: res.displayName,
+		})
+	}
+}
+
+func init() {
+	registerCollectorPermissions("iap",
+		"compute.backendServices.list",
+		"iap.web.getIamPolicy",
+		"iap.webTypes.getIamPolicy",
+		"iap.webServices.getIamPolicy",
+		"iap.tunnel.getIamPolicy")
+}
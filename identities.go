@@ -0,0 +1,271 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// terminatedStatuses are the employment statuses, lowercased, of people who have left.
+var terminatedStatuses = map[string]bool{
+	"terminated": true,
+	"inactive":   true,
+	"separated":  true,
+	"former":     true,
+	"offboarded": true,
+	"left":       true,
+}
+
+// identityHeaders name the columns of an HR csv export, lowercased, that hold each field.
+var identityHeaders = map[string][]string{
+	"email":      {"email", "work email", "primaryemail", "mail", "username"},
+	"department": {"department", "dept"},
+	"manager":    {"manager", "manager email", "manageremail"},
+	"status":     {"employmentstatus", "employment status", "status", "active"},
+}
+
+// identity is what an HR or identity provider export says about a person.
+type identity struct {
+	Email            string
+	Department       string
+	Manager          string
+	EmploymentStatus string
+}
+
+// Terminated tells whether the person has left.
+func (i *identity) Terminated() bool {
+	return terminatedStatuses[strings.ToLower(i.EmploymentStatus)]
+}
+
+// identityStatus reads a status column, which a boolean active column gives as true or false.
+func identityStatus(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true":
+		return "active"
+	case "false":
+		return "inactive"
+	}
+	return strings.TrimSpace(value)
+}
+
+// loadIdentities reads --identities, a csv export with a header row or a SCIM json document of
+// users, keyed by lowercased email.
+func loadIdentities(filename string) (map[string]*identity, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error opening %s: %v", filename, err))
+	}
+	var people []*identity
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		people, err = readScimIdentities(data)
+	} else {
+		people, err = readCsvIdentities(data)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error parsing identities in %s: %v", filename, err))
+	}
+	identities := make(map[string]*identity, len(people))
+	for _, person := range people {
+		if person.Email != "" {
+			identities[strings.ToLower(person.Email)] = person
+		}
+	}
+	fmt.Printf("Read %d people from %s\n", len(identities), filename)
+	return identities, nil
+}
+
+func readCsvIdentities(data []byte) ([]*identity, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no header row")
+	}
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for field, names := range identityHeaders {
+			for _, n := range names {
+				if _, ok := columns[field]; !ok && name == n {
+					columns[field] = i
+				}
+			}
+		}
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New(fmt.Sprintf("no email column, expected one of %s", strings.Join(identityHeaders["email"], ", ")))
+	}
+	get := func(record []string, field string) string {
+		if i, ok := columns[field]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	people := make([]*identity, 0, len(records)-1)
+	for _, record := range records[1:] {
+		people = append(people, &identity{
+			Email:            get(record, "email"),
+			Department:       get(record, "department"),
+			Manager:          get(record, "manager"),
+			EmploymentStatus: identityStatus(get(record, "status")),
+		})
+	}
+	return people, nil
+}
+
+type scimUser struct {
+	Id       string `json:"id"`
+	UserName string `json:"userName"`
+	Active   *bool  `json:"active"`
+	Emails   []struct {
+		Value   string `json:"value"`
+		Primary bool   `json:"primary"`
+	} `json:"emails"`
+	// the enterprise extension has the department and manager
+	Enterprise struct {
+		Department string `json:"department"`
+		Manager    struct {
+			Value       string `json:"value"`
+			DisplayName string `json:"displayName"`
+		} `json:"manager"`
+	} `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+}
+
+// email is the user's primary email, or their userName when it is one.
+func (u *scimUser) email() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	if strings.Contains(u.UserName, "@") {
+		return u.UserName
+	}
+	return ""
+}
+
+// readScimIdentities reads a SCIM ListResponse, or a json array of SCIM users. A manager is
+// given by id, which is looked up among the users.
+func readScimIdentities(data []byte) ([]*identity, error) {
+	var users []*scimUser
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &users); err != nil {
+			return nil, err
+		}
+	} else {
+		var list struct {
+			Resources []*scimUser `json:"Resources"`
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+		users = list.Resources
+	}
+	emails := make(map[string]string, len(users))
+	for _, u := range users {
+		emails[u.Id] = u.email()
+	}
+	people := make([]*identity, 0, len(users))
+	for _, u := range users {
+		person := &identity{Email: u.email(), Department: u.Enterprise.Department}
+		if m := u.Enterprise.Manager; m.Value != "" && emails[m.Value] != "" {
+			person.Manager = emails[m.Value]
+		} else if m.DisplayName != "" {
+			person.Manager = m.DisplayName
+		} else {
+			person.Manager = m.Value
+		}
+		if u.Active != nil {
+			person.EmploymentStatus = identityStatus(fmt.Sprint(*u.Active))
+		}
+		people = append(people, person)
+	}
+	return people, nil
+}
+
+func (r *resourceManager) identityColumns() bool {
+	return r.identities != nil
+}
+
+// Identity is the person a user member is, nil when the identities don't have them. A deleted
+// user is matched by the email they had.
+func (r *resourceManager) Identity(member string) *identity {
+	member = strings.TrimPrefix(member, "deleted:")
+	if !strings.HasPrefix(member, "user:") {
+		return nil
+	}
+	email := strings.TrimPrefix(member, "user:")
+	if i := strings.Index(email, "?uid="); i >= 0 {
+		email = email[:i]
+	}
+	return r.identities[strings.ToLower(email)]
+}
+
+// IdentityColumns are the Department, Manager, and EmploymentStatus columns of a member.
+func (r *resourceManager) IdentityColumns(member string) (string, string, string) {
+	person := r.Identity(member)
+	if person == nil {
+		return "", "", ""
+	}
+	return person.Department, person.Manager, person.EmploymentStatus
+}
+
+// terminatedMembersReport lists the bindings still held by people the identities say have left,
+// riskiest first for each: these should have been removed when they left.
+func terminatedMembersReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Member", "Department", "Manager", "EmploymentStatus", "Resource", "Type", "ResourceName", "Role", "BindingRisk"}
+	if !resman.identityColumns() {
+		logerr.Printf("The terminated-members report needs --identities\n")
+		return header, [][]string{}, nil
+	}
+	held := make([]*Row, 0)
+	for _, row := range rows {
+		if person := resman.Identity(row.Member); person != nil && person.Terminated() {
+			held = append(held, row)
+		}
+	}
+	sort.SliceStable(held, func(i, j int) bool {
+		if held[i].Member != held[j].Member {
+			return held[i].Member < held[j].Member
+		}
+		return held[i].Risk > held[j].Risk
+	})
+	records := make([][]string, len(held))
+	for i, row := range held {
+		department, manager, status := resman.IdentityColumns(row.Member)
+		records[i] = []string{row.Member, department, manager, status, resman.ResourceColumn(row), row.Type, row.Name, row.Role,
+			fmt.Sprint(row.Risk)}
+	}
+	return header, records, nil
+}
+
+func init() {
+	registerReport("terminated-members", terminatedMembersReport)
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"google.golang.org/api/iam/v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeIdentities(t *testing.T, name string, data string) string {
+	dir, err := ioutil.TempDir("", "identities")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	filename := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(filename, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadCsvIdentities(t *testing.T) {
+	filename := writeIdentities(t, "hr.csv", `Name,Work Email,Dept,Manager Email,Active
+Alice,Alice@Example.com,Finance,carol@example.com,true
+Bob,bob@example.com,Sales,carol@example.com,false
+Nobody,,Sales,,true
+`)
+	identities, err := loadIdentities(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*identity{
+		"alice@example.com": {Email: "Alice@Example.com", Department: "Finance", Manager: "carol@example.com", EmploymentStatus: "active"},
+		"bob@example.com":   {Email: "bob@example.com", Department: "Sales", Manager: "carol@example.com", EmploymentStatus: "inactive"},
+	}
+	if !reflect.DeepEqual(identities, want) {
+		t.Errorf("identities = %v, want %v", identities, want)
+	}
+	if _, err := loadIdentities(writeIdentities(t, "bad.csv", "Name,Dept\nAlice,Finance\n")); err == nil {
+		t.Errorf("loading a csv without an email column succeeded")
+	}
+}
+
+func TestLoadScimIdentities(t *testing.T) {
+	filename := writeIdentities(t, "users.json", `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+  "Resources": [
+    {"id": "1", "userName": "carol@example.com", "active": true},
+    {"id": "2", "userName": "alice", "active": false,
+     "emails": [{"value": "alice.old@example.com"}, {"value": "alice@example.com", "primary": true}],
+     "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"department": "Finance", "manager": {"value": "1"}}},
+    {"id": "3", "userName": "dan@example.com",
+     "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"manager": {"value": "9", "displayName": "Erin"}}}
+  ]
+}`)
+	identities, err := loadIdentities(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*identity{
+		"carol@example.com": {Email: "carol@example.com", EmploymentStatus: "active"},
+		"alice@example.com": {Email: "alice@example.com", Department: "Finance", Manager: "carol@example.com", EmploymentStatus: "inactive"},
+		"dan@example.com":   {Email: "dan@example.com", Manager: "Erin"},
+	}
+	if !reflect.DeepEqual(identities, want) {
+		t.Errorf("identities = %v, want %v", identities, want)
+	}
+}
+
+func TestTerminatedMembersReport(t *testing.T) {
+	resman := &resourceManager{identities: map[string]*identity{
+		"alice@example.com": {Email: "alice@example.com", Department: "Finance", Manager: "carol@example.com", EmploymentStatus: "Terminated"},
+		"bob@example.com":   {Email: "bob@example.com", EmploymentStatus: "active"},
+	}}
+	rows := []*Row{
+		{Resource: "p", Type: "project", Name: "projects/p", Member: "user:alice@example.com", Role: "roles/viewer", Risk: 1},
+		{Resource: "p", Type: "project", Name: "projects/p", Member: "deleted:user:Alice@example.com?uid=123", Role: "roles/owner", Risk: 9},
+		{Resource: "p", Type: "project", Name: "projects/p", Member: "user:alice@example.com", Role: "roles/editor", Risk: 5},
+		{Resource: "p", Type: "project", Name: "projects/p", Member: "user:bob@example.com", Role: "roles/owner", Risk: 9},
+		{Resource: "p", Type: "project", Name: "projects/p", Member: "group:alice@example.com", Role: "roles/owner", Risk: 9},
+	}
+	_, records, err := terminatedMembersReport(rows, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"deleted:user:Alice@example.com?uid=123", "Finance", "carol@example.com", "Terminated", "p", "project", "projects/p", "roles/owner", "9"},
+		{"user:alice@example.com", "Finance", "carol@example.com", "Terminated", "p", "project", "projects/p", "roles/editor", "5"},
+		{"user:alice@example.com", "Finance", "carol@example.com", "Terminated", "p", "project", "projects/p", "roles/viewer", "1"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records:\n%v\nwant:\n%v", records, want)
+	}
+}
+
+func TestPrintQuotesIdentityColumns(t *testing.T) {
+	resman := &resourceManager{identities: map[string]*identity{
+		"alice@example.com": {Email: "alice@example.com", Department: "Sales, EMEA", Manager: "Doe, Jane", EmploymentStatus: "active"},
+	}, bindingRoles: make(map[string]*iam.Role)}
+	row := &Row{Resource: "p", Type: "project", Member: "user:alice@example.com", Role: "roles/viewer"}
+	resman.bindingRoles[bindingRoleKey(row)] = &iam.Role{Name: row.Role, IncludedPermissions: []string{"resourcemanager.projects.get"}}
+	var out bytes.Buffer
+	writer := bufio.NewWriter(&out)
+	if err := row.Print(writer, resman); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+	if !strings.HasSuffix(out.String(), `,"Sales, EMEA","Doe, Jane",active`+"\n") {
+		t.Fatalf("Print() = %q, want the department and manager quoted", out.String())
+	}
+	record, err := csv.NewReader(&out).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := record[len(record)-3:]
+	if want := []string{"Sales, EMEA", "Doe, Jane", "active"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("identity columns = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// impersonationPermissions let a member act as a service account by getting tokens for it or
+// signing as it. implicitDelegation lets it do so through a chain of service accounts.
+var impersonationPermissions = map[string]bool{
+	"iam.serviceAccounts.getAccessToken":     true,
+	"iam.serviceAccounts.getOpenIdToken":     true,
+	"iam.serviceAccounts.signBlob":           true,
+	"iam.serviceAccounts.signJwt":            true,
+	"iam.serviceAccounts.implicitDelegation": true,
+}
+
+// impersonationChain is the shortest chain through which a member can act as a service account:
+// Path starts with the member and ends with the service account.
+type impersonationChain struct {
+	Member         string
+	ServiceAccount string
+	Path           []string
+}
+
+// impersonationChains follows the edges from each member to the service accounts it can
+// impersonate, and from those to the ones they can impersonate in turn, returning every
+// reachable pair with its shortest chain, sorted by member then service account.
+func impersonationChains(edges map[string]map[string]bool) []*impersonationChain {
+	starts := make([]string, 0, len(edges))
+	for m := range edges {
+		starts = append(starts, m)
+	}
+	sort.Strings(starts)
+	chains := make([]*impersonationChain, 0)
+	for _, start := range starts {
+		previous := map[string]string{start: ""}
+		queue := []string{start}
+		reached := make([]string, 0)
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, next := range sortedKeys(edges[current]) {
+				if _, seen := previous[next]; seen {
+					continue
+				}
+				previous[next] = current
+				reached = append(reached, next)
+				queue = append(queue, next)
+			}
+		}
+		sort.Strings(reached)
+		for _, sa := range reached {
+			path := []string{sa}
+			for at := previous[sa]; at != ""; at = previous[at] {
+				path = append([]string{at}, path...)
+			}
+			chains = append(chains, &impersonationChain{Member: start, ServiceAccount: sa, Path: path})
+		}
+	}
+	return chains
+}
+
+// serviceAccountResource returns the service account member a serviceaccount row is the
+// policy of, from its full name //iam.googleapis.com/projects/p/serviceAccounts/<email>.
+func serviceAccountResource(row *Row) string {
+	i := strings.LastIndex(row.Name, "/serviceAccounts/")
+	if i < 0 {
+		return ""
+	}
+	email := row.Name[i+len("/serviceAccounts/"):]
+	if !strings.Contains(email, "@") {
+		return ""
+	}
+	return "serviceAccount:" + strings.ToLower(email)
+}
+
+// impersonationEdges finds who can impersonate which service account: grants on a service
+// account reach it, and grants on a project, folder, or the org reach every service account
+// known to live below it, from the bindings they hold or their own policies.
+func impersonationEdges(rows []*Row, resman *resourceManager) map[string]map[string]bool {
+	// resource name to its parent's, for projects and folders
+	parents := make(map[string]string)
+	// project name to its service accounts
+	accounts := make(map[string]map[string]bool)
+	addAccount := func(project string, sa string) {
+		if accounts[project] == nil {
+			accounts[project] = make(map[string]bool)
+		}
+		accounts[project][sa] = true
+	}
+	for _, row := range rows {
+		switch {
+		case row.Type == "project" || row.Type == "folder":
+			if row.Parent != "" {
+				parents[graphResourceName(row)] = row.Parent
+			}
+		case row.Type == "serviceaccount" && strings.HasPrefix(row.Parent, "projects/"):
+			if sa := serviceAccountResource(row); sa != "" {
+				addAccount(row.Parent, sa)
+			}
+		}
+		if row.MemberProject != "" && strings.HasPrefix(row.Member, "serviceAccount:") &&
+			memberClass(row.Member) != memberClassGoogleManaged {
+			addAccount("projects/"+row.MemberProject, memberStatusKey(row.Member))
+		}
+	}
+	// every service account below each resource
+	below := make(map[string]map[string]bool)
+	for project, sas := range accounts {
+		for at := project; at != ""; at = parents[at] {
+			if below[at] == nil {
+				below[at] = make(map[string]bool)
+			}
+			for sa := range sas {
+				below[at][sa] = true
+			}
+		}
+	}
+	edges := make(map[string]map[string]bool)
+	for _, row := range rows {
+		permissions, err := resman.GetRolePermissions(row)
+		if err != nil {
+			continue
+		}
+		grants := false
+		for _, p := range permissions {
+			if impersonationPermissions[p] {
+				grants = true
+				break
+			}
+		}
+		if !grants {
+			continue
+		}
+		targets := below[graphResourceName(row)]
+		if row.Type == "serviceaccount" {
+			targets = map[string]bool{serviceAccountResource(row): true}
+		}
+		member := memberStatusKey(row.Member)
+		for sa := range targets {
+			if sa == "" || sa == member {
+				continue
+			}
+			if edges[member] == nil {
+				edges[member] = make(map[string]bool)
+			}
+			edges[member][sa] = true
+		}
+	}
+	return edges
+}
+
+// impersonationReport lists every service account each member can act as, directly or through
+// a chain of impersonations, with the shortest chain. A member reaching many service accounts,
+// or reaching them through others, has a larger blast radius than its own bindings show.
+func impersonationReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	chains := impersonationChains(impersonationEdges(rows, resman))
+	records := make([][]string, len(chains))
+	for i, c := range chains {
+		records[i] = []string{c.Member, c.ServiceAccount, strconv.Itoa(len(c.Path) - 1), strings.Join(c.Path, " -> ")}
+	}
+	return []string{"Member", "ServiceAccount", "Hops", "Chain"}, records, nil
+}
+
+func init() {
+	registerReport("impersonation", impersonationReport)
+}
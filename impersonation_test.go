@@ -0,0 +1,71 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImpersonationChains(t *testing.T) {
+	edges := map[string]map[string]bool{
+		"user:dev@example.com":                            {"serviceAccount:ci@build.iam.gserviceaccount.com": true},
+		"serviceAccount:ci@build.iam.gserviceaccount.com": {"serviceAccount:deploy@prod.iam.gserviceaccount.com": true},
+		"serviceAccount:deploy@prod.iam.gserviceaccount.com": {
+			"serviceAccount:ci@build.iam.gserviceaccount.com": true,
+			"serviceAccount:db@prod.iam.gserviceaccount.com":  true,
+		},
+		"group:ops@example.com": {
+			"serviceAccount:deploy@prod.iam.gserviceaccount.com": true,
+			"serviceAccount:db@prod.iam.gserviceaccount.com":     true,
+		},
+	}
+	got := make([]string, 0)
+	for _, c := range impersonationChains(edges) {
+		got = append(got, strings.Join(c.Path, " > "))
+	}
+	want := []string{
+		"group:ops@example.com > serviceAccount:deploy@prod.iam.gserviceaccount.com > serviceAccount:ci@build.iam.gserviceaccount.com",
+		"group:ops@example.com > serviceAccount:db@prod.iam.gserviceaccount.com",
+		"group:ops@example.com > serviceAccount:deploy@prod.iam.gserviceaccount.com",
+		"serviceAccount:ci@build.iam.gserviceaccount.com > serviceAccount:deploy@prod.iam.gserviceaccount.com > serviceAccount:db@prod.iam.gserviceaccount.com",
+		"serviceAccount:ci@build.iam.gserviceaccount.com > serviceAccount:deploy@prod.iam.gserviceaccount.com",
+		"serviceAccount:deploy@prod.iam.gserviceaccount.com > serviceAccount:ci@build.iam.gserviceaccount.com",
+		"serviceAccount:deploy@prod.iam.gserviceaccount.com > serviceAccount:db@prod.iam.gserviceaccount.com",
+		"user:dev@example.com > serviceAccount:ci@build.iam.gserviceaccount.com",
+		"user:dev@example.com > serviceAccount:ci@build.iam.gserviceaccount.com > serviceAccount:deploy@prod.iam.gserviceaccount.com > serviceAccount:db@prod.iam.gserviceaccount.com",
+		"user:dev@example.com > serviceAccount:ci@build.iam.gserviceaccount.com > serviceAccount:deploy@prod.iam.gserviceaccount.com",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("impersonationChains =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestServiceAccountResource(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"//iam.googleapis.com/projects/p/serviceAccounts/CI@p.iam.gserviceaccount.com", "serviceAccount:ci@p.iam.gserviceaccount.com"},
+		{"//iam.googleapis.com/projects/p/serviceAccounts/1234567890", ""},
+		{"projects/p", ""},
+	}
+	for _, tt := range tests {
+		if got := serviceAccountResource(&Row{Name: tt.name}); got != tt.want {
+			t.Errorf("serviceAccountResource(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
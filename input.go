@@ -0,0 +1,262 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// offlineTransport refuses every request, for runs reading an export given with --input, or
+// roles given with --roles-file without credentials.
+type offlineTransport struct {
+	input string
+}
+
+func (t *offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errors.New(fmt.Sprintf("%s %s not sent, running offline from %s", req.Method, req.URL, t.input))
+}
+
+// exportReader turns the permission rows of an export back into bindings, one per resource,
+// member, role, and condition, and the roles into the permissions the export lists for them.
+type exportReader struct {
+	snap     *Snapshot
+	bindings map[string]*Row
+	seen     map[string]map[string]bool
+	line     int
+}
+
+func newExportReader(filename string) *exportReader {
+	return &exportReader{
+		snap: &Snapshot{
+			Id:         filename,
+			Created:    time.Now().UTC(),
+			Rows:       make([]*Row, 0),
+			Roles:      make(map[string][]string),
+			RoleStages: make(map[string]string),
+		},
+		bindings: make(map[string]*Row),
+		seen:     make(map[string]map[string]bool),
+	}
+}
+
+func (e *exportReader) add(row *Row, permission string) {
+	key := fmt.Sprintf("%s,%s,%s", row.Key(), row.Origin, conditionExpression(row.Condition))
+	if _, ok := e.bindings[key]; !ok {
+		e.bindings[key] = row
+		e.snap.Rows = append(e.snap.Rows, row)
+	}
+	// UNKNOWN stands for a role whose permissions couldn't be read when exporting
+	if permission == "" || permission == "UNKNOWN" {
+		return
+	}
+	if e.seen[row.Role] == nil {
+		e.seen[row.Role] = make(map[string]bool)
+	}
+	if !e.seen[row.Role][permission] {
+		e.seen[row.Role][permission] = true
+		e.snap.Roles[row.Role] = append(e.snap.Roles[row.Role], permission)
+	}
+}
+
+func conditionExpression(c *Expr) string {
+	if c == nil {
+		return ""
+	}
+	return c.Expression
+}
+
+// readExport reads a csv, json, or ndjson export back, telling them apart by extension. Exports
+// don't keep the parents of resources, nor the conditions of bindings in the csv format.
+func readExport(filename string) (*Snapshot, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	e := newExportReader(filename)
+	switch filepath.Ext(filename) {
+	case ".json":
+		err = e.readJSON(f)
+	case ".ndjson":
+		err = e.readNDJSON(f)
+	default:
+		err = e.readCsv(f)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to read export %s: %v", filename, err))
+	}
+	return e.snap, nil
+}
+
+// readCsv reads the csv format. Its fields aren't quoted, so a row with more fields than the
+// header has display name with commas, which are put back together.
+func (e *exportReader) readCsv(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	column := make(map[string]int)
+	for i, name := range header {
+		column[name] = i
+	}
+	for _, name := range []string{"Resource", "Type", "Member", "Role", "Permission"} {
+		if _, ok := column[name]; !ok {
+			return errors.New(fmt.Sprintf("no %s column", name))
+		}
+	}
+	displayName := column["DisplayName"]
+	e.line = 1
+	for {
+		record, err := reader.Read()
+		e.line++
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if extra := len(record) - len(header); extra > 0 {
+			joined := strings.Join(record[displayName:displayName+extra+1], ",")
+			record = append(append(record[:displayName:displayName], joined), record[displayName+extra+1:]...)
+		}
+		if len(record) != len(header) {
+			return errors.New(fmt.Sprintf("line %d has %d fields, expected %d", e.line, len(record), len(header)))
+		}
+		get := func(name string) string {
+			if i, ok := column[name]; ok {
+				return record[i]
+			}
+			return ""
+		}
+		row := &Row{
+			Resource:       get("Resource"),
+			Type:           get("Type"),
+			Name:           get("ResourceName"),
+			DisplayName:    get("DisplayName"),
+			Member:         get("Member"),
+			MemberProject:  get("MemberProject"),
+			Role:           get("Role"),
+			LifecycleState: get("LifecycleState"),
+			Origin:         get("Origin"),
+			PolicyEtag:     get("PolicyEtag"),
+			GrantedAt:      get("GrantedAt"),
+			GrantedBy:      get("GrantedBy"),
+		}
+		row.Risk, _ = strconv.Atoi(get("BindingRisk"))
+		row.BindingIndex, _ = strconv.Atoi(get("BindingIndex"))
+		row.Count, _ = strconv.Atoi(get("Count"))
+		e.add(row, get("Permission"))
+	}
+}
+
+func (e *exportReader) addRecord(p *permissionRecord) {
+	e.add(&Row{
+		Resource:       p.Resource,
+		Type:           p.Type,
+		Name:           p.ResourceName,
+		DisplayName:    p.DisplayName,
+		Member:         p.Member,
+		MemberProject:  p.MemberProject,
+		Role:           p.Role,
+		Risk:           p.BindingRisk,
+		LifecycleState: p.LifecycleState,
+		Count:          p.Count,
+		Condition:      p.Condition,
+		Origin:         p.Origin,
+		PolicyEtag:     p.PolicyEtag,
+		GrantedAt:      p.GrantedAt,
+		GrantedBy:      p.GrantedBy,
+		BindingIndex:   bindingIndexValue(p.BindingIndex),
+	}, p.Permission)
+}
+
+func (e *exportReader) readJSON(r io.Reader) error {
+	doc := &exportDocument{}
+	if err := json.NewDecoder(r).Decode(doc); err != nil {
+		return err
+	}
+	e.snap.OrgId = doc.OrgId
+	e.snap.Created = doc.Created
+	for _, p := range doc.Rows {
+		e.addRecord(p)
+	}
+	return nil
+}
+
+func (e *exportReader) readNDJSON(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		p := &permissionRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), p); err != nil {
+			return err
+		}
+		e.addRecord(p)
+	}
+	return scanner.Err()
+}
+
+// openSnapshot loads the snapshot or export given by id, or the latest snapshot of orgId (or of
+// the only org in the store) when id is empty.
+func openSnapshot(storeDir string, orgId string, id string) (*Snapshot, error) {
+	store, err := NewSnapshotStore(storeDir)
+	if err != nil {
+		return nil, err
+	}
+	if id != "" {
+		return loadSnapshotOrExport(store, id)
+	}
+	snap, err := store.LatestOf(orgId)
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, errors.New(fmt.Sprintf("No snapshots in %s, run snapshot save first", store.dir))
+	}
+	return snap, nil
+}
+
+// loadSnapshotOrExport loads a snapshot from the store, or reads an export when id is the path
+// of an existing file.
+func loadSnapshotOrExport(store *snapshotStore, id string) (*Snapshot, error) {
+	if info, err := os.Stat(id); err == nil && !info.IsDir() {
+		return readExport(id)
+	}
+	return store.Load(id)
+}
+
+func bindingIndexValue(index *int) int {
+	if index == nil {
+		return 0
+	}
+	return *index
+}
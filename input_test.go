@@ -0,0 +1,85 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCsvExport(t *testing.T) {
+	csv := `Resource,Type,ResourceName,DisplayName,Member,MemberClass,MemberProject,Role,Permission,BindingRisk,MemberRisk,LifecycleState,Count
+p1,project,projects/p1,Billing, Prod,user:a@example.com,user,,roles/viewer,resourcemanager.projects.get,1,3,ACTIVE,2
+p1,project,projects/p1,Billing, Prod,user:a@example.com,user,,roles/viewer,storage.buckets.list,1,3,ACTIVE,2
+p1,project,projects/p1,Billing, Prod,serviceAccount:x@p1.iam.gserviceaccount.com,service-account,p1,roles/viewer,resourcemanager.projects.get,1,1,ACTIVE,1
+123,organization,organizations/123,,group:g@example.com,group,,roles/custom,UNKNOWN,0,0,,1
+`
+	e := newExportReader("test.csv")
+	if err := e.readCsv(strings.NewReader(csv)); err != nil {
+		t.Fatal(err)
+	}
+	rows := e.snap.Rows
+	if len(rows) != 3 {
+		t.Fatalf("got %d bindings, want 3", len(rows))
+	}
+	want := &Row{Resource: "p1", Type: "project", Name: "projects/p1", DisplayName: "Billing, Prod",
+		Member: "user:a@example.com", Role: "roles/viewer", Risk: 1, LifecycleState: "ACTIVE", Count: 2}
+	if !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("first binding = %+v, want %+v", rows[0], want)
+	}
+	if rows[1].MemberProject != "p1" {
+		t.Errorf("MemberProject = %q, want p1", rows[1].MemberProject)
+	}
+	if got := e.snap.Roles["roles/viewer"]; !reflect.DeepEqual(got, []string{"resourcemanager.projects.get", "storage.buckets.list"}) {
+		t.Errorf("roles/viewer permissions = %v", got)
+	}
+	if _, ok := e.snap.Roles["roles/custom"]; ok {
+		t.Errorf("role with UNKNOWN permissions was kept")
+	}
+}
+
+func TestReadCsvExportErrors(t *testing.T) {
+	tests := []string{
+		"Resource,Type,Member,Role\n",
+		"Resource,Type,Member,Role,Permission\np1,project,user:a@example.com\n",
+	}
+	for _, csv := range tests {
+		if err := newExportReader("test.csv").readCsv(strings.NewReader(csv)); err == nil {
+			t.Errorf("readCsv(%q) succeeded, want an error", csv)
+		}
+	}
+}
+
+func TestReadNDJSONExport(t *testing.T) {
+	ndjson := `{"schemaVersion":2,"resource":"b","type":"bucket","member":"user:a@example.com","role":"roles/storage.objectViewer","permission":"storage.objects.get","condition":{"expression":"request.time < timestamp(\"2020-01-01T00:00:00Z\")"}}
+{"schemaVersion":2,"resource":"b","type":"bucket","member":"user:a@example.com","role":"roles/storage.objectViewer","permission":"storage.objects.get"}
+
+{"schemaVersion":2,"resource":"b","type":"bucket","member":"user:a@example.com","role":"roles/storage.objectViewer","permission":"storage.objects.list"}
+`
+	e := newExportReader("test.ndjson")
+	if err := e.readNDJSON(strings.NewReader(ndjson)); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.snap.Rows) != 2 {
+		t.Fatalf("got %d bindings, want the conditional and the unconditional one", len(e.snap.Rows))
+	}
+	if e.snap.Rows[0].Condition == nil || e.snap.Rows[1].Condition != nil {
+		t.Errorf("conditions = %v, %v", e.snap.Rows[0].Condition, e.snap.Rows[1].Condition)
+	}
+	if got := len(e.snap.Roles["roles/storage.objectViewer"]); got != 2 {
+		t.Errorf("got %d permissions, want 2", got)
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"sync"
+	"time"
+)
+
+// spanStats totals every run of one collector or phase: how long it took, and the API requests
+// and throttling retries made while it ran. A span running inside another counts toward both,
+// so the projects collector includes the kms collector run for each project.
+type spanStats struct {
+	Kind     string  `json:"kind"`
+	Name     string  `json:"name"`
+	Runs     int     `json:"runs"`
+	Seconds  float64 `json:"seconds"`
+	ApiCalls int     `json:"apiCalls"`
+	Retries  int     `json:"retries"`
+}
+
+// spans are the totals of every span run so far, in the order they first started, and the
+// ones running now.
+var spans = struct {
+	sync.Mutex
+	list   []*spanStats
+	active []*spanStats
+	// --otel-endpoint span id of each of active
+	ids []string
+}{}
+
+// currentSpanId is the --otel-endpoint span id of the innermost running span that has one.
+func currentSpanId() string {
+	spans.Lock()
+	defer spans.Unlock()
+	return innermostSpanId()
+}
+
+func innermostSpanId() string {
+	for i := len(spans.ids) - 1; i >= 0; i-- {
+		if spans.ids[i] != "" {
+			return spans.ids[i]
+		}
+	}
+	return ""
+}
+
+// beginSpan starts timing a run of a collector or phase and returns the func that ends it and
+// returns the run's own totals. While it runs, every API request and retry counts toward it.
+// With --exec-trace it is also a region of the trace, and with --otel-endpoint a span.
+func beginSpan(kind string, name string) func() spanStats {
+	spans.Lock()
+	var s *spanStats
+	for _, known := range spans.list {
+		if known.Kind == kind && known.Name == name {
+			s = known
+		}
+	}
+	if s == nil {
+		s = &spanStats{Kind: kind, Name: name}
+		spans.list = append(spans.list, s)
+	}
+	id, parentId := otel.NewSpanId(), innermostSpanId()
+	spans.active = append(spans.active, s)
+	spans.ids = append(spans.ids, id)
+	calls, retries := s.ApiCalls, s.Retries
+	spans.Unlock()
+	region := trace.StartRegion(context.Background(), kind+" "+name)
+	start := time.Now()
+	return func() spanStats {
+		elapsed := time.Since(start)
+		region.End()
+		spans.Lock()
+		s.Runs++
+		s.Seconds += elapsed.Seconds()
+		for i := len(spans.active) - 1; i >= 0; i-- {
+			if spans.active[i] == s && spans.ids[i] == id {
+				spans.active = append(spans.active[:i], spans.active[i+1:]...)
+				spans.ids = append(spans.ids[:i], spans.ids[i+1:]...)
+				break
+			}
+		}
+		run := spanStats{Kind: kind, Name: name, Runs: 1, Seconds: elapsed.Seconds(), ApiCalls: s.ApiCalls - calls, Retries: s.Retries - retries}
+		spans.Unlock()
+		otel.Record(id, parentId, name, otelKindInternal, start, []*otlpAttribute{
+			otlpString("policygopher.kind", kind), otlpInt("api_calls", run.ApiCalls), otlpInt("retries", run.Retries),
+		}, nil)
+		return run
+	}
+}
+
+// activeSpans calls fn once with each running span, even one running inside itself.
+func activeSpans(fn func(s *spanStats)) {
+	spans.Lock()
+	defer spans.Unlock()
+	seen := make(map[*spanStats]bool)
+	for _, s := range spans.active {
+		if !seen[s] {
+			seen[s] = true
+			fn(s)
+		}
+	}
+}
+
+func recordSpanApiCall() {
+	activeSpans(func(s *spanStats) { s.ApiCalls++ })
+}
+
+func recordSpanRetry() {
+	activeSpans(func(s *spanStats) { s.Retries++ })
+}
+
+func spanKey(s *spanStats) string {
+	return s.Kind + "/" + s.Name
+}
+
+// snapshotSpans copies the totals so far, so one export of several can take its own out of
+// them with spansSince.
+func snapshotSpans() map[string]spanStats {
+	spans.Lock()
+	defer spans.Unlock()
+	snapshot := make(map[string]spanStats, len(spans.list))
+	for _, s := range spans.list {
+		snapshot[spanKey(s)] = *s
+	}
+	return snapshot
+}
+
+// spansSince is the totals of the spans that ran since before was taken, slowest first.
+func spansSince(before map[string]spanStats) []*spanStats {
+	spans.Lock()
+	defer spans.Unlock()
+	since := make([]*spanStats, 0)
+	for _, s := range spans.list {
+		was := before[spanKey(s)]
+		if s.Runs == was.Runs {
+			continue
+		}
+		since = append(since, &spanStats{
+			Kind:     s.Kind,
+			Name:     s.Name,
+			Runs:     s.Runs - was.Runs,
+			Seconds:  s.Seconds - was.Seconds,
+			ApiCalls: s.ApiCalls - was.ApiCalls,
+			Retries:  s.Retries - was.Retries,
+		})
+	}
+	sort.SliceStable(since, func(i, j int) bool { return since[i].Seconds > since[j].Seconds })
+	return since
+}
+
+// Timings are the spans of this export, slowest first.
+func (r *resourceManager) Timings() []*spanStats {
+	return spansSince(r.spansAtStart)
+}
+
+// Collectors are the collector spans of this export, slowest first.
+func (r *resourceManager) Collectors() []*spanStats {
+	collectors := make([]*spanStats, 0)
+	for _, s := range r.Timings() {
+		if s.Kind == "collector" {
+			collectors = append(collectors, s)
+		}
+	}
+	return collectors
+}
+
+func timingsReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Kind", "Name", "Runs", "Seconds", "ApiCalls", "Retries"}
+	records := make([][]string, 0)
+	for _, s := range resman.Timings() {
+		records = append(records, []string{s.Kind, s.Name, fmt.Sprint(s.Runs), fmt.Sprintf("%.3f", s.Seconds),
+			fmt.Sprint(s.ApiCalls), fmt.Sprint(s.Retries)})
+	}
+	return header, records, nil
+}
+
+// printCollectorTimings prints how long each collector took with its API requests and retries,
+// after the API call counts.
+func printCollectorTimings() {
+	collectors := make([]*spanStats, 0)
+	for _, s := range spansSince(nil) {
+		if s.Kind == "collector" {
+			collectors = append(collectors, s)
+		}
+	}
+	if len(collectors) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Collectors: %8s %8s %8s %8s\n", "seconds", "runs", "calls", "retries")
+	for _, s := range collectors {
+		fmt.Fprintf(os.Stderr, "%-20s %8.1f %8d %8d %8d\n", s.Name, s.Seconds, s.Runs, s.ApiCalls, s.Retries)
+	}
+}
+
+// profiler writes a CPU profile for go tool pprof and an execution trace for go tool trace
+// while the app runs, for diagnosing slow crawls.
+type profiler struct {
+	cpu   *os.File
+	trace *os.File
+}
+
+func startProfiling(cpuProfile string, execTrace string) (*profiler, error) {
+	p := &profiler{}
+	if cpuProfile != "" {
+		f, err := os.Create(cpuProfile)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to create CPU profile %s: %v", cpuProfile, err))
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, errors.New(fmt.Sprintf("Unable to start CPU profile: %v", err))
+		}
+		p.cpu = f
+	}
+	if execTrace != "" {
+		f, err := os.Create(execTrace)
+		if err != nil {
+			p.Stop()
+			return nil, errors.New(fmt.Sprintf("Unable to create execution trace %s: %v", execTrace, err))
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			p.Stop()
+			return nil, errors.New(fmt.Sprintf("Unable to start execution trace: %v", err))
+		}
+		p.trace = f
+	}
+	return p, nil
+}
+
+func (p *profiler) Stop() {
+	if p == nil {
+		return
+	}
+	if p.cpu != nil {
+		pprof.StopCPUProfile()
+		p.cpu.Close()
+		log.Printf("Wrote CPU profile %s, read it with go tool pprof", p.cpu.Name())
+	}
+	if p.trace != nil {
+		trace.Stop()
+		p.trace.Close()
+		log.Printf("Wrote execution trace %s, read it with go tool trace", p.trace.Name())
+	}
+}
+
+func init() {
+	registerReport("timings", timingsReport)
+}
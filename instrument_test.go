@@ -0,0 +1,82 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestSpans(t *testing.T) {
+	before := snapshotSpans()
+	endProjects := beginSpan("collector", "test-projects")
+	recordSpanApiCall()
+	for i := 0; i < 2; i++ {
+		endKms := beginSpan("collector", "test-kms")
+		recordSpanApiCall()
+		recordSpanRetry()
+		if run := endKms(); run.Runs != 1 || run.ApiCalls != 1 || run.Retries != 1 {
+			t.Errorf("kms run = %+v, want 1 call and 1 retry", run)
+		}
+	}
+	if run := endProjects(); run.ApiCalls != 3 || run.Retries != 2 {
+		t.Errorf("projects run = %+v, want 3 calls and 2 retries", run)
+	}
+	recordSpanApiCall()
+
+	got := make(map[string]*spanStats)
+	for _, s := range spansSince(before) {
+		got[s.Name] = s
+	}
+	if len(got) != 2 {
+		t.Fatalf("spansSince = %v, want test-projects and test-kms", got)
+	}
+	if s := got["test-projects"]; s.Runs != 1 || s.ApiCalls != 3 || s.Retries != 2 {
+		t.Errorf("test-projects = %+v", s)
+	}
+	if s := got["test-kms"]; s.Runs != 2 || s.ApiCalls != 2 || s.Retries != 2 {
+		t.Errorf("test-kms = %+v", s)
+	}
+	if since := spansSince(snapshotSpans()); len(since) != 0 {
+		t.Errorf("spansSince(now) = %v, want none", since)
+	}
+}
+
+func TestNestedSpanCountsOnce(t *testing.T) {
+	before := snapshotSpans()
+	outer := beginSpan("phase", "test-recursive")
+	inner := beginSpan("phase", "test-recursive")
+	recordSpanApiCall()
+	inner()
+	outer()
+	since := spansSince(before)
+	if len(since) != 1 || since[0].Runs != 2 || since[0].ApiCalls != 1 {
+		t.Errorf("spansSince = %+v, want 2 runs and 1 call", since)
+	}
+}
+
+func TestTimingsReport(t *testing.T) {
+	resman := &resourceManager{spansAtStart: snapshotSpans()}
+	beginSpan("collector", "test-report")()
+	header, records, err := timingsReport(nil, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(header) != 6 || len(records) != 1 || records[0][0] != "collector" || records[0][1] != "test-report" || records[0][2] != "1" {
+		t.Errorf("timingsReport = %v %v", header, records)
+	}
+	if collectors := resman.Collectors(); len(collectors) != 1 || collectors[0].Name != "test-report" {
+		t.Errorf("Collectors = %v", collectors)
+	}
+}
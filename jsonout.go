@@ -0,0 +1,220 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// permissionRecord is a permission row as written by the json and ndjson formats, see rowSchema.
+type permissionRecord struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Resource      string `json:"resource"`
+	Type          string `json:"type"`
+	ResourceName  string `json:"resourceName,omitempty"`
+	DisplayName   string `json:"displayName,omitempty"`
+	Member        string `json:"member"`
+	MemberClass   string `json:"memberClass"`
+	MemberProject string `json:"memberProject,omitempty"`
+	Role          string `json:"role"`
+	Permission    string `json:"permission"`
+	BindingRisk   int    `json:"bindingRisk"`
+	MemberRisk    int    `json:"memberRisk"`
+	// LifecycleState is empty for organizations
+	LifecycleState   string `json:"lifecycleState,omitempty"`
+	MemberState      string `json:"memberState,omitempty"`
+	MemberLastActive string `json:"memberLastActive,omitempty"`
+	Condition        *Expr  `json:"condition,omitempty"`
+	// ConditionActive is true, false, or unknown with --evaluate-conditions-at
+	ConditionActive string `json:"conditionActive,omitempty"`
+	RunId           string `json:"runId,omitempty"`
+	Source          string `json:"source,omitempty"`
+	Count           int    `json:"count,omitempty"`
+	Origin          string `json:"origin,omitempty"`
+	PermissionValid string `json:"permissionValid,omitempty"`
+	ServiceEnabled  string `json:"serviceEnabled,omitempty"`
+	Perimeter       string `json:"perimeter,omitempty"`
+	// PermissionService, PermissionResource, and PermissionVerb are set with --permission-columns
+	PermissionService  string `json:"permissionService,omitempty"`
+	PermissionResource string `json:"permissionResource,omitempty"`
+	PermissionVerb     string `json:"permissionVerb,omitempty"`
+	// PolicyEtag and BindingIndex are set with --provenance, BindingIndex only for rows with an etag
+	PolicyEtag   string `json:"policyEtag,omitempty"`
+	BindingIndex *int   `json:"bindingIndex,omitempty"`
+	// set with --binding-age-days
+	GrantedAt string `json:"grantedAt,omitempty"`
+	GrantedBy string `json:"grantedBy,omitempty"`
+	// set with --identities
+	Department       string `json:"department,omitempty"`
+	Manager          string `json:"manager,omitempty"`
+	EmploymentStatus string `json:"employmentStatus,omitempty"`
+}
+
+// permissionRecords expands a row into one record per permission, like Row.Print.
+func (r *Row) permissionRecords(rm *resourceManager) []*permissionRecord {
+	permissions := rm.outputPermissions(r)
+	records := make([]*permissionRecord, len(permissions))
+	for i, p := range permissions {
+		records[i] = &permissionRecord{
+			SchemaVersion:  schemaVersion,
+			Resource:       rm.ResourceColumn(r),
+			Type:           r.Type,
+			ResourceName:   r.Name,
+			DisplayName:    r.DisplayName,
+			Member:         r.Member,
+			MemberClass:    memberClass(r.Member),
+			MemberProject:  r.MemberProject,
+			Role:           r.Role,
+			Permission:     p,
+			BindingRisk:    r.Risk,
+			MemberRisk:     rm.memberRisk[r.Member],
+			LifecycleState: r.LifecycleState,
+			Condition:      r.Condition,
+		}
+		if rm.memberStatusColumns() {
+			records[i].MemberState, records[i].MemberLastActive = rm.MemberStatus(r.Member)
+		}
+		if !rm.conditionTime.IsZero() {
+			records[i].ConditionActive = rm.ConditionActive(r)
+		}
+		if rm.sourceColumns() {
+			records[i].RunId = rm.runId
+			records[i].Source = rm.Scope()
+		}
+		if rm.countColumn {
+			records[i].Count = r.Count
+		}
+		if rm.originColumn() {
+			records[i].Origin = RowOrigin(r)
+		}
+		if rm.permissionValidColumn() {
+			records[i].PermissionValid = rm.PermissionValid(r, p)
+		}
+		if rm.serviceEnabledColumn() {
+			records[i].ServiceEnabled = rm.ServiceEnabled(r, p)
+		}
+		if rm.perimeterColumn() {
+			records[i].Perimeter = rm.Perimeter(r)
+		}
+		if rm.permissionColumns {
+			parts := parsePermission(p)
+			records[i].PermissionService, records[i].PermissionResource, records[i].PermissionVerb = parts.Service, parts.Resource, parts.Verb
+		}
+		if rm.provenance && r.PolicyEtag != "" {
+			index := r.BindingIndex
+			records[i].PolicyEtag, records[i].BindingIndex = r.PolicyEtag, &index
+		}
+		if rm.bindingAgeDays > 0 {
+			records[i].GrantedAt, records[i].GrantedBy = r.GrantedAt, r.GrantedBy
+		}
+		if rm.identityColumns() {
+			records[i].Department, records[i].Manager, records[i].EmploymentStatus = rm.IdentityColumns(r.Member)
+		}
+	}
+	rm.permissionRows += len(records)
+	return records
+}
+
+// exportDocument is the json format's document. writeJSONRows streams it without holding
+// Rows, so its fields are written in this order.
+type exportDocument struct {
+	SchemaVersion int                 `json:"schemaVersion"`
+	OrgId         string              `json:"orgId"`
+	Organization  *orgMetadata        `json:"organization,omitempty"`
+	Created       time.Time           `json:"created"`
+	Tool          *buildMetadata      `json:"tool"`
+	Rows          []*permissionRecord `json:"rows"`
+}
+
+// writeJSON writes the rows as a single json document, or one json object per line with ndjson.
+func writeJSON(filename string, rows []*Row, resman *resourceManager, ndjson bool) error {
+	return writeJSONRows(filename, sliceRows(rows), resman, ndjson)
+}
+
+// writeJSONRows writes the rows each yields like writeJSON. The document is written as the
+// records are made, so only one row's records are held at a time.
+func writeJSONRows(filename string, each rowSource, resman *resourceManager, ndjson bool) error {
+	f, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	defer timeTrack(fmt.Sprintf("Printing JSON %s", filename))()
+	fmt.Printf("Printing JSON %s\n", filename)
+	encoder := json.NewEncoder(f)
+	if ndjson {
+		err = each(func(row *Row) error {
+			for _, record := range row.permissionRecords(resman) {
+				if err := encoder.Encode(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	} else {
+		err = writeJSONDocument(f, each, resman)
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error encoding %s: %v", filename, err))
+	}
+	return f.Commit()
+}
+
+// writeJSONDocument writes the same bytes json.Encoder writes for an exportDocument, one
+// record at a time.
+func writeJSONDocument(w io.Writer, each rowSource, resman *resourceManager) error {
+	head, err := json.Marshal(&exportDocument{
+		SchemaVersion: schemaVersion,
+		OrgId:         resman.orgId,
+		Organization:  resman.OrgMetadata(),
+		Created:       time.Now().UTC(),
+		Tool:          toolBuild,
+	})
+	if err != nil {
+		return err
+	}
+	// {...,"rows":null} becomes {...,"rows":[ followed by the records
+	head = bytes.TrimSuffix(head, []byte("null}"))
+	if _, err := w.Write(append(head, '[')); err != nil {
+		return err
+	}
+	first := true
+	err = each(func(row *Row) error {
+		for _, record := range row.permissionRecords(resman) {
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if !first {
+				data = append([]byte{','}, data...)
+			}
+			first = false
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
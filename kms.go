@@ -0,0 +1,112 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+type listKeyRingsResponse struct {
+	KeyRings []struct {
+		Name string `json:"name"`
+	} `json:"keyRings"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// keyRingPolicy is a key ring's policy as read in one location, added to the rows afterwards.
+type keyRingPolicy struct {
+	name     string
+	location string
+	policy   *Policy
+}
+
+// kmsKeyRings lists the key rings of a project in one location.
+func (r *resourceManager) kmsKeyRings(projectId string, location string) ([]string, error) {
+	names := make([]string, 0)
+	pageToken := ""
+	for {
+		u := fmt.Sprintf("https://cloudkms.googleapis.com/v1/projects/%s/locations/%s/keyRings?pageSize=1000",
+			url.PathEscape(projectId), url.PathEscape(location))
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		resp := &listKeyRingsResponse{}
+		if err := r.getJSON(u, resp); err != nil {
+			return nil, err
+		}
+		for _, k := range resp.KeyRings {
+			names = append(names, k.Name)
+		}
+		if resp.NextPageToken == "" {
+			return names, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// addKmsPolicies adds the policies of a project's Cloud KMS key rings, where
+// roles/cloudkms.cryptoKeyEncrypterDecrypter is usually granted. Key rings are regional, so
+// every location Cloud KMS offers the project is searched concurrently; the policies are
+// added afterwards in a stable order.
+func (r *resourceManager) addKmsPolicies(projectId string, rows *[]*Row) error {
+	var mu sync.Mutex
+	found := make([]*keyRingPolicy, 0)
+	err := r.ForEachLocation("cloudkms", projectId, func(location string) error {
+		names, err := r.kmsKeyRings(projectId, location)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			policy, err := r.serviceIamPolicy("cloudkms", name)
+			if err != nil {
+				logerr.Printf("Unable to get policy of key ring %s: %v\n", name, err)
+				r.markUnreadable(Row{
+					Resource:    name[len(fmt.Sprintf("projects/%s/locations/%s/keyRings/", projectId, location)):],
+					Type:        "keyring",
+					Parent:      fmt.Sprintf("projects/%s", projectId),
+					Name:        "//cloudkms.googleapis.com/" + name,
+					DisplayName: name,
+				}, err)
+				continue
+			}
+			mu.Lock()
+			found = append(found, &keyRingPolicy{name: name, location: location, policy: policy})
+			mu.Unlock()
+		}
+		return nil
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].name < found[j].name })
+	for _, k := range found {
+		r.resourcesScanned++
+		r.addPolicy(k.policy, rows, Row{
+			Resource:    k.name[len(fmt.Sprintf("projects/%s/locations/%s/keyRings/", projectId, k.location)):],
+			Type:        "keyring",
+			Parent:      fmt.Sprintf("projects/%s", projectId),
+			Name:        "//cloudkms.googleapis.com/" + k.name,
+			DisplayName: k.name,
+		})
+	}
+	return err
+}
+
+func init() {
+	registerCollectorPermissions("kms",
+		"cloudkms.locations.list",
+		"cloudkms.keyRings.list",
+		"cloudkms.keyRings.getIamPolicy")
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// locationConcurrency is how many locations of one project a regional collector crawls at once.
+const locationConcurrency = 8
+
+// locationCache remembers the locations each service offers each project, so collectors
+// sharing a service don't list them again.
+type locationCache struct {
+	sync.Mutex
+	locations map[string][]string
+}
+
+type listLocationsResponse struct {
+	Locations []struct {
+		LocationId string `json:"locationId"`
+	} `json:"locations"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// Locations lists the locations a regional API serves for a project through the standard
+// locations.list method, e.g. service cloudkms, artifactregistry, run or secretmanager.
+func (r *resourceManager) Locations(service string, projectId string) ([]string, error) {
+	key := service + "/" + projectId
+	r.locations.Lock()
+	if r.locations.locations == nil {
+		r.locations.locations = make(map[string][]string)
+	}
+	cached, ok := r.locations.locations[key]
+	r.locations.Unlock()
+	if ok {
+		return cached, nil
+	}
+	locations := make([]string, 0)
+	pageToken := ""
+	for {
+		u := fmt.Sprintf("https://%s.googleapis.com/v1/projects/%s/locations?pageSize=1000", service, url.PathEscape(projectId))
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		resp := &listLocationsResponse{}
+		if err := r.getJSON(u, resp); err != nil {
+			return nil, err
+		}
+		for _, l := range resp.Locations {
+			locations = append(locations, l.LocationId)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+	sort.Strings(locations)
+	r.locations.Lock()
+	r.locations.locations[key] = locations
+	r.locations.Unlock()
+	return locations, nil
+}
+
+// ForEachLocation calls collect for every location of a project's regional service, up to
+// locationConcurrency at a time. Every location is tried; the errors of those that failed
+// are returned together.
+func (r *resourceManager) ForEachLocation(service string, projectId string, collect func(location string) error) error {
+	locations, err := r.Locations(service, projectId)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to list %s locations of project %s: %v", service, projectId, err))
+	}
+	var mu sync.Mutex
+	failed := make([]string, 0)
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < locationConcurrency && i < len(locations); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for location := range work {
+				if err := collect(location); err != nil {
+					mu.Lock()
+					failed = append(failed, fmt.Sprintf("%s: %v", location, err))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, location := range locations {
+		work <- location
+	}
+	close(work)
+	wg.Wait()
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return errors.New(fmt.Sprintf("%s in project %s failed in %d of %d locations: %s",
+			service, projectId, len(failed), len(locations), strings.Join(failed, "; ")))
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// loginTimeout is how long --login waits for the browser to come back with a code.
+const loginTimeout = 5 * time.Minute
+
+// authorizedUser is a user's credentials in the format of application default credentials,
+// so google.CredentialsFromJSON reads them back.
+type authorizedUser struct {
+	Type         string `json:"type"`
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// loginCredentialsFile is where --login stores the credentials it obtained.
+func loginCredentialsFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "policygopher", "credentials.json"), nil
+}
+
+// randomToken is a random url-safe string, for the OAuth state and PKCE verifier.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// pkceChallenge is the S256 code challenge of a PKCE verifier.
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// openBrowser tries to show url in the user's browser; the url is printed either way.
+func openBrowser(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err == nil {
+		go cmd.Wait()
+	}
+}
+
+// loginCallback answers the browser's redirect to the loopback address, handing the
+// authorization code, or the error, to codes.
+func loginCallback(state string, codes chan<- string, errs chan<- error) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "State mismatch, start policygopher --login again.", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			fmt.Fprintf(w, "Login failed: %s. You can close this window.\n", q.Get("error"))
+			errs <- errors.New(fmt.Sprintf("Login failed: %s", q.Get("error")))
+		case q.Get("code") == "":
+			http.Error(w, "No authorization code in the redirect.", http.StatusBadRequest)
+			return
+		default:
+			fmt.Fprintln(w, "policygopher is logged in. You can close this window.")
+			codes <- q.Get("code")
+		}
+	}
+}
+
+// login runs the OAuth flow for installed apps: the user consents in a browser, which
+// redirects back to a server on a loopback port with a code exchanged for a refresh token.
+// The credentials are stored in loginCredentialsFile, where later runs find them.
+func login(ctx context.Context, clientFile string) error {
+	if clientFile == "" {
+		return errors.New("--login needs --oauth-client, the json of an OAuth client of type Desktop app")
+	}
+	data, err := ioutil.ReadFile(clientFile)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to read OAuth client %s: %v", clientFile, err))
+	}
+	config, err := google.ConfigFromJSON(data, cloudPlatformScope)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to use OAuth client %s: %v", clientFile, err))
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	config.RedirectURL = fmt.Sprintf("http://%s/", listener.Addr())
+	state, err := randomToken()
+	if err != nil {
+		return err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return err
+	}
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	server := &http.Server{Handler: loginCallback(state, codes, errs)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	url := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce,
+		oauth2.SetAuthURLParam("code_challenge", pkceChallenge(verifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"))
+	fmt.Printf("Log in to Google Cloud in your browser, opening:\n\n    %s\n\n", url)
+	openBrowser(url)
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-errs:
+		return err
+	case <-time.After(loginTimeout):
+		return errors.New(fmt.Sprintf("No login after %s", loginTimeout))
+	}
+	token, err := config.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", verifier))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to exchange the authorization code: %v", err))
+	}
+	if token.RefreshToken == "" {
+		return errors.New("The login returned no refresh token")
+	}
+	return storeLogin(&authorizedUser{
+		Type:         "authorized_user",
+		ClientId:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		RefreshToken: token.RefreshToken,
+	})
+}
+
+// storeLogin writes the credentials of a login, readable by the user only.
+func storeLogin(user *authorizedUser) error {
+	filename, err := loginCredentialsFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(user, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filename, append(data, '\n'), 0600); err != nil {
+		return errors.New(fmt.Sprintf("Unable to store credentials in %s: %v", filename, err))
+	}
+	fmt.Printf("Stored credentials in %s\n", filename)
+	return nil
+}
+
+// loginTokenSource is the token source of the credentials --login stored, nil when there are none.
+func loginTokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	filename, err := loginCredentialsFile()
+	if err != nil {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := google.CredentialsFromJSON(ctx, data, cloudPlatformScope)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to use the credentials in %s, run --login again: %v", filename, err))
+	}
+	return credentials.TokenSource, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestPkceChallenge(t *testing.T) {
+	if got := pkceChallenge("verifier"); got != "iMnq5o6zALKXGivsnlom_0F5_WYda32GHkxlV7mq7hQ" {
+		t.Errorf("pkceChallenge() = %s", got)
+	}
+}
+
+func TestLoginCallback(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantCode   string
+		wantErr    bool
+	}{
+		{"?state=s&code=c", http.StatusOK, "c", false},
+		{"?state=other&code=c", http.StatusBadRequest, "", false},
+		{"?state=s", http.StatusBadRequest, "", false},
+		{"?state=s&error=access_denied", http.StatusOK, "", true},
+	}
+	for _, tt := range tests {
+		codes := make(chan string, 1)
+		errs := make(chan error, 1)
+		w := httptest.NewRecorder()
+		loginCallback("s", codes, errs)(w, httptest.NewRequest("GET", "/"+tt.query, nil))
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status %d, want %d", tt.query, w.Code, tt.wantStatus)
+		}
+		select {
+		case code := <-codes:
+			if code != tt.wantCode {
+				t.Errorf("%s: code %q, want %q", tt.query, code, tt.wantCode)
+			}
+		case err := <-errs:
+			if !tt.wantErr {
+				t.Errorf("%s: unexpected error %v", tt.query, err)
+			}
+		default:
+			if tt.wantCode != "" || tt.wantErr {
+				t.Errorf("%s: nothing handed over", tt.query)
+			}
+		}
+	}
+}
+
+func TestStoreLogin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	if ts, err := loginTokenSource(context.Background()); ts != nil || err != nil {
+		t.Errorf("loginTokenSource() before --login = %v, %v, want nil", ts, err)
+	}
+	err = storeLogin(&authorizedUser{Type: "authorized_user", ClientId: "id", ClientSecret: "secret", RefreshToken: "refresh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename, _ := loginCredentialsFile()
+	if info, err := os.Stat(filename); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("stored credentials %s: %v, %v, want mode 0600", filename, info, err)
+	}
+	if ts, err := loginTokenSource(context.Background()); ts == nil || err != nil {
+		t.Errorf("loginTokenSource() after --login = %v, %v", ts, err)
+	}
+}
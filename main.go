@@ -15,108 +15,1176 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"golang.org/x/oauth2"
 	"gopkg.in/urfave/cli.v1"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
 var logerr *log.Logger
 
+const defaultFilename = "member_role_permissions.csv"
+
+type Options struct {
+	Filename             string
+	CredentialsPath      string
+	Login                bool
+	OAuthClient          string
+	OrgId                string
+	ProjectId            string
+	StoreDir             string
+	Incremental          bool
+	NotifyWebhook        string
+	ShardBy              string
+	Reports              string
+	ReportDir            string
+	HideGoogleManaged    bool
+	RiskWeights          string
+	Format               string
+	Config               string
+	HttpCache            string
+	RolesFile            string
+	Record               string
+	Replay               string
+	ApiConcurrency       int
+	RawPolicies          string
+	ResourceNameStyle    string
+	Dedup                bool
+	SortBy               string
+	StatsFile            string
+	Tui                  bool
+	Allowlist            string
+	ResourcePolicies     bool
+	CountOnly            bool
+	KeepMemberSpelling   bool
+	SourceColumns        bool
+	Provenance           bool
+	BindingAgeDays       int
+	RunId                string
+	EvaluateConditions   string
+	ServiceAccountStatus bool
+	UserStatus           bool
+	AdminSubject         string
+	Identities           string
+	DormantDays          int
+	ServiceAccountKeys   bool
+	MaxKeyAge            string
+	FailOn               string
+	LowMemory            bool
+	CheckAccess          bool
+	SharedVpc            bool
+	Iap                  bool
+	Kms                  bool
+	PolicyTags           bool
+	Repos                bool
+	BigQueryAcls         bool
+	BucketAcls           bool
+	Input                string
+	TraceApi             bool
+	TraceFile            string
+	CpuProfile           string
+	ExecTrace            string
+	OtelEndpoint         string
+	PermissionValidity   bool
+	StreamTo             string
+	CollapsePermissions  bool
+	PermissionColumns    bool
+	Service              string
+	PermissionResource   string
+	Verb                 string
+	ServiceEnablement    bool
+	VpcSc                bool
+	AccessApproval       bool
+	MaxRowsPerFile       int
+	Checksums            bool
+	Sign                 string
+	SignKey              string
+	RedactMembers        string
+	RedactSalt           string
+}
+
 func main() {
-	defer timeTrack(time.Now(), "Total time")
-	var filename string
-	var credentialsPath string
-	var orgId string
-	var projectId string
+	defer timeTrack("Total time")()
+	opts := &Options{}
+	logerr = log.New(errorCount, "Error: ", 0)
 	app := cli.NewApp()
 	app.Name = "policygopher"
 	app.UsageText = "policygopher [options]"
 	app.Usage = "Dumps all members roles and permissions for a GCP organization"
+	app.Version = toolBuild.Version
+	cli.VersionPrinter = func(c *cli.Context) {
+		printVersion(c.App.Writer, c.App.Name, toolBuild)
+	}
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:        "file",
-			Value:       "member_role_permissions.csv",
-			Usage:       "csv file output",
-			Destination: &filename,
+			Value:       defaultFilename,
+			Usage:       "file output, named after --format when left at the default; - writes to stdout and everything else to stderr",
+			Destination: &opts.Filename,
+		},
+		cli.StringFlag{
+			Name:        "format",
+			Value:       "csv",
+			Usage:       "output format: csv, json, ndjson (see the schema command), yaml-tree for one YAML document nesting resources under their parents, or cypher for a cypher-shell script loading a Neo4j graph",
+			Destination: &opts.Format,
+		},
+		cli.StringFlag{
+			Name:        "input",
+			Usage:       "read the bindings from a csv, json, or ndjson export instead of the APIs, to rerun reports and formats offline",
+			Destination: &opts.Input,
 		},
 		cli.StringFlag{
 			Name:        "org, o",
 			Usage:       "Organization ID",
-			Destination: &orgId,
+			Destination: &opts.OrgId,
 		},
 		cli.StringFlag{
 			Name:        "project, p",
 			Usage:       "Project ID, used to find Org ID if unspecified",
-			Destination: &projectId,
+			Destination: &opts.ProjectId,
 		},
 		cli.StringFlag{
 			Name:        "credentials, c",
 			Usage:       "credentials.json, used to find Org ID if Org ID or ProjectID are unspecified",
 			EnvVar:      "GOOGLE_APPLICATION_DEFAULT",
-			Destination: &credentialsPath,
+			Destination: &opts.CredentialsPath,
+		},
+		cli.BoolFlag{
+			Name:        "check-access",
+			Usage:       "before collecting, test which permissions the collectors need are held at the org and --project, and print what the export won't see",
+			Destination: &opts.CheckAccess,
+		},
+		cli.BoolFlag{
+			Name:        "login",
+			Usage:       "log in with a browser and store the credentials for this and later runs, instead of a key or gcloud",
+			Destination: &opts.Login,
+		},
+		cli.StringFlag{
+			Name:        "oauth-client",
+			Usage:       "json of the OAuth client of type Desktop app --login logs in through",
+			Destination: &opts.OAuthClient,
+		},
+		cli.StringFlag{
+			Name:        "config",
+			Usage:       "json config file, see README; an orgs list there crawls each org with its own credentials",
+			Destination: &opts.Config,
+		},
+		cli.StringFlag{
+			Name:        "http-cache",
+			Usage:       "directory caching API responses that carry an ETag, revalidated with If-None-Match on later runs",
+			Destination: &opts.HttpCache,
+		},
+		cli.StringFlag{
+			Name:        "roles-file",
+			Usage:       "predefined roles written by the roles dump command, looked up there instead of with the IAM API",
+			Destination: &opts.RolesFile,
+		},
+		cli.StringFlag{
+			Name:        "record",
+			Usage:       "directory to save every API response to, for later --replay",
+			Destination: &opts.Record,
+		},
+		cli.StringFlag{
+			Name:        "replay",
+			Usage:       "directory of responses saved with --record to answer API calls from, without GCP access",
+			Destination: &opts.Replay,
+		},
+		cli.IntFlag{
+			Name:        "api-concurrency",
+			Value:       8,
+			Usage:       "most requests in flight to one API, lowered automatically while the API is returning quota errors",
+			Destination: &opts.ApiConcurrency,
+		},
+		cli.IntFlag{
+			Name:        "max-api-calls",
+			Usage:       "fail without writing output or a snapshot once a request would go over this many API requests, retries included; 0 for no limit",
+			Destination: &apiCalls.max,
+		},
+		cli.BoolFlag{
+			Name:        "trace-api",
+			Usage:       "log every API request sent, retries included, with its response status and latency",
+			Destination: &opts.TraceApi,
+		},
+		cli.StringFlag{
+			Name:        "trace-file",
+			Usage:       "file --trace-api appends to instead of stderr",
+			Destination: &opts.TraceFile,
+		},
+		cli.StringFlag{
+			Name:        "cpu-profile",
+			Usage:       "file to write a CPU profile of the run to, for go tool pprof",
+			Destination: &opts.CpuProfile,
+		},
+		cli.StringFlag{
+			Name:        "exec-trace",
+			Usage:       "file to write an execution trace of the run to, with a region for each collector and phase, for go tool trace",
+			Destination: &opts.ExecTrace,
+		},
+		cli.StringFlag{
+			Name:        "otel-endpoint",
+			Usage:       "OpenTelemetry collector to send a trace of the run to, with OTLP over HTTP, e.g. http://localhost:4318",
+			Destination: &opts.OtelEndpoint,
+		},
+		cli.StringFlag{
+			Name:        "store",
+			Usage:       "snapshot store directory (default: \"~/.policygopher/snapshots\")",
+			Destination: &opts.StoreDir,
+		},
+		cli.BoolFlag{
+			Name:        "incremental",
+			Usage:       "fetch every policy but reuse role permissions from the latest snapshot for policies whose etag is unchanged, then save a new snapshot",
+			Destination: &opts.Incremental,
+		},
+		cli.StringFlag{
+			Name:        "notify-webhook",
+			Usage:       "Slack-compatible webhook URL notified of new high-risk bindings whenever a snapshot is saved",
+			Destination: &opts.NotifyWebhook,
+		},
+		cli.StringFlag{
+			Name:        "stream-to",
+			Usage:       "URL to POST collected policies to in json batches while the crawl goes on, see README",
+			Destination: &opts.StreamTo,
+		},
+		cli.StringFlag{
+			Name:        "allowlist",
+			Usage:       "json file of accepted bindings left out of snapshot diffs and webhook notifications, see README",
+			Destination: &opts.Allowlist,
+		},
+		cli.StringFlag{
+			Name:        "shard-by",
+			Usage:       "write one csv per project or folder into a directory named after --file, without its extension; a project goes with the folder it is directly in, and org-level rows share the org's csv",
+			Destination: &opts.ShardBy,
+		},
+		cli.IntFlag{
+			Name:        "max-rows-per-file",
+			Usage:       "split the export into numbered parts of at most this many rows, listed with their row counts and checksums in a manifest json; 0 for one file",
+			Destination: &opts.MaxRowsPerFile,
+		},
+		cli.BoolFlag{
+			Name:        "low-memory",
+			Usage:       "sort the export in runs on disk and stream it out from them, instead of expanding every row in memory",
+			Destination: &opts.LowMemory,
+		},
+		cli.BoolFlag{
+			Name:        "checksums",
+			Usage:       "write a <file>.sha256 next to the export, each report, and the stats, readable by sha256sum -c",
+			Destination: &opts.Checksums,
+		},
+		cli.StringFlag{
+			Name:        "sign",
+			Usage:       "also sign the export, reports, and stats with cosign (<file>.sig) or gpg (<file>.asc)",
+			Destination: &opts.Sign,
+		},
+		cli.StringFlag{
+			Name:        "sign-key",
+			Usage:       "key --sign signs with: a cosign key reference, keyless when empty, or a gpg key id, the default key when empty",
+			Destination: &opts.SignKey,
+		},
+		cli.StringFlag{
+			Name:        "reports",
+			Usage:       fmt.Sprintf("comma separated reports to write alongside the export: %s", strings.Join(reportNames(), ", ")),
+			Destination: &opts.Reports,
+		},
+		cli.StringFlag{
+			Name:        "report-dir",
+			Value:       ".",
+			Usage:       "directory reports are written to, as <report>.csv",
+			Destination: &opts.ReportDir,
+		},
+		cli.StringFlag{
+			Name:        "resource-name-style",
+			Value:       "legacy",
+			Usage:       "how the Resource column is written: legacy, canonical (projects/my-project), or full (//cloudresourcemanager.googleapis.com/projects/my-project)",
+			Destination: &opts.ResourceNameStyle,
+		},
+		cli.BoolFlag{
+			Name:        "count-only",
+			Usage:       "print binding counts per resource type and resource instead of writing the export, without looking up roles",
+			Destination: &opts.CountOnly,
+		},
+		cli.StringFlag{
+			Name:        "stats-file",
+			Usage:       "json file to write run totals and phase durations to after the export",
+			Destination: &opts.StatsFile,
+		},
+		cli.BoolFlag{
+			Name:        "tui",
+			Usage:       "redraw a dashboard of collector progress, errors, API calls per second, and the latest messages on the terminal instead of printing each message",
+			Destination: &opts.Tui,
+		},
+		cli.BoolFlag{
+			Name:        "resource-policies",
+			Usage:       "also export policies set on resources inside projects, with one Cloud Asset Inventory search per project",
+			Destination: &opts.ResourcePolicies,
+		},
+		cli.BoolFlag{
+			Name:        "shared-vpc",
+			Usage:       "also collect Shared VPC service project attachments and the policies of host projects' subnetworks",
+			Destination: &opts.SharedVpc,
+		},
+		cli.BoolFlag{
+			Name:        "iap",
+			Usage:       "also collect the policies of Identity-Aware Proxy web apps, backend services, and TCP forwarding tunnels",
+			Destination: &opts.Iap,
+		},
+		cli.BoolFlag{
+			Name:        "kms",
+			Usage:       "also collect the policies of Cloud KMS key rings in every location",
+			Destination: &opts.Kms,
+		},
+		cli.BoolFlag{
+			Name:        "policy-tags",
+			Usage:       "also collect the policies of Data Catalog taxonomies and policy tags that grant BigQuery column-level access",
+			Destination: &opts.PolicyTags,
+		},
+		cli.BoolFlag{
+			Name:        "repos",
+			Usage:       "also collect the policies of Cloud Source Repositories and Artifact Registry repositories",
+			Destination: &opts.Repos,
+		},
+		cli.BoolFlag{
+			Name:        "bigquery-acls",
+			Usage:       "also collect BigQuery dataset ACLs as rows of the equivalent roles, adding an Origin column",
+			Destination: &opts.BigQueryAcls,
+		},
+		cli.BoolFlag{
+			Name:        "bucket-acls",
+			Usage:       "also collect the ACLs of buckets without uniform bucket-level access as rows of the legacy storage roles, adding an Origin column",
+			Destination: &opts.BucketAcls,
+		},
+		cli.BoolFlag{
+			Name:        "permission-validity",
+			Usage:       "add a PermissionValid column telling whether each permission can apply to the bound resource's type, from queryTestablePermissions",
+			Destination: &opts.PermissionValidity,
+		},
+		cli.BoolFlag{
+			Name:        "service-enablement",
+			Usage:       "add a ServiceEnabled column telling whether each permission's API is enabled in the bound resource's project, from the Service Usage API",
+			Destination: &opts.ServiceEnablement,
+		},
+		cli.BoolFlag{
+			Name:        "vpc-sc",
+			Usage:       "add a Perimeter column naming the VPC Service Controls perimeters the bound resource's project is in, from Access Context Manager",
+			Destination: &opts.VpcSc,
+		},
+		cli.BoolFlag{
+			Name:        "access-approval",
+			Usage:       "read the Access Approval enrollment and settings of the org, folders, and projects for the access-approval report",
+			Destination: &opts.AccessApproval,
+		},
+		cli.BoolFlag{
+			Name:        "collapse-permissions",
+			Usage:       "write one Permission per service summarizing the role's permissions in it, e.g. \"storage: 47 permissions (incl. setIamPolicy)\", instead of every permission",
+			Destination: &opts.CollapsePermissions,
+		},
+		cli.BoolFlag{
+			Name:        "permission-columns",
+			Usage:       "add PermissionService, PermissionResource, and PermissionVerb columns splitting each permission, e.g. compute, instances, setIamPolicy",
+			Destination: &opts.PermissionColumns,
+		},
+		cli.StringFlag{
+			Name:        "service",
+			Usage:       "comma separated services, e.g. storage, to write only the permissions of",
+			Destination: &opts.Service,
+		},
+		cli.StringFlag{
+			Name:        "permission-resource",
+			Usage:       "comma separated resources, e.g. buckets, to write only the permissions on",
+			Destination: &opts.PermissionResource,
+		},
+		cli.StringFlag{
+			Name:        "verb",
+			Usage:       "comma separated verbs, e.g. setIamPolicy, to write only the permissions for",
+			Destination: &opts.Verb,
+		},
+		cli.StringFlag{
+			Name:        "raw-policies",
+			Usage:       "directory to write each resource's IAM policy to as returned by the API, as <name>.json",
+			Destination: &opts.RawPolicies,
+		},
+		cli.StringFlag{
+			Name:        "sort-by",
+			Value:       defaultSortBy,
+			Usage:       "comma separated fields rows are sorted by: resource, type, member, role; none keeps the order they were collected in",
+			Destination: &opts.SortBy,
+		},
+		cli.BoolFlag{
+			Name:        "keep-member-spelling",
+			Usage:       "write members exactly as policies spell them, instead of lowercasing emails and folding gmail dots and +tags",
+			Destination: &opts.KeepMemberSpelling,
+		},
+		cli.StringFlag{
+			Name:        "redact-members",
+			Usage:       "hide the users and groups in the output and reports, as a keyed hash of their email (hash) or as *@<domain> (domain-only)",
+			Destination: &opts.RedactMembers,
+		},
+		cli.StringFlag{
+			Name:        "redact-salt",
+			Usage:       "secret key of --redact-members hash; the same salt gives the same hashes across runs",
+			Destination: &opts.RedactSalt,
+		},
+		cli.BoolFlag{
+			Name:        "service-account-status",
+			Usage:       "add MemberState and MemberLastActive columns telling whether bound service accounts are disabled or deleted and when they last authenticated",
+			Destination: &opts.ServiceAccountStatus,
+		},
+		cli.BoolFlag{
+			Name:        "user-status",
+			Usage:       "add MemberState and MemberLastActive columns telling whether bound users are suspended and when they last logged in, from the Admin SDK",
+			Destination: &opts.UserStatus,
+		},
+		cli.StringFlag{
+			Name:        "admin-subject",
+			Usage:       "Workspace admin the --credentials service account acts as with domain-wide delegation for --user-status",
+			Destination: &opts.AdminSubject,
+		},
+		cli.StringFlag{
+			Name:        "identities",
+			Usage:       "HR or identity provider export, a csv with a header row or SCIM users as .json, adding Department, Manager, and EmploymentStatus columns for bound users",
+			Destination: &opts.Identities,
+		},
+		cli.IntFlag{
+			Name:        "dormant-days",
+			Value:       90,
+			Usage:       "days without activity after which the dormant-members report lists a member",
+			Destination: &opts.DormantDays,
+		},
+		cli.BoolFlag{
+			Name:        "service-account-keys",
+			Usage:       "list the user-managed keys of the service accounts of every project for the service-account-keys report",
+			Destination: &opts.ServiceAccountKeys,
+		},
+		cli.StringFlag{
+			Name:        "max-key-age",
+			Value:       "90d",
+			Usage:       "age in days (90d) or as a duration (2160h) after which the service-account-keys report flags a key as Stale; 0 for none",
+			Destination: &opts.MaxKeyAge,
+		},
+		cli.StringFlag{
+			Name:        "fail-on",
+			Usage:       fmt.Sprintf("comma separated conditions that make the export exit with status %d once its outputs are written: %s", failOnExitCode, strings.Join(failConditionNames(), ", ")),
+			Destination: &opts.FailOn,
+		},
+		cli.StringFlag{
+			Name:        "evaluate-conditions-at",
+			Usage:       "add a ConditionActive column telling whether each conditional binding grants access at this RFC 3339 time, or now",
+			Destination: &opts.EvaluateConditions,
+		},
+		cli.BoolFlag{
+			Name:        "source-columns",
+			Usage:       "add RunId and Source columns naming the run and the org (or project-<id>) each row was crawled from",
+			Destination: &opts.SourceColumns,
+		},
+		cli.StringFlag{
+			Name:        "run-id",
+			Usage:       "run ID written by --source-columns, a UTC timestamp with a random suffix by default",
+			Destination: &opts.RunId,
+		},
+		cli.BoolFlag{
+			Name:        "provenance",
+			Usage:       "add PolicyEtag and BindingIndex columns tracing each row to the policy revision and binding it was read from",
+			Destination: &opts.Provenance,
+		},
+		cli.IntFlag{
+			Name:        "binding-age-days",
+			Usage:       "add GrantedAt and GrantedBy columns saying when and by whom each binding was added, from this many days of Admin Activity audit logs; 0 not to read them",
+			Destination: &opts.BindingAgeDays,
+		},
+		cli.BoolFlag{
+			Name:        "dedup",
+			Usage:       "merge bindings of the same role to the same member on the same resource, adding a Count column",
+			Destination: &opts.Dedup,
+		},
+		cli.BoolFlag{
+			Name:        "hide-google-managed",
+			Usage:       "leave bindings held by Google-managed service agents out of the csv output",
+			Destination: &opts.HideGoogleManaged,
+		},
+		cli.StringFlag{
+			Name:        "risk-weights",
+			Usage:       "json file of permission (or glob pattern) to risk weight, overriding the built-in weights",
+			Destination: &opts.RiskWeights,
+		},
+		cli.IntFlag{
+			Name:        "top",
+			Value:       topN,
+			Usage:       "number of entries in top-N reports",
+			Destination: &topN,
+		},
+	}
+	app.Flags = withEnvVars(app.Flags)
+	var gkeFile string
+	var gkeRbacFile string
+	var listen string
+	var collectors string
+	var simMember, simRole, simResource, simSnapshot string
+	var testableSnapshot string
+	var desiredFile string
+	var orgPoliciesFile string
+	var replSnapshot string
+	deployment := &deployConfig{}
+	var deployCollectors, deployDir string
+	var deployApply bool
+	app.Commands = []cli.Command{
+		{
+			Name:  "snapshot",
+			Usage: "Save, list, and diff policy snapshots kept in the local store",
+			Subcommands: []cli.Command{
+				{
+					Name:  "save",
+					Usage: "Collect all policy bindings and save them as a new snapshot",
+					Action: func(c *cli.Context) error {
+						return saveSnapshot(opts)
+					},
+				},
+				{
+					Name:  "list",
+					Usage: "List saved snapshots",
+					Action: func(c *cli.Context) error {
+						return listSnapshots(opts.StoreDir)
+					},
+				},
+				{
+					Name:      "diff",
+					Usage:     "Show bindings added and removed between two snapshots, the latest two by default",
+					ArgsUsage: "[old-id|export-file] [new-id|export-file]",
+					Action: func(c *cli.Context) error {
+						return diffSnapshots(opts.StoreDir, c.Args().Get(0), c.Args().Get(1), opts.Allowlist)
+					},
+				},
+			},
+		},
+		{
+			Name:  "gke",
+			Usage: "List GKE clusters with their IAM-relevant settings, optionally with RBAC bindings to Google identities",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "file",
+					Value:       "gke_clusters.csv",
+					Usage:       "csv file output for clusters",
+					Destination: &gkeFile,
+				},
+				cli.StringFlag{
+					Name:        "rbac-file",
+					Usage:       "csv file output for Kubernetes RBAC bindings that reference Google identities, skipped if empty",
+					Destination: &gkeRbacFile,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return exportGke(opts, gkeFile, gkeRbacFile)
+			},
+		},
+		{
+			Name:  "auditor-role",
+			Usage: "Print the minimal custom role needed to run the given collectors",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "collectors",
+					Usage:       fmt.Sprintf("comma separated collectors, all by default: %s", strings.Join(collectorNames(), ", ")),
+					Destination: &collectors,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return printAuditorRole(collectors)
+			},
+		},
+		{
+			Name:  "roles",
+			Usage: "Inspect IAM roles",
+			Subcommands: []cli.Command{
+				{
+					Name:  "dump",
+					Usage: fmt.Sprintf("Write every predefined role with its permissions to --roles-file, %s by default", defaultRolesFile),
+					Action: func(c *cli.Context) error {
+						return dumpRoles(opts)
+					},
+				},
+				{
+					Name:      "diff",
+					Usage:     "Show the permissions granted by only one of two roles",
+					ArgsUsage: "<role> <role>",
+					Action: func(c *cli.Context) error {
+						return diffRoles(opts, c.Args().Get(0), c.Args().Get(1))
+					},
+				},
+			},
+		},
+		{
+			Name:  "permissions",
+			Usage: "Inspect IAM permissions",
+			Subcommands: []cli.Command{
+				{
+					Name:      "testable",
+					Usage:     "List the permissions that can be granted on a resource with how many members hold each in a snapshot",
+					ArgsUsage: "<resource>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "snapshot",
+							Usage:       "snapshot id or export file to count members in, the latest snapshot by default",
+							Destination: &testableSnapshot,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						return printTestablePermissions(opts, testableSnapshot, c.Args().Get(0))
+					},
+				},
+			},
+		},
+		{
+			Name:  "simulate",
+			Usage: "Preview the effect of IAM changes on a saved snapshot",
+			Subcommands: []cli.Command{
+				{
+					Name:  "remove-binding",
+					Usage: "Show which permissions a member would lose if one of their bindings were removed",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "member",
+							Usage:       "member of the binding, e.g. user:alice@example.com",
+							Destination: &simMember,
+						},
+						cli.StringFlag{
+							Name:        "role",
+							Usage:       "role of the binding, e.g. roles/editor",
+							Destination: &simRole,
+						},
+						cli.StringFlag{
+							Name:        "resource",
+							Usage:       "resource holding the binding, e.g. projects/my-project",
+							Destination: &simResource,
+						},
+						cli.StringFlag{
+							Name:        "snapshot",
+							Usage:       "snapshot id or export file to simulate against, the latest snapshot by default",
+							Destination: &simSnapshot,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						return simulateRemoveBinding(opts.StoreDir, opts.OrgId, simSnapshot, simMember, simRole, simResource)
+					},
+				},
+			},
+		},
+		{
+			Name:  "verify",
+			Usage: "Compare the org's bindings to a desired state, exiting with status 2 when they drifted",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "desired",
+					Usage:       "desired bindings, a document shaped like --format yaml-tree (.yaml) or a csv, json, or ndjson export",
+					Destination: &desiredFile,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return verifyDesired(opts, desiredFile)
+			},
+		},
+		{
+			Name:  "org-policies",
+			Usage: "Export whether each boolean constraint set in the org is enforced on the org and every folder and project, inheritance included",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "file",
+					Value:       defaultOrgPoliciesFile,
+					Usage:       "csv file to write",
+					Destination: &orgPoliciesFile,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return exportOrgPolicies(opts, orgPoliciesFile)
+			},
+		},
+		{
+			Name:  "repl",
+			Usage: "Query the bindings of a snapshot interactively by member, role, resource, or permission",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "snapshot",
+					Usage:       "snapshot id or export file to query, the latest snapshot by default",
+					Destination: &replSnapshot,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return runRepl(opts, replSnapshot)
+			},
+		},
+		{
+			Name:      "schema",
+			Usage:     "Print the JSON Schemas of the json and ndjson formats",
+			ArgsUsage: fmt.Sprintf("[%s]", strings.Join(schemaNames(), "|")),
+			Action: func(c *cli.Context) error {
+				return printSchema(c.Args().Get(0))
+			},
+		},
+		{
+			Name:  "serve",
+			Usage: "Serve snapshots from the store over gRPC, see proto/policygopher.proto",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "listen",
+					Value:       "localhost:50051",
+					Usage:       "address to listen on",
+					Destination: &listen,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return serveGrpc(opts.StoreDir, opts.OrgId, listen)
+			},
+		},
+		{
+			Name:  "deploy",
+			Usage: "Write, or apply, the Terraform configuration running policygopher on a schedule as a Cloud Run job exporting to BigQuery",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "host-project",
+					Usage:       "project the job, bucket, and dataset are created in",
+					Destination: &deployment.Project,
+				},
+				cli.StringFlag{
+					Name:        "region",
+					Value:       "us-central1",
+					Usage:       "region of the job, its schedule, the bucket, and the dataset",
+					Destination: &deployment.Region,
+				},
+				cli.StringFlag{
+					Name:        "image",
+					Usage:       "policygopher container image the job runs",
+					Destination: &deployment.Image,
+				},
+				cli.StringFlag{
+					Name:        "bucket",
+					Usage:       "bucket created for the exports",
+					Destination: &deployment.Bucket,
+				},
+				cli.StringFlag{
+					Name:        "dataset",
+					Value:       "policygopher",
+					Usage:       "BigQuery dataset created with an external table over the latest export",
+					Destination: &deployment.Dataset,
+				},
+				cli.StringFlag{
+					Name:        "schedule",
+					Value:       "0 6 * * *",
+					Usage:       "cron schedule of the job",
+					Destination: &deployment.Schedule,
+				},
+				cli.StringFlag{
+					Name:        "collectors",
+					Usage:       fmt.Sprintf("comma separated collectors the job's custom role allows, core by default: %s", strings.Join(collectorNames(), ", ")),
+					Destination: &deployCollectors,
+				},
+				cli.StringFlag{
+					Name:        "dir",
+					Value:       "policygopher-deploy",
+					Usage:       "directory main.tf is written to",
+					Destination: &deployDir,
+				},
+				cli.BoolFlag{
+					Name:        "apply",
+					Usage:       "run terraform init and apply in --dir once main.tf is written",
+					Destination: &deployApply,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				deployment.OrgId = opts.OrgId
+				deployment.Reports = opts.Reports
+				return deploy(deployment, deployCollectors, deployDir, deployApply)
+			},
 		},
 	}
 
 	app.Action = func(c *cli.Context) error {
-		return printToCsv(filename, credentialsPath, orgId, projectId)
+		return exportPolicies(opts)
+	}
+	var prof *profiler
+	app.Before = func(c *cli.Context) error {
+		var err error
+		if prof, err = startProfiling(opts.CpuProfile, opts.ExecTrace); err != nil {
+			return err
+		}
+		if opts.OtelEndpoint != "" {
+			otel = startOtel(opts.OtelEndpoint)
+		}
+		if opts.Login {
+			return login(context.Background(), opts.OAuthClient)
+		}
+		return nil
+	}
+	app.After = func(c *cli.Context) error {
+		apiCalls.Print()
+		printCollectorTimings()
+		prof.Stop()
+		otel.Shutdown()
+		return nil
 	}
 	err := app.Run(os.Args)
+	var failed *failOnError
+	var drifted *driftError
+	if errors.As(err, &failed) || errors.As(err, &drifted) {
+		log.Print(err)
+		os.Exit(failOnExitCode)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func printToCsv(filename string, credentialsPath string, orgId string, projectId string) error {
-	ctx := context.Background()
-	if _, err := os.Stat(filename); err == nil {
-		log.Printf("Fils %s found, skipping export roles", filename)
-		return nil
+func exportPolicies(opts *Options) error {
+	if err := checkFormat(opts.Format); err != nil {
+		return err
+	}
+	if err := checkSigner(opts.Sign); err != nil {
+		return err
 	}
-	f, err := os.Create(fmt.Sprintf("tmp.%s", filename))
+	if err := checkRedaction(opts.RedactMembers, opts.RedactSalt); err != nil {
+		return err
+	}
+	config, err := loadConfig(opts.Config)
 	if err != nil {
 		return err
 	}
-	writer := bufio.NewWriter(f)
-	_, err = fmt.Fprintf(writer, "%s,%s,%s,%s,%s\n", "Resource", "Type", "Member", "Role", "Permission")
+	if opts.Filename == stdoutFilename {
+		if len(config.Orgs) > 0 {
+			return errors.New("--file - can't be used with a config file listing several orgs")
+		}
+		useStdoutForExport()
+	}
+	if opts.Tui {
+		if !isTerminal(os.Stderr) {
+			log.Printf("stderr isn't a terminal, ignoring --tui")
+		} else if tui, err = startDashboard(os.Stderr); err != nil {
+			return err
+		} else {
+			defer tui.Stop()
+		}
+	}
+	if opts.SourceColumns {
+		if opts.RunId == "" {
+			opts.RunId = newRunId()
+		}
+		log.Printf("Run ID %s", opts.RunId)
+	}
+	if opts.Input != "" && (len(config.Orgs) > 0 || opts.Incremental) {
+		return errors.New("--input can't be used with --incremental or a config file listing several orgs")
+	}
+	if len(config.Orgs) > 0 {
+		err = exportOrgs(opts, config)
+	} else {
+		_, err = exportOrg(opts, nil)
+	}
 	if err != nil {
 		return err
 	}
-	logerr = log.New(os.Stderr, "Error: ", 0)
+	if err := sealOutputs(opts); err != nil {
+		return err
+	}
+	return failOnResult()
+}
+
+func exportFilename(opts *Options) string {
+	if opts.Format != "csv" && opts.Filename == defaultFilename {
+		// yaml-tree is one of the shapes a .yaml file can have
+		ext := strings.TrimSuffix(opts.Format, "-tree")
+		return strings.TrimSuffix(opts.Filename, filepath.Ext(opts.Filename)) + "." + ext
+	}
+	return opts.Filename
+}
 
-	resman, err := NewResourceManager(ctx, credentialsPath, orgId, projectId)
+// exportOrg exports a single org, using ts instead of application default credentials when set.
+// It returns nil and no error when the output already exists.
+func exportOrg(opts *Options, ts oauth2.TokenSource) (*orgSummary, error) {
+	ctx := context.Background()
+	output := exportFilename(opts)
+	if opts.ShardBy != "" {
+		if output == stdoutFilename {
+			return nil, errors.New("--shard-by writes a directory and can't be used with --file -")
+		}
+		if opts.Format != "csv" {
+			return nil, errors.New("--shard-by only supports the csv format")
+		}
+		if opts.ShardBy != "project" && opts.ShardBy != "folder" {
+			return nil, errors.New(fmt.Sprintf("Unknown --shard-by %s, expected project or folder", opts.ShardBy))
+		}
+		output = strings.TrimSuffix(output, filepath.Ext(output))
+	}
+	if opts.MaxRowsPerFile > 0 {
+		if output == stdoutFilename || opts.ShardBy != "" {
+			return nil, errors.New("--max-rows-per-file can't be used with --file - or --shard-by")
+		}
+		if !chunkFormats[opts.Format] {
+			return nil, errors.New("--max-rows-per-file only supports the csv, json, and ndjson formats")
+		}
+	}
+	if opts.LowMemory {
+		if opts.ShardBy != "" || opts.MaxRowsPerFile > 0 {
+			return nil, errors.New("--low-memory can't be used with --shard-by or --max-rows-per-file")
+		}
+		if !spoolFormats[opts.Format] {
+			return nil, errors.New("--low-memory only supports the csv, json, and ndjson formats")
+		}
+	}
+	if opts.CollapsePermissions && opts.PermissionValidity {
+		return nil, errors.New("--collapse-permissions and --permission-validity can't be used together")
+	}
+	reportList, err := parseReports(opts.Reports)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	failOn, err := parseFailOn(opts.FailOn)
+	if err != nil {
+		return nil, err
+	}
+	config, err := loadConfig(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.BreakGlass) > 0 && !stringSet(failOn)["break-glass"] {
+		failOn = append(failOn, "break-glass")
+	}
+	weights, err := loadRiskWeights(opts.RiskWeights)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := loadIdentities(opts.Identities)
+	if err != nil {
+		return nil, err
+	}
+	sortBy, err := parseSortBy(opts.SortBy)
+	if err != nil {
+		return nil, err
+	}
+	allow, err := loadAllowlist(opts.Allowlist)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(output); err == nil && output != stdoutFilename && !opts.CountOnly {
+		log.Printf("Fils %s found, skipping export roles", output)
+		return nil, nil
+	}
+	var input *Snapshot
+	if opts.Input != "" {
+		if input, err = readExport(opts.Input); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Read %d bindings from %s\n", len(input.Rows), opts.Input)
+		if opts.OrgId == "" && opts.ProjectId == "" {
+			// the org can't be looked up offline, json exports know it and csv ones don't
+			inputOpts := *opts
+			inputOpts.OrgId = input.OrgId
+			if inputOpts.OrgId == "" {
+				inputOpts.OrgId = "input"
+			}
+			opts = &inputOpts
+		}
+	}
+	recorder := newStatsRecorder()
+	resman, err := newResourceManagerFromOptions(ctx, opts, ts)
+	if err != nil {
+		return nil, err
+	}
+	resman.riskWeights = weights
+	resman.identities = identities
+	if len(config.BreakGlass) > 0 {
+		resman.breakGlass = breakGlassAccounts(config.BreakGlass, resman.orgId)
+	}
+	if err := resman.SetResourceNameStyle(opts.ResourceNameStyle); err != nil {
+		return nil, err
+	}
+	if opts.RawPolicies != "" {
+		if err := resman.SetRawPolicyDir(opts.RawPolicies); err != nil {
+			return nil, err
+		}
+	}
+	if input != nil {
+		resman.SeedRoles(input)
+	}
+	var store *snapshotStore
+	if opts.Incremental {
+		if store, err = NewSnapshotStore(opts.StoreDir); err != nil {
+			return nil, err
+		}
+		if err := useLatestSnapshot(store, resman); err != nil {
+			return nil, err
+		}
 	}
 
-	allRows, err := resman.GetAllPolicyRows()
+	if opts.CheckAccess && input == nil {
+		if err := resman.CheckSelfAccess(enabledCollectors(opts), opts.ProjectId); err != nil {
+			logerr.Printf("Unable to check access: %v\n", err)
+		}
+	}
+	var allRows *[]*Row
+	if input != nil {
+		allRows = &input.Rows
+	} else if allRows, err = resman.GetAllPolicyRows(); err != nil {
+		return nil, err
+	}
+	rows, err := enrichRows(opts, *allRows, resman)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer timeTrack(time.Now(), "Printing CSV")
-	fmt.Println("Printing CSV")
-	for _, row := range *allRows {
-		if err := row.Print(writer, resman); err != nil {
-			logerr.Printf("%v\n", err)
+	if err := apiCalls.Exceeded(); err != nil {
+		return nil, err
+	}
+	shown := rows
+	if opts.HideGoogleManaged {
+		shown = withoutGoogleManaged(rows)
+		fmt.Printf("Hiding %d bindings held by Google-managed service agents\n", len(rows)-len(shown))
+	}
+	if opts.CountOnly {
+		printBindingCounts(shown, resman)
+		summary := summarizeRows(shown)
+		summary.Organization = resman.OrgMetadata()
+		return summary, nil
+	}
+	if !resman.conditionTime.IsZero() {
+		printConditionSummary(shown, resman)
+	}
+	var spool *rowSpool
+	if opts.LowMemory {
+		if spool, err = spoolRows(shown, rowLess(sortBy, resman)); err != nil {
+			return nil, err
+		}
+		defer spool.Close()
+		shown = nil
+	} else {
+		sortRows(shown, sortBy, resman)
+		if opts.ShardBy != "" {
+			err = writeShards(output, opts.ShardBy, shown, resman)
+		} else if opts.MaxRowsPerFile > 0 {
+			err = writeChunks(output, opts.Format, opts.MaxRowsPerFile, shown, resman)
+		} else {
+			err = renderers[opts.Format](output, shown, resman)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := writeReports(reportList, opts.ReportDir, rows, resman); err != nil {
+		return nil, err
+	}
+	if err := checkFailConditions(failOn, rows, resman); err != nil {
+		return nil, err
+	}
+	if store != nil {
+		snap, err := storeSnapshot(store, resman, rows, opts.NotifyWebhook, allow)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Saved snapshot %s for the next incremental run\n", snap.Id)
+	}
+	var stats *exportStats
+	if opts.StatsFile != "" {
+		stats = recorder.Stats(resman, rows)
+	}
+	summary := summarizeRows(rows)
+	summary.Organization = resman.OrgMetadata()
+	if spool != nil {
+		// nothing needs the rows anymore, let them go before they become a row per permission
+		rows, allRows, input = nil, nil, nil
+		if err := writeSpooled(output, opts.Format, spool, resman); err != nil {
+			return nil, err
+		}
+		if stats != nil {
+			stats.Finished, stats.PermissionRows = time.Now().UTC(), resman.permissionRows
+		}
+	}
+	if stats != nil {
+		if err := writeStats(opts.StatsFile, stats); err != nil {
+			return nil, err
 		}
 	}
-	if err := os.Rename(fmt.Sprintf("tmp.%s", filename), filename); err != nil {
-		return errors.New(fmt.Sprintf("Unable to move tmp.%s to %s: %v", filename, filename, err))
+	return summary, nil
+}
+
+func writeCsv(filename string, rows []*Row, resman *resourceManager) error {
+	defer timeTrack(fmt.Sprintf("Printing CSV %s", filename))()
+	fmt.Printf("Printing CSV %s\n", filename)
+	return writeCsvFile(filename, rows, resman)
+}
+
+// writeCsvFile writes rows as csv to filename, or stdout for -.
+func writeCsvFile(filename string, rows []*Row, resman *resourceManager) error {
+	return writeCsvRows(filename, sliceRows(rows), resman)
+}
+
+// writeCsvRows writes the rows each yields as csv to filename, or stdout for -.
+func writeCsvRows(filename string, each rowSource, resman *resourceManager) error {
+	f, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	writer := f.Writer
+	_, err = fmt.Fprintf(writer, "%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s", "Resource", "Type", "ResourceName", "DisplayName",
+		"Member", "MemberClass", "MemberProject", "Role", "Permission", "BindingRisk", "MemberRisk", "LifecycleState")
+	if err == nil && resman.memberStatusColumns() {
+		_, err = writer.WriteString(",MemberState,MemberLastActive")
+	}
+	if err == nil && !resman.conditionTime.IsZero() {
+		_, err = writer.WriteString(",ConditionActive")
+	}
+	if err == nil && resman.sourceColumns() {
+		_, err = writer.WriteString(",RunId,Source")
+	}
+	if err == nil && resman.countColumn {
+		_, err = writer.WriteString(",Count")
+	}
+	if err == nil && resman.originColumn() {
+		_, err = writer.WriteString(",Origin")
+	}
+	if err == nil && resman.permissionValidColumn() {
+		_, err = writer.WriteString(",PermissionValid")
+	}
+	if err == nil && resman.serviceEnabledColumn() {
+		_, err = writer.WriteString(",ServiceEnabled")
 	}
-	if err := writer.Flush(); err != nil {
-		return errors.New(fmt.Sprintf("Error flushing writer: %v", err))
+	if err == nil && resman.perimeterColumn() {
+		_, err = writer.WriteString(",Perimeter")
 	}
-	if err := f.Close(); err != nil {
-		return errors.New(fmt.Sprintf("Error closing file: %v", err))
+	if err == nil && resman.permissionColumns {
+		_, err = writer.WriteString(",PermissionService,PermissionResource,PermissionVerb")
 	}
-	return nil
+	if err == nil && resman.provenance {
+		_, err = writer.WriteString(",PolicyEtag,BindingIndex")
+	}
+	if err == nil && resman.bindingAgeDays > 0 {
+		_, err = writer.WriteString(",GrantedAt,GrantedBy")
+	}
+	if err == nil && resman.identityColumns() {
+		_, err = writer.WriteString(",Department,Manager,EmploymentStatus")
+	}
+	if err == nil {
+		_, err = writer.WriteString("\n")
+	}
+	if err != nil {
+		return err
+	}
+	err = each(func(row *Row) error {
+		if err := row.Print(writer, resman); err != nil {
+			logerr.Printf("%v\n", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return f.Commit()
 }
 
-func timeTrack(start time.Time, name string) {
-	elapsed := time.Since(start)
-	log.Printf("%s took %s", name, elapsed)
+// timeTrack starts timing a phase and returns the func that logs how long it took and records
+// it, with the API requests and retries made meanwhile, for the stats file.
+func timeTrack(name string) func() {
+	end := beginSpan("phase", name)
+	return func() {
+		run := end()
+		recordPhase(run)
+		log.Printf("%s took %s", name, time.Duration(run.Seconds*float64(time.Second)))
+	}
 }
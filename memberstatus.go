@@ -0,0 +1,197 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"google.golang.org/api/iam/v1"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memberStatusWorkers is how many projects' service accounts are looked up at once.
+const memberStatusWorkers = 8
+
+// memberStatus is what is known of a member beyond the policies naming it.
+type memberStatus struct {
+	// ACTIVE, DISABLED, or DELETED
+	State string
+	// last authentication seen, zero when there was none in the observation period
+	LastActive time.Time
+}
+
+// memberStatusColumns reports whether rows carry the MemberState and MemberLastActive columns.
+func (r *resourceManager) memberStatusColumns() bool {
+	return r.memberStates != nil
+}
+
+// memberStatusKey is the key of memberStates: the member with its email lowercased, as the
+// directory and the IAM API spell them, so lookups work with --keep-member-spelling too.
+func memberStatusKey(member string) string {
+	i := strings.Index(member, ":")
+	return member[:i+1] + strings.ToLower(member[i+1:])
+}
+
+// MemberStatus returns the MemberState and MemberLastActive columns of a member, empty when unknown.
+func (r *resourceManager) MemberStatus(member string) (string, string) {
+	if strings.HasPrefix(member, "deleted:") {
+		return "DELETED", ""
+	}
+	status, ok := r.memberStates[memberStatusKey(member)]
+	if !ok {
+		return "", ""
+	}
+	if status.LastActive.IsZero() {
+		return status.State, ""
+	}
+	return status.State, status.LastActive.UTC().Format(time.RFC3339)
+}
+
+type serviceAccountActivity struct {
+	Activity struct {
+		LastAuthenticatedTime string `json:"lastAuthenticatedTime"`
+		ServiceAccount        struct {
+			ServiceAccountId string `json:"serviceAccountId"`
+		} `json:"serviceAccount"`
+	} `json:"activity"`
+}
+
+type queryActivitiesResponse struct {
+	Activities    []*serviceAccountActivity `json:"activities"`
+	NextPageToken string                    `json:"nextPageToken"`
+}
+
+// serviceAccountLastAuthentication returns when each service account of a project, by unique
+// id, last authenticated according to Policy Intelligence.
+func (r *resourceManager) serviceAccountLastAuthentication(project string) (map[string]time.Time, error) {
+	last := make(map[string]time.Time)
+	pageToken := ""
+	for {
+		u := fmt.Sprintf("https://policyanalyzer.googleapis.com/v1/projects/%s/locations/global/activityTypes/serviceAccountLastAuthentication/activities:query?pageSize=1000",
+			url.PathEscape(project))
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		resp := &queryActivitiesResponse{}
+		if err := r.getJSON(u, resp); err != nil {
+			return last, err
+		}
+		for _, a := range resp.Activities {
+			t, err := time.Parse(time.RFC3339, a.Activity.LastAuthenticatedTime)
+			if err == nil {
+				last[a.Activity.ServiceAccount.ServiceAccountId] = t
+			}
+		}
+		if resp.NextPageToken == "" {
+			return last, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// serviceAccountStatuses lists the service accounts of a project with their state and last
+// authentication, keyed by member.
+func (r *resourceManager) serviceAccountStatuses(project string) (map[string]*memberStatus, error) {
+	statuses := make(map[string]*memberStatus)
+	ids := make(map[string]string)
+	err := r.service.Projects.ServiceAccounts.List(fmt.Sprintf("projects/%s", project)).PageSize(100).
+		Fields("nextPageToken,accounts(email,uniqueId,disabled)").
+		Pages(r.ctx, func(page *iam.ListServiceAccountsResponse) error {
+			for _, sa := range page.Accounts {
+				member := "serviceAccount:" + strings.ToLower(sa.Email)
+				status := &memberStatus{State: "ACTIVE"}
+				if sa.Disabled {
+					status.State = "DISABLED"
+				}
+				statuses[member] = status
+				ids[sa.UniqueId] = member
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	last, err := r.serviceAccountLastAuthentication(project)
+	if err != nil {
+		logerr.Printf("Unable to query last authentication of service accounts in project %s: %v\n", project, err)
+	}
+	for id, t := range last {
+		if member, ok := ids[id]; ok {
+			statuses[member].LastActive = t
+		}
+	}
+	return statuses, nil
+}
+
+// AnnotateServiceAccountStatus looks up the service accounts bound in rows in the projects
+// they belong to. An account missing from a project that could be listed has been deleted;
+// Google-managed service agents live in Google's projects and stay unknown.
+func (r *resourceManager) AnnotateServiceAccountStatus(rows []*Row) {
+	defer timeTrack("Looking up service accounts")()
+	if r.memberStates == nil {
+		r.memberStates = make(map[string]*memberStatus)
+	}
+	members := make(map[string]map[string]bool)
+	for _, row := range rows {
+		if !strings.HasPrefix(row.Member, "serviceAccount:") || row.MemberProject == "" ||
+			memberClass(row.Member) == memberClassGoogleManaged {
+			continue
+		}
+		if members[row.MemberProject] == nil {
+			members[row.MemberProject] = make(map[string]bool)
+		}
+		members[row.MemberProject][row.Member] = true
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	work := make(chan string)
+	for i := 0; i < memberStatusWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for project := range work {
+				statuses, err := r.serviceAccountStatuses(project)
+				if err != nil {
+					logerr.Printf("Unable to list service accounts of project %s: %v\n", project, err)
+					continue
+				}
+				mu.Lock()
+				for member := range members[project] {
+					key := memberStatusKey(member)
+					if status, ok := statuses[key]; ok {
+						r.memberStates[key] = status
+					} else {
+						r.memberStates[key] = &memberStatus{State: "DELETED"}
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for project := range members {
+		work <- project
+	}
+	close(work)
+	wg.Wait()
+	fmt.Printf("Looked up service accounts bound in %d projects\n", len(members))
+}
+
+func init() {
+	registerCollectorPermissions("service-account-status",
+		"iam.serviceAccounts.list",
+		"policyanalyzer.serviceAccountLastAuthenticationActivities.query")
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+)
+
+// normalizeMember gives every spelling of the same principal one form: emails are lowercased,
+// gmail addresses lose their dots and +tags (googlemail.com is gmail.com), and googlegroups.com
+// addresses lose their +tags. Deleted members keep their ?uid= suffix as is. Only user, group,
+// serviceAccount and domain members are emails or domains; principal:// and principalSet://
+// subjects, allUsers and the like are case-sensitive or fixed and are left alone.
+func normalizeMember(member string) string {
+	i := strings.LastIndex(member, ":")
+	if i < 0 {
+		return member
+	}
+	prefix, email := member[:i+1], member[i+1:]
+	switch strings.TrimPrefix(prefix, "deleted:") {
+	case "user:", "group:", "serviceAccount:", "domain:":
+	default:
+		return member
+	}
+	suffix := ""
+	if j := strings.Index(email, "?uid="); j >= 0 {
+		email, suffix = email[:j], email[j:]
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return prefix + strings.ToLower(email) + suffix
+	}
+	local, domain := strings.ToLower(email[:at]), strings.ToLower(email[at+1:])
+	switch domain {
+	case "gmail.com", "googlemail.com":
+		domain = "gmail.com"
+		local = strings.Replace(local, ".", "", -1)
+		fallthrough
+	case "googlegroups.com":
+		if plus := strings.Index(local, "+"); plus >= 0 {
+			local = local[:plus]
+		}
+	}
+	return prefix + local + "@" + domain + suffix
+}
@@ -0,0 +1,45 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestNormalizeMember(t *testing.T) {
+	tests := []struct {
+		member string
+		want   string
+	}{
+		{"user:Alice@Example.com", "user:alice@example.com"},
+		{"user:First.Last+work@googlemail.com", "user:firstlast@gmail.com"},
+		{"group:Team+alerts@googlegroups.com", "group:team@googlegroups.com"},
+		{"group:ops+oncall@example.com", "group:ops+oncall@example.com"},
+		{"serviceAccount:CI@build-prj.iam.gserviceaccount.com", "serviceAccount:ci@build-prj.iam.gserviceaccount.com"},
+		{"domain:Example.COM", "domain:example.com"},
+		{"deleted:user:Bob@Example.com?uid=123ABC", "deleted:user:bob@example.com?uid=123ABC"},
+		{"principal://iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/p/subject/MixedCase",
+			"principal://iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/p/subject/MixedCase"},
+		{"principalSet://iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/p/attribute.repository/Org/Repo",
+			"principalSet://iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/p/attribute.repository/Org/Repo"},
+		{"principal://iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/p/subject/system:serviceaccount:NS:SA",
+			"principal://iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/p/subject/system:serviceaccount:NS:SA"},
+		{"allUsers", "allUsers"},
+		{"projectOwner:My-Project", "projectOwner:My-Project"},
+	}
+	for _, tt := range tests {
+		if got := normalizeMember(tt.member); got != tt.want {
+			t.Errorf("normalizeMember(%q) = %q, want %q", tt.member, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const maxNotifyRows = 50
+
+// Roles ending in Admin or .admin are also treated as high-risk, see isHighRiskRole.
+var highRiskRoles = map[string]bool{
+	"roles/owner":                          true,
+	"roles/editor":                         true,
+	"roles/iam.serviceAccountUser":         true,
+	"roles/iam.serviceAccountTokenCreator": true,
+	"roles/iam.workloadIdentityUser":       true,
+	"roles/compute.osAdminLogin":           true,
+	"roles/resourcemanager.projectCreator": true,
+	"roles/deploymentmanager.editor":       true,
+}
+
+func isHighRiskRole(role string) bool {
+	if highRiskRoles[role] {
+		return true
+	}
+	return strings.HasSuffix(role, "Admin") || strings.HasSuffix(role, ".admin")
+}
+
+func highRiskRows(rows []*Row) []*Row {
+	risky := make([]*Row, 0)
+	for _, row := range rows {
+		if isHighRiskRole(row.Role) {
+			risky = append(risky, row)
+		}
+	}
+	return risky
+}
+
+// notifyNewBindings posts a Slack-compatible summary of high-risk bindings added since prev
+// that the allowlist doesn't accept.
+func notifyNewBindings(webhook string, prev *Snapshot, snap *Snapshot, allow *allowlist) error {
+	if prev == nil {
+		fmt.Println("No previous snapshot to compare against, skipping notification")
+		return nil
+	}
+	added, _ := DiffRows(prev.Rows, snap.Rows)
+	risky := allow.Unapproved(highRiskRows(added))
+	if len(risky) == 0 {
+		fmt.Printf("No new high-risk bindings since snapshot %s\n", prev.Id)
+		return nil
+	}
+	var text bytes.Buffer
+	fmt.Fprintf(&text, "policygopher: %d new high-risk bindings in org %s (snapshot %s -> %s)\n",
+		len(risky), snap.OrgId, prev.Id, snap.Id)
+	for i, row := range risky {
+		if i == maxNotifyRows {
+			fmt.Fprintf(&text, "...and %d more\n", len(risky)-maxNotifyRows)
+			break
+		}
+		fmt.Fprintf(&text, "• `%s` on %s `%s`: %s", row.Role, row.Type, row.Resource, row.Member)
+		if row.Condition != nil {
+			fmt.Fprintf(&text, " if `%s`", row.Condition.Expression)
+		}
+		fmt.Fprintln(&text)
+	}
+	payload, err := json.Marshal(map[string]string{"text": text.String()})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error posting notification: %v", err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New(fmt.Sprintf("Notification webhook returned %s", resp.Status))
+	}
+	fmt.Printf("Posted %d new high-risk bindings to webhook\n", len(risky))
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// orgMetadata describes the organization an export is of, so exports and reports of several
+// orgs can be told apart without looking their ids up.
+type orgMetadata struct {
+	DisplayName string `json:"displayName,omitempty"`
+	// Domain is the primary domain of the Google Workspace or Cloud Identity account owning the
+	// org, which is also its display name
+	Domain              string `json:"domain,omitempty"`
+	DirectoryCustomerId string `json:"directoryCustomerId,omitempty"`
+}
+
+// orgDomain is the domain an org display name names, or "" when it isn't one.
+func orgDomain(displayName string) string {
+	if strings.Contains(displayName, ".") && !strings.ContainsAny(displayName, " /@") {
+		return strings.ToLower(displayName)
+	}
+	return ""
+}
+
+// OrgMetadata looks up the org's display name, domain, and directory customer id once. It is
+// nil for a project without an org and offline, where there is nothing to ask.
+func (r *resourceManager) OrgMetadata() *orgMetadata {
+	if r.standaloneProject != "" || r.offline {
+		return nil
+	}
+	r.orgMetaOnce.Do(func() {
+		org, err := r.v1.Organizations.Get(fmt.Sprintf("organizations/%s", r.orgId)).
+			Fields("displayName,owner(directoryCustomerId)").Context(r.ctx).Do()
+		if err != nil {
+			logerr.Printf("Unable to get org %s: %v\n", r.orgId, err)
+			return
+		}
+		r.orgMeta = &orgMetadata{DisplayName: org.DisplayName, Domain: orgDomain(org.DisplayName)}
+		if org.Owner != nil {
+			r.orgMeta.DirectoryCustomerId = org.Owner.DirectoryCustomerId
+		}
+	})
+	return r.orgMeta
+}
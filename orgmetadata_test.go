@@ -0,0 +1,42 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestOrgDomain(t *testing.T) {
+	tests := map[string]string{
+		"Example.com":        "example.com",
+		"corp.example.co.uk": "corp.example.co.uk",
+		"Example Inc":        "",
+		"example":            "",
+		"":                   "",
+	}
+	for displayName, want := range tests {
+		if got := orgDomain(displayName); got != want {
+			t.Errorf("orgDomain(%q) = %q, want %q", displayName, got, want)
+		}
+	}
+}
+
+func TestOrgMetadataOffline(t *testing.T) {
+	r := &resourceManager{orgId: "123", offline: true}
+	if meta := r.OrgMetadata(); meta != nil {
+		t.Errorf("OrgMetadata() offline = %+v, want nil", meta)
+	}
+	if name := r.GetOrgDisplayName(); name != "123" {
+		t.Errorf("GetOrgDisplayName() offline = %s, want the org id", name)
+	}
+}
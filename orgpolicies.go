@@ -0,0 +1,244 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	crmv1 "google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/option"
+	"strconv"
+)
+
+const defaultOrgPoliciesFile = "org_policies.csv"
+
+// orgPolicyNode is the org, a folder, or a project, with the org policies set on it by
+// constraint. Nodes are listed parents first.
+type orgPolicyNode struct {
+	Name        string
+	Type        string
+	DisplayName string
+	Parent      string
+	Policies    map[string]*crmv1.OrgPolicy
+}
+
+// effectiveOrgPolicy is whether a boolean constraint is enforced on a node, and where that
+// comes from: the node setting it, the nearest ancestor that does, or the constraint's default.
+type effectiveOrgPolicy struct {
+	Node       *orgPolicyNode
+	Constraint string
+	Enforced   bool
+	// name of the node whose policy decides, or "default"
+	Source string
+	// what the node's own policy says, see orgPolicySetting
+	Setting string
+}
+
+// orgPolicySetting is what a policy sets a boolean constraint to: enforced, not enforced,
+// restore default, or "" when it leaves it to the parent.
+func orgPolicySetting(p *crmv1.OrgPolicy) string {
+	switch {
+	case p == nil:
+		return ""
+	case p.RestoreDefault != nil:
+		return "restore default"
+	case p.BooleanPolicy != nil && p.BooleanPolicy.Enforced:
+		return "enforced"
+	case p.BooleanPolicy != nil:
+		return "not enforced"
+	}
+	return ""
+}
+
+// evaluateBooleanPolicies works out every boolean constraint set somewhere in nodes on every
+// node. Boolean policies aren't merged: a node's own policy wins, restoring the default resets
+// it, and a node without one inherits its parent's value, the org's parent being the
+// constraint's default. defaults says which constraints are enforced by default; constraints
+// with list policies aren't boolean and are left out.
+func evaluateBooleanPolicies(nodes []*orgPolicyNode, defaults map[string]bool) []*effectiveOrgPolicy {
+	constraints := make(map[string]bool)
+	for _, node := range nodes {
+		for constraint, p := range node.Policies {
+			if p.ListPolicy == nil && orgPolicySetting(p) != "" {
+				constraints[constraint] = true
+			}
+		}
+	}
+	effective := make([]*effectiveOrgPolicy, 0)
+	for _, constraint := range sortedKeys(constraints) {
+		byNode := make(map[string]*effectiveOrgPolicy)
+		for _, node := range nodes {
+			e := &effectiveOrgPolicy{Node: node, Constraint: constraint, Enforced: defaults[constraint], Source: "default"}
+			if parent, ok := byNode[node.Parent]; ok {
+				e.Enforced, e.Source = parent.Enforced, parent.Source
+			}
+			p := node.Policies[constraint]
+			switch e.Setting = orgPolicySetting(p); e.Setting {
+			case "restore default":
+				e.Enforced, e.Source = defaults[constraint], "default"
+			case "enforced", "not enforced":
+				e.Enforced, e.Source = p.BooleanPolicy.Enforced, node.Name
+			}
+			byNode[node.Name] = e
+			effective = append(effective, e)
+		}
+	}
+	return effective
+}
+
+// orgPolicyHierarchy lists the org, every folder below it, and every project in them, parents
+// first. Folders pending deletion and the projects in them are left out.
+func (r *resourceManager) orgPolicyHierarchy() ([]*orgPolicyNode, error) {
+	org := fmt.Sprintf("organizations/%s", r.orgId)
+	nodes := []*orgPolicyNode{{Name: org, Type: "organization", DisplayName: r.GetOrgDisplayName()}}
+	for i := 0; i < len(nodes); i++ {
+		parent := nodes[i]
+		if parent.Type == "project" {
+			continue
+		}
+		folders, err := r.FoldersList(parent.Name)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to list folders of %s: %v", parent.Name, err))
+		}
+		for _, f := range folders {
+			if f.LifecycleState == "DELETE_REQUESTED" {
+				continue
+			}
+			nodes = append(nodes, &orgPolicyNode{Name: f.Name, Type: "folder", DisplayName: f.DisplayName, Parent: parent.Name})
+		}
+		filter := fmt.Sprintf("parent.type:%s parent.id:%s", parent.Type, resourceNumber(parent.Name))
+		projects, err := r.ProjectsListByFilter(filter)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to list projects of %s: %v", parent.Name, err))
+		}
+		for _, p := range projects {
+			if p.LifecycleState != "ACTIVE" {
+				continue
+			}
+			nodes = append(nodes, &orgPolicyNode{Name: "projects/" + p.ProjectId, Type: "project", DisplayName: p.Name, Parent: parent.Name})
+		}
+	}
+	return nodes, nil
+}
+
+// resourceNumber is the id at the end of organizations/123 or folders/456.
+func resourceNumber(name string) string {
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '/' {
+			return name[i+1:]
+		}
+	}
+	return name
+}
+
+// readOrgPolicies lists the policies set on a node.
+func (r *resourceManager) readOrgPolicies(service *crmv1.Service, node *orgPolicyNode) error {
+	node.Policies = make(map[string]*crmv1.OrgPolicy)
+	add := func(page *crmv1.ListOrgPoliciesResponse) error {
+		for _, p := range page.Policies {
+			node.Policies[p.Constraint] = p
+		}
+		return nil
+	}
+	request := &crmv1.ListOrgPoliciesRequest{}
+	var err error
+	switch node.Type {
+	case "organization":
+		err = service.Organizations.ListOrgPolicies(node.Name, request).Pages(r.ctx, add)
+	case "folder":
+		err = service.Folders.ListOrgPolicies(node.Name, request).Pages(r.ctx, add)
+	default:
+		err = service.Projects.ListOrgPolicies(node.Name, request).Pages(r.ctx, add)
+	}
+	return classifyError(node.Name, err)
+}
+
+// booleanConstraintDefaults says for every boolean constraint available to the org whether it
+// is enforced when no policy sets it.
+func (r *resourceManager) booleanConstraintDefaults(service *crmv1.Service) (map[string]bool, error) {
+	defaults := make(map[string]bool)
+	err := service.Organizations.ListAvailableOrgPolicyConstraints(fmt.Sprintf("organizations/%s", r.orgId),
+		&crmv1.ListAvailableOrgPolicyConstraintsRequest{}).Pages(r.ctx, func(page *crmv1.ListAvailableOrgPolicyConstraintsResponse) error {
+		for _, c := range page.Constraints {
+			if c.BooleanConstraint != nil {
+				defaults[c.Name] = c.ConstraintDefault == "DENY"
+			}
+		}
+		return nil
+	})
+	return defaults, err
+}
+
+func orgPolicyRecords(effective []*effectiveOrgPolicy) ([]string, [][]string) {
+	header := []string{"Resource", "Type", "DisplayName", "Parent", "Constraint", "Enforced", "Source", "Policy"}
+	records := make([][]string, len(effective))
+	for i, e := range effective {
+		records[i] = []string{e.Node.Name, e.Node.Type, e.Node.DisplayName, e.Node.Parent, e.Constraint,
+			strconv.FormatBool(e.Enforced), e.Source, e.Setting}
+	}
+	return header, records
+}
+
+// exportOrgPolicies writes whether each boolean constraint set anywhere in the org is enforced
+// on the org and every folder and project, with inheritance and overrides worked out.
+func exportOrgPolicies(opts *Options, filename string) error {
+	ctx := context.Background()
+	resman, err := newResourceManagerFromOptions(ctx, opts, nil)
+	if err != nil {
+		return err
+	}
+	if resman.orgId == "" {
+		return errors.New("org-policies needs an organization, set --org or --project of a project in one")
+	}
+	var clientOptions []option.ClientOption
+	if resman.client != nil {
+		clientOptions = append(clientOptions, option.WithHTTPClient(resman.client))
+	}
+	service, err := crmv1.NewService(ctx, clientOptions...)
+	if err != nil {
+		return err
+	}
+	defaults, err := resman.booleanConstraintDefaults(service)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to list the org's constraints: %v", err))
+	}
+	nodes, err := resman.orgPolicyHierarchy()
+	if err != nil {
+		return err
+	}
+	policies, lists := 0, 0
+	for _, node := range nodes {
+		if err := resman.readOrgPolicies(service, node); err != nil {
+			// its policies can't be known, it is evaluated as if it inherited everything
+			logerr.Printf("Unable to list org policies of %s: %v\n", node.Name, err)
+			continue
+		}
+		for _, p := range node.Policies {
+			policies++
+			if p.ListPolicy != nil {
+				lists++
+			}
+		}
+	}
+	effective := evaluateBooleanPolicies(nodes, defaults)
+	fmt.Printf("Read %d org policies on %d resources, %d of list constraints not evaluated\n", policies, len(nodes), lists)
+	header, records := orgPolicyRecords(effective)
+	return writeReport(filename, header, records)
+}
+
+func init() {
+	registerCollectorPermissions("org-policies", "orgpolicy.policy.get")
+}
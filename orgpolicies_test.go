@@ -0,0 +1,71 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	crmv1 "google.golang.org/api/cloudresourcemanager/v1"
+	"reflect"
+	"testing"
+)
+
+func TestEvaluateBooleanPolicies(t *testing.T) {
+	enforce := func(enforced bool) *crmv1.OrgPolicy {
+		return &crmv1.OrgPolicy{BooleanPolicy: &crmv1.BooleanPolicy{Enforced: enforced}}
+	}
+	nodes := []*orgPolicyNode{
+		{Name: "organizations/1", Type: "organization", Policies: map[string]*crmv1.OrgPolicy{
+			"constraints/iam.disableServiceAccountKeyCreation": enforce(true),
+			"constraints/gcp.resourceLocations":                {ListPolicy: &crmv1.ListPolicy{AllowedValues: []string{"in:eu-locations"}}},
+		}},
+		{Name: "folders/2", Type: "folder", Parent: "organizations/1", Policies: map[string]*crmv1.OrgPolicy{
+			"constraints/iam.disableServiceAccountKeyCreation": enforce(false),
+		}},
+		{Name: "folders/3", Type: "folder", Parent: "folders/2", Policies: map[string]*crmv1.OrgPolicy{
+			"constraints/iam.disableServiceAccountKeyCreation": {RestoreDefault: &crmv1.RestoreDefault{}},
+			"constraints/compute.skipDefaultNetworkCreation":   enforce(true),
+		}},
+		{Name: "projects/a", Type: "project", Parent: "folders/2"},
+		{Name: "projects/b", Type: "project", Parent: "folders/3"},
+		// its policies couldn't be read
+		{Name: "projects/c", Type: "project", Parent: "organizations/1"},
+	}
+	defaults := map[string]bool{"constraints/compute.skipDefaultNetworkCreation": false}
+	_, records := orgPolicyRecords(evaluateBooleanPolicies(nodes, defaults))
+	want := [][]string{
+		{"organizations/1", "organization", "", "", "constraints/compute.skipDefaultNetworkCreation", "false", "default", ""},
+		{"folders/2", "folder", "", "organizations/1", "constraints/compute.skipDefaultNetworkCreation", "false", "default", ""},
+		{"folders/3", "folder", "", "folders/2", "constraints/compute.skipDefaultNetworkCreation", "true", "folders/3", "enforced"},
+		{"projects/a", "project", "", "folders/2", "constraints/compute.skipDefaultNetworkCreation", "false", "default", ""},
+		{"projects/b", "project", "", "folders/3", "constraints/compute.skipDefaultNetworkCreation", "true", "folders/3", ""},
+		{"projects/c", "project", "", "organizations/1", "constraints/compute.skipDefaultNetworkCreation", "false", "default", ""},
+		{"organizations/1", "organization", "", "", "constraints/iam.disableServiceAccountKeyCreation", "true", "organizations/1", "enforced"},
+		{"folders/2", "folder", "", "organizations/1", "constraints/iam.disableServiceAccountKeyCreation", "false", "folders/2", "not enforced"},
+		{"folders/3", "folder", "", "folders/2", "constraints/iam.disableServiceAccountKeyCreation", "false", "default", "restore default"},
+		{"projects/a", "project", "", "folders/2", "constraints/iam.disableServiceAccountKeyCreation", "false", "folders/2", ""},
+		{"projects/b", "project", "", "folders/3", "constraints/iam.disableServiceAccountKeyCreation", "false", "default", ""},
+		{"projects/c", "project", "", "organizations/1", "constraints/iam.disableServiceAccountKeyCreation", "true", "organizations/1", ""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records:\n%v\nwant:\n%v", records, want)
+	}
+}
+
+func TestResourceNumber(t *testing.T) {
+	for name, want := range map[string]string{"organizations/1": "1", "folders/22": "22", "3": "3"} {
+		if got := resourceNumber(name); got != want {
+			t.Errorf("resourceNumber(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+type orgSummary struct {
+	Organization *orgMetadata
+	Projects     int
+	Folders      int
+	Bindings     int
+	Members      int
+	HighRisk     int
+}
+
+func summarizeRows(rows []*Row) *orgSummary {
+	summary := &orgSummary{Bindings: len(rows)}
+	resources := make(map[string]string)
+	members := make(map[string]bool)
+	for _, row := range rows {
+		resources[row.Type+"/"+row.Resource] = row.Type
+		members[row.Member] = true
+		if isHighRiskRole(row.Role) {
+			summary.HighRisk++
+		}
+	}
+	for _, resType := range resources {
+		switch resType {
+		case "project":
+			summary.Projects++
+		case "folder":
+			summary.Folders++
+		}
+	}
+	summary.Members = len(members)
+	return summary
+}
+
+// exportOrgs crawls every org in the config with its own credentials, writing <orgId>_<file>
+// and reports under <report-dir>/org_<orgId>, then a consolidated orgs_summary.csv.
+func exportOrgs(opts *Options, config *Config) error {
+	ctx := context.Background()
+	dir, base := filepath.Split(exportFilename(opts))
+	records := make([][]string, 0, len(config.Orgs))
+	for _, org := range config.Orgs {
+		fmt.Printf("Crawling org %s %s\n", org.OrgId, org.Name)
+		status := "ok"
+		summary := &orgSummary{}
+		ts, err := org.TokenSource(ctx)
+		if err == nil {
+			orgOpts := *opts
+			orgOpts.OrgId = org.OrgId
+			orgOpts.Filename = outputPath(dir, fmt.Sprintf("%s_%s", org.OrgId, base))
+			orgOpts.ReportDir = outputPath(opts.ReportDir, fmt.Sprintf("org_%s", org.OrgId))
+			if opts.StatsFile != "" {
+				statsDir, statsBase := filepath.Split(opts.StatsFile)
+				orgOpts.StatsFile = outputPath(statsDir, fmt.Sprintf("%s_%s", org.OrgId, statsBase))
+			}
+			var s *orgSummary
+			if s, err = exportOrg(&orgOpts, ts); s != nil {
+				summary = s
+			}
+		}
+		if err != nil {
+			logerr.Printf("Org %s: %v\n", org.OrgId, err)
+			status = err.Error()
+		}
+		meta := &orgMetadata{}
+		if summary.Organization != nil {
+			meta = summary.Organization
+		}
+		records = append(records, []string{
+			org.OrgId, org.Name, meta.DisplayName, meta.Domain, meta.DirectoryCustomerId,
+			strconv.Itoa(summary.Projects), strconv.Itoa(summary.Folders), strconv.Itoa(summary.Bindings), strconv.Itoa(summary.Members), strconv.Itoa(summary.HighRisk), status,
+		})
+	}
+	return writeReport(outputPath(opts.ReportDir, "orgs_summary.csv"),
+		[]string{"OrgId", "Name", "DisplayName", "Domain", "DirectoryCustomerId", "Projects", "Folders", "Bindings", "Members", "HighRiskBindings", "Status"},
+		records)
+}
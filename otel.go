@@ -0,0 +1,266 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// spans are posted in batches of this many, so a long crawl doesn't hold them all
+	otelBatchSize = 512
+	// OTLP span kinds
+	otelKindInternal = 1
+	otelKindClient   = 3
+	// OTLP status code of a failed span
+	otelStatusError = 2
+)
+
+// otel sends a span for the run, each collector and phase, and each API request to the
+// --otel-endpoint collector, nil without it. Its methods do nothing on nil.
+var otel *otelExporter
+
+// otelExporter posts spans to an OpenTelemetry collector with OTLP over HTTP, encoded as
+// json, which every collector accepts without a client library. A run is one trace.
+type otelExporter struct {
+	mu       sync.Mutex
+	url      string
+	client   *http.Client
+	traceId  string
+	rootId   string
+	started  time.Time
+	resource []*otlpAttribute
+	pending  []*otlpSpan
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	// OTLP json writes 64-bit integers as strings
+	IntValue *string `json:"intValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+func otlpString(key string, value string) *otlpAttribute {
+	return &otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func otlpInt(key string, value int) *otlpAttribute {
+	s := fmt.Sprint(value)
+	return &otlpAttribute{Key: key, Value: otlpValue{IntValue: &s}}
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string           `json:"traceId"`
+	SpanId            string           `json:"spanId"`
+	ParentSpanId      string           `json:"parentSpanId,omitempty"`
+	Name              string           `json:"name"`
+	Kind              int              `json:"kind"`
+	StartTimeUnixNano string           `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string           `json:"endTimeUnixNano"`
+	Attributes        []*otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus      `json:"status,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	} `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []*otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpTime(t time.Time) string {
+	return fmt.Sprint(t.UnixNano())
+}
+
+// randomHex is n random bytes in hex, for trace and span ids.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// otlpTracesUrl is where spans go for an --otel-endpoint, which can be the collector's base url
+// or the full traces url.
+func otlpTracesUrl(endpoint string) string {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if strings.HasSuffix(endpoint, "/v1/traces") {
+		return endpoint
+	}
+	return endpoint + "/v1/traces"
+}
+
+// startOtel starts the run's trace. Spans are posted with their own client, so posting them
+// isn't traced or counted as an API call.
+func startOtel(endpoint string) *otelExporter {
+	return &otelExporter{
+		url:     otlpTracesUrl(endpoint),
+		client:  &http.Client{Timeout: 30 * time.Second},
+		traceId: randomHex(16),
+		rootId:  randomHex(8),
+		started: time.Now(),
+		resource: []*otlpAttribute{
+			otlpString("service.name", "policygopher"),
+			otlpString("service.version", toolBuild.Version),
+		},
+	}
+}
+
+// NewSpanId is the id of a span about to start, "" without an exporter.
+func (o *otelExporter) NewSpanId() string {
+	if o == nil {
+		return ""
+	}
+	return randomHex(8)
+}
+
+// Record queues a finished span, parented to the run's span when parentId is "", and posts
+// the queue once it is a batch.
+func (o *otelExporter) Record(spanId string, parentId string, name string, kind int, start time.Time, attributes []*otlpAttribute, err error) {
+	if o == nil || spanId == "" {
+		return
+	}
+	if parentId == "" {
+		parentId = o.rootId
+	}
+	span := &otlpSpan{
+		TraceId:           o.traceId,
+		SpanId:            spanId,
+		ParentSpanId:      parentId,
+		Name:              name,
+		Kind:              kind,
+		StartTimeUnixNano: otlpTime(start),
+		EndTimeUnixNano:   otlpTime(time.Now()),
+		Attributes:        attributes,
+	}
+	if err != nil {
+		span.Status = &otlpStatus{Code: otelStatusError, Message: err.Error()}
+	}
+	o.mu.Lock()
+	o.pending = append(o.pending, span)
+	var batch []*otlpSpan
+	if len(o.pending) >= otelBatchSize {
+		batch, o.pending = o.pending, nil
+	}
+	o.mu.Unlock()
+	if batch != nil {
+		if err := o.post(batch); err != nil {
+			logerr.Printf("%v\n", err)
+		}
+	}
+}
+
+// Shutdown ends the run's span and posts every span still queued.
+func (o *otelExporter) Shutdown() {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	batch := append(o.pending, &otlpSpan{
+		TraceId:           o.traceId,
+		SpanId:            o.rootId,
+		Name:              "policygopher",
+		Kind:              otelKindInternal,
+		StartTimeUnixNano: otlpTime(o.started),
+		EndTimeUnixNano:   otlpTime(time.Now()),
+		Attributes:        []*otlpAttribute{otlpInt("errors", errorCount.Lines()), otlpInt("api_calls", apiCalls.Total())},
+	})
+	o.pending = nil
+	o.mu.Unlock()
+	if err := o.post(batch); err != nil {
+		logerr.Printf("%v\n", err)
+	}
+}
+
+func (o *otelExporter) post(spans []*otlpSpan) error {
+	scope := &otlpScopeSpans{Spans: spans}
+	scope.Scope.Name = "github.com/glickbot/policygopher"
+	scope.Scope.Version = toolBuild.Version
+	resource := &otlpResourceSpans{ScopeSpans: []*otlpScopeSpans{scope}}
+	resource.Resource.Attributes = o.resource
+	body, err := json.Marshal(&otlpTraces{ResourceSpans: []*otlpResourceSpans{resource}})
+	if err != nil {
+		return err
+	}
+	resp, err := o.client.Post(o.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to send %d spans to %s: %v", len(spans), o.url, err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.New(fmt.Sprintf("Unable to send %d spans to %s: %s %s", len(spans), o.url, resp.Status, strings.TrimSpace(string(msg))))
+	}
+	return nil
+}
+
+// otelTransport makes a client span of every API request, from before it waits for an
+// --api-concurrency slot to its last retry, under the collector or phase running it.
+type otelTransport struct {
+	base http.RoundTripper
+}
+
+func (t *otelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	spanId, parentId := otel.NewSpanId(), currentSpanId()
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	attributes := []*otlpAttribute{
+		otlpString("http.method", req.Method),
+		otlpString("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path),
+		otlpString("rpc.method", apiMethod(req)),
+	}
+	failed := err
+	if err == nil {
+		attributes = append(attributes, otlpInt("http.status_code", resp.StatusCode))
+		if resp.StatusCode >= 400 {
+			failed = errors.New(resp.Status)
+		}
+	}
+	otel.Record(spanId, parentId, apiMethod(req), otelKindClient, start, attributes, failed)
+	return resp, err
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestOtlpTracesUrl(t *testing.T) {
+	for endpoint, want := range map[string]string{
+		"http://localhost:4318":              "http://localhost:4318/v1/traces",
+		"http://localhost:4318/":             "http://localhost:4318/v1/traces",
+		"https://otel.example.com/v1/traces": "https://otel.example.com/v1/traces",
+	} {
+		if got := otlpTracesUrl(endpoint); got != want {
+			t.Errorf("otlpTracesUrl(%s) = %s, want %s", endpoint, got, want)
+		}
+	}
+}
+
+func TestOtelSpans(t *testing.T) {
+	var mu sync.Mutex
+	posted := make([]*otlpSpan, 0)
+	var path string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var traces otlpTraces
+		if err := json.NewDecoder(r.Body).Decode(&traces); err != nil {
+			t.Errorf("decoding spans: %v", err)
+		}
+		mu.Lock()
+		path = r.URL.Path
+		for _, rs := range traces.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				posted = append(posted, ss.Spans...)
+			}
+		}
+		mu.Unlock()
+	}))
+	defer collector.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "denied", http.StatusForbidden)
+	}))
+	defer api.Close()
+
+	otel = startOtel(collector.URL)
+	defer func() { otel = nil }()
+	client := &http.Client{Transport: &otelTransport{base: http.DefaultTransport}}
+	end := beginSpan("collector", "test-otel")
+	resp, err := client.Get(api.URL + "/v1/projects/p:getIamPolicy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	end()
+	otel.Shutdown()
+
+	if path != "/v1/traces" {
+		t.Errorf("posted to %s, want /v1/traces", path)
+	}
+	if len(posted) != 3 {
+		t.Fatalf("posted %d spans, want the request, the collector, and the run", len(posted))
+	}
+	call, collect, run := posted[0], posted[1], posted[2]
+	if run.Name != "policygopher" || run.ParentSpanId != "" || len(run.TraceId) != 32 || len(run.SpanId) != 16 {
+		t.Errorf("run span = %+v", run)
+	}
+	if collect.Name != "test-otel" || collect.ParentSpanId != run.SpanId || collect.TraceId != run.TraceId || collect.Kind != otelKindInternal {
+		t.Errorf("collector span = %+v", collect)
+	}
+	if call.ParentSpanId != collect.SpanId || call.Kind != otelKindClient || call.Status == nil || call.Status.Code != otelStatusError {
+		t.Errorf("request span = %+v", call)
+	}
+}
+
+func TestNilOtel(t *testing.T) {
+	var o *otelExporter
+	if id := o.NewSpanId(); id != "" {
+		t.Errorf("NewSpanId = %s, want none", id)
+	}
+	o.Record("", "", "nothing", otelKindInternal, startOtel("http://localhost").started, nil, nil)
+	o.Shutdown()
+}
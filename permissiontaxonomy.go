@@ -0,0 +1,121 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// permissionParts are what a permission is named after: compute.instances.setIamPolicy is the
+// setIamPolicy verb on compute's instances.
+type permissionParts struct {
+	Service  string
+	Resource string
+	Verb     string
+}
+
+// parsePermission splits a permission into its parts. Resources can span several segments,
+// e.g. resourcemanager.hierarchyNodes.createTagBinding has one but some have more; a
+// --collapse-permissions summary only has a service, and UNKNOWN has no part at all.
+func parsePermission(permission string) permissionParts {
+	if i := strings.Index(permission, ":"); i >= 0 {
+		return permissionParts{Service: permission[:i]}
+	}
+	segments := strings.Split(permission, ".")
+	if len(segments) < 3 {
+		return permissionParts{}
+	}
+	return permissionParts{
+		Service:  segments[0],
+		Resource: strings.Join(segments[1:len(segments)-1], "."),
+		Verb:     segments[len(segments)-1],
+	}
+}
+
+// permissionFilter keeps the permissions whose parts are among those given with --service,
+// --permission-resource, and --verb, ignoring case. A part given no value matches anything.
+type permissionFilter struct {
+	services  map[string]bool
+	resources map[string]bool
+	verbs     map[string]bool
+}
+
+// newPermissionFilter parses the comma separated lists of the filter flags, and returns nil
+// when all are empty.
+func newPermissionFilter(services string, resources string, verbs string) *permissionFilter {
+	if services == "" && resources == "" && verbs == "" {
+		return nil
+	}
+	return &permissionFilter{
+		services:  lowerSet(services),
+		resources: lowerSet(resources),
+		verbs:     lowerSet(verbs),
+	}
+}
+
+func lowerSet(list string) map[string]bool {
+	if list == "" {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, s := range strings.Split(list, ",") {
+		set[strings.ToLower(strings.TrimSpace(s))] = true
+	}
+	return set
+}
+
+func (f *permissionFilter) Match(permission string) bool {
+	parts := parsePermission(permission)
+	return (f.services == nil || f.services[strings.ToLower(parts.Service)]) &&
+		(f.resources == nil || f.resources[strings.ToLower(parts.Resource)]) &&
+		(f.verbs == nil || f.verbs[strings.ToLower(parts.Verb)])
+}
+
+// Filter returns the permissions f matches, in the same order.
+func (f *permissionFilter) Filter(permissions []string) []string {
+	kept := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		if f.Match(p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// filterRowsByPermission drops the bindings granting no permission the filter matches. Those
+// whose role can't be resolved are kept, written with an UNKNOWN permission.
+func (r *resourceManager) filterRowsByPermission(rows []*Row) []*Row {
+	kept := make([]*Row, 0, len(rows))
+	for _, row := range rows {
+		permissions, err := r.GetRolePermissions(row)
+		if err != nil || len(r.permissionFilter.Filter(permissions)) > 0 {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+func init() {
+	registerEnricher("permission-filter", stageRewrite,
+		func(opts *Options) bool {
+			return opts.Service != "" || opts.PermissionResource != "" || opts.Verb != ""
+		},
+		func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+			kept := resman.filterRowsByPermission(rows)
+			fmt.Printf("Keeping %d of %d bindings granting permissions matching --service, --permission-resource, and --verb\n", len(kept), len(rows))
+			return kept, nil
+		})
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"google.golang.org/api/iam/v1"
+	"reflect"
+	"testing"
+)
+
+func TestParsePermission(t *testing.T) {
+	tests := []struct {
+		permission string
+		want       permissionParts
+	}{
+		{"compute.instances.setIamPolicy", permissionParts{"compute", "instances", "setIamPolicy"}},
+		{"storage.objects.get", permissionParts{"storage", "objects", "get"}},
+		{"cloudbuild.builds.approve.all", permissionParts{"cloudbuild", "builds.approve", "all"}},
+		{"storage: 47 permissions (incl. setIamPolicy)", permissionParts{Service: "storage"}},
+		{"UNKNOWN", permissionParts{}},
+	}
+	for _, test := range tests {
+		if got := parsePermission(test.permission); got != test.want {
+			t.Errorf("parsePermission(%q) = %+v, want %+v", test.permission, got, test.want)
+		}
+	}
+}
+
+func TestPermissionFilter(t *testing.T) {
+	if f := newPermissionFilter("", "", ""); f != nil {
+		t.Errorf("newPermissionFilter with no flags = %+v, want nil", f)
+	}
+	f := newPermissionFilter("storage, Compute", "", "setiampolicy")
+	permissions := []string{"compute.instances.setIamPolicy", "compute.instances.get", "storage.buckets.setIamPolicy",
+		"iam.serviceAccounts.setIamPolicy", "UNKNOWN"}
+	want := []string{"compute.instances.setIamPolicy", "storage.buckets.setIamPolicy"}
+	if got := f.Filter(permissions); !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter() = %v, want %v", got, want)
+	}
+	f = newPermissionFilter("", "buckets", "")
+	if !f.Match("storage.buckets.get") || f.Match("storage.objects.get") {
+		t.Error("--permission-resource buckets should match storage.buckets.get only")
+	}
+}
+
+func TestFilterRowsByPermission(t *testing.T) {
+	resman := &resourceManager{bindingRoles: make(map[string]*iam.Role)}
+	resman.permissionFilter = newPermissionFilter("", "", "setIamPolicy")
+	admin := &Row{Resource: "p", Type: "project", Member: "user:a@example.com", Role: "roles/resourcemanager.projectIamAdmin"}
+	viewer := &Row{Resource: "p", Type: "project", Member: "user:b@example.com", Role: "roles/viewer"}
+	resman.bindingRoles[bindingRoleKey(admin)] = &iam.Role{Name: admin.Role,
+		IncludedPermissions: []string{"resourcemanager.projects.getIamPolicy", "resourcemanager.projects.setIamPolicy"}}
+	resman.bindingRoles[bindingRoleKey(viewer)] = &iam.Role{Name: viewer.Role,
+		IncludedPermissions: []string{"resourcemanager.projects.get"}}
+	kept := resman.filterRowsByPermission([]*Row{admin, viewer})
+	if len(kept) != 1 || kept[0] != admin {
+		t.Fatalf("filterRowsByPermission kept %v, want only the admin binding", kept)
+	}
+
+	resman.permissionColumns = true
+	var out bytes.Buffer
+	writer := bufio.NewWriter(&out)
+	if err := admin.Print(writer, resman); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+	want := "p,project,,,user:a@example.com,customer,,roles/resourcemanager.projectIamAdmin,resourcemanager.projects.setIamPolicy,0,0,," +
+		"resourcemanager,projects,setIamPolicy\n"
+	if out.String() != want {
+		t.Errorf("Print() = %q, want %q", out.String(), want)
+	}
+}
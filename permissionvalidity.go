@@ -0,0 +1,108 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"google.golang.org/api/iam/v1"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fullResourceName is the name IAM's permission APIs take, e.g.
+// //cloudresourcemanager.googleapis.com/projects/my-project.
+func fullResourceName(row *Row) string {
+	if strings.HasPrefix(row.Name, "//") {
+		return row.Name
+	}
+	return fullResourceNamePrefix + row.Name
+}
+
+// TestablePermissions lists the permissions that can be granted on a resource, those of the
+// resources below it included, from iam.permissions.queryTestablePermissions.
+func (r *resourceManager) TestablePermissions(name string) ([]string, error) {
+	permissions := make([]string, 0)
+	err := r.service.Permissions.QueryTestablePermissions(&iam.QueryTestablePermissionsRequest{
+		FullResourceName: name,
+		PageSize:         1000,
+	}).Fields("nextPageToken,permissions(name)").Pages(r.ctx, func(page *iam.QueryTestablePermissionsResponse) error {
+		for _, p := range page.Permissions {
+			permissions = append(permissions, p.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, classifyError(name, err)
+	}
+	return permissions, nil
+}
+
+// resolveTestablePermissions looks up the testable permissions of each resource type in rows
+// once, on the first resource of that type, as they depend on the type and not the resource.
+func (r *resourceManager) resolveTestablePermissions(rows []*Row) {
+	samples := make(map[string]string)
+	for _, row := range rows {
+		if _, ok := samples[row.Type]; !ok && row.Name != "" {
+			samples[row.Type] = fullResourceName(row)
+		}
+	}
+	r.testablePermissions = make(map[string]map[string]bool)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for resourceType, name := range samples {
+		wg.Add(1)
+		go func(resourceType string, name string) {
+			defer wg.Done()
+			permissions, err := r.TestablePermissions(name)
+			if err != nil {
+				logerr.Printf("Unable to list testable permissions of %s resources: %v\n", resourceType, err)
+				return
+			}
+			testable := make(map[string]bool, len(permissions))
+			for _, p := range permissions {
+				testable[p] = true
+			}
+			mu.Lock()
+			r.testablePermissions[resourceType] = testable
+			mu.Unlock()
+		}(resourceType, name)
+	}
+	wg.Wait()
+	fmt.Printf("Listed testable permissions of %d resource types\n", len(r.testablePermissions))
+}
+
+// permissionValidColumn reports whether rows carry the PermissionValid column.
+func (r *resourceManager) permissionValidColumn() bool {
+	return r.testablePermissions != nil
+}
+
+// PermissionValid is the PermissionValid column: whether permission can apply to the row's
+// resource or anything below it, or empty when the testable permissions of its type are unknown.
+func (r *resourceManager) PermissionValid(row *Row, permission string) string {
+	testable, ok := r.testablePermissions[row.Type]
+	if !ok || permission == "UNKNOWN" {
+		return ""
+	}
+	return strconv.FormatBool(testable[permission])
+}
+
+func init() {
+	registerEnricher("permission-validity", stageAnnotate, func(opts *Options) bool { return opts.PermissionValidity },
+		func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+			resman.resolveTestablePermissions(rows)
+			return rows, nil
+		})
+}
@@ -0,0 +1,54 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestPermissionValid(t *testing.T) {
+	r := &resourceManager{testablePermissions: map[string]map[string]bool{
+		"bucket": {"storage.objects.get": true},
+	}}
+	tests := []struct {
+		resourceType string
+		permission   string
+		want         string
+	}{
+		{"bucket", "storage.objects.get", "true"},
+		{"bucket", "compute.instances.create", "false"},
+		{"bucket", "UNKNOWN", ""},
+		{"project", "storage.objects.get", ""},
+	}
+	for _, tt := range tests {
+		if got := r.PermissionValid(&Row{Type: tt.resourceType}, tt.permission); got != tt.want {
+			t.Errorf("PermissionValid(%s, %s) = %q, want %q", tt.resourceType, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestFullResourceName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"projects/p", "//cloudresourcemanager.googleapis.com/projects/p"},
+		{"organizations/1", "//cloudresourcemanager.googleapis.com/organizations/1"},
+		{"//storage.googleapis.com/projects/_/buckets/b", "//storage.googleapis.com/projects/_/buckets/b"},
+	}
+	for _, tt := range tests {
+		if got := fullResourceName(&Row{Name: tt.name}); got != tt.want {
+			t.Errorf("fullResourceName(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
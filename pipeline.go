@@ -0,0 +1,175 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// An export runs in three stages: collectors gather the bindings (GetAllPolicyRows, or --input),
+// enrichers annotate or rewrite them, and a renderer writes them in the --format asked for.
+// Enrichers and renderers register themselves in init, like reports.
+
+// enrichFunc returns the rows with what the enricher adds, or the rows replacing them.
+type enrichFunc func(rows []*Row, resman *resourceManager) ([]*Row, error)
+
+type enricher struct {
+	name string
+	// stage enrichers run in, lowest first: annotations, then roles, then what needs roles
+	stage   int
+	enabled func(opts *Options) bool
+	enrich  enrichFunc
+}
+
+const (
+	stageAnnotate = 10
+	stageRoles    = 20
+	// members are redacted before rows are merged, so members redacted alike merge too
+	stageRedact  = 25
+	stageRewrite = 30
+	stageScore   = 40
+)
+
+var enrichers = make(map[string]*enricher)
+
+func registerEnricher(name string, stage int, enabled func(opts *Options) bool, fn enrichFunc) {
+	enrichers[name] = &enricher{name: name, stage: stage, enabled: enabled, enrich: fn}
+}
+
+// enabledEnrichers returns the enrichers opts turns on, by stage then name.
+func enabledEnrichers(opts *Options) []*enricher {
+	enabled := make([]*enricher, 0, len(enrichers))
+	for _, e := range enrichers {
+		if e.enabled(opts) {
+			enabled = append(enabled, e)
+		}
+	}
+	sort.Slice(enabled, func(i, j int) bool {
+		if enabled[i].stage != enabled[j].stage {
+			return enabled[i].stage < enabled[j].stage
+		}
+		return enabled[i].name < enabled[j].name
+	})
+	return enabled
+}
+
+// enrichRows runs every enabled enricher over the collected rows.
+func enrichRows(opts *Options, rows []*Row, resman *resourceManager) ([]*Row, error) {
+	for _, e := range enabledEnrichers(opts) {
+		end := timeTrack(fmt.Sprintf("Enriching with %s", e.name))
+		var err error
+		if rows, err = e.enrich(rows, resman); err != nil {
+			return nil, errors.New(fmt.Sprintf("Error enriching rows with %s: %v", e.name, err))
+		}
+		end()
+	}
+	return rows, nil
+}
+
+type renderFunc func(output string, rows []*Row, resman *resourceManager) error
+
+// rowSource calls fn with each row to render in turn, stopping at the first error; renderers
+// that can stream take one so --low-memory can feed them from its spool.
+type rowSource func(fn func(row *Row) error) error
+
+// sliceRows is the rowSource of rows held in memory.
+func sliceRows(rows []*Row) rowSource {
+	return func(fn func(row *Row) error) error {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+var renderers = make(map[string]renderFunc)
+
+func registerRenderer(format string, fn renderFunc) {
+	renderers[format] = fn
+}
+
+func rendererNames() []string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkFormat validates --format against the registered renderers.
+func checkFormat(format string) error {
+	if _, ok := renderers[format]; !ok {
+		return errors.New(fmt.Sprintf("Unknown --format %s, expected one of %s", format, strings.Join(rendererNames(), ", ")))
+	}
+	return nil
+}
+
+func always(opts *Options) bool {
+	return true
+}
+
+// resolvesRoles tells whether the run needs role permissions, which --count-only doesn't.
+func resolvesRoles(opts *Options) bool {
+	return !opts.CountOnly
+}
+
+func init() {
+	registerEnricher("member-projects", stageAnnotate, always, func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+		resman.AnnotateMemberProjects(rows)
+		return rows, nil
+	})
+	registerEnricher("service-account-status", stageAnnotate, func(opts *Options) bool { return opts.ServiceAccountStatus },
+		func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+			resman.AnnotateServiceAccountStatus(rows)
+			return rows, nil
+		})
+	registerEnricher("user-status", stageAnnotate, func(opts *Options) bool { return opts.UserStatus },
+		func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+			if err := resman.AnnotateUserStatus(rows); err != nil {
+				logerr.Printf("Unable to look up users in the directory: %v\n", err)
+			}
+			return rows, nil
+		})
+	registerEnricher("permissions", stageRoles, resolvesRoles, func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+		resman.ResolveRoles(rows)
+		return rows, nil
+	})
+	registerEnricher("dedup", stageRewrite, func(opts *Options) bool { return opts.Dedup },
+		func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+			deduped := dedupRows(rows)
+			fmt.Printf("Merged %d duplicate bindings\n", len(rows)-len(deduped))
+			resman.countColumn = true
+			return deduped, nil
+		})
+	registerEnricher("risk", stageScore, resolvesRoles, func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+		resman.ScoreRows(rows, resman.riskWeights)
+		return rows, nil
+	})
+
+	registerRenderer("csv", writeCsv)
+	registerRenderer("cypher", writeCypher)
+	registerRenderer("json", func(output string, rows []*Row, resman *resourceManager) error {
+		return writeJSON(output, rows, resman, false)
+	})
+	registerRenderer("ndjson", func(output string, rows []*Row, resman *resourceManager) error {
+		return writeJSON(output, rows, resman, true)
+	})
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnabledEnrichers(t *testing.T) {
+	tests := []struct {
+		opts *Options
+		want []string
+	}{
+		{&Options{}, []string{"member-projects", "permissions", "risk"}},
+		{&Options{CountOnly: true, Dedup: true}, []string{"member-projects", "dedup"}},
+		{&Options{Dedup: true, ServiceAccountStatus: true, UserStatus: true},
+			[]string{"member-projects", "service-account-status", "user-status", "permissions", "dedup", "risk"}},
+	}
+	for _, tt := range tests {
+		got := make([]string, 0)
+		for _, e := range enabledEnrichers(tt.opts) {
+			got = append(got, e.name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("enabledEnrichers(%+v) = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	for _, format := range []string{"csv", "cypher", "json", "ndjson"} {
+		if err := checkFormat(format); err != nil {
+			t.Errorf("checkFormat(%s) = %v", format, err)
+		}
+	}
+	if err := checkFormat("xml"); err == nil {
+		t.Errorf("checkFormat(xml) succeeded, want an error")
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type dataCatalogTaxonomy struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type dataCatalogPolicyTag struct {
+	Name            string `json:"name"`
+	DisplayName     string `json:"displayName"`
+	ParentPolicyTag string `json:"parentPolicyTag"`
+}
+
+type listTaxonomiesResponse struct {
+	Taxonomies    []*dataCatalogTaxonomy `json:"taxonomies"`
+	NextPageToken string                 `json:"nextPageToken"`
+}
+
+type listPolicyTagsResponse struct {
+	PolicyTags    []*dataCatalogPolicyTag `json:"policyTags"`
+	NextPageToken string                  `json:"nextPageToken"`
+}
+
+// policyTagPolicy is the policy of a taxonomy or policy tag as read in one location, added to
+// the rows afterwards.
+type policyTagPolicy struct {
+	name        string
+	resType     string
+	displayName string
+	policy      *Policy
+}
+
+// dataCatalogTaxonomies lists the policy tag taxonomies of a project in one location.
+func (r *resourceManager) dataCatalogTaxonomies(projectId string, location string) ([]*dataCatalogTaxonomy, error) {
+	taxonomies := make([]*dataCatalogTaxonomy, 0)
+	pageToken := ""
+	for {
+		u := fmt.Sprintf("https://datacatalog.googleapis.com/v1/projects/%s/locations/%s/taxonomies?pageSize=1000",
+			url.PathEscape(projectId), url.PathEscape(location))
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		resp := &listTaxonomiesResponse{}
+		if err := r.getJSON(u, resp); err != nil {
+			return nil, err
+		}
+		taxonomies = append(taxonomies, resp.Taxonomies...)
+		if resp.NextPageToken == "" {
+			return taxonomies, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// dataCatalogPolicyTags lists all the policy tags of a taxonomy, nested ones included.
+func (r *resourceManager) dataCatalogPolicyTags(taxonomy string) ([]*dataCatalogPolicyTag, error) {
+	tags := make([]*dataCatalogPolicyTag, 0)
+	pageToken := ""
+	for {
+		u := fmt.Sprintf("https://datacatalog.googleapis.com/v1/%s/policyTags?pageSize=1000", taxonomy)
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		resp := &listPolicyTagsResponse{}
+		if err := r.getJSON(u, resp); err != nil {
+			return nil, err
+		}
+		tags = append(tags, resp.PolicyTags...)
+		if resp.NextPageToken == "" {
+			return tags, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// getDataCatalogPolicy reads the IAM policy of a taxonomy or policy tag, with conditions.
+func (r *resourceManager) getDataCatalogPolicy(name string) (*Policy, error) {
+	var raw json.RawMessage
+	body := map[string]interface{}{"options": map[string]int{"requestedPolicyVersion": 3}}
+	if err := r.postJSON(fmt.Sprintf("https://datacatalog.googleapis.com/v1/%s:getIamPolicy", name), body, &raw); err != nil {
+		return nil, classifyError(name, err)
+	}
+	policy := &Policy{}
+	if err := json.Unmarshal(raw, policy); err != nil {
+		return nil, err
+	}
+	policy.raw = raw
+	return policy, nil
+}
+
+// policyTagPath names a policy tag by its taxonomy and the chain of parent tags above it,
+// such as "PII / Contact / Email", since tag display names are only unique among siblings.
+func policyTagPath(taxonomy string, tag *dataCatalogPolicyTag, byName map[string]*dataCatalogPolicyTag) string {
+	names := []string{tag.DisplayName}
+	seen := map[string]bool{tag.Name: true}
+	for parent := byName[tag.ParentPolicyTag]; parent != nil && !seen[parent.Name]; parent = byName[parent.ParentPolicyTag] {
+		seen[parent.Name] = true
+		names = append([]string{parent.DisplayName}, names...)
+	}
+	return strings.Join(append([]string{taxonomy}, names...), " / ")
+}
+
+// addPolicyTagPolicies adds the policies of a project's Data Catalog taxonomies and their policy
+// tags. BigQuery column-level security is granted there, through
+// roles/datacatalog.categoryFineGrainedReader, and is invisible in dataset and table policies.
+// Taxonomies live in BigQuery locations, so every location Data Catalog offers the project is
+// searched concurrently; the policies are added afterwards in a stable order.
+func (r *resourceManager) addPolicyTagPolicies(projectId string, rows *[]*Row) error {
+	var mu sync.Mutex
+	found := make([]*policyTagPolicy, 0)
+	add := func(name string, resType string, displayName string) {
+		policy, err := r.getDataCatalogPolicy(name)
+		if err != nil {
+			logerr.Printf("Unable to get policy of %s %s: %v\n", resType, name, err)
+			r.markUnreadable(Row{
+				Resource:    name[strings.LastIndex(name, "/")+1:],
+				Type:        resType,
+				Parent:      fmt.Sprintf("projects/%s", projectId),
+				Name:        "//datacatalog.googleapis.com/" + name,
+				DisplayName: displayName,
+			}, err)
+			return
+		}
+		mu.Lock()
+		found = append(found, &policyTagPolicy{name: name, resType: resType, displayName: displayName, policy: policy})
+		mu.Unlock()
+	}
+	err := r.ForEachLocation("datacatalog", projectId, func(location string) error {
+		taxonomies, err := r.dataCatalogTaxonomies(projectId, location)
+		if err != nil {
+			return err
+		}
+		for _, taxonomy := range taxonomies {
+			add(taxonomy.Name, "taxonomy", taxonomy.DisplayName)
+			tags, err := r.dataCatalogPolicyTags(taxonomy.Name)
+			if err != nil {
+				logerr.Printf("Unable to list policy tags of taxonomy %s: %v\n", taxonomy.Name, err)
+				continue
+			}
+			byName := make(map[string]*dataCatalogPolicyTag)
+			for _, tag := range tags {
+				byName[tag.Name] = tag
+			}
+			for _, tag := range tags {
+				add(tag.Name, "policytag", policyTagPath(taxonomy.DisplayName, tag, byName))
+			}
+		}
+		return nil
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].name < found[j].name })
+	for _, p := range found {
+		r.resourcesScanned++
+		r.addPolicy(p.policy, rows, Row{
+			Resource:    p.name[strings.LastIndex(p.name, "/")+1:],
+			Type:        p.resType,
+			Parent:      fmt.Sprintf("projects/%s", projectId),
+			Name:        "//datacatalog.googleapis.com/" + p.name,
+			DisplayName: p.displayName,
+		})
+	}
+	return err
+}
+
+func init() {
+	registerCollectorPermissions("policy-tags",
+		"datacatalog.taxonomies.list",
+		"datacatalog.taxonomies.get",
+		"datacatalog.taxonomies.getIamPolicy")
+}
@@ -0,0 +1,42 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestPolicyTagPath(t *testing.T) {
+	pii := &dataCatalogPolicyTag{Name: "t/policyTags/1", DisplayName: "Contact"}
+	email := &dataCatalogPolicyTag{Name: "t/policyTags/2", DisplayName: "Email", ParentPolicyTag: "t/policyTags/1"}
+	orphan := &dataCatalogPolicyTag{Name: "t/policyTags/3", DisplayName: "Phone", ParentPolicyTag: "t/policyTags/9"}
+	loop := &dataCatalogPolicyTag{Name: "t/policyTags/4", DisplayName: "Loop", ParentPolicyTag: "t/policyTags/4"}
+	byName := map[string]*dataCatalogPolicyTag{}
+	for _, tag := range []*dataCatalogPolicyTag{pii, email, orphan, loop} {
+		byName[tag.Name] = tag
+	}
+	tests := []struct {
+		tag  *dataCatalogPolicyTag
+		want string
+	}{
+		{pii, "PII / Contact"},
+		{email, "PII / Contact / Email"},
+		{orphan, "PII / Phone"},
+		{loop, "PII / Loop"},
+	}
+	for _, tt := range tests {
+		if got := policyTagPath("PII", tt.tag, byName); got != tt.want {
+			t.Errorf("policyTagPath(%q) = %q, want %q", tt.tag.Name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Service agents are named service-<project number>@<agent domain> (or <number>@ for older agents).
+var serviceAgentLocalPart = regexp.MustCompile(`^(service-(org-|folder-)?)?[0-9]+$`)
+
+var serviceAgentDomains = map[string]string{
+	"cloudservices.gserviceaccount.com":                      "Google APIs",
+	"container-engine-robot.iam.gserviceaccount.com":         "container",
+	"compute-system.iam.gserviceaccount.com":                 "compute",
+	"containerregistry.iam.gserviceaccount.com":              "containerregistry",
+	"dataflow-service-producer-prod.iam.gserviceaccount.com": "dataflow",
+	"dataproc-accounts.iam.gserviceaccount.com":              "dataproc",
+	"cloudcomposer-accounts.iam.gserviceaccount.com":         "composer",
+	"gs-project-accounts.iam.gserviceaccount.com":            "storage",
+	"serverless-robot-prod.iam.gserviceaccount.com":          "run",
+	"gcf-admin-robot.iam.gserviceaccount.com":                "cloudfunctions",
+	"cloud-ml.google.com.iam.gserviceaccount.com":            "ml",
+	"cloud-redis.iam.gserviceaccount.com":                    "redis",
+	"cloud-tpu.iam.gserviceaccount.com":                      "tpu",
+	"bigquery-encryption.iam.gserviceaccount.com":            "bigquery",
+	"gae-api-prod.google.com.iam.gserviceaccount.com":        "appengine",
+	"sourcerepo-service-accounts.iam.gserviceaccount.com":    "sourcerepo",
+	"genomics-api.google.com.iam.gserviceaccount.com":        "genomics",
+	"firebase-rules.iam.gserviceaccount.com":                 "firebaserules",
+	"cloud-filer.iam.gserviceaccount.com":                    "file",
+}
+
+const (
+	memberClassCustomer       = "customer"
+	memberClassGoogleManaged  = "google-managed"
+	memberClassDefaultAccount = "default-service-account"
+)
+
+var defaultServiceAccount = regexp.MustCompile(`^([0-9]+-compute@developer|[a-z][a-z0-9-]*@appspot|[0-9]+@cloudbuild)\.gserviceaccount\.com$`)
+
+// memberEmail strips the member type prefix (user:, serviceAccount:, deleted:...) from an IAM member.
+func memberEmail(member string) string {
+	if i := strings.LastIndex(member, ":"); i >= 0 {
+		member = member[i+1:]
+	}
+	if i := strings.Index(member, "?uid="); i >= 0 {
+		member = member[:i]
+	}
+	return member
+}
+
+// serviceAgent returns the service a Google-managed service agent belongs to, or "" for any other member.
+func serviceAgent(member string) string {
+	email := memberEmail(member)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	local, domain := email[:at], email[at+1:]
+	if strings.HasPrefix(domain, "gcp-sa-") && strings.HasSuffix(domain, ".iam.gserviceaccount.com") {
+		return strings.TrimSuffix(strings.TrimPrefix(domain, "gcp-sa-"), ".iam.gserviceaccount.com")
+	}
+	if service, ok := serviceAgentDomains[domain]; ok && serviceAgentLocalPart.MatchString(local) {
+		return service
+	}
+	return ""
+}
+
+// memberClass tells customer principals apart from Google-managed service agents and the
+// default service accounts Google creates in customer projects.
+func memberClass(member string) string {
+	if serviceAgent(member) != "" {
+		return memberClassGoogleManaged
+	}
+	if strings.HasPrefix(member, "serviceAccount:") && defaultServiceAccount.MatchString(memberEmail(member)) {
+		return memberClassDefaultAccount
+	}
+	return memberClassCustomer
+}
+
+func withoutGoogleManaged(rows []*Row) []*Row {
+	filtered := make([]*Row, 0, len(rows))
+	for _, row := range rows {
+		if memberClass(row.Member) != memberClassGoogleManaged {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered
+}
+
+func serviceAgentReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	records := make([][]string, 0)
+	for _, row := range rows {
+		if service := serviceAgent(row.Member); service != "" {
+			records = append(records, []string{service, row.Member, resman.ResourceColumn(row), row.Type, row.Role})
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return strings.Join(records[i], ",") < strings.Join(records[j], ",")
+	})
+	return []string{"Service", "Member", "Resource", "Type", "Role"}, records, nil
+}
+
+func init() {
+	registerReport("service-agents", serviceAgentReport)
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Service accounts named after the number of the project that owns them.
+var projectNumberMember = regexp.MustCompile(`^(?:service-)?([0-9]+)(?:-compute)?@`)
+
+func (r *resourceManager) recordProject(projectId string, number int64) {
+	n := strconv.FormatInt(number, 10)
+	r.projectIds[n] = projectId
+	r.projectNumbers[projectId] = n
+}
+
+// ProjectIdForNumber resolves a project number, asking the API for projects outside the crawl.
+// Unresolvable numbers are cached as "".
+func (r *resourceManager) ProjectIdForNumber(number string) string {
+	if id, ok := r.projectIds[number]; ok {
+		return id
+	}
+	r.projectIds[number] = ""
+	p, err := r.v1.Projects.Get(number).Fields("projectId,projectNumber").Context(r.ctx).Do()
+	if err != nil {
+		return ""
+	}
+	r.recordProject(p.ProjectId, p.ProjectNumber)
+	return p.ProjectId
+}
+
+// ProjectNumberForId is the reverse of ProjectIdForNumber, also asking the API for projects
+// outside the crawl.
+func (r *resourceManager) ProjectNumberForId(projectId string) string {
+	if n, ok := r.projectNumbers[projectId]; ok {
+		return n
+	}
+	r.projectNumbers[projectId] = ""
+	p, err := r.v1.Projects.Get(projectId).Fields("projectId,projectNumber").Context(r.ctx).Do()
+	if err != nil {
+		return ""
+	}
+	r.recordProject(p.ProjectId, p.ProjectNumber)
+	return r.projectNumbers[projectId]
+}
+
+// MemberProject returns the id of the project a service account lives in, or "" for other members.
+func (r *resourceManager) MemberProject(member string) string {
+	if !strings.HasPrefix(member, "serviceAccount:") {
+		return ""
+	}
+	email := memberEmail(member)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	domain := email[at+1:]
+	switch {
+	case strings.HasSuffix(domain, ".iam.gserviceaccount.com") && !strings.HasPrefix(domain, "gcp-sa-") &&
+		serviceAgentDomains[domain] == "":
+		return strings.TrimSuffix(domain, ".iam.gserviceaccount.com")
+	case domain == "appspot.gserviceaccount.com":
+		return email[:at]
+	}
+	if m := projectNumberMember.FindStringSubmatch(email); m != nil {
+		if id := r.ProjectIdForNumber(m[1]); id != "" {
+			return id
+		}
+		return m[1]
+	}
+	return ""
+}
+
+func (r *resourceManager) AnnotateMemberProjects(rows []*Row) {
+	for _, row := range rows {
+		row.MemberProject = r.MemberProject(row.Member)
+	}
+}
@@ -0,0 +1,585 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: proto/policygopher.proto
+
+package policygopher // import "github.com/glickbot/policygopher/proto"
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type PolicyRow struct {
+	Resource             string   `protobuf:"bytes,1,opt,name=resource,proto3" json:"resource,omitempty"`
+	Type                 string   `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Member               string   `protobuf:"bytes,3,opt,name=member,proto3" json:"member,omitempty"`
+	Role                 string   `protobuf:"bytes,4,opt,name=role,proto3" json:"role,omitempty"`
+	Parent               string   `protobuf:"bytes,5,opt,name=parent,proto3" json:"parent,omitempty"`
+	Risk                 int32    `protobuf:"varint,6,opt,name=risk,proto3" json:"risk,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PolicyRow) Reset()         { *m = PolicyRow{} }
+func (m *PolicyRow) String() string { return proto.CompactTextString(m) }
+func (*PolicyRow) ProtoMessage()    {}
+func (*PolicyRow) Descriptor() ([]byte, []int) {
+	return fileDescriptor_policygopher_cac323924277776e, []int{0}
+}
+func (m *PolicyRow) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PolicyRow.Unmarshal(m, b)
+}
+func (m *PolicyRow) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PolicyRow.Marshal(b, m, deterministic)
+}
+func (dst *PolicyRow) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PolicyRow.Merge(dst, src)
+}
+func (m *PolicyRow) XXX_Size() int {
+	return xxx_messageInfo_PolicyRow.Size(m)
+}
+func (m *PolicyRow) XXX_DiscardUnknown() {
+	xxx_messageInfo_PolicyRow.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PolicyRow proto.InternalMessageInfo
+
+func (m *PolicyRow) GetResource() string {
+	if m != nil {
+		return m.Resource
+	}
+	return ""
+}
+
+func (m *PolicyRow) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *PolicyRow) GetMember() string {
+	if m != nil {
+		return m.Member
+	}
+	return ""
+}
+
+func (m *PolicyRow) GetRole() string {
+	if m != nil {
+		return m.Role
+	}
+	return ""
+}
+
+func (m *PolicyRow) GetParent() string {
+	if m != nil {
+		return m.Parent
+	}
+	return ""
+}
+
+func (m *PolicyRow) GetRisk() int32 {
+	if m != nil {
+		return m.Risk
+	}
+	return 0
+}
+
+type StreamPolicyRowsRequest struct {
+	SnapshotId           string   `protobuf:"bytes,1,opt,name=snapshot_id,json=snapshotId,proto3" json:"snapshot_id,omitempty"`
+	OrgId                string   `protobuf:"bytes,2,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StreamPolicyRowsRequest) Reset()         { *m = StreamPolicyRowsRequest{} }
+func (m *StreamPolicyRowsRequest) String() string { return proto.CompactTextString(m) }
+func (*StreamPolicyRowsRequest) ProtoMessage()    {}
+func (*StreamPolicyRowsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_policygopher_cac323924277776e, []int{1}
+}
+func (m *StreamPolicyRowsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StreamPolicyRowsRequest.Unmarshal(m, b)
+}
+func (m *StreamPolicyRowsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StreamPolicyRowsRequest.Marshal(b, m, deterministic)
+}
+func (dst *StreamPolicyRowsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamPolicyRowsRequest.Merge(dst, src)
+}
+func (m *StreamPolicyRowsRequest) XXX_Size() int {
+	return xxx_messageInfo_StreamPolicyRowsRequest.Size(m)
+}
+func (m *StreamPolicyRowsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamPolicyRowsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamPolicyRowsRequest proto.InternalMessageInfo
+
+func (m *StreamPolicyRowsRequest) GetSnapshotId() string {
+	if m != nil {
+		return m.SnapshotId
+	}
+	return ""
+}
+
+func (m *StreamPolicyRowsRequest) GetOrgId() string {
+	if m != nil {
+		return m.OrgId
+	}
+	return ""
+}
+
+type GetMemberAccessRequest struct {
+	Member               string   `protobuf:"bytes,1,opt,name=member,proto3" json:"member,omitempty"`
+	SnapshotId           string   `protobuf:"bytes,2,opt,name=snapshot_id,json=snapshotId,proto3" json:"snapshot_id,omitempty"`
+	OrgId                string   `protobuf:"bytes,3,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetMemberAccessRequest) Reset()         { *m = GetMemberAccessRequest{} }
+func (m *GetMemberAccessRequest) String() string { return proto.CompactTextString(m) }
+func (*GetMemberAccessRequest) ProtoMessage()    {}
+func (*GetMemberAccessRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_policygopher_cac323924277776e, []int{2}
+}
+func (m *GetMemberAccessRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetMemberAccessRequest.Unmarshal(m, b)
+}
+func (m *GetMemberAccessRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetMemberAccessRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetMemberAccessRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetMemberAccessRequest.Merge(dst, src)
+}
+func (m *GetMemberAccessRequest) XXX_Size() int {
+	return xxx_messageInfo_GetMemberAccessRequest.Size(m)
+}
+func (m *GetMemberAccessRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetMemberAccessRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetMemberAccessRequest proto.InternalMessageInfo
+
+func (m *GetMemberAccessRequest) GetMember() string {
+	if m != nil {
+		return m.Member
+	}
+	return ""
+}
+
+func (m *GetMemberAccessRequest) GetSnapshotId() string {
+	if m != nil {
+		return m.SnapshotId
+	}
+	return ""
+}
+
+func (m *GetMemberAccessRequest) GetOrgId() string {
+	if m != nil {
+		return m.OrgId
+	}
+	return ""
+}
+
+type GetMemberAccessResponse struct {
+	SnapshotId string       `protobuf:"bytes,1,opt,name=snapshot_id,json=snapshotId,proto3" json:"snapshot_id,omitempty"`
+	Rows       []*PolicyRow `protobuf:"bytes,2,rep,name=rows,proto3" json:"rows,omitempty"`
+	// Permissions are only known for roles the snapshot resolved.
+	Permissions          []string `protobuf:"bytes,3,rep,name=permissions,proto3" json:"permissions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetMemberAccessResponse) Reset()         { *m = GetMemberAccessResponse{} }
+func (m *GetMemberAccessResponse) String() string { return proto.CompactTextString(m) }
+func (*GetMemberAccessResponse) ProtoMessage()    {}
+func (*GetMemberAccessResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_policygopher_cac323924277776e, []int{3}
+}
+func (m *GetMemberAccessResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetMemberAccessResponse.Unmarshal(m, b)
+}
+func (m *GetMemberAccessResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetMemberAccessResponse.Marshal(b, m, deterministic)
+}
+func (dst *GetMemberAccessResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetMemberAccessResponse.Merge(dst, src)
+}
+func (m *GetMemberAccessResponse) XXX_Size() int {
+	return xxx_messageInfo_GetMemberAccessResponse.Size(m)
+}
+func (m *GetMemberAccessResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetMemberAccessResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetMemberAccessResponse proto.InternalMessageInfo
+
+func (m *GetMemberAccessResponse) GetSnapshotId() string {
+	if m != nil {
+		return m.SnapshotId
+	}
+	return ""
+}
+
+func (m *GetMemberAccessResponse) GetRows() []*PolicyRow {
+	if m != nil {
+		return m.Rows
+	}
+	return nil
+}
+
+func (m *GetMemberAccessResponse) GetPermissions() []string {
+	if m != nil {
+		return m.Permissions
+	}
+	return nil
+}
+
+type DiffSnapshotsRequest struct {
+	OldId                string   `protobuf:"bytes,1,opt,name=old_id,json=oldId,proto3" json:"old_id,omitempty"`
+	NewId                string   `protobuf:"bytes,2,opt,name=new_id,json=newId,proto3" json:"new_id,omitempty"`
+	OrgId                string   `protobuf:"bytes,3,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DiffSnapshotsRequest) Reset()         { *m = DiffSnapshotsRequest{} }
+func (m *DiffSnapshotsRequest) String() string { return proto.CompactTextString(m) }
+func (*DiffSnapshotsRequest) ProtoMessage()    {}
+func (*DiffSnapshotsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_policygopher_cac323924277776e, []int{4}
+}
+func (m *DiffSnapshotsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DiffSnapshotsRequest.Unmarshal(m, b)
+}
+func (m *DiffSnapshotsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DiffSnapshotsRequest.Marshal(b, m, deterministic)
+}
+func (dst *DiffSnapshotsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DiffSnapshotsRequest.Merge(dst, src)
+}
+func (m *DiffSnapshotsRequest) XXX_Size() int {
+	return xxx_messageInfo_DiffSnapshotsRequest.Size(m)
+}
+func (m *DiffSnapshotsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DiffSnapshotsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DiffSnapshotsRequest proto.InternalMessageInfo
+
+func (m *DiffSnapshotsRequest) GetOldId() string {
+	if m != nil {
+		return m.OldId
+	}
+	return ""
+}
+
+func (m *DiffSnapshotsRequest) GetNewId() string {
+	if m != nil {
+		return m.NewId
+	}
+	return ""
+}
+
+func (m *DiffSnapshotsRequest) GetOrgId() string {
+	if m != nil {
+		return m.OrgId
+	}
+	return ""
+}
+
+type DiffSnapshotsResponse struct {
+	OldId                string       `protobuf:"bytes,1,opt,name=old_id,json=oldId,proto3" json:"old_id,omitempty"`
+	NewId                string       `protobuf:"bytes,2,opt,name=new_id,json=newId,proto3" json:"new_id,omitempty"`
+	Added                []*PolicyRow `protobuf:"bytes,3,rep,name=added,proto3" json:"added,omitempty"`
+	Removed              []*PolicyRow `protobuf:"bytes,4,rep,name=removed,proto3" json:"removed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *DiffSnapshotsResponse) Reset()         { *m = DiffSnapshotsResponse{} }
+func (m *DiffSnapshotsResponse) String() string { return proto.CompactTextString(m) }
+func (*DiffSnapshotsResponse) ProtoMessage()    {}
+func (*DiffSnapshotsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_policygopher_cac323924277776e, []int{5}
+}
+func (m *DiffSnapshotsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DiffSnapshotsResponse.Unmarshal(m, b)
+}
+func (m *DiffSnapshotsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DiffSnapshotsResponse.Marshal(b, m, deterministic)
+}
+func (dst *DiffSnapshotsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DiffSnapshotsResponse.Merge(dst, src)
+}
+func (m *DiffSnapshotsResponse) XXX_Size() int {
+	return xxx_messageInfo_DiffSnapshotsResponse.Size(m)
+}
+func (m *DiffSnapshotsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DiffSnapshotsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DiffSnapshotsResponse proto.InternalMessageInfo
+
+func (m *DiffSnapshotsResponse) GetOldId() string {
+	if m != nil {
+		return m.OldId
+	}
+	return ""
+}
+
+func (m *DiffSnapshotsResponse) GetNewId() string {
+	if m != nil {
+		return m.NewId
+	}
+	return ""
+}
+
+func (m *DiffSnapshotsResponse) GetAdded() []*PolicyRow {
+	if m != nil {
+		return m.Added
+	}
+	return nil
+}
+
+func (m *DiffSnapshotsResponse) GetRemoved() []*PolicyRow {
+	if m != nil {
+		return m.Removed
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*PolicyRow)(nil), "policygopher.PolicyRow")
+	proto.RegisterType((*StreamPolicyRowsRequest)(nil), "policygopher.StreamPolicyRowsRequest")
+	proto.RegisterType((*GetMemberAccessRequest)(nil), "policygopher.GetMemberAccessRequest")
+	proto.RegisterType((*GetMemberAccessResponse)(nil), "policygopher.GetMemberAccessResponse")
+	proto.RegisterType((*DiffSnapshotsRequest)(nil), "policygopher.DiffSnapshotsRequest")
+	proto.RegisterType((*DiffSnapshotsResponse)(nil), "policygopher.DiffSnapshotsResponse")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// PolicyGopherClient is the client API for PolicyGopher service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type PolicyGopherClient interface {
+	StreamPolicyRows(ctx context.Context, in *StreamPolicyRowsRequest, opts ...grpc.CallOption) (PolicyGopher_StreamPolicyRowsClient, error)
+	GetMemberAccess(ctx context.Context, in *GetMemberAccessRequest, opts ...grpc.CallOption) (*GetMemberAccessResponse, error)
+	DiffSnapshots(ctx context.Context, in *DiffSnapshotsRequest, opts ...grpc.CallOption) (*DiffSnapshotsResponse, error)
+}
+
+type policyGopherClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewPolicyGopherClient(cc *grpc.ClientConn) PolicyGopherClient {
+	return &policyGopherClient{cc}
+}
+
+func (c *policyGopherClient) StreamPolicyRows(ctx context.Context, in *StreamPolicyRowsRequest, opts ...grpc.CallOption) (PolicyGopher_StreamPolicyRowsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_PolicyGopher_serviceDesc.Streams[0], "/policygopher.PolicyGopher/StreamPolicyRows", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &policyGopherStreamPolicyRowsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PolicyGopher_StreamPolicyRowsClient interface {
+	Recv() (*PolicyRow, error)
+	grpc.ClientStream
+}
+
+type policyGopherStreamPolicyRowsClient struct {
+	grpc.ClientStream
+}
+
+func (x *policyGopherStreamPolicyRowsClient) Recv() (*PolicyRow, error) {
+	m := new(PolicyRow)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *policyGopherClient) GetMemberAccess(ctx context.Context, in *GetMemberAccessRequest, opts ...grpc.CallOption) (*GetMemberAccessResponse, error) {
+	out := new(GetMemberAccessResponse)
+	err := c.cc.Invoke(ctx, "/policygopher.PolicyGopher/GetMemberAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyGopherClient) DiffSnapshots(ctx context.Context, in *DiffSnapshotsRequest, opts ...grpc.CallOption) (*DiffSnapshotsResponse, error) {
+	out := new(DiffSnapshotsResponse)
+	err := c.cc.Invoke(ctx, "/policygopher.PolicyGopher/DiffSnapshots", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PolicyGopherServer is the server API for PolicyGopher service.
+type PolicyGopherServer interface {
+	StreamPolicyRows(*StreamPolicyRowsRequest, PolicyGopher_StreamPolicyRowsServer) error
+	GetMemberAccess(context.Context, *GetMemberAccessRequest) (*GetMemberAccessResponse, error)
+	DiffSnapshots(context.Context, *DiffSnapshotsRequest) (*DiffSnapshotsResponse, error)
+}
+
+func RegisterPolicyGopherServer(s *grpc.Server, srv PolicyGopherServer) {
+	s.RegisterService(&_PolicyGopher_serviceDesc, srv)
+}
+
+func _PolicyGopher_StreamPolicyRows_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamPolicyRowsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PolicyGopherServer).StreamPolicyRows(m, &policyGopherStreamPolicyRowsServer{stream})
+}
+
+type PolicyGopher_StreamPolicyRowsServer interface {
+	Send(*PolicyRow) error
+	grpc.ServerStream
+}
+
+type policyGopherStreamPolicyRowsServer struct {
+	grpc.ServerStream
+}
+
+func (x *policyGopherStreamPolicyRowsServer) Send(m *PolicyRow) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _PolicyGopher_GetMemberAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMemberAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyGopherServer).GetMemberAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/policygopher.PolicyGopher/GetMemberAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyGopherServer).GetMemberAccess(ctx, req.(*GetMemberAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyGopher_DiffSnapshots_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffSnapshotsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyGopherServer).DiffSnapshots(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/policygopher.PolicyGopher/DiffSnapshots",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyGopherServer).DiffSnapshots(ctx, req.(*DiffSnapshotsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _PolicyGopher_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "policygopher.PolicyGopher",
+	HandlerType: (*PolicyGopherServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetMemberAccess",
+			Handler:    _PolicyGopher_GetMemberAccess_Handler,
+		},
+		{
+			MethodName: "DiffSnapshots",
+			Handler:    _PolicyGopher_DiffSnapshots_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPolicyRows",
+			Handler:       _PolicyGopher_StreamPolicyRows_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/policygopher.proto",
+}
+
+func init() {
+	proto.RegisterFile("proto/policygopher.proto", fileDescriptor_policygopher_cac323924277776e)
+}
+
+var fileDescriptor_policygopher_cac323924277776e = []byte{
+	// 460 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x94, 0xc1, 0x6f, 0xd3, 0x30,
+	0x14, 0xc6, 0x95, 0xa6, 0x29, 0xf4, 0x75, 0x08, 0x64, 0xb1, 0xd5, 0xea, 0x85, 0x28, 0x30, 0xa9,
+	0x12, 0xa2, 0x85, 0x4d, 0x9c, 0x38, 0x81, 0x90, 0xa6, 0x1d, 0x90, 0x20, 0xe3, 0x80, 0x40, 0x02,
+	0xb5, 0xf1, 0x5b, 0x1a, 0x2d, 0xc9, 0x0b, 0xb6, 0x4b, 0xb5, 0x3f, 0x81, 0x33, 0x77, 0xae, 0xfc,
+	0x9b, 0x28, 0x76, 0x96, 0x25, 0x65, 0xcd, 0xc4, 0xcd, 0xfe, 0xf2, 0xcb, 0x7b, 0xdf, 0xf7, 0x6c,
+	0x19, 0x78, 0x21, 0x49, 0xd3, 0xbc, 0xa0, 0x34, 0x89, 0x2e, 0x63, 0x2a, 0x56, 0x28, 0x67, 0x46,
+	0x62, 0x7b, 0x4d, 0x2d, 0xf8, 0xe5, 0xc0, 0xf0, 0xbd, 0x11, 0x42, 0xda, 0xb0, 0x09, 0xdc, 0x95,
+	0xa8, 0x68, 0x2d, 0x23, 0xe4, 0x8e, 0xef, 0x4c, 0x87, 0x61, 0xbd, 0x67, 0x0c, 0xfa, 0xfa, 0xb2,
+	0x40, 0xde, 0x33, 0xba, 0x59, 0xb3, 0x03, 0x18, 0x64, 0x98, 0x2d, 0x51, 0x72, 0xd7, 0xa8, 0xd5,
+	0xae, 0x64, 0x25, 0xa5, 0xc8, 0xfb, 0x96, 0x2d, 0xd7, 0x25, 0x5b, 0x2c, 0x24, 0xe6, 0x9a, 0x7b,
+	0x96, 0xb5, 0x3b, 0xc3, 0x26, 0xea, 0x82, 0x0f, 0x7c, 0x67, 0xea, 0x85, 0x66, 0x1d, 0x7c, 0x80,
+	0xf1, 0x99, 0x96, 0xb8, 0xc8, 0x6a, 0x6b, 0x2a, 0xc4, 0xef, 0x6b, 0x54, 0x9a, 0x3d, 0x82, 0x91,
+	0xca, 0x17, 0x85, 0x5a, 0x91, 0xfe, 0x96, 0x88, 0xca, 0x25, 0x5c, 0x49, 0xa7, 0x82, 0xed, 0xc3,
+	0x80, 0x64, 0x5c, 0x7e, 0xb3, 0x4e, 0x3d, 0x92, 0xf1, 0xa9, 0x08, 0x56, 0x70, 0x70, 0x82, 0xfa,
+	0x9d, 0xf1, 0xf7, 0x3a, 0x8a, 0x50, 0xd5, 0x15, 0xaf, 0x43, 0x38, 0xad, 0x10, 0x5b, 0x9d, 0x7a,
+	0x1d, 0x9d, 0xdc, 0x66, 0xa7, 0x9f, 0x0e, 0x8c, 0xff, 0x69, 0xa5, 0x0a, 0xca, 0x15, 0xde, 0xee,
+	0xfe, 0x69, 0x39, 0xb9, 0x8d, 0xe2, 0x3d, 0xdf, 0x9d, 0x8e, 0x8e, 0xc6, 0xb3, 0xd6, 0x01, 0xd6,
+	0xd3, 0x08, 0x0d, 0xc4, 0x7c, 0x18, 0x15, 0x28, 0xb3, 0x44, 0xa9, 0x84, 0x72, 0xc5, 0x5d, 0xdf,
+	0x9d, 0x0e, 0xc3, 0xa6, 0x14, 0x7c, 0x81, 0x87, 0x6f, 0x93, 0xf3, 0xf3, 0xb3, 0xaa, 0x41, 0x9d,
+	0xb9, 0xb4, 0x9e, 0x8a, 0x6b, 0x0b, 0x1e, 0xa5, 0xc2, 0x26, 0xca, 0x71, 0xd3, 0x98, 0x5d, 0x8e,
+	0x9b, 0xdd, 0x41, 0xff, 0x38, 0xb0, 0xbf, 0x55, 0xbd, 0x8a, 0xf9, 0x7f, 0xe5, 0x9f, 0x81, 0xb7,
+	0x10, 0x02, 0x05, 0x77, 0xbb, 0x43, 0x5b, 0x8a, 0xbd, 0x80, 0x3b, 0x12, 0x33, 0xfa, 0x81, 0x82,
+	0xf7, 0xbb, 0x7f, 0xb8, 0xe2, 0x8e, 0x7e, 0xf7, 0x60, 0xcf, 0xca, 0x27, 0x86, 0x61, 0x1f, 0xe1,
+	0xc1, 0xf6, 0x05, 0x63, 0x87, 0xed, 0x32, 0x3b, 0x2e, 0xe0, 0x64, 0x57, 0xb7, 0xe7, 0x0e, 0xfb,
+	0x0a, 0xf7, 0xb7, 0x0e, 0x9e, 0x3d, 0x69, 0xd3, 0x37, 0x5f, 0xc1, 0xc9, 0xe1, 0x2d, 0x54, 0x35,
+	0xd6, 0x4f, 0x70, 0xaf, 0x35, 0x6f, 0x16, 0xb4, 0xff, 0xbb, 0xe9, 0xa8, 0x27, 0x8f, 0x3b, 0x19,
+	0x5b, 0xf9, 0xcd, 0xcb, 0xcf, 0xc7, 0x71, 0xa2, 0x57, 0xeb, 0xe5, 0x2c, 0xa2, 0x6c, 0x1e, 0xa7,
+	0x49, 0x74, 0xb1, 0x24, 0xdd, 0x7a, 0x3e, 0xe6, 0xe6, 0xf9, 0x78, 0xd5, 0x94, 0x96, 0x03, 0xa3,
+	0x1d, 0xff, 0x1d, 0x00, 0xdc, 0x07, 0xfb, 0x2c, 0x6e, 0x04, 0x00, 0x00,
+}
@@ -0,0 +1,53 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// rawPolicyFilename names the file of a resource's policy after its canonical name,
+// e.g. projects/foo becomes projects_foo.json.
+func rawPolicyFilename(dir string, name string) string {
+	return outputPath(dir, strings.Replace(strings.TrimPrefix(name, "//"), "/", "_", -1)+".json")
+}
+
+// writeRawPolicy saves the policy exactly as the API returned it, with version, etag,
+// conditions and audit configs that the flattened rows leave out.
+func (r *resourceManager) writeRawPolicy(name string, policy *Policy) error {
+	if r.rawPolicyDir == "" || policy.raw == nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(policy.raw, "", "  ")
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error encoding policy of %s: %v", name, err))
+	}
+	return writeFileAtomic(rawPolicyFilename(r.rawPolicyDir, name), append(data, '\n'), 0600)
+}
+
+// SetRawPolicyDir makes every policy collected from now on also be written to dir.
+func (r *resourceManager) SetRawPolicyDir(dir string) error {
+	if !isGcsPath(dir) {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return errors.New(fmt.Sprintf("Unable to create raw policy directory %s: %v", dir, err))
+		}
+	}
+	r.rawPolicyDir = dir
+	return nil
+}
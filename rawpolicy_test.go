@@ -0,0 +1,38 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRawPolicyFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"organizations/123", "organizations_123.json"},
+		{"folders/456", "folders_456.json"},
+		{"projects/my-project", "projects_my-project.json"},
+		{"//storage.googleapis.com/projects/_/buckets/logs", "storage.googleapis.com_projects___buckets_logs.json"},
+		{"//iap.googleapis.com/projects/42/iap_web", "iap.googleapis.com_projects_42_iap_web.json"},
+	}
+	for _, tt := range tests {
+		if got, want := rawPolicyFilename("policies", tt.name), filepath.Join("policies", tt.want); got != want {
+			t.Errorf("rawPolicyFilename(%q) = %q, want %q", tt.name, got, want)
+		}
+	}
+}
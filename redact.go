@@ -0,0 +1,104 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	redactHash       = "hash"
+	redactDomainOnly = "domain-only"
+)
+
+// checkRedaction validates --redact-members, warning about hashes anyone can recompute.
+func checkRedaction(mode string, salt string) error {
+	switch mode {
+	case redactHash:
+		if salt == "" {
+			logerr.Printf("--redact-members hash without --redact-salt can be reversed by hashing guessed emails\n")
+		}
+		return nil
+	case "", redactDomainOnly:
+		return nil
+	}
+	return errors.New(fmt.Sprintf("Unknown --redact-members %s, expected %s or %s", mode, redactHash, redactDomainOnly))
+}
+
+// redactMember hides the person behind a user or group member: hash replaces the email with a
+// keyed hash that is the same for the same member across rows and runs with the same salt, and
+// domain-only keeps only its domain. Service accounts, domains, and allUsers aren't people and
+// are kept, so their member classes and reports still work.
+func redactMember(member string, mode string, salt string) string {
+	if strings.HasPrefix(member, "deleted:") {
+		inner := strings.TrimPrefix(member, "deleted:")
+		if i := strings.Index(inner, "?uid="); i >= 0 {
+			inner = inner[:i]
+		}
+		return "deleted:" + redactMember(inner, mode, salt)
+	}
+	i := strings.Index(member, ":")
+	if i < 0 {
+		return member
+	}
+	kind, email := member[:i], member[i+1:]
+	if kind != "user" && kind != "group" {
+		return member
+	}
+	domain := ""
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain = email[at+1:]
+	}
+	if mode == redactDomainOnly {
+		return fmt.Sprintf("%s:*@%s", kind, domain)
+	}
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(strings.ToLower(email)))
+	hash := hex.EncodeToString(mac.Sum(nil))[:16]
+	if domain == "" {
+		return fmt.Sprintf("%s:%s", kind, hash)
+	}
+	return fmt.Sprintf("%s:%s@%s", kind, hash, domain)
+}
+
+// redactRows redacts the members of rows. Hashed members keep what is known of their status
+// under the new name; a domain-only name stands for several members and has none.
+func (r *resourceManager) redactRows(rows []*Row, mode string, salt string) {
+	redacted := make(map[string]string)
+	for _, row := range rows {
+		name, ok := redacted[row.Member]
+		if !ok {
+			name = redactMember(row.Member, mode, salt)
+			redacted[row.Member] = name
+			if status, ok := r.memberStates[memberStatusKey(row.Member)]; ok && mode == redactHash && name != row.Member {
+				r.memberStates[memberStatusKey(name)] = status
+			}
+		}
+		row.Member = name
+	}
+}
+
+func init() {
+	registerEnricher("redact-members", stageRedact, func(opts *Options) bool { return opts.RedactMembers != "" },
+		func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+			resman.redactRows(rows, resman.redactMode, resman.redactSalt)
+			return rows, nil
+		})
+}
@@ -0,0 +1,61 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRedactMember(t *testing.T) {
+	hashed := redactMember("user:alice@example.com", redactHash, "s3cret")
+	tests := []struct {
+		member, mode, want string
+	}{
+		{"user:alice@example.com", redactDomainOnly, "user:*@example.com"},
+		{"group:admins@example.com", redactDomainOnly, "group:*@example.com"},
+		{"deleted:user:bob@example.com?uid=123", redactDomainOnly, "deleted:user:*@example.com"},
+		{"serviceAccount:ci@web.iam.gserviceaccount.com", redactDomainOnly, "serviceAccount:ci@web.iam.gserviceaccount.com"},
+		{"domain:example.com", redactHash, "domain:example.com"},
+		{"allUsers", redactHash, "allUsers"},
+		{"user:ALICE@example.com", redactHash, hashed},
+	}
+	for _, test := range tests {
+		if got := redactMember(test.member, test.mode, "s3cret"); got != test.want {
+			t.Errorf("redactMember(%s, %s) = %s, want %s", test.member, test.mode, got, test.want)
+		}
+	}
+	if len(hashed) != len("user:")+16+len("@example.com") || hashed == "user:alice@example.com" {
+		t.Errorf("redactMember(hash) = %s", hashed)
+	}
+	if redactMember("user:alice@example.com", redactHash, "other") == hashed {
+		t.Errorf("redactMember(hash) doesn't depend on the salt")
+	}
+}
+
+func TestRedactRowsKeepsStatus(t *testing.T) {
+	active := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	r := &resourceManager{memberStates: map[string]*memberStatus{
+		"user:alice@example.com": {State: "SUSPENDED", LastActive: active},
+	}}
+	rows := []*Row{{Member: "user:alice@example.com"}, {Member: "user:alice@example.com"}}
+	r.redactRows(rows, redactHash, "s3cret")
+	if rows[0].Member != rows[1].Member || rows[0].Member == "user:alice@example.com" {
+		t.Fatalf("redactRows() members = %s, %s", rows[0].Member, rows[1].Member)
+	}
+	if state, _ := r.MemberStatus(rows[0].Member); state != "SUSPENDED" {
+		t.Errorf("MemberStatus() of redacted member = %q, want SUSPENDED", state)
+	}
+}
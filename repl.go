@@ -0,0 +1,227 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const replPrompt = "policygopher> "
+
+// defaultReplLimit is how many bindings a query prints before counting the rest.
+const defaultReplLimit = 50
+
+const replHelp = `Commands:
+  member <member>          bindings of members matching, e.g. member alice@
+  role <role>              bindings of roles matching, e.g. role roles/owner
+  resource <resource>      bindings on resources matching, e.g. resource projects/foo
+  permission <permission>  bindings whose role grants the permission, e.g. permission iam.roles.create
+  summary                  count the bindings, members, roles, and resources in the snapshot
+  limit <n>                print at most n bindings a query, 0 for all
+  help                     show this help
+  quit                     leave, as does end of input
+A query matches values exactly when any do, and case-insensitively as a substring otherwise.
+`
+
+// replSession answers queries on the bindings of one snapshot for the repl command.
+type replSession struct {
+	snap  *Snapshot
+	limit int
+}
+
+func newReplSession(snap *Snapshot) *replSession {
+	return &replSession{snap: snap, limit: defaultReplLimit}
+}
+
+// runRepl loads a snapshot, the latest by default, and reads queries from stdin until quit or
+// end of input.
+func runRepl(opts *Options, snapshotId string) error {
+	snap, err := openSnapshot(opts.StoreDir, opts.OrgId, snapshotId)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d bindings from snapshot %s, type help for commands\n", len(snap.Rows), snap.Id)
+	return newReplSession(snap).Run(os.Stdin, os.Stdout)
+}
+
+// Run prompts for commands on in and answers them on out.
+func (s *replSession) Run(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, replPrompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		if !s.Exec(scanner.Text(), out) {
+			return nil
+		}
+	}
+}
+
+// Exec runs one command line, returning false when the session should end.
+func (s *replSession) Exec(line string, out io.Writer) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	command, arg := strings.ToLower(fields[0]), strings.Join(fields[1:], " ")
+	switch command {
+	case "member", "role", "resource", "permission":
+		if arg == "" {
+			fmt.Fprintf(out, "%s needs a value, type help for examples\n", command)
+			return true
+		}
+		var rows []*Row
+		switch command {
+		case "member":
+			rows = matchRows(s.snap.Rows, arg, func(row *Row) []string { return []string{row.Member} })
+		case "role":
+			rows = matchRows(s.snap.Rows, arg, func(row *Row) []string { return []string{row.Role} })
+		case "resource":
+			rows = matchRows(s.snap.Rows, strings.TrimPrefix(arg, fullResourceNamePrefix), func(row *Row) []string {
+				return []string{row.Name, row.Resource}
+			})
+		case "permission":
+			rows = s.permissionRows(arg, out)
+		}
+		s.printRows(rows, out)
+	case "summary":
+		s.printSummary(out)
+	case "limit":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			fmt.Fprintf(out, "limit needs a number of bindings, 0 for all, not %q\n", arg)
+			return true
+		}
+		s.limit = n
+	case "help", "?":
+		fmt.Fprint(out, replHelp)
+	case "quit", "exit":
+		return false
+	default:
+		fmt.Fprintf(out, "Unknown command %q, type help for commands\n", command)
+	}
+	return true
+}
+
+// matchRows returns the rows with a value equal to query, or when there are none, the rows
+// with a value containing it, ignoring case either way.
+func matchRows(rows []*Row, query string, values func(*Row) []string) []*Row {
+	query = strings.ToLower(query)
+	var exact, partial []*Row
+	for _, row := range rows {
+		isExact, isPartial := false, false
+		for _, v := range values(row) {
+			v = strings.ToLower(v)
+			isExact = isExact || v == query
+			isPartial = isPartial || (v != "" && strings.Contains(v, query))
+		}
+		if isExact {
+			exact = append(exact, row)
+		} else if isPartial {
+			partial = append(partial, row)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return partial
+}
+
+// permissionRows returns the rows whose role grants permission, going by the roles saved with
+// the snapshot. Roles missing from it are listed once, since their bindings can't be checked.
+func (s *replSession) permissionRows(permission string, out io.Writer) []*Row {
+	var rows []*Row
+	missing := make(map[string]bool)
+	for _, row := range s.snap.Rows {
+		permissions, ok := snapshotRolePermissions(s.snap, row)
+		if !ok {
+			missing[row.Role] = true
+			continue
+		}
+		for _, p := range permissions {
+			if p == permission {
+				rows = append(rows, row)
+				break
+			}
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(out, "Permissions of %d roles are not in snapshot %s, their bindings are skipped: %s\n",
+			len(missing), s.snap.Id, strings.Join(sortedKeys(missing), ", "))
+	}
+	return rows
+}
+
+func (s *replSession) printRows(rows []*Row, out io.Writer) {
+	sorted := make([]*Row, len(rows))
+	copy(sorted, rows)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if replResource(sorted[i]) != replResource(sorted[j]) {
+			return replResource(sorted[i]) < replResource(sorted[j])
+		}
+		if sorted[i].Role != sorted[j].Role {
+			return sorted[i].Role < sorted[j].Role
+		}
+		return sorted[i].Member < sorted[j].Member
+	})
+	shown := sorted
+	if s.limit > 0 && len(shown) > s.limit {
+		shown = shown[:s.limit]
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, row := range shown {
+		line := fmt.Sprintf("%s\t%s\t%s", replResource(row), row.Role, row.Member)
+		if row.Condition != nil {
+			line += fmt.Sprintf("\tif %s", row.Condition.Expression)
+		}
+		fmt.Fprintln(w, line)
+	}
+	w.Flush()
+	if len(shown) < len(sorted) {
+		fmt.Fprintf(out, "%d bindings, first %d shown, see limit\n", len(sorted), len(shown))
+	} else {
+		fmt.Fprintf(out, "%d bindings\n", len(sorted))
+	}
+}
+
+// printSummary counts what the snapshot holds, to get a feel for it before querying.
+func (s *replSession) printSummary(out io.Writer) {
+	members, roles, resources := make(map[string]bool), make(map[string]bool), make(map[string]bool)
+	for _, row := range s.snap.Rows {
+		members[row.Member] = true
+		roles[row.Role] = true
+		resources[replResource(row)] = true
+	}
+	fmt.Fprintf(out, "snapshot %s of org %s taken %s\n", s.snap.Id, s.snap.OrgId, s.snap.Created.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(out, "%d bindings, %d members, %d roles, %d resources\n", len(s.snap.Rows), len(members), len(roles), len(resources))
+}
+
+// replResource names a row's resource canonically when the snapshot has the name, as exports
+// from before Name was added don't.
+func replResource(row *Row) string {
+	if row.Name != "" {
+		return row.Name
+	}
+	return row.Resource
+}
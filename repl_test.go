@@ -0,0 +1,90 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func replTestSnapshot() *Snapshot {
+	return &Snapshot{
+		Id:    "test",
+		OrgId: "1",
+		Rows: []*Row{
+			{Name: "organizations/1", Type: "organization", Resource: "1", Member: "group:admins@example.com", Role: "roles/owner"},
+			{Name: "projects/foo", Type: "project", Resource: "foo", Member: "user:alice@example.com", Role: "roles/ownerLite"},
+			{Name: "projects/foo", Type: "project", Resource: "foo", Member: "user:alice@example.com", Role: "roles/viewer"},
+			{Name: "projects/foobar", Type: "project", Resource: "foobar", Member: "user:bob@example.com", Role: "roles/editor",
+				Condition: &Expr{Expression: "request.time < timestamp(\"2027-01-01T00:00:00Z\")"}},
+		},
+		Roles: map[string][]string{
+			"roles/owner":  {"iam.roles.create", "resourcemanager.projects.get"},
+			"roles/viewer": {"resourcemanager.projects.get"},
+			"roles/editor": {"resourcemanager.projects.get"},
+		},
+	}
+}
+
+func TestReplQueries(t *testing.T) {
+	for _, test := range []struct {
+		line    string
+		want    []string
+		notWant []string
+	}{
+		{"member ALICE@", []string{"projects/foo  roles/ownerLite  user:alice@example.com", "2 bindings"}, []string{"bob@"}},
+		{"role roles/owner", []string{"organizations/1", "1 bindings"}, []string{"roles/ownerLite"}},
+		{"role owner", []string{"roles/owner ", "roles/ownerLite", "2 bindings"}, nil},
+		{"resource projects/foo", []string{"roles/viewer", "2 bindings"}, []string{"foobar"}},
+		{"resource //cloudresourcemanager.googleapis.com/projects/foobar", []string{"if request.time", "1 bindings"}, nil},
+		{"permission iam.roles.create", []string{"group:admins@example.com", "1 bindings", "roles/ownerLite"}, []string{"alice@"}},
+		{"summary", []string{"4 bindings, 3 members, 4 roles, 3 resources"}, nil},
+		{"role", []string{"role needs a value"}, nil},
+		{"grant foo", []string{"Unknown command \"grant\""}, nil},
+	} {
+		var out bytes.Buffer
+		if !newReplSession(replTestSnapshot()).Exec(test.line, &out) {
+			t.Errorf("%q ended the session", test.line)
+		}
+		for _, w := range test.want {
+			if !strings.Contains(out.String(), w) {
+				t.Errorf("%q printed %q, want %q in it", test.line, out.String(), w)
+			}
+		}
+		for _, w := range test.notWant {
+			if strings.Contains(out.String(), w) {
+				t.Errorf("%q printed %q, want no %q in it", test.line, out.String(), w)
+			}
+		}
+	}
+}
+
+func TestReplRun(t *testing.T) {
+	var out bytes.Buffer
+	in := strings.NewReader("limit 1\nrole roles/\n\nquit\nmember bob@\n")
+	if err := newReplSession(replTestSnapshot()).Run(in, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "4 bindings, first 1 shown") {
+		t.Errorf("limit wasn't applied: %q", out.String())
+	}
+	if strings.Contains(out.String(), "bob@") {
+		t.Errorf("commands after quit ran: %q", out.String())
+	}
+	if got := strings.Count(out.String(), replPrompt); got != 4 {
+		t.Errorf("prompted %d times, want 4", got)
+	}
+}
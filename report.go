@@ -0,0 +1,107 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type reportFunc func(rows []*Row, resman *resourceManager) ([]string, [][]string, error)
+
+var reports = make(map[string]reportFunc)
+
+// reportJSONFunc writes a report's json next to its csv, for reports whose shape a csv doesn't
+// hold well.
+type reportJSONFunc func(filename string, rows []*Row, resman *resourceManager) error
+
+var reportsJSON = make(map[string]reportJSONFunc)
+
+func registerReport(name string, fn reportFunc) {
+	reports[name] = fn
+}
+
+func registerReportJSON(name string, fn reportJSONFunc) {
+	reportsJSON[name] = fn
+}
+
+func reportNames() []string {
+	names := make([]string, 0, len(reports))
+	for name := range reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseReports validates a comma separated list of report names.
+func parseReports(list string) ([]string, error) {
+	names := make([]string, 0)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := reports[name]; !ok {
+			return nil, errors.New(fmt.Sprintf("Unknown report %s, expected one of %s", name, strings.Join(reportNames(), ", ")))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// writeReports writes each named report to <dir>/<name>.csv, and to <dir>/<name>.json too when
+// it has a json form.
+func writeReports(names []string, dir string, rows []*Row, resman *resourceManager) error {
+	for _, name := range names {
+		header, records, err := reports[name](rows, resman)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error building report %s: %v", name, err))
+		}
+		if err := writeReport(outputPath(dir, name+".csv"), header, records); err != nil {
+			return err
+		}
+		if writeJSON, ok := reportsJSON[name]; ok {
+			if err := writeJSON(outputPath(dir, name+".json"), rows, resman); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeReport writes a small csv report next to the main export.
+func writeReport(filename string, header []string, records [][]string) error {
+	f, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	w := csv.NewWriter(f)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(records); err != nil {
+		return errors.New(fmt.Sprintf("Error writing %s: %v", filename, err))
+	}
+	if err := f.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d rows to %s\n", len(records), filename)
+	return nil
+}
@@ -0,0 +1,219 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type listSourceReposResponse struct {
+	Repos []struct {
+		Name string `json:"name"`
+	} `json:"repos"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+type listArtifactReposResponse struct {
+	Repositories []struct {
+		Name   string `json:"name"`
+		Format string `json:"format"`
+	} `json:"repositories"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// repoPolicy is a repository's policy, added to the rows once every location has been read.
+type repoPolicy struct {
+	name   string
+	policy *Policy
+}
+
+// addSourceRepoPolicies adds the policies of a project's Cloud Source Repositories.
+func (r *resourceManager) addSourceRepoPolicies(projectId string, rows *[]*Row) error {
+	pageToken := ""
+	for {
+		u := fmt.Sprintf("https://sourcerepo.googleapis.com/v1/projects/%s/repos?pageSize=500", url.PathEscape(projectId))
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		resp := &listSourceReposResponse{}
+		if err := r.getJSON(u, resp); err != nil {
+			return err
+		}
+		for _, repo := range resp.Repos {
+			policy, err := r.serviceIamPolicy("sourcerepo", repo.Name)
+			if err != nil {
+				logerr.Printf("Unable to get policy of repository %s: %v\n", repo.Name, err)
+				r.markUnreadable(repoRow(projectId, "source_repo", "//source.googleapis.com/", repo.Name), err)
+				continue
+			}
+			r.addRepoPolicy(projectId, "source_repo", "//source.googleapis.com/", repo.Name, policy, rows)
+		}
+		if resp.NextPageToken == "" {
+			return nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// artifactRepos lists the Artifact Registry repositories of a project in one location.
+func (r *resourceManager) artifactRepos(projectId string, location string) ([]string, error) {
+	names := make([]string, 0)
+	pageToken := ""
+	for {
+		u := fmt.Sprintf("https://artifactregistry.googleapis.com/v1/projects/%s/locations/%s/repositories?pageSize=1000",
+			url.PathEscape(projectId), url.PathEscape(location))
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		resp := &listArtifactReposResponse{}
+		if err := r.getJSON(u, resp); err != nil {
+			return nil, err
+		}
+		for _, repo := range resp.Repositories {
+			names = append(names, repo.Name)
+		}
+		if resp.NextPageToken == "" {
+			return names, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// addArtifactRepoPolicies adds the policies of a project's Artifact Registry repositories,
+// searching every location concurrently like addKmsPolicies.
+func (r *resourceManager) addArtifactRepoPolicies(projectId string, rows *[]*Row) error {
+	var mu sync.Mutex
+	found := make([]*repoPolicy, 0)
+	err := r.ForEachLocation("artifactregistry", projectId, func(location string) error {
+		names, err := r.artifactRepos(projectId, location)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			policy, err := r.serviceIamPolicy("artifactregistry", name)
+			if err != nil {
+				logerr.Printf("Unable to get policy of repository %s: %v\n", name, err)
+				r.markUnreadable(repoRow(projectId, "artifact_repo", "//artifactregistry.googleapis.com/", name), err)
+				continue
+			}
+			mu.Lock()
+			found = append(found, &repoPolicy{name: name, policy: policy})
+			mu.Unlock()
+		}
+		return nil
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].name < found[j].name })
+	for _, repo := range found {
+		r.addRepoPolicy(projectId, "artifact_repo", "//artifactregistry.googleapis.com/", repo.name, repo.policy, rows)
+	}
+	return err
+}
+
+func (r *resourceManager) addRepoPolicy(projectId string, resType string, prefix string, name string, policy *Policy, rows *[]*Row) {
+	r.resourcesScanned++
+	r.addPolicy(policy, rows, repoRow(projectId, resType, prefix, name))
+}
+
+// repoRow describes a repository, name being its relative resource name.
+func repoRow(projectId string, resType string, prefix string, name string) Row {
+	short := name[strings.LastIndex(name, "/")+1:]
+	return Row{
+		Resource:    short,
+		Type:        resType,
+		Parent:      fmt.Sprintf("projects/%s", projectId),
+		Name:        prefix + name,
+		DisplayName: short,
+	}
+}
+
+// addRepoPolicies adds the policies of both kinds of code and artifact repository.
+func (r *resourceManager) addRepoPolicies(projectId string, rows *[]*Row) {
+	if err := r.addSourceRepoPolicies(projectId, rows); err != nil {
+		logerr.Printf("Unable to list source repositories of project %s: %v\n", projectId, err)
+	}
+	if err := r.addArtifactRepoPolicies(projectId, rows); err != nil {
+		logerr.Printf("%v\n", err)
+	}
+}
+
+// repoAccess is how much a role lets a member do with a repository's contents.
+func repoAccess(role string) string {
+	switch {
+	case role == "roles/owner" || role == "roles/editor" || strings.HasSuffix(role, ".admin") ||
+		strings.HasSuffix(role, ".repoAdmin") || strings.HasSuffix(role, ".createOnPushRepoAdmin"):
+		return "admin"
+	case role == "roles/viewer" || strings.HasSuffix(role, ".reader"):
+		return "read"
+	case strings.HasSuffix(role, ".writer") || strings.HasSuffix(role, ".createOnPushWriter"):
+		return "write"
+	}
+	return ""
+}
+
+// repoRole tells whether a role granted on a project or above reaches into its repositories.
+func repoRole(role string) bool {
+	return strings.HasPrefix(role, "roles/source.") || strings.HasPrefix(role, "roles/artifactregistry.") ||
+		role == "roles/owner" || role == "roles/editor" || role == "roles/viewer"
+}
+
+// repoAccessReport lists who can read, write, or administer code and artifacts: the grants on
+// each repository, and the grants on projects that reach every repository in them.
+func repoAccessReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Repository", "Type", "Member", "Role", "Access"}
+	if !resman.repos {
+		logerr.Printf("The repo-access report needs --repos\n")
+		return header, [][]string{}, nil
+	}
+	records := make([][]string, 0)
+	for _, row := range rows {
+		repo := ""
+		switch {
+		case row.Type == "source_repo" || row.Type == "artifact_repo":
+			repo = row.Name
+		case row.Type == "project" && repoRole(row.Role):
+			repo = row.Name + "/*"
+		default:
+			continue
+		}
+		access := repoAccess(row.Role)
+		if access == "" {
+			continue
+		}
+		records = append(records, []string{repo, row.Type, row.Member, row.Role, access})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		for k := range records[i] {
+			if records[i][k] != records[j][k] {
+				return records[i][k] < records[j][k]
+			}
+		}
+		return false
+	})
+	return header, records, nil
+}
+
+func init() {
+	registerReport("repo-access", repoAccessReport)
+	registerCollectorPermissions("repos",
+		"source.repos.list",
+		"source.repos.getIamPolicy",
+		"artifactregistry.locations.list",
+		"artifactregistry.repositories.list",
+		"artifactregistry.repositories.getIamPolicy")
+}
@@ -0,0 +1,41 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestRepoAccess(t *testing.T) {
+	tests := []struct {
+		role string
+		want string
+	}{
+		{"roles/source.reader", "read"},
+		{"roles/source.writer", "write"},
+		{"roles/source.admin", "admin"},
+		{"roles/artifactregistry.reader", "read"},
+		{"roles/artifactregistry.writer", "write"},
+		{"roles/artifactregistry.createOnPushWriter", "write"},
+		{"roles/artifactregistry.repoAdmin", "admin"},
+		{"roles/artifactregistry.createOnPushRepoAdmin", "admin"},
+		{"roles/owner", "admin"},
+		{"roles/viewer", "read"},
+		{"roles/logging.viewer", ""},
+	}
+	for _, tt := range tests {
+		if got := repoAccess(tt.role); got != tt.want {
+			t.Errorf("repoAccess(%q) = %q, want %q", tt.role, got, tt.want)
+		}
+	}
+}
@@ -17,37 +17,121 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/cloudasset/v1"
 	v1beta1 "google.golang.org/api/cloudresourcemanager/v1beta1"
 	v2beta1 "google.golang.org/api/cloudresourcemanager/v2beta1"
 	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 type Row struct {
-	Resource string
-	Type     string
-	Role     string
-	Member   string
+	Resource string `json:"resource"`
+	Type     string `json:"type"`
+	Role     string `json:"role"`
+	Member   string `json:"member"`
+	Parent   string `json:"parent,omitempty"`
+	Risk     int    `json:"risk,omitempty"`
+	// Name is the canonical resource name (organizations/1, folders/2, projects/my-project).
+	Name        string `json:"name,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	// MemberProject is the project a service account member belongs to.
+	MemberProject  string `json:"memberProject,omitempty"`
+	LifecycleState string `json:"lifecycleState,omitempty"`
+	// bindings merged into this row by --dedup
+	Count int `json:"count,omitempty"`
+	// condition of the binding, nil when it has none
+	Condition *Expr `json:"condition,omitempty"`
+	// Origin is where the binding comes from when it isn't an IAM policy, see originBigQueryAcl
+	Origin string `json:"origin,omitempty"`
+	// etag of the policy the binding was read from and its index among the policy's bindings,
+	// see --provenance
+	PolicyEtag   string `json:"policyEtag,omitempty"`
+	BindingIndex int    `json:"bindingIndex,omitempty"`
+	// when the binding was last added and by whom, from audit logs, see --binding-age-days
+	GrantedAt string `json:"grantedAt,omitempty"`
+	GrantedBy string `json:"grantedBy,omitempty"`
+	// conditions of the other bindings merged into this row by --dedup, nil for unconditional ones
+	merged []*Expr
+}
+
+func (r *Row) Key() string {
+	return fmt.Sprintf("%s,%s,%s,%s", r.Resource, r.Type, r.Member, r.Role)
+}
+
+// csvFields joins values into csv columns the way encoding/csv writes them, quoting those
+// holding a comma, quote, or line break.
+func csvFields(values ...string) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write(values)
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
 }
 
 func (r *Row) Print(writer *bufio.Writer, rm *resourceManager) error {
-	var permissions []string
 	var err error
-	permissions, err = rm.GetRolePermissions(r)
-	if err != nil {
-		logerr.Printf("Error getting permissions for %s\n", r.Role)
-		permissions = []string{"UNKNOWN"}
-	}
-	for _, p := range permissions {
-		_, err := fmt.Fprintf(writer, "%s,%s,%s,%s,%s\n", r.Resource, r.Type, r.Member, r.Role, p)
+	for _, p := range rm.outputPermissions(r) {
+		_, err = fmt.Fprintf(writer, "%s,%s,%s,%s,%s,%s,%s,%s,%s,%d,%d,%s", rm.ResourceColumn(r), r.Type, r.Name, r.DisplayName,
+			r.Member, memberClass(r.Member), r.MemberProject, r.Role, p, r.Risk, rm.memberRisk[r.Member], r.LifecycleState)
+		if err == nil && rm.memberStatusColumns() {
+			state, lastActive := rm.MemberStatus(r.Member)
+			_, err = fmt.Fprintf(writer, ",%s,%s", state, lastActive)
+		}
+		if err == nil && !rm.conditionTime.IsZero() {
+			_, err = fmt.Fprintf(writer, ",%s", rm.ConditionActive(r))
+		}
+		if err == nil && rm.sourceColumns() {
+			_, err = fmt.Fprintf(writer, ",%s,%s", rm.runId, rm.Scope())
+		}
+		if err == nil && rm.countColumn {
+			_, err = fmt.Fprintf(writer, ",%d", r.Count)
+		}
+		if err == nil && rm.originColumn() {
+			_, err = fmt.Fprintf(writer, ",%s", RowOrigin(r))
+		}
+		if err == nil && rm.permissionValidColumn() {
+			_, err = fmt.Fprintf(writer, ",%s", rm.PermissionValid(r, p))
+		}
+		if err == nil && rm.serviceEnabledColumn() {
+			_, err = fmt.Fprintf(writer, ",%s", rm.ServiceEnabled(r, p))
+		}
+		if err == nil && rm.perimeterColumn() {
+			_, err = fmt.Fprintf(writer, ",%s", rm.Perimeter(r))
+		}
+		if err == nil && rm.permissionColumns {
+			parts := parsePermission(p)
+			_, err = fmt.Fprintf(writer, ",%s,%s,%s", parts.Service, parts.Resource, parts.Verb)
+		}
+		if err == nil && rm.provenance {
+			_, err = fmt.Fprintf(writer, ",%s,%s", r.PolicyEtag, bindingIndexColumn(r))
+		}
+		if err == nil && rm.bindingAgeDays > 0 {
+			_, err = fmt.Fprintf(writer, ",%s,%s", r.GrantedAt, r.GrantedBy)
+		}
+		if err == nil && rm.identityColumns() {
+			department, manager, status := rm.IdentityColumns(r.Member)
+			// taken as is from the HR export, so they may hold commas, like "Doe, Jane"
+			_, err = fmt.Fprintf(writer, ",%s", csvFields(department, manager, status))
+		}
+		if err == nil {
+			_, err = writer.WriteString("\n")
+		}
 		if err != nil {
 			break
 		}
+		rm.permissionRows++
 	}
 	return err
 }
@@ -59,28 +143,174 @@ type resourceManager struct {
 	orgId   string
 	service *iam.Service
 	roleMap map[string]*iam.Role
+	// role of each binding, keyed by bindingRoleKey
+	bindingRoles map[string]*iam.Role
+	// guards roleMap and bindingRoles, which ResolveRoles fills from several goroutines
+	roleMu sync.Mutex
+	// predefined roles read from --roles-file, see UseRolesFile
+	predefined []*iam.Role
+	// collapses concurrent Roles.Get calls for the same role into one
+	roleCalls  flightGroup
+	etags      map[string]string
+	baseline   *Snapshot
+	unchanged  int
+	changed    int
+	client     *http.Client
+	memberRisk map[string]int
+	// project number to id and back, filled as projects are listed
+	projectIds     map[string]string
+	projectNumbers map[string]string
+	// policies are also written here as json when set
+	rawPolicyDir string
+	// audit configs of every policy collected
+	auditConfigs []*resourceAuditConfig
+	// how the Resource column is written, see resourceNameStyles
+	resourceNameStyle string
+	// rows were de-duplicated and carry a Count column
+	countColumn bool
+	// written with Scope() on every row when set, see --source-columns
+	runId string
+	// conditions are evaluated at this time when set, see --evaluate-conditions-at
+	conditionTime time.Time
+	// look up the state of bound service accounts, see AnnotateServiceAccountStatus
+	serviceAccountStatus bool
+	// member to state and last activity, nil unless a status lookup ran
+	memberStates map[string]*memberStatus
+	// Directory API client when users are looked up, see AnnotateUserStatus
+	directory *http.Client
+	// people from --identities by lowercased email, nil without it
+	identities map[string]*identity
+	// members inactive for longer are listed by the dormant-members report
+	dormantDays int
+	// set instead of orgId when the project being exported has no organization
+	standaloneProject string
+	// also collect policies of resources inside projects, see addResourcePolicies
+	resourcePolicies bool
+	// give every spelling of a member one form, see normalizeMember
+	normalizeMembers bool
+	// weights ScoreRows uses in the risk enricher
+	riskWeights *riskWeights
+	// write one summary per service instead of every permission, see collapsePermissions
+	collapsePermissions bool
+	// split permissions into PermissionService, PermissionResource, and PermissionVerb columns
+	permissionColumns bool
+	// write only the permissions matching --service, --permission-resource, and --verb
+	permissionFilter *permissionFilter
+	// add the PolicyEtag and BindingIndex columns, see --provenance
+	provenance bool
+	// days of audit logs to date bindings from, 0 not to, see --binding-age-days
+	bindingAgeDays int
+	// the org's metadata, looked up once by OrgMetadata
+	orgMeta     *orgMetadata
+	orgMetaOnce sync.Once
+	// reading --input, with no API to ask
+	offline bool
+	// --redact-members mode and --redact-salt, see redactMember
+	redactMode string
+	redactSalt string
+	// locations of regional services, see Locations
+	locations locationCache
+	// Cloud Asset Inventory client, created on first use
+	cai *cloudasset.Service
+	// Compute Engine client, created on first use
+	compute *compute.Service
+	// collect the policies of IAP-protected resources, see addIapPolicies
+	iap bool
+	// collect the policies of Cloud KMS key rings, see addKmsPolicies
+	kms bool
+	// collect the policies of Data Catalog taxonomies and policy tags, see addPolicyTagPolicies
+	policyTags bool
+	// collect the policies of source and artifact repositories, see addRepoPolicies
+	repos bool
+	// translate BigQuery dataset ACLs into rows, see addBigQueryAcls
+	bigQueryAcls bool
+	// translate the ACLs of buckets without uniform bucket-level access into rows, see addBucketAcls
+	bucketAcls   bool
+	bucketAccess []*bucketAccess
+	// resource type to the permissions that can be granted on it, nil unless
+	// --permission-validity asked for them, see PermissionValid
+	testablePermissions map[string]map[string]bool
+	// project to the services enabled in it, nil unless --service-enablement asked for them,
+	// see ServiceEnabled
+	enabledServices map[string]map[string]bool
+	// the org's service perimeters and the regular ones each project is in, nil unless --vpc-sc
+	// asked for them, see Perimeter
+	servicePerimeters []*servicePerimeter
+	projectPerimeters map[string][]string
+	// the org, folders, and projects with their Access Approval settings, nil unless
+	// --access-approval asked for them
+	accessApproval []*accessApprovalResource
+	// user-managed service account keys, nil unless --service-account-keys asked for them, and
+	// the age after which they are stale
+	serviceAccountKeyList []*serviceAccountKey
+	// the permissions the collectors need and whether they are held, nil unless
+	// --check-access asked for them
+	selfAccess []*selfPermission
+	maxKeyAge  time.Duration
+	// resources whose policy couldn't be read, see markUnreadable
+	unreadablePolicies []*unreadablePolicy
+	unreadableMu       sync.Mutex
+	// resource type to the policies read, see Coverage
+	policiesRead map[string]int
+	// break-glass accounts from --config to verify in this org, nil when it lists none
+	breakGlass []*BreakGlassAccount
+	// asset types a collector reads itself, which addResourcePolicies leaves out
+	collectedAssetTypes map[string]bool
+	// collect Shared VPC attachments and subnet policies, see GetSharedVpcRows
+	sharedVpc      bool
+	xpnAttachments []*xpnAttachment
+	// posts policies as they are collected, see --stream-to
+	stream *policyStreamer
+	// totals for --stats-file
+	projectsScanned  int
+	resourcesScanned int
+	foldersScanned   int
+	permissionRows   int
+	// span totals when the export started, see Timings
+	spansAtStart map[string]spanStats
+	// every folder in the org, nested ones included, listed once by FolderTree
+	folderTree []*v2beta1.Folder
+	// parent and display name of the folders and projects crawled, by canonical name, so the
+	// hierarchy is known even above resources that hold no bindings, see Hierarchy
+	hierarchy map[string]hierarchyNode
 }
 
-func NewResourceManager(ctx context.Context, credentialsPath string, orgId string, projectId string) (*resourceManager, error) {
-	v1, err := v1beta1.NewService(ctx)
+type hierarchyNode struct {
+	Parent      string
+	DisplayName string
+}
+
+// newResourceManager uses client for every API call when set, application default credentials otherwise.
+func newResourceManager(ctx context.Context, credentialsPath string, orgId string, projectId string, client *http.Client) (*resourceManager, error) {
+	var clientOptions []option.ClientOption
+	if client != nil {
+		clientOptions = append(clientOptions, option.WithHTTPClient(client))
+	}
+	v1, err := v1beta1.NewService(ctx, clientOptions...)
 	if err != nil {
 		return &resourceManager{}, err
 	}
-	v2, err := v2beta1.NewService(ctx)
+	v2, err := v2beta1.NewService(ctx, clientOptions...)
 	if err != nil {
 		return &resourceManager{}, err
 	}
-	service, err := iam.NewService(ctx)
+	service, err := iam.NewService(ctx, clientOptions...)
 	if err != nil {
 		return &resourceManager{}, err
 	}
 	r := &resourceManager{
-		ctx:     ctx,
-		v1:      v1,
-		v2:      v2,
-		orgId:   orgId,
-		service: service,
-		roleMap: make(map[string]*iam.Role, 0),
+		ctx:            ctx,
+		v1:             v1,
+		v2:             v2,
+		orgId:          orgId,
+		service:        service,
+		roleMap:        make(map[string]*iam.Role, 0),
+		bindingRoles:   make(map[string]*iam.Role),
+		etags:          make(map[string]string, 0),
+		client:         client,
+		projectIds:     make(map[string]string),
+		projectNumbers: make(map[string]string),
+		spansAtStart:   snapshotSpans(),
 	}
 	if r.orgId == "" {
 		fmt.Println("OrgId not specified, checking by ProjectId")
@@ -103,6 +333,16 @@ func NewResourceManager(ctx context.Context, credentialsPath string, orgId strin
 	return r, nil
 }
 
+// Partial responses: only the fields policygopher reads are requested, which matters for
+// list pages and roles on large orgs. Anything not listed here comes back empty.
+const (
+	roleFields             googleapi.Field = "name,stage,deleted,includedPermissions"
+	policyFields           googleapi.Field = "version,etag,bindings,auditConfigs"
+	organizationListFields googleapi.Field = "nextPageToken,organizations(name,displayName,organizationId)"
+	projectListFields      googleapi.Field = "nextPageToken,projects(name,projectId,projectNumber,parent,lifecycleState)"
+	folderListFields       googleapi.Field = "nextPageToken,folders(name,parent,displayName,lifecycleState)"
+)
+
 func (r *resourceManager) GetRolePermissions(row *Row) ([]string, error) {
 	role, err := r.GetRole(row)
 	if err != nil {
@@ -111,7 +351,26 @@ func (r *resourceManager) GetRolePermissions(row *Row) ([]string, error) {
 	return role.IncludedPermissions, nil
 }
 
+// GetRole returns the role of a binding, from the binding cache ResolveRoles fills when it has run.
 func (r *resourceManager) GetRole(row *Row) (*iam.Role, error) {
+	key := bindingRoleKey(row)
+	r.roleMu.Lock()
+	role, ok := r.bindingRoles[key]
+	r.roleMu.Unlock()
+	if ok {
+		return role, nil
+	}
+	role, err := r.lookupRole(row)
+	if err != nil {
+		return role, err
+	}
+	r.roleMu.Lock()
+	r.bindingRoles[key] = role
+	r.roleMu.Unlock()
+	return role, nil
+}
+
+func (r *resourceManager) lookupRole(row *Row) (*iam.Role, error) {
 	var try_uri string
 	var role *iam.Role
 	var err error
@@ -133,18 +392,95 @@ func (r *resourceManager) GetRole(row *Row) (*iam.Role, error) {
 	return role, nil
 }
 
+// SetBaseline seeds the role cache from a previous snapshot, so roles bound only in
+// policies whose etag hasn't changed are not fetched again.
+func (r *resourceManager) SetBaseline(snap *Snapshot) {
+	r.baseline = snap
+	r.SeedRoles(snap)
+}
+
+// SeedRoles reuses the roles saved in snap. Snapshots from before roleStages was saved can't
+// tell deprecated roles apart, so their roles are fetched again.
+func (r *resourceManager) SeedRoles(snap *Snapshot) {
+	if snap.RoleStages == nil {
+		return
+	}
+	r.roleMu.Lock()
+	defer r.roleMu.Unlock()
+	for uri, permissions := range snap.Roles {
+		role := &iam.Role{Name: uri, IncludedPermissions: permissions}
+		switch stage := snap.RoleStages[uri]; stage {
+		case "DELETED":
+			role.Deleted = true
+		case "":
+		default:
+			role.Stage = stage
+		}
+		r.roleMap[uri] = role
+	}
+}
+
+// RoleStages returns the stage of every cached role roleStage flags, always non-nil so that
+// snapshots saved with it are told apart from older ones.
+func (r *resourceManager) RoleStages() map[string]string {
+	r.roleMu.Lock()
+	defer r.roleMu.Unlock()
+	stages := make(map[string]string)
+	for uri, role := range r.roleMap {
+		if stage := roleStage(role); stage != "" {
+			stages[uri] = stage
+		}
+	}
+	return stages
+}
+
+func (r *resourceManager) RolePermissionsCache() map[string][]string {
+	r.roleMu.Lock()
+	defer r.roleMu.Unlock()
+	roles := make(map[string][]string, len(r.roleMap))
+	for uri, role := range r.roleMap {
+		roles[uri] = role.IncludedPermissions
+	}
+	return roles
+}
+
+func (r *resourceManager) forgetRoles(bindings []*Binding, resource string, resType string) {
+	r.roleMu.Lock()
+	defer r.roleMu.Unlock()
+	for _, b := range bindings {
+		delete(r.roleMap, b.Role)
+		delete(r.roleMap, fmt.Sprintf("%ss/%s/%s", resType, resource, b.Role))
+	}
+}
+
 func (r *resourceManager) _getRoleByUri(uri string) (*iam.Role, error) {
-	var role *iam.Role
-	var err error
-	if role, ok := r.roleMap[uri]; ok {
+	r.roleMu.Lock()
+	role, ok := r.roleMap[uri]
+	r.roleMu.Unlock()
+	if ok {
 		return role, nil
 	}
-	role, err = r.service.Roles.Get(uri).Do()
+	val, err := r.roleCalls.Do(uri, func() (interface{}, error) {
+		// a call that just finished may have cached it after the check above
+		r.roleMu.Lock()
+		role, ok := r.roleMap[uri]
+		r.roleMu.Unlock()
+		if ok {
+			return role, nil
+		}
+		role, err := r.service.Roles.Get(uri).Fields(roleFields).Do()
+		if err != nil {
+			return nil, classifyError(uri, err)
+		}
+		r.roleMu.Lock()
+		r.roleMap[uri] = role
+		r.roleMu.Unlock()
+		return role, nil
+	})
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("uri[%s]: %v", uri, err))
+		return nil, err
 	}
-	r.roleMap[uri] = role
-	return role, err
+	return val.(*iam.Role), nil
 }
 
 func (r *resourceManager) getProjectIdFromCredentials(credentialsPath string) (string, error) {
@@ -154,7 +490,11 @@ func (r *resourceManager) getProjectIdFromCredentials(credentialsPath string) (s
 		return credentials.ProjectID, nil
 	}
 	if credentialsPath == "" {
-		return "", errors.New("unable to get application default credentials, please specify credentials json")
+		if config := activeGcloudConfig(); config != nil && config.Project != "" {
+			fmt.Printf("Project ID found from gcloud configuration %s: %s\n", config.Name, config.Project)
+			return config.Project, nil
+		}
+		return "", errors.New("unable to get application default credentials, please specify credentials json or run gcloud config set project")
 	}
 	_, err = os.Stat(credentialsPath)
 	if err != nil {
@@ -180,13 +520,35 @@ func (r *resourceManager) GetOrgIdFromProjectId(projectId string) error {
 	if err != nil {
 		return errors.New(fmt.Sprintf("Unable to get org for project %s: %v", projectId, err))
 	}
-	r.orgId = thisProjectAncestry[len(thisProjectAncestry)-1].ResourceId.Id
-	fmt.Printf("OrgId of %s found from Project ID %s\n", r.orgId, projectId)
+	for _, a := range thisProjectAncestry {
+		if a.ResourceId.Type == "organization" {
+			r.orgId = a.ResourceId.Id
+			fmt.Printf("OrgId of %s found from Project ID %s\n", r.orgId, projectId)
+			return nil
+		}
+	}
+	fmt.Printf("Project %s has no organization, exporting just the project\n", projectId)
+	r.standaloneProject = projectId
 	return nil
 }
 
+// Scope is the org id, or project-<id> for a project without an org; snapshots are kept per scope.
+func (r *resourceManager) Scope() string {
+	if r.standaloneProject != "" {
+		return "project-" + r.standaloneProject
+	}
+	return r.orgId
+}
+
+func (r *resourceManager) GetOrgDisplayName() string {
+	if meta := r.OrgMetadata(); meta != nil {
+		return meta.DisplayName
+	}
+	return r.orgId
+}
+
 func (r *resourceManager) OrganizationsList() ([]*v1beta1.Organization, error) {
-	orgListReq := r.v1.Organizations.List()
+	orgListReq := r.v1.Organizations.List().Fields(organizationListFields)
 	orgs := make([]*v1beta1.Organization, 0)
 	if err := orgListReq.Pages(r.ctx, func(page *v1beta1.ListOrganizationsResponse) error {
 		for _, org := range page.Organizations {
@@ -200,28 +562,55 @@ func (r *resourceManager) OrganizationsList() ([]*v1beta1.Organization, error) {
 }
 
 type Project struct {
-	Name      string
-	ProjectId string
+	Name           string
+	ProjectId      string
+	Parent         string
+	LifecycleState string
 }
 
+// ProjectsList lists the projects in the org, directly or in any folder of FolderTree, with one
+// list call per parent since the filter can't ask for a whole subtree.
 func (r *resourceManager) ProjectsList() ([]*Project, error) {
-	return r.ProjectsListByFilter(fmt.Sprintf("parent.type:organization parent.id:%s", r.orgId))
+	folders, err := r.FolderTree()
+	if err != nil {
+		return []*Project{}, err
+	}
+	filters := []string{fmt.Sprintf("parent.type:organization parent.id:%s", r.orgId)}
+	for _, f := range folders {
+		if f.LifecycleState != "DELETE_REQUESTED" {
+			filters = append(filters, fmt.Sprintf("parent.type:folder parent.id:%s", resourceNumber(f.Name)))
+		}
+	}
+	projects := make([]*Project, 0)
+	for _, filter := range filters {
+		found, err := r.ProjectsListByFilter(filter)
+		if err != nil {
+			return projects, err
+		}
+		projects = append(projects, found...)
+	}
+	return projects, nil
 }
 
 func (r *resourceManager) ProjectsListByFilter(filter string) ([]*Project, error) {
 	projects := make([]*Project, 0)
-	pListReq := r.v1.Projects.List()
+	pListReq := r.v1.Projects.List().Fields(projectListFields)
 	if filter != "" {
 		pListReq.Filter(filter)
 	}
 	if err := pListReq.Pages(r.ctx, func(page *v1beta1.ListProjectsResponse) error {
 		for _, p := range page.Projects {
-			projects = append(projects,
-				&Project{
-					Name:      p.Name,
-					ProjectId: p.ProjectId,
-				},
-			)
+			r.recordProject(p.ProjectId, p.ProjectNumber)
+			project := &Project{
+				Name:           p.Name,
+				ProjectId:      p.ProjectId,
+				LifecycleState: p.LifecycleState,
+			}
+			if p.Parent != nil {
+				project.Parent = fmt.Sprintf("%ss/%s", p.Parent.Type, p.Parent.Id)
+			}
+			r.recordHierarchy("projects/"+p.ProjectId, project.Parent, p.Name)
+			projects = append(projects, project)
 		}
 		return nil
 	}); err != nil {
@@ -232,7 +621,7 @@ func (r *resourceManager) ProjectsListByFilter(filter string) ([]*Project, error
 
 func (r *resourceManager) FoldersList(parent string) ([]*v2beta1.Folder, error) {
 	folders := make([]*v2beta1.Folder, 0)
-	fListReq := r.v2.Folders.List()
+	fListReq := r.v2.Folders.List().Fields(folderListFields)
 	if parent != "" {
 		fListReq.Parent(parent)
 	}
@@ -247,6 +636,54 @@ func (r *resourceManager) FoldersList(parent string) ([]*v2beta1.Folder, error)
 	return folders, nil
 }
 
+// FolderTree lists every folder in the org, walking down from the org one level at a time, so
+// parents come before their children. Folders pending deletion are listed but not walked into,
+// as a folder can only be deleted once empty. The tree is listed once and kept for the run,
+// since the folder and project collectors both need it. A folder whose children can't be
+// listed is logged and its subtree left out.
+func (r *resourceManager) FolderTree() ([]*v2beta1.Folder, error) {
+	if r.folderTree != nil {
+		return r.folderTree, nil
+	}
+	tree := make([]*v2beta1.Folder, 0)
+	parents := []string{fmt.Sprintf("organizations/%s", r.orgId)}
+	for i := 0; i < len(parents); i++ {
+		folders, err := r.FoldersList(parents[i])
+		if err != nil {
+			if i > 0 && errors.Is(classifyError(parents[i], err), ErrPermissionDenied) {
+				logerr.Printf("Unable to list the folders in %s: %v\n", parents[i], err)
+				continue
+			}
+			return tree, err
+		}
+		for _, f := range folders {
+			tree = append(tree, f)
+			r.recordHierarchy(f.Name, f.Parent, f.DisplayName)
+			if f.LifecycleState != "DELETE_REQUESTED" {
+				parents = append(parents, f.Name)
+			}
+		}
+	}
+	r.folderTree = tree
+	return tree, nil
+}
+
+func (r *resourceManager) recordHierarchy(name string, parent string, displayName string) {
+	if r.hierarchy == nil {
+		r.hierarchy = make(map[string]hierarchyNode)
+	}
+	r.hierarchy[name] = hierarchyNode{Parent: parent, DisplayName: displayName}
+}
+
+// Hierarchy returns the parent and display name of the folders and projects crawled, by
+// canonical name. It's nil for a resourceManager that crawled nothing, like one reading --input.
+func (r *resourceManager) Hierarchy() map[string]hierarchyNode {
+	if r == nil {
+		return nil
+	}
+	return r.hierarchy
+}
+
 type Ancestor struct {
 	ResourceId *ResourceId `json:"resourceId,omitempty"`
 }
@@ -277,17 +714,25 @@ func convertAncestors(ancestors interface{}) []*Ancestor {
 }
 
 type Policy struct {
-	Bindings []*Binding `json:"bindings,omitempty"`
-	Etag     string     `json:"etag,omitempty"`
+	Bindings     []*Binding     `json:"bindings,omitempty"`
+	Etag         string         `json:"etag,omitempty"`
+	AuditConfigs []*AuditConfig `json:"auditConfigs,omitempty"`
+
+	// raw is the policy as the API returned it
+	raw interface{}
 }
 
 func (p *Policy) convertV1(policy *v1beta1.Policy) {
+	p.raw = policy
 	p.Etag = policy.Etag
+	p.AuditConfigs = convertAuditConfigsV1(policy.AuditConfigs)
 	p.convertBindingsV1(policy.Bindings)
 }
 
 func (p *Policy) convertV2(policy *v2beta1.Policy) {
+	p.raw = policy
 	p.Etag = policy.Etag
+	p.AuditConfigs = convertAuditConfigsV2(policy.AuditConfigs)
 	p.convertBindingsV2(policy.Bindings)
 }
 
@@ -315,7 +760,7 @@ type Binding struct {
 func (b *Binding) convertV1(binding *v1beta1.Binding) {
 	b.Members = binding.Members
 	b.Role = binding.Role
-	if b.Condition != nil {
+	if binding.Condition != nil {
 		b.Condition = &Expr{}
 		b.Condition.convertV1(binding.Condition)
 	}
@@ -323,7 +768,7 @@ func (b *Binding) convertV1(binding *v1beta1.Binding) {
 func (b *Binding) convertV2(binding *v2beta1.Binding) {
 	b.Members = binding.Members
 	b.Role = binding.Role
-	if b.Condition != nil {
+	if binding.Condition != nil {
 		b.Condition = &Expr{}
 		b.Condition.convertV2(binding.Condition)
 	}
@@ -349,13 +794,20 @@ func (e *Expr) convertV2(expr *v2beta1.Expr) {
 	e.Title = expr.Title
 }
 
+// getIamPolicyV3 calls getIamPolicy on resource, a URL without the method, asking for policy
+// version 3: the generated clients of google.golang.org/api v0.3.2 can't set
+// requestedPolicyVersion, and at version 1 conditional bindings come back without their conditions.
+func (r *resourceManager) getIamPolicyV3(resource string, v interface{}) error {
+	body := map[string]interface{}{"options": map[string]int{"requestedPolicyVersion": 3}}
+	return r.postJSON(fmt.Sprintf("%s:getIamPolicy?fields=%s", resource, policyFields), body, v)
+}
+
 func (r *resourceManager) GetIamPolicyForProject(projectId string) (*Policy, error) {
 
 	policy := &Policy{}
-	gpcall := r.v1.Projects.GetIamPolicy(fmt.Sprintf("%s", projectId), &v1beta1.GetIamPolicyRequest{})
-	policyResponse, err := gpcall.Context(r.ctx).Do()
-	if err != nil {
-		return policy, err
+	policyResponse := &v1beta1.Policy{}
+	if err := r.getIamPolicyV3(r.v1.BasePath+"v1beta1/projects/"+projectId, policyResponse); err != nil {
+		return policy, classifyError("projects/"+projectId, err)
 	}
 	policy.convertV1(policyResponse)
 	return policy, nil
@@ -363,10 +815,9 @@ func (r *resourceManager) GetIamPolicyForProject(projectId string) (*Policy, err
 
 func (r *resourceManager) GetIamPolicyForOrganization() (*Policy, error) {
 	policy := &Policy{}
-	gpcall := r.v1.Organizations.GetIamPolicy(fmt.Sprintf("organizations/%s", r.orgId), &v1beta1.GetIamPolicyRequest{})
-	policyResponse, err := gpcall.Context(r.ctx).Do()
-	if err != nil {
-		return policy, err
+	policyResponse := &v1beta1.Policy{}
+	if err := r.getIamPolicyV3(fmt.Sprintf("%sv1beta1/organizations/%s", r.v1.BasePath, r.orgId), policyResponse); err != nil {
+		return policy, classifyError(fmt.Sprintf("organizations/%s", r.orgId), err)
 	}
 	policy.convertV1(policyResponse)
 	return policy, nil
@@ -375,48 +826,109 @@ func (r *resourceManager) GetIamPolicyForOrganization() (*Policy, error) {
 func (r *resourceManager) GetIamPolicyForFolder(folderId string) (*Policy, error) {
 
 	policy := &Policy{}
-	gpcall := r.v2.Folders.GetIamPolicy(fmt.Sprintf("%s", folderId), &v2beta1.GetIamPolicyRequest{})
-	policyResponse, err := gpcall.Context(r.ctx).Do()
-	if err != nil {
-		return policy, err
+	policyResponse := &v2beta1.Policy{}
+	if err := r.getIamPolicyV3(r.v2.BasePath+"v2beta1/"+folderId, policyResponse); err != nil {
+		return policy, classifyError(folderId, err)
 	}
 	policy.convertV2(policyResponse)
 	return policy, nil
 }
 
-func addBindings(bindings []*Binding, rows *[]*Row, resource string, resType string) {
-	for _, b := range bindings {
+// addBindings appends a copy of base for every member of every binding.
+func addBindings(bindings []*Binding, rows *[]*Row, base Row) {
+	for i, b := range bindings {
 		for _, m := range b.Members {
-			row := &Row{
-				Resource: resource,
-				Type:     resType,
-				Role:     b.Role,
-				Member:   m,
+			row := base
+			row.Role = b.Role
+			row.Member = m
+			row.Condition = b.Condition
+			row.BindingIndex = i
+			*rows = append(*rows, &row)
+			if m == "allUsers" || m == "allAuthenticatedUsers" {
+				tui.Finding("%s holds %s on %s %s", m, b.Role, base.Type, base.Name)
 			}
-			*rows = append(*rows, row)
+		}
+	}
+}
+
+func (r *resourceManager) addPolicy(policy *Policy, rows *[]*Row, base Row) {
+	policy = r.completePolicy(base.Name, policy)
+	if r.baseline != nil {
+		if etag, ok := r.baseline.Etags[base.Name]; ok && etag == policy.Etag {
+			r.unchanged++
+		} else {
+			r.changed++
+			r.forgetRoles(policy.Bindings, base.Resource, base.Type)
+		}
+	}
+	r.etags[base.Name] = policy.Etag
+	if r.policiesRead == nil {
+		r.policiesRead = make(map[string]int)
+	}
+	r.policiesRead[base.Type]++
+	if r.stream != nil {
+		r.stream.Add(policy, base)
+	}
+	if err := r.writeRawPolicy(base.Name, policy); err != nil {
+		logerr.Printf("%v\n", err)
+	}
+	r.addAuditConfigs(policy.AuditConfigs, base)
+	base.PolicyEtag = policy.Etag
+	start := len(*rows)
+	addBindings(policy.Bindings, rows, base)
+	if r.normalizeMembers {
+		for _, row := range (*rows)[start:] {
+			row.Member = normalizeMember(row.Member)
 		}
 	}
 }
 
 func (r *resourceManager) GetFolderPolicyRows() (*[]*Row, error) {
+	defer beginSpan("collector", "folders")()
 	var rows []*Row
 	rows = make([]*Row, 0)
-	folders, err := r.FoldersList(fmt.Sprintf("organizations/%s", r.orgId))
+	folders, err := r.FolderTree()
 	if err != nil {
 		return &rows, err
 	}
+	tui.Start("folders", len(folders))
 	for _, f := range folders {
+		tui.Step("folders")
+		// folders pending deletion deny getIamPolicy and have no effective policy left
+		if f.LifecycleState == "DELETE_REQUESTED" {
+			fmt.Printf("Skipping folder %s (%s), pending deletion\n", f.Name, f.DisplayName)
+			continue
+		}
+		r.foldersScanned++
+		base := Row{
+			Resource:       f.Name,
+			Type:           "folder",
+			Parent:         f.Parent,
+			Name:           f.Name,
+			DisplayName:    f.DisplayName,
+			LifecycleState: f.LifecycleState,
+		}
 		policy, err := r.GetIamPolicyForFolder(f.Name)
+		if errors.Is(err, ErrNotFound) {
+			fmt.Printf("Skipping folder %s (%s), deleted since it was listed\n", f.Name, f.DisplayName)
+			continue
+		}
+		if errors.Is(err, ErrPermissionDenied) {
+			logerr.Printf("Unable to get policy of folder %s: %v\n", f.Name, err)
+			r.markUnreadable(base, err)
+			continue
+		}
 		if err != nil {
 			logerr.Printf("Unable to get more info on folder %s: %v\n", f.Name, err)
 			return &rows, err
 		}
-		addBindings(policy.Bindings, &rows, f.Name, "folder")
+		r.addPolicy(policy, &rows, base)
 	}
 	return &rows, nil
 }
 
 func (r *resourceManager) GetProjectPolicyRows() (*[]*Row, error) {
+	defer beginSpan("collector", "projects")()
 	var rows []*Row
 	rows = make([]*Row, 0)
 
@@ -424,48 +936,161 @@ func (r *resourceManager) GetProjectPolicyRows() (*[]*Row, error) {
 	if err != nil {
 		return &rows, err
 	}
+	r.projectsScanned += len(projects)
+	tui.Start("projects", len(projects))
 	for _, p := range projects {
+		tui.Step("projects")
+		base := Row{
+			Resource:       p.Name,
+			Type:           "project",
+			Parent:         p.Parent,
+			Name:           fmt.Sprintf("projects/%s", p.ProjectId),
+			DisplayName:    p.Name,
+			LifecycleState: p.LifecycleState,
+		}
 		policy, err := r.GetIamPolicyForProject(p.ProjectId)
-		if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			fmt.Printf("Skipping project %s, deleted since it was listed\n", p.ProjectId)
+			continue
+		}
+		if errors.Is(err, ErrPermissionDenied) {
+			// the policies of resources inside it may still be readable
+			logerr.Printf("Unable to get policy of project %s: %v\n", p.ProjectId, err)
+			r.markUnreadable(base, err)
+		} else if err != nil {
 			logerr.Printf("Unable to get more info on project %s: %v\n", p.Name, err)
 			return &rows, err
+		} else {
+			r.addPolicy(policy, &rows, base)
 		}
-		addBindings(policy.Bindings, &rows, p.Name, "project")
+		r.addProjectResources(p.ProjectId, &rows)
 	}
 	return &rows, nil
 }
 
 func (r *resourceManager) GetOrgPolicyRows() (*[]*Row, error) {
+	defer beginSpan("collector", "organization")()
 	var rows []*Row
 	rows = make([]*Row, 0)
 
+	tui.Start("organization", 1)
 	orgPolicy, err := r.GetIamPolicyForOrganization()
 	if err != nil {
 		return &rows, err
 	}
-	addBindings(orgPolicy.Bindings, &rows, r.orgId, "organization")
+	tui.Step("organization")
+	r.addPolicy(orgPolicy, &rows, Row{
+		Resource:    r.orgId,
+		Type:        "organization",
+		Name:        fmt.Sprintf("organizations/%s", r.orgId),
+		DisplayName: r.GetOrgDisplayName(),
+	})
 
 	return &rows, nil
 }
 
-func (r *resourceManager) GetAllPolicyRows() (*[]*Row, error) {
-	allRows := make([]*Row, 0)
-	newRows, err := r.GetOrgPolicyRows()
+// GetStandaloneProjectPolicyRows collects the policy of a project that has no organization.
+func (r *resourceManager) GetStandaloneProjectPolicyRows() (*[]*Row, error) {
+	defer beginSpan("collector", "project")()
+	rows := make([]*Row, 0)
+	p, err := r.v1.Projects.Get(r.standaloneProject).Fields("name,projectId,projectNumber,lifecycleState").Context(r.ctx).Do()
 	if err != nil {
-		return nil, err
+		return &rows, err
 	}
-	allRows = append(allRows, *newRows...)
-
-	newRows, err = r.GetFolderPolicyRows()
+	r.recordProject(p.ProjectId, p.ProjectNumber)
+	r.projectsScanned++
+	policy, err := r.GetIamPolicyForProject(p.ProjectId)
 	if err != nil {
-		return nil, err
+		return &rows, err
 	}
-	allRows = append(allRows, *newRows...)
+	r.addPolicy(policy, &rows, Row{
+		Resource:       p.Name,
+		Type:           "project",
+		Name:           fmt.Sprintf("projects/%s", p.ProjectId),
+		DisplayName:    p.Name,
+		LifecycleState: p.LifecycleState,
+	})
+	r.addProjectResources(p.ProjectId, &rows)
+	return &rows, nil
+}
 
-	newRows, err = r.GetProjectPolicyRows()
-	if err != nil {
-		return nil, err
+// addProjectResources adds the policies of resources inside a project that the options ask for.
+func (r *resourceManager) addProjectResources(projectId string, rows *[]*Row) {
+	if r.resourcePolicies {
+		end := beginSpan("collector", "resource-policies")
+		if err := r.addResourcePolicies(projectId, rows); err != nil {
+			logerr.Printf("Unable to search resource policies of project %s: %v\n", projectId, err)
+		}
+		end()
+	}
+	if r.iap {
+		end := beginSpan("collector", "iap")
+		r.addIapPolicies(projectId, rows)
+		end()
+	}
+	if r.kms {
+		end := beginSpan("collector", "kms")
+		if err := r.addKmsPolicies(projectId, rows); err != nil {
+			logerr.Printf("%v\n", err)
+		}
+		end()
+	}
+	if r.policyTags {
+		end := beginSpan("collector", "policy-tags")
+		if err := r.addPolicyTagPolicies(projectId, rows); err != nil {
+			logerr.Printf("%v\n", err)
+		}
+		end()
+	}
+	if r.repos {
+		end := beginSpan("collector", "repos")
+		r.addRepoPolicies(projectId, rows)
+		end()
+	}
+	if r.bigQueryAcls {
+		end := beginSpan("collector", "bigquery-acls")
+		if err := r.addBigQueryAcls(projectId, rows); err != nil {
+			logerr.Printf("Unable to list BigQuery datasets of project %s: %v\n", projectId, err)
+		}
+		end()
+	}
+	if r.bucketAcls {
+		end := beginSpan("collector", "bucket-acls")
+		if err := r.addBucketAcls(projectId, rows); err != nil {
+			logerr.Printf("Unable to list buckets of project %s: %v\n", projectId, err)
+		}
+		end()
+	}
+}
+
+func (r *resourceManager) GetAllPolicyRows() (*[]*Row, error) {
+	allRows := make([]*Row, 0)
+	collectors := []func() (*[]*Row, error){r.GetOrgPolicyRows, r.GetFolderPolicyRows, r.GetProjectPolicyRows}
+	if r.sharedVpc {
+		collectors = append(collectors, r.GetSharedVpcRows)
+	}
+	if r.standaloneProject != "" {
+		collectors = []func() (*[]*Row, error){r.GetStandaloneProjectPolicyRows}
+	}
+	for _, collect := range collectors {
+		newRows, err := collect()
+		if err != nil {
+			return nil, err
+		}
+		allRows = append(allRows, *newRows...)
+	}
+	if r.baseline != nil {
+		fmt.Printf("%d policies unchanged since snapshot %s, %d changed or new\n", r.unchanged, r.baseline.Id, r.changed)
+	}
+	if len(r.unreadablePolicies) > 0 {
+		coverage := r.Coverage()
+		total := coverage[len(coverage)-1]
+		fmt.Printf("Read the policies of %d of %d resources (%.1f%%), the unreadable-policies report lists the others\n",
+			total.Read, total.Discovered, total.Percent())
+	}
+	if r.stream != nil {
+		r.stream.Close()
+		r.stream = nil
 	}
-	allRows = append(allRows, *newRows...)
 	return &allRows, nil
 }
@@ -0,0 +1,116 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeCrmOrg is an org of nested folders and projects served by newFakeCrm: org 1 with a policy,
+// folders/2 (Prod) under it with a policy, folders/3 (Web) under Prod with an empty policy,
+// folders/4 pending deletion, project web in Web, and project top directly in the org.
+var fakeCrmOrg = map[string]string{
+	"GET /v1beta1/organizations/1": `{"name": "organizations/1", "displayName": "example.com"}`,
+	"POST /v1beta1/organizations/1:getIamPolicy": `{"etag": "o", "bindings": [
+		{"role": "roles/owner", "members": ["group:admins@example.com"]}]}`,
+	"GET /v2/folders?parent=organizations/1": `{"folders": [
+		{"name": "folders/2", "parent": "organizations/1", "displayName": "Prod", "lifecycleState": "ACTIVE"},
+		{"name": "folders/4", "parent": "organizations/1", "displayName": "Old", "lifecycleState": "DELETE_REQUESTED"}]}`,
+	"GET /v2/folders?parent=folders/2": `{"folders": [
+		{"name": "folders/3", "parent": "folders/2", "displayName": "Web", "lifecycleState": "ACTIVE"}]}`,
+	"GET /v2/folders?parent=folders/3": `{}`,
+	"POST /v2beta1/folders/2:getIamPolicy": `{"etag": "f2", "bindings": [
+		{"role": "roles/viewer", "members": ["group:prod@example.com"]}]}`,
+	"POST /v2beta1/folders/3:getIamPolicy": `{"etag": "f3"}`,
+	"GET /v1beta1/projects?filter=parent.type:organization parent.id:1": `{"projects": [
+		{"name": "Top", "projectId": "top", "projectNumber": "10", "parent": {"type": "organization", "id": "1"}, "lifecycleState": "ACTIVE"}]}`,
+	"GET /v1beta1/projects?filter=parent.type:folder parent.id:2": `{}`,
+	"GET /v1beta1/projects?filter=parent.type:folder parent.id:3": `{"projects": [
+		{"name": "Web", "projectId": "web", "projectNumber": "11", "parent": {"type": "folder", "id": "3"}, "lifecycleState": "ACTIVE"}]}`,
+	"POST /v1beta1/projects/top:getIamPolicy": `{"etag": "pt", "bindings": [
+		{"role": "roles/editor", "members": ["user:d@example.com"]}]}`,
+	"POST /v1beta1/projects/web:getIamPolicy": `{"etag": "pw", "bindings": [
+		{"role": "roles/viewer", "members": ["user:b@example.com"]},
+		{"role": "roles/owner", "members": ["user:e@example.com"]}]}`,
+}
+
+// newFakeCrm returns a resourceManager for org 1 whose Resource Manager calls are answered from
+// responses, failing the test on any other call.
+func newFakeCrm(t *testing.T, responses map[string]string) *resourceManager {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := req.Method + " " + req.URL.Path
+		if parent := req.URL.Query().Get("parent"); parent != "" {
+			key += "?parent=" + parent
+		}
+		if filter := req.URL.Query().Get("filter"); filter != "" {
+			key += "?filter=" + filter
+		}
+		body, ok := responses[key]
+		if !ok {
+			t.Errorf("unexpected call %s", key)
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	resman, err := newResourceManager(context.Background(), "", "1", "", server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resman.v1.BasePath = server.URL + "/"
+	resman.v2.BasePath = server.URL + "/"
+	return resman
+}
+
+func TestGetAllPolicyRowsCrawlsNestedFolders(t *testing.T) {
+	resman := newFakeCrm(t, fakeCrmOrg)
+	rows, err := resman.GetAllPolicyRows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(*rows))
+	for _, row := range *rows {
+		got = append(got, fmt.Sprintf("%s<%s %s %s", row.Name, row.Parent, row.Role, row.Member))
+	}
+	sort.Strings(got)
+	want := []string{
+		"folders/2<organizations/1 roles/viewer group:prod@example.com",
+		"organizations/1< roles/owner group:admins@example.com",
+		"projects/top<organizations/1 roles/editor user:d@example.com",
+		"projects/web<folders/3 roles/owner user:e@example.com",
+		"projects/web<folders/3 roles/viewer user:b@example.com",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("rows =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if resman.foldersScanned != 2 || resman.projectsScanned != 2 {
+		t.Errorf("scanned %d folders and %d projects, want 2 and 2", resman.foldersScanned, resman.projectsScanned)
+	}
+	hierarchy := resman.Hierarchy()
+	if node := hierarchy["folders/3"]; node.Parent != "folders/2" || node.DisplayName != "Web" {
+		t.Errorf("hierarchy of folders/3 = %+v", node)
+	}
+	if node := hierarchy["projects/web"]; node.Parent != "folders/3" {
+		t.Errorf("hierarchy of projects/web = %+v", node)
+	}
+}
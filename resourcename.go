@@ -0,0 +1,58 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const fullResourceNamePrefix = "//cloudresourcemanager.googleapis.com/"
+
+// resourceNameStyles are the ways the Resource column can be written:
+// legacy keeps the org id, folder name and project display name policygopher always wrote,
+// canonical uses CRM names (projects/my-project), and full uses the full resource names
+// Cloud Asset Inventory exports are keyed by (//cloudresourcemanager.googleapis.com/projects/my-project).
+var resourceNameStyles = []string{"legacy", "canonical", "full"}
+
+func (r *resourceManager) SetResourceNameStyle(style string) error {
+	for _, s := range resourceNameStyles {
+		if s == style {
+			r.resourceNameStyle = style
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("Unknown --resource-name-style %s, expected one of %v", style, resourceNameStyles))
+}
+
+// ResourceColumn is the value written to the Resource column of a row.
+func (r *resourceManager) ResourceColumn(row *Row) string {
+	if row.Name == "" {
+		return row.Resource
+	}
+	if strings.HasPrefix(row.Name, "//") {
+		// resources inside projects only have a full resource name, and never had a legacy
+		// one: their short name alone (a subnetwork or key ring name) is ambiguous across projects
+		return row.Name
+	}
+	switch r.resourceNameStyle {
+	case "canonical":
+		return row.Name
+	case "full":
+		return fullResourceNamePrefix + row.Name
+	}
+	return row.Resource
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strconv"
+)
+
+type resourcePrivilege struct {
+	row          *Row
+	members      map[string]bool
+	adminMembers map[string]bool
+	permissions  map[string]bool
+}
+
+// overprivilegedResourcesReport ranks the --top projects and folders by how many members
+// hold admin-level roles on them, then by how many distinct permissions their policy grants,
+// to show where IAM cleanup pays off first.
+func overprivilegedResourcesReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	resources := make(map[string]*resourcePrivilege)
+	for _, row := range rows {
+		if row.Type != "project" && row.Type != "folder" {
+			continue
+		}
+		key := row.Type + "/" + row.Resource
+		res, ok := resources[key]
+		if !ok {
+			res = &resourcePrivilege{
+				row:          row,
+				members:      make(map[string]bool),
+				adminMembers: make(map[string]bool),
+				permissions:  make(map[string]bool),
+			}
+			resources[key] = res
+		}
+		res.members[row.Member] = true
+		if isHighRiskRole(row.Role) {
+			res.adminMembers[row.Member] = true
+		}
+		permissions, err := resman.GetRolePermissions(row)
+		if err != nil {
+			continue
+		}
+		for _, p := range permissions {
+			res.permissions[p] = true
+		}
+	}
+	ranked := make([]*resourcePrivilege, 0, len(resources))
+	for _, res := range resources {
+		ranked = append(ranked, res)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if len(a.adminMembers) != len(b.adminMembers) {
+			return len(a.adminMembers) > len(b.adminMembers)
+		}
+		if len(a.permissions) != len(b.permissions) {
+			return len(a.permissions) > len(b.permissions)
+		}
+		return a.row.Key() < b.row.Key()
+	})
+	if len(ranked) > topN {
+		ranked = ranked[:topN]
+	}
+	records := make([][]string, len(ranked))
+	for i, res := range ranked {
+		records[i] = []string{
+			strconv.Itoa(i + 1), resman.ResourceColumn(res.row), res.row.Type, res.row.Name, res.row.DisplayName,
+			strconv.Itoa(len(res.adminMembers)), strconv.Itoa(len(res.permissions)), strconv.Itoa(len(res.members)),
+		}
+	}
+	return []string{"Rank", "Resource", "Type", "ResourceName", "DisplayName", "AdminMembers", "UniquePermissions", "Members"},
+		records, nil
+}
+
+func init() {
+	registerReport("overprivileged-resources", overprivilegedResourcesReport)
+}
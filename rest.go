@@ -0,0 +1,94 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2/google"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// getJSON calls REST endpoints that google.golang.org/api v0.3.2 has no (or an outdated) client for.
+func (r *resourceManager) getJSON(url string, v interface{}) error {
+	if r.client == nil {
+		client, err := google.DefaultClient(r.ctx, cloudPlatformScope)
+		if err != nil {
+			return err
+		}
+		r.client = client
+	}
+	return getJSONWithClient(r.client, url, v)
+}
+
+// postJSON is getJSON for methods called with POST, such as getIamPolicy.
+func (r *resourceManager) postJSON(url string, body interface{}, v interface{}) error {
+	if r.client == nil {
+		client, err := google.DefaultClient(r.ctx, cloudPlatformScope)
+		if err != nil {
+			return err
+		}
+		r.client = client
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	return decodeJSONResponse("POST", url, resp, v)
+}
+
+// httpError is a response other than 200 OK from one of the REST helpers.
+type httpError struct {
+	Method string
+	URL    string
+	Code   int
+	Status string
+	Body   string
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("%s %s: %s: %s", e.Method, e.URL, e.Status, e.Body)
+}
+
+func getJSONWithClient(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	return decodeJSONResponse("GET", url, resp, v)
+}
+
+func decodeJSONResponse(method string, url string, resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &httpError{Method: method, URL: url, Code: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(body))}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.New(fmt.Sprintf("%s %s: unable to decode response: %v", method, url, err))
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var topN = 25
+
+// Keys are permissions or path.Match patterns over them; an exact match wins over patterns,
+// otherwise the highest matching pattern weight is used.
+var defaultRiskWeights = map[string]int{
+	"*.setIamPolicy":                             10,
+	"iam.serviceAccounts.actAs":                  10,
+	"iam.serviceAccounts.getAccessToken":         10,
+	"iam.serviceAccounts.getOpenIdToken":         8,
+	"iam.serviceAccounts.implicitDelegation":     10,
+	"iam.serviceAccounts.signBlob":               9,
+	"iam.serviceAccounts.signJwt":                9,
+	"iam.serviceAccountKeys.create":              10,
+	"iam.roles.create":                           6,
+	"iam.roles.update":                           8,
+	"orgpolicy.policy.set":                       8,
+	"resourcemanager.projects.delete":            6,
+	"resourcemanager.folders.delete":             6,
+	"compute.instances.setMetadata":              6,
+	"compute.instances.setServiceAccount":        7,
+	"compute.projects.setCommonInstanceMetadata": 7,
+	"deploymentmanager.deployments.create":       7,
+	"cloudbuild.builds.create":                   7,
+	"cloudfunctions.functions.create":            5,
+	"cloudfunctions.functions.update":            5,
+	"run.services.create":                        5,
+	"secretmanager.versions.access":              6,
+	"cloudkms.cryptoKeyVersions.useToDecrypt":    5,
+	"storage.objects.get":                        2,
+	"bigquery.tables.getData":                    2,
+	"*.delete":                                   1,
+	"*.create":                                   1,
+	"*.update":                                   1,
+}
+
+type riskWeights struct {
+	exact    map[string]int
+	patterns map[string]int
+	cache    map[string]int
+}
+
+func loadRiskWeights(filename string) (*riskWeights, error) {
+	weights := make(map[string]int, len(defaultRiskWeights))
+	for k, v := range defaultRiskWeights {
+		weights[k] = v
+	}
+	if filename != "" {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Error opening %s: %v", filename, err))
+		}
+		overrides := make(map[string]int)
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return nil, errors.New(fmt.Sprintf("Error parsing risk weights in %s: %v", filename, err))
+		}
+		for k, v := range overrides {
+			weights[k] = v
+		}
+	}
+	w := &riskWeights{
+		exact:    make(map[string]int),
+		patterns: make(map[string]int),
+		cache:    make(map[string]int),
+	}
+	for k, v := range weights {
+		if strings.ContainsAny(k, "*?[") {
+			if _, err := path.Match(k, ""); err != nil {
+				return nil, errors.New(fmt.Sprintf("Bad risk weight pattern %s: %v", k, err))
+			}
+			w.patterns[k] = v
+		} else {
+			w.exact[k] = v
+		}
+	}
+	return w, nil
+}
+
+func (w *riskWeights) Weight(permission string) int {
+	if v, ok := w.exact[permission]; ok {
+		return v
+	}
+	if v, ok := w.cache[permission]; ok {
+		return v
+	}
+	weight := 0
+	for pattern, v := range w.patterns {
+		if ok, _ := path.Match(pattern, permission); ok && v > weight {
+			weight = v
+		}
+	}
+	w.cache[permission] = weight
+	return weight
+}
+
+// ScoreRows sets each binding's risk to the sum of its role's permission weights and
+// totals them per member. Roles that can't be resolved score 0.
+func (r *resourceManager) ScoreRows(rows []*Row, w *riskWeights) {
+	roleScores := make(map[string]int)
+	r.memberRisk = make(map[string]int)
+	for _, row := range rows {
+		role, err := r.GetRole(row)
+		if err != nil {
+			continue
+		}
+		score, ok := roleScores[role.Name]
+		if !ok {
+			for _, p := range role.IncludedPermissions {
+				score += w.Weight(p)
+			}
+			roleScores[role.Name] = score
+		}
+		row.Risk = score
+		r.memberRisk[row.Member] += score
+	}
+}
+
+func riskiestMembersReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	if resman.memberRisk == nil {
+		return nil, nil, errors.New("rows have not been risk scored")
+	}
+	bindings := make(map[string]int)
+	riskiest := make(map[string]*Row)
+	for _, row := range rows {
+		bindings[row.Member]++
+		if top, ok := riskiest[row.Member]; !ok || row.Risk > top.Risk {
+			riskiest[row.Member] = row
+		}
+	}
+	members := make([]string, 0, len(bindings))
+	for m := range bindings {
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if resman.memberRisk[members[i]] != resman.memberRisk[members[j]] {
+			return resman.memberRisk[members[i]] > resman.memberRisk[members[j]]
+		}
+		return members[i] < members[j]
+	})
+	if len(members) > topN {
+		members = members[:topN]
+	}
+	records := make([][]string, len(members))
+	for i, m := range members {
+		top := riskiest[m]
+		records[i] = []string{
+			strconv.Itoa(i + 1), m, memberClass(m), strconv.Itoa(resman.memberRisk[m]), strconv.Itoa(bindings[m]),
+			fmt.Sprintf("%s on %s %s", top.Role, top.Type, top.Resource),
+		}
+	}
+	return []string{"Rank", "Member", "MemberClass", "RiskScore", "Bindings", "RiskiestBinding"}, records, nil
+}
+
+func init() {
+	registerReport("riskiest-members", riskiestMembersReport)
+}
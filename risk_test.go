@@ -0,0 +1,99 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"google.golang.org/api/iam/v1"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRiskWeights(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "weights.json")
+	if err := ioutil.WriteFile(filename, []byte(`{"storage.objects.delete": 4, "iam.serviceAccounts.actAs": 3, "storage.*": 2}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w, err := loadRiskWeights(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]int{
+		"iam.serviceAccounts.actAs":             3,  // overridden
+		"storage.objects.delete":                4,  // exact wins over patterns
+		"storage.buckets.setIamPolicy":          10, // highest of storage.* and *.setIamPolicy
+		"storage.objects.list":                  2,
+		"resourcemanager.projects.setIamPolicy": 10,
+		"compute.instances.list":                0,
+	}
+	for permission, want := range cases {
+		if got := w.Weight(permission); got != want {
+			t.Errorf("Weight(%s) = %d, want %d", permission, got, want)
+		}
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	if err := ioutil.WriteFile(bad, []byte(`{"storage.[": 1}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadRiskWeights(bad); err == nil {
+		t.Errorf("loadRiskWeights accepted a bad pattern")
+	}
+}
+
+func TestRiskiestMembersReport(t *testing.T) {
+	w, err := loadRiskWeights("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resman := &resourceManager{bindingRoles: make(map[string]*iam.Role)}
+	rows := []*Row{
+		{Resource: "p", Type: "project", Member: "user:a@example.com", Role: "roles/viewer"},
+		{Resource: "p", Type: "project", Member: "user:a@example.com", Role: "roles/iam.serviceAccountUser"},
+		{Resource: "q", Type: "project", Member: "user:b@example.com", Role: "roles/owner"},
+		{Resource: "q", Type: "project", Member: "user:c@example.com", Role: "roles/viewer"},
+	}
+	permissions := map[string][]string{
+		"roles/viewer":                 {"resourcemanager.projects.get"},
+		"roles/iam.serviceAccountUser": {"iam.serviceAccounts.actAs", "iam.serviceAccounts.get"},
+		"roles/owner":                  {"resourcemanager.projects.setIamPolicy", "resourcemanager.projects.delete", "storage.objects.get"},
+	}
+	for _, row := range rows {
+		resman.bindingRoles[bindingRoleKey(row)] = &iam.Role{Name: row.Role, IncludedPermissions: permissions[row.Role]}
+	}
+	if _, _, err := riskiestMembersReport(rows, resman); err == nil {
+		t.Errorf("riskiestMembersReport succeeded on rows that were not scored")
+	}
+	resman.ScoreRows(rows, w)
+	if rows[1].Risk != 10 || rows[2].Risk != 18 {
+		t.Errorf("risks = %d, %d, want 10, 18", rows[1].Risk, rows[2].Risk)
+	}
+	header, records, err := riskiestMembersReport(rows, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(header) != 6 {
+		t.Errorf("header = %v", header)
+	}
+	want := [][]string{
+		{"1", "user:b@example.com", memberClassCustomer, "18", "1", "roles/owner on project q"},
+		{"2", "user:a@example.com", memberClassCustomer, "10", "2", "roles/iam.serviceAccountUser on project p"},
+		{"3", "user:c@example.com", memberClassCustomer, "0", "1", "roles/viewer on project q"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records:\n%v\nwant:\n%v", records, want)
+	}
+}
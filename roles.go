@@ -0,0 +1,114 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+	"net/http"
+	"sort"
+)
+
+// newRoleResolver returns a resource manager that can only look up roles, for commands that
+// don't crawl an org and so shouldn't need to discover one.
+// With --roles-file they also work without credentials, as long as only predefined roles are
+// asked about.
+func newRoleResolver(ctx context.Context, opts *Options) (*resourceManager, error) {
+	client, err := newHTTPClient(ctx, opts, nil)
+	if err != nil && opts.RolesFile != "" {
+		client, err = &http.Client{Transport: &offlineTransport{input: opts.RolesFile}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	service, err := iam.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, err
+	}
+	resman := &resourceManager{
+		ctx:          ctx,
+		orgId:        opts.OrgId,
+		service:      service,
+		roleMap:      make(map[string]*iam.Role, 0),
+		bindingRoles: make(map[string]*iam.Role),
+		client:       client,
+	}
+	if opts.RolesFile != "" {
+		if err := resman.UseRolesFile(opts.RolesFile); err != nil {
+			return nil, err
+		}
+	}
+	return resman, nil
+}
+
+// diffPermissions returns the permissions only in a and only in b, sorted.
+func diffPermissions(a []string, b []string) ([]string, []string) {
+	inA := make(map[string]bool, len(a))
+	for _, p := range a {
+		inA[p] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, p := range b {
+		inB[p] = true
+	}
+	onlyA := make([]string, 0)
+	for p := range inA {
+		if !inB[p] {
+			onlyA = append(onlyA, p)
+		}
+	}
+	onlyB := make([]string, 0)
+	for p := range inB {
+		if !inA[p] {
+			onlyB = append(onlyB, p)
+		}
+	}
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+	return onlyA, onlyB
+}
+
+// diffRoles prints the permissions granted by only one of two roles. Roles are given by
+// their full name: roles/editor, organizations/123/roles/auditor, projects/foo/roles/deployer.
+func diffRoles(opts *Options, a string, b string) error {
+	if a == "" || b == "" {
+		return errors.New("roles diff needs two role names")
+	}
+	resman, err := newRoleResolver(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+	roleA, err := resman._getRoleByUri(a)
+	if err != nil {
+		return err
+	}
+	roleB, err := resman._getRoleByUri(b)
+	if err != nil {
+		return err
+	}
+	onlyA, onlyB := diffPermissions(roleA.IncludedPermissions, roleB.IncludedPermissions)
+	fmt.Printf("Diff %s (%d permissions) -> %s (%d permissions): %d only in %s, %d only in %s\n",
+		a, len(roleA.IncludedPermissions), b, len(roleB.IncludedPermissions), len(onlyA), a, len(onlyB), b)
+	for _, p := range onlyA {
+		fmt.Printf("- %s\n", p)
+	}
+	for _, p := range onlyB {
+		fmt.Printf("+ %s\n", p)
+	}
+	return nil
+}
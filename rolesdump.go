@@ -0,0 +1,93 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/api/iam/v1"
+	"io/ioutil"
+	"time"
+)
+
+// defaultRolesFile is where roles dump writes when --roles-file isn't set.
+const defaultRolesFile = "roles.json"
+
+// rolesDump is the file roles dump writes: every predefined role with its permissions, for
+// --roles-file to look roles up in instead of the IAM API.
+type rolesDump struct {
+	Created time.Time   `json:"created"`
+	Roles   []*iam.Role `json:"roles"`
+}
+
+// dumpRoles writes every predefined role to --roles-file, replacing what an earlier dump
+// wrote there.
+func dumpRoles(opts *Options) error {
+	filename := opts.RolesFile
+	if filename == "" {
+		filename = defaultRolesFile
+	}
+	// list the roles afresh rather than from the file being refreshed
+	live := *opts
+	live.RolesFile = ""
+	resman, err := newRoleResolver(context.Background(), &live)
+	if err != nil {
+		return err
+	}
+	roles, err := resman.predefinedRoles()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(&rolesDump{Created: time.Now().UTC(), Roles: roles}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filename, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d predefined roles to %s\n", len(roles), filename)
+	return nil
+}
+
+func loadRolesDump(filename string) (*rolesDump, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dump := &rolesDump{}
+	if err := json.Unmarshal(data, dump); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to read roles from %s: %v", filename, err))
+	}
+	return dump, nil
+}
+
+// UseRolesFile looks predefined roles up in a file written by roles dump. Custom roles aren't
+// in it and are still fetched.
+func (r *resourceManager) UseRolesFile(filename string) error {
+	dump, err := loadRolesDump(filename)
+	if err != nil {
+		return err
+	}
+	r.roleMu.Lock()
+	defer r.roleMu.Unlock()
+	for _, role := range dump.Roles {
+		r.roleMap[role.Name] = role
+	}
+	r.predefined = dump.Roles
+	fmt.Printf("Using %d predefined roles dumped to %s on %s\n", len(dump.Roles), filename, dump.Created.Format("2006-01-02"))
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"google.golang.org/api/iam/v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUseRolesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "roles.json")
+	dump := &rolesDump{
+		Created: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		Roles: []*iam.Role{
+			{Name: "roles/viewer", Title: "Viewer", Stage: "GA", IncludedPermissions: []string{"resourcemanager.projects.get"}},
+			{Name: "roles/old", Stage: "DEPRECATED", IncludedPermissions: []string{"storage.buckets.get"}},
+		},
+	}
+	data, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	resman := &resourceManager{roleMap: make(map[string]*iam.Role)}
+	if err := resman.UseRolesFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	// no IAM client: a lookup outside the file would panic
+	role, err := resman._getRoleByUri("roles/viewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(role.IncludedPermissions, []string{"resourcemanager.projects.get"}) {
+		t.Errorf("roles/viewer permissions = %v", role.IncludedPermissions)
+	}
+	if stage := resman.RoleStages()["roles/old"]; stage != "DEPRECATED" {
+		t.Errorf("roles/old stage = %q, want DEPRECATED", stage)
+	}
+	predefined, err := resman.predefinedRoles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(predefined) != 2 {
+		t.Errorf("predefinedRoles() returned %d roles, want 2", len(predefined))
+	}
+}
+
+func TestLoadRolesDumpInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "roles.json")
+	if err := ioutil.WriteFile(filename, []byte("roles/viewer"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadRolesDump(filename); err == nil {
+		t.Error("loadRolesDump accepted a file that isn't json")
+	}
+}
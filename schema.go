@@ -0,0 +1,138 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// schemaVersion is bumped whenever a field is removed or changes meaning; new optional fields
+// keep the version. Every json document and ndjson line carries it. Version 2 opened type up
+// from organization, folder and project to the asset types of resources inside projects.
+const schemaVersion = 2
+
+// rowSchema describes one permission row, a line of --format ndjson.
+const rowSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "urn:policygopher:schema:row:v2",
+  "title": "policygopher permission row",
+  "description": "One permission a member gets from one role binding on one resource.",
+  "type": "object",
+  "required": ["schemaVersion", "resource", "type", "member", "memberClass", "role", "permission", "bindingRisk", "memberRisk"],
+  "properties": {
+    "schemaVersion": {"const": 2},
+    "resource": {"type": "string", "description": "Resource column, see --resource-name-style"},
+    "type": {"type": "string", "description": "organization, folder, project, or with --resource-policies the asset type, e.g. bucket"},
+    "resourceName": {"type": "string", "description": "canonical name, e.g. projects/my-project"},
+    "displayName": {"type": "string"},
+    "member": {"type": "string", "description": "IAM member, e.g. user:alice@example.com"},
+    "memberClass": {"enum": ["customer", "google-managed", "default-service-account"]},
+    "memberProject": {"type": "string", "description": "project a service account belongs to"},
+    "role": {"type": "string"},
+    "permission": {"type": "string"},
+    "bindingRisk": {"type": "integer", "minimum": 0},
+    "memberRisk": {"type": "integer", "minimum": 0},
+    "lifecycleState": {"type": "string", "description": "ACTIVE or DELETE_REQUESTED for projects and folders"},
+    "memberState": {"enum": ["ACTIVE", "DISABLED", "DELETED", "SUSPENDED"], "description": "state of the member, with --service-account-status or --user-status"},
+    "memberLastActive": {"type": "string", "format": "date-time", "description": "last authentication or login of the member seen"},
+    "condition": {
+      "type": "object",
+      "description": "IAM condition of the binding",
+      "properties": {
+        "title": {"type": "string"},
+        "description": {"type": "string"},
+        "expression": {"type": "string", "description": "CEL expression"},
+        "location": {"type": "string"}
+      }
+    },
+    "conditionActive": {"enum": ["true", "false", "unknown"], "description": "whether the condition holds at --evaluate-conditions-at"},
+    "runId": {"type": "string", "description": "run the row was crawled in, see --source-columns"},
+    "source": {"type": "string", "description": "org ID, or project-<id> for a project without an org, the row was crawled from"},
+    "count": {"type": "integer", "minimum": 1, "description": "bindings merged into the row by --dedup"},
+    "origin": {"enum": ["iam", "bigquery-acl", "gcs-acl"], "description": "where the binding comes from, with --bigquery-acls or --bucket-acls"},
+    "permissionValid": {"enum": ["true", "false"], "description": "whether the permission can apply to the resource's type, with --permission-validity"},
+    "serviceEnabled": {"enum": ["true", "false"], "description": "whether the permission's API is enabled in the resource's project, with --service-enablement"},
+    "perimeter": {"type": "string", "description": "space separated VPC Service Controls perimeters the resource's project is in, with --vpc-sc"},
+    "permissionService": {"type": "string", "description": "service the permission belongs to, e.g. compute, with --permission-columns"},
+    "permissionResource": {"type": "string", "description": "resource the permission acts on, e.g. instances, with --permission-columns"},
+    "permissionVerb": {"type": "string", "description": "what the permission allows, e.g. setIamPolicy, with --permission-columns"},
+    "policyEtag": {"type": "string", "description": "etag of the policy revision the binding was read from, with --provenance"},
+    "bindingIndex": {"type": "integer", "minimum": 0, "description": "index of the binding in that policy's bindings, with --provenance"},
+    "grantedAt": {"type": "string", "format": "date-time", "description": "when the binding was last added according to the audit logs, with --binding-age-days"},
+    "grantedBy": {"type": "string", "description": "who added it, with --binding-age-days"},
+    "department": {"type": "string", "description": "department of the bound user, with --identities"},
+    "manager": {"type": "string", "description": "manager of the bound user, with --identities"},
+    "employmentStatus": {"type": "string", "description": "employment status of the bound user, e.g. active or terminated, with --identities"}
+  }
+}
+`
+
+// exportSchema describes the document written by --format json.
+const exportSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "urn:policygopher:schema:export:v2",
+  "title": "policygopher export",
+  "type": "object",
+  "required": ["schemaVersion", "orgId", "created", "rows"],
+  "properties": {
+    "schemaVersion": {"const": 2},
+    "orgId": {"type": "string"},
+    "organization": {
+      "type": "object",
+      "description": "the org's display name, Workspace or Cloud Identity domain, and directory customer id, when they could be looked up",
+      "properties": {
+        "displayName": {"type": "string"},
+        "domain": {"type": "string"},
+        "directoryCustomerId": {"type": "string"}
+      }
+    },
+    "created": {"type": "string", "format": "date-time"},
+    "rows": {"type": "array", "items": {"$ref": "urn:policygopher:schema:row:v2"}}
+  }
+}
+`
+
+var schemas = map[string]string{
+	"row":    rowSchema,
+	"export": exportSchema,
+}
+
+func schemaNames() []string {
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printSchema prints the named JSON Schema, or all of them one after another when name is empty.
+func printSchema(name string) error {
+	if name == "" {
+		for _, n := range schemaNames() {
+			fmt.Print(schemas[n])
+		}
+		return nil
+	}
+	schema, ok := schemas[name]
+	if !ok {
+		return errors.New(fmt.Sprintf("Unknown schema %s, expected one of: %s", name, strings.Join(schemaNames(), ", ")))
+	}
+	fmt.Print(schema)
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+)
+
+// signers are the tools --sign can make detached signatures with, each returning the signature
+// file it writes next to the signed one and the command writing it.
+var signers = map[string]func(filename string, key string) (string, []string){
+	"cosign": func(filename string, key string) (string, []string) {
+		signature := filename + ".sig"
+		args := []string{"cosign", "sign-blob", "--yes", "--output-signature", signature}
+		if key != "" {
+			args = append(args, "--key", key)
+		}
+		return signature, append(args, filename)
+	},
+	"gpg": func(filename string, key string) (string, []string) {
+		signature := filename + ".asc"
+		args := []string{"gpg", "--batch", "--yes", "--armor", "--detach-sign", "--output", signature}
+		if key != "" {
+			args = append(args, "--local-user", key)
+		}
+		return signature, append(args, filename)
+	},
+}
+
+// checkSigner validates --sign.
+func checkSigner(signer string) error {
+	if _, ok := signers[signer]; signer != "" && !ok {
+		return errors.New(fmt.Sprintf("Unknown --sign %s, expected cosign or gpg", signer))
+	}
+	return nil
+}
+
+// checksumLine is filename's line of a checksum file, in the format sha256sum -c reads.
+func checksumLine(filename string, sum string) string {
+	return fmt.Sprintf("%s  %s\n", sum, path.Base(filepath.ToSlash(filename)))
+}
+
+// sealOutputs writes a <file>.sha256 next to every output file of the run with --checksums,
+// and a detached signature with --sign, so the integrity of audit evidence can be checked
+// later. Files on Cloud Storage can be checksummed but not signed.
+func sealOutputs(opts *Options) error {
+	if !opts.Checksums && opts.Sign == "" {
+		return nil
+	}
+	outputs := committedOutputs()
+	for _, filename := range outputs {
+		if opts.Checksums {
+			c, _ := committed(filename)
+			if err := writeFileAtomic(filename+".sha256", []byte(checksumLine(filename, c.Sha256)), 0644); err != nil {
+				return err
+			}
+		}
+		if opts.Sign == "" {
+			continue
+		}
+		if isGcsPath(filename) {
+			logerr.Printf("Not signing %s: only local files can be signed\n", filename)
+			continue
+		}
+		signature, args := signers[opts.Sign](filename, opts.SignKey)
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stderr, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return errors.New(fmt.Sprintf("Unable to sign %s with %s: %v", filename, opts.Sign, err))
+		}
+		fmt.Printf("Signed %s in %s\n", filename, signature)
+	}
+	if opts.Checksums {
+		fmt.Printf("Wrote checksums of %d files\n", len(outputs))
+	}
+	return nil
+}
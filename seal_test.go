@@ -0,0 +1,66 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSealOutputsChecksums(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	report := filepath.Join(dir, "report.csv")
+	if err := writeOutputFile(report, []byte("a,b\n")); err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(dir, "cache.json")
+	if err := writeFileAtomic(cache, []byte("{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := sealOutputs(&Options{Checksums: true}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(report + ".sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sha256sum of "a,b\n"
+	want := "5be08c9684a1d25efcee09318204824278b08bbfb4aef973ffefd0b9d7478313  report.csv\n"
+	if string(data) != want {
+		t.Errorf("report.csv.sha256 = %q, want %q", data, want)
+	}
+	if _, err := os.Stat(cache + ".sha256"); err == nil {
+		t.Errorf("cache.json was checksummed")
+	}
+}
+
+func TestSigners(t *testing.T) {
+	signature, args := signers["gpg"]("out/report.csv", "audit@example.com")
+	want := []string{"gpg", "--batch", "--yes", "--armor", "--detach-sign", "--output", "out/report.csv.asc",
+		"--local-user", "audit@example.com", "out/report.csv"}
+	if signature != "out/report.csv.asc" || !reflect.DeepEqual(args, want) {
+		t.Errorf("gpg signer = %s %q", signature, args)
+	}
+	if err := checkSigner("pgp"); err == nil {
+		t.Errorf("checkSigner(pgp) didn't fail")
+	}
+}
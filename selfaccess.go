@@ -0,0 +1,235 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// testPermissionsBatch is how many permissions one testIamPermissions call asks about.
+const testPermissionsBatch = 100
+
+// collectorOptions tell which collectors an export runs, so --check-access only tests the
+// permissions those need. gke and gke-rbac belong to the gke command.
+var collectorOptions = map[string]func(opts *Options) bool{
+	"core":                   always,
+	"resource-policies":      func(opts *Options) bool { return opts.ResourcePolicies },
+	"shared-vpc":             func(opts *Options) bool { return opts.SharedVpc },
+	"iap":                    func(opts *Options) bool { return opts.Iap },
+	"kms":                    func(opts *Options) bool { return opts.Kms },
+	"policy-tags":            func(opts *Options) bool { return opts.PolicyTags },
+	"repos":                  func(opts *Options) bool { return opts.Repos },
+	"bigquery-acls":          func(opts *Options) bool { return opts.BigQueryAcls },
+	"bucket-acls":            func(opts *Options) bool { return opts.BucketAcls },
+	"service-account-status": func(opts *Options) bool { return opts.ServiceAccountStatus },
+	"service-account-keys":   func(opts *Options) bool { return opts.ServiceAccountKeys },
+	"service-enablement":     func(opts *Options) bool { return opts.ServiceEnablement },
+	"vpc-sc":                 func(opts *Options) bool { return opts.VpcSc },
+	"access-approval":        func(opts *Options) bool { return opts.AccessApproval },
+	"binding-age":            func(opts *Options) bool { return opts.BindingAgeDays > 0 },
+	"custom-role-usage": func(opts *Options) bool {
+		reports, _ := parseReports(opts.Reports)
+		return stringSet(reports)["custom-role-usage"]
+	},
+}
+
+// blindSpots say what an export misses without a permission, for the core collector's
+// permissions, or without any of a collector's permissions.
+var blindSpots = map[string]string{
+	"resourcemanager.organizations.get":          "the org can't be described and its display name is missing",
+	"resourcemanager.organizations.getIamPolicy": "the org's own bindings are missing",
+	"resourcemanager.folders.list":               "no folder, or binding on a folder, is seen",
+	"resourcemanager.folders.getIamPolicy":       "folders are found but their bindings are missing",
+	"resourcemanager.projects.get":               "projects can't be looked up by id, for the org and member projects",
+	"resourcemanager.projects.list":              "no project, or binding on a project, is seen",
+	"resourcemanager.projects.getIamPolicy":      "projects are found but their bindings are missing",
+	"iam.roles.get":                              "custom roles have no permissions, so their bindings expand to nothing",
+	"resource-policies":                          "bindings on resources inside projects are missing",
+	"shared-vpc":                                 "Shared VPC attachments and subnetwork bindings are missing",
+	"iap":                                        "bindings on IAP-protected apps and tunnels are missing",
+	"kms":                                        "bindings on Cloud KMS key rings are missing",
+	"policy-tags":                                "BigQuery column-level grants on policy tags are missing",
+	"repos":                                      "bindings on source and artifact repositories are missing",
+	"bigquery-acls":                              "BigQuery dataset ACLs are missing",
+	"bucket-acls":                                "bucket ACLs are missing",
+	"service-account-status":                     "service accounts have no MemberState or MemberLastActive",
+	"service-account-keys":                       "service account keys are missing from the service-account-keys report",
+	"service-enablement":                         "the ServiceEnabled column is empty",
+	"vpc-sc":                                     "the Perimeter column is empty",
+	"access-approval":                            "the access-approval report is empty",
+	"binding-age":                                "the GrantedAt and GrantedBy columns are empty",
+	"custom-role-usage":                          "the custom-role-usage report has no unused roles",
+}
+
+// selfPermission is whether the identity running the export holds one permission a collector
+// needs, at one scope. Untestable permissions can't be granted at that scope.
+type selfPermission struct {
+	Scope      string
+	Collector  string
+	Permission string
+	Granted    bool
+	Testable   bool
+}
+
+// blindSpot says what a missing permission costs the export, empty when it isn't missing.
+func (p *selfPermission) blindSpot() string {
+	if p.Granted || !p.Testable {
+		return ""
+	}
+	if spot, ok := blindSpots[p.Permission]; ok {
+		return spot
+	}
+	return blindSpots[p.Collector]
+}
+
+type testIamPermissionsResponse struct {
+	Permissions []string `json:"permissions"`
+}
+
+// testPermissions asks Resource Manager which of permissions the caller holds on an org,
+// or project. Permissions that can't be granted there make the whole call fail, so
+// on a 400 each is asked about alone and those that still fail come back untestable.
+func (r *resourceManager) testPermissions(scope string, permissions []string) (granted map[string]bool, untestable map[string]bool, err error) {
+	granted, untestable = make(map[string]bool), make(map[string]bool)
+	u := fmt.Sprintf("https://cloudresourcemanager.googleapis.com/v1/%s:testIamPermissions", scope)
+	for start := 0; start < len(permissions); start += testPermissionsBatch {
+		batch := permissions[start:]
+		if len(batch) > testPermissionsBatch {
+			batch = batch[:testPermissionsBatch]
+		}
+		resp := &testIamPermissionsResponse{}
+		err := r.postJSON(u, map[string][]string{"permissions": batch}, resp)
+		var h *httpError
+		if errors.As(err, &h) && h.Code == http.StatusBadRequest && len(batch) > 1 {
+			for _, p := range batch {
+				one, bad, err := r.testPermissions(scope, []string{p})
+				if err != nil {
+					return nil, nil, err
+				}
+				for q := range one {
+					granted[q] = true
+				}
+				for q := range bad {
+					untestable[q] = true
+				}
+			}
+			continue
+		}
+		if errors.As(err, &h) && h.Code == http.StatusBadRequest {
+			untestable[batch[0]] = true
+			continue
+		}
+		if err != nil {
+			return nil, nil, classifyError(scope, err)
+		}
+		for _, p := range resp.Permissions {
+			granted[p] = true
+		}
+	}
+	return granted, untestable, nil
+}
+
+// enabledCollectors are the collectors the options turn on, sorted.
+func enabledCollectors(opts *Options) []string {
+	names := make([]string, 0)
+	for name, enabled := range collectorOptions {
+		if enabled(opts) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckSelfAccess tests every permission the collectors need at the org and, when there is
+// one, the project given with --project, and prints what the export won't see. testIamPermissions
+// only answers for the identity making the call, so this is the export's own identity.
+func (r *resourceManager) CheckSelfAccess(collectors []string, projectId string) error {
+	defer timeTrack("Checking access")()
+	scopes := make([]string, 0)
+	if r.standaloneProject != "" {
+		scopes = append(scopes, "projects/"+r.standaloneProject)
+	} else {
+		scopes = append(scopes, fmt.Sprintf("organizations/%s", r.orgId))
+		if projectId != "" {
+			scopes = append(scopes, "projects/"+projectId)
+		}
+	}
+	r.selfAccess = make([]*selfPermission, 0)
+	for _, scope := range scopes {
+		needed := make(map[string]string)
+		for _, collector := range collectors {
+			for _, p := range collectorPermissions[collector] {
+				if _, ok := needed[p]; !ok {
+					needed[p] = collector
+				}
+			}
+		}
+		permissions := make([]string, 0, len(needed))
+		for p := range needed {
+			permissions = append(permissions, p)
+		}
+		sort.Strings(permissions)
+		granted, untestable, err := r.testPermissions(scope, permissions)
+		if err != nil {
+			return err
+		}
+		held := 0
+		for _, p := range permissions {
+			self := &selfPermission{Scope: scope, Collector: needed[p], Permission: p, Granted: granted[p], Testable: !untestable[p]}
+			if self.Granted {
+				held++
+			}
+			r.selfAccess = append(r.selfAccess, self)
+		}
+		fmt.Printf("Holding %d of the %d permissions the collectors need at %s\n", held, len(permissions), scope)
+	}
+	spots := make(map[string]bool)
+	for _, p := range r.selfAccess {
+		if spot := p.blindSpot(); spot != "" && !spots[spot] {
+			spots[spot] = true
+			logerr.Printf("Missing %s at %s: %s\n", p.Permission, p.Scope, spot)
+		}
+	}
+	return nil
+}
+
+// selfAccessReport lists the permissions --check-access tested, missing ones first, with the
+// blind spot each leaves in the export.
+func selfAccessReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Scope", "Collector", "Permission", "Granted", "BlindSpot"}
+	if resman.selfAccess == nil {
+		logerr.Printf("The self-access report needs --check-access\n")
+		return header, [][]string{}, nil
+	}
+	records := make([][]string, 0, len(resman.selfAccess))
+	for _, p := range resman.selfAccess {
+		granted := strconv.FormatBool(p.Granted)
+		if !p.Testable {
+			granted = "untestable"
+		}
+		records = append(records, []string{p.Scope, p.Collector, p.Permission, granted, p.blindSpot()})
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i][4] != "" && records[j][4] == "" })
+	return header, records, nil
+}
+
+func init() {
+	registerReport("self-access", selfAccessReport)
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeTestIamPermissions answers testIamPermissions like Resource Manager: 400 for a request
+// naming a permission in invalid, and otherwise the requested ones in held.
+func fakeTestIamPermissions(held map[string]bool, invalid map[string]bool, calls *int) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*calls++
+		body := struct{ Permissions []string }{}
+		json.NewDecoder(req.Body).Decode(&body)
+		granted := make([]string, 0)
+		for _, p := range body.Permissions {
+			if invalid[p] {
+				return &http.Response{StatusCode: 400, Status: "400 Bad Request",
+					Body: ioutil.NopCloser(strings.NewReader(`{"error":{"message":"Permission ` + p + ` is not valid"}}`))}, nil
+			}
+			if held[p] {
+				granted = append(granted, p)
+			}
+		}
+		data, _ := json.Marshal(map[string][]string{"permissions": granted})
+		return &http.Response{StatusCode: 200, Status: "200 OK", Body: ioutil.NopCloser(strings.NewReader(string(data)))}, nil
+	})}
+}
+
+func TestTestPermissions(t *testing.T) {
+	calls := 0
+	resman := &resourceManager{client: fakeTestIamPermissions(
+		map[string]bool{"a.b.get": true, "c.d.list": true}, map[string]bool{"x.y.z": true}, &calls)}
+	granted, untestable, err := resman.testPermissions("organizations/1", []string{"a.b.get", "a.b.list", "c.d.list", "x.y.z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]bool{"a.b.get": true, "c.d.list": true}; !reflect.DeepEqual(granted, want) {
+		t.Errorf("granted = %v, want %v", granted, want)
+	}
+	if want := map[string]bool{"x.y.z": true}; !reflect.DeepEqual(untestable, want) {
+		t.Errorf("untestable = %v, want %v", untestable, want)
+	}
+	if calls != 5 {
+		t.Errorf("calls = %d, want 5: the batch, then each permission alone", calls)
+	}
+}
+
+func TestCheckSelfAccess(t *testing.T) {
+	calls := 0
+	held := map[string]bool{}
+	for _, p := range collectorPermissions["core"] {
+		held[p] = p != "iam.roles.get"
+	}
+	resman := &resourceManager{orgId: "1", client: fakeTestIamPermissions(held, nil, &calls)}
+	logerr = log.New(ioutil.Discard, "", 0)
+	if err := resman.CheckSelfAccess([]string{"core", "kms"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	header, records, err := selfAccessReport(nil, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(header) != 5 || len(records) != len(collectorPermissions["core"])+len(collectorPermissions["kms"]) {
+		t.Fatalf("selfAccessReport() = %d records", len(records))
+	}
+	missing := 0
+	for _, r := range records {
+		if r[3] == "false" {
+			missing++
+		}
+	}
+	if missing != 1+len(collectorPermissions["kms"]) {
+		t.Errorf("%d permissions missing, want iam.roles.get and the kms ones", missing)
+	}
+	if records[0][4] == "" || records[len(records)-1][4] != "" {
+		t.Errorf("missing permissions with their blind spot should come first: %q", records)
+	}
+}
+
+func TestEnabledCollectors(t *testing.T) {
+	opts := &Options{Kms: true, Reports: "custom-role-usage"}
+	if got, want := enabledCollectors(opts), []string{"core", "custom-role-usage", "kms"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("enabledCollectors() = %q, want %q", got, want)
+	}
+	for name := range collectorOptions {
+		if _, ok := collectorPermissions[name]; !ok {
+			t.Errorf("collector %s has no permissions registered", name)
+		}
+		if _, ok := blindSpots[name]; !ok && name != "core" {
+			t.Errorf("collector %s has no blind spot", name)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/api/iam/v1"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serviceAccountKeyWorkers is how many projects' service account keys are listed at once.
+const serviceAccountKeyWorkers = 8
+
+// noKeyExpiry is the validBeforeTime of keys that never expire.
+const noKeyExpiry = "9999-12-31T23:59:59Z"
+
+// serviceAccountKey is a user-managed key of one of the service accounts of a project.
+type serviceAccountKey struct {
+	ServiceAccount string
+	Project        string
+	KeyId          string
+	Created        time.Time
+	// zero for keys that never expire
+	Expires time.Time
+}
+
+// parseKeyAge parses --max-key-age: a number of days like 90d, or a Go duration like 2160h.
+func parseKeyAge(age string) (time.Duration, error) {
+	if age == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(age, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(age, "d"))
+		if err == nil && days >= 0 {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(age); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, errors.New(fmt.Sprintf("Invalid --max-key-age %s, expected days like 90d or a duration like 2160h", age))
+}
+
+// serviceAccountKeys lists the user-managed keys of every service account of a project.
+func (r *resourceManager) serviceAccountKeys(project string) ([]*serviceAccountKey, error) {
+	emails := make([]string, 0)
+	err := r.service.Projects.ServiceAccounts.List(fmt.Sprintf("projects/%s", project)).PageSize(100).
+		Fields("nextPageToken,accounts(email)").
+		Pages(r.ctx, func(page *iam.ListServiceAccountsResponse) error {
+			for _, sa := range page.Accounts {
+				emails = append(emails, sa.Email)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, classifyError(project, err)
+	}
+	keys := make([]*serviceAccountKey, 0)
+	for _, email := range emails {
+		resp, err := r.service.Projects.ServiceAccounts.Keys.List(fmt.Sprintf("projects/-/serviceAccounts/%s", email)).
+			KeyTypes("USER_MANAGED").Fields("keys(name,validAfterTime,validBeforeTime)").Context(r.ctx).Do()
+		if err != nil {
+			logerr.Printf("Unable to list keys of service account %s: %v\n", email, err)
+			continue
+		}
+		for _, k := range resp.Keys {
+			key := &serviceAccountKey{ServiceAccount: strings.ToLower(email), Project: project, KeyId: path.Base(k.Name)}
+			key.Created, _ = time.Parse(time.RFC3339, k.ValidAfterTime)
+			if k.ValidBeforeTime != noKeyExpiry {
+				key.Expires, _ = time.Parse(time.RFC3339, k.ValidBeforeTime)
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// ResolveServiceAccountKeys lists the user-managed service account keys of every project rows
+// are in, several projects at once.
+func (r *resourceManager) ResolveServiceAccountKeys(rows []*Row) {
+	defer timeTrack("Listing service account keys")()
+	r.serviceAccountKeyList = make([]*serviceAccountKey, 0)
+	projects := make(map[string]bool)
+	for _, row := range rows {
+		if project := rowProject(row); project != "" {
+			projects[project] = true
+		}
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	work := make(chan string)
+	for i := 0; i < serviceAccountKeyWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for project := range work {
+				keys, err := r.serviceAccountKeys(project)
+				if err != nil {
+					logerr.Printf("Unable to list service accounts of project %s: %v\n", project, err)
+					continue
+				}
+				mu.Lock()
+				r.serviceAccountKeyList = append(r.serviceAccountKeyList, keys...)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, project := range sortedKeys(projects) {
+		work <- project
+	}
+	close(work)
+	wg.Wait()
+	sort.Slice(r.serviceAccountKeyList, func(i, j int) bool {
+		a, b := r.serviceAccountKeyList[i], r.serviceAccountKeyList[j]
+		if a.ServiceAccount != b.ServiceAccount {
+			return a.ServiceAccount < b.ServiceAccount
+		}
+		return a.KeyId < b.KeyId
+	})
+	fmt.Printf("Listed %d user-managed service account keys in %d projects\n", len(r.serviceAccountKeyList), len(projects))
+}
+
+// staleKey tells a key older than maxAge at now; a zero maxAge flags none.
+func staleKey(key *serviceAccountKey, maxAge time.Duration, now time.Time) bool {
+	return maxAge > 0 && !key.Created.IsZero() && now.Sub(key.Created) > maxAge
+}
+
+// serviceAccountKeyRecords are the lines of the service-account-keys report, stale keys first.
+func serviceAccountKeyRecords(keys []*serviceAccountKey, maxAge time.Duration, now time.Time) [][]string {
+	records := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		created, expires, age := "", "", ""
+		if !key.Created.IsZero() {
+			created = key.Created.UTC().Format(time.RFC3339)
+			age = strconv.Itoa(int(now.Sub(key.Created).Hours() / 24))
+		}
+		if !key.Expires.IsZero() {
+			expires = key.Expires.UTC().Format(time.RFC3339)
+		}
+		records = append(records, []string{key.ServiceAccount, key.Project, key.KeyId, created, expires, age,
+			strconv.FormatBool(staleKey(key, maxAge, now))})
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i][6] == "true" && records[j][6] != "true" })
+	return records
+}
+
+// serviceAccountKeysReport lists the user-managed service account keys with their age, flagging
+// those older than --max-key-age as Stale.
+func serviceAccountKeysReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"ServiceAccount", "Project", "KeyId", "Created", "Expires", "AgeDays", "Stale"}
+	if resman.serviceAccountKeyList == nil {
+		logerr.Printf("The service-account-keys report needs --service-account-keys\n")
+		return header, [][]string{}, nil
+	}
+	return header, serviceAccountKeyRecords(resman.serviceAccountKeyList, resman.maxKeyAge, time.Now()), nil
+}
+
+// staleKeysCondition is the stale-keys --fail-on condition: any key older than --max-key-age.
+func staleKeysCondition(rows []*Row, resman *resourceManager) (string, error) {
+	if resman.serviceAccountKeyList == nil {
+		return "", errors.New("--fail-on stale-keys needs --service-account-keys")
+	}
+	now := time.Now()
+	stale := 0
+	for _, key := range resman.serviceAccountKeyList {
+		if staleKey(key, resman.maxKeyAge, now) {
+			stale++
+		}
+	}
+	if stale == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("%d service account keys older than %s", stale, resman.maxKeyAge), nil
+}
+
+func init() {
+	registerEnricher("service-account-keys", stageAnnotate, func(opts *Options) bool { return opts.ServiceAccountKeys },
+		func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+			resman.ResolveServiceAccountKeys(rows)
+			return rows, nil
+		})
+	registerReport("service-account-keys", serviceAccountKeysReport)
+	registerFailCondition("stale-keys", staleKeysCondition)
+	registerCollectorPermissions("service-account-keys", "iam.serviceAccounts.list", "iam.serviceAccountKeys.list")
+}
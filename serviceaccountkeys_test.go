@@ -0,0 +1,92 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseKeyAge(t *testing.T) {
+	tests := []struct {
+		age     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"90d", 90 * 24 * time.Hour, false},
+		{"0d", 0, false},
+		{"2160h", 2160 * time.Hour, false},
+		{"0", 0, false},
+		{"", 0, false},
+		{"-1d", 0, true},
+		{"ninety", 0, true},
+		{"d", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseKeyAge(tt.age)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseKeyAge(%q) = %v, %v, want %v, error %v", tt.age, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestServiceAccountKeyRecords(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	keys := []*serviceAccountKey{
+		{ServiceAccount: "a@p.iam.gserviceaccount.com", Project: "p", KeyId: "k1", Created: now.AddDate(0, 0, -10)},
+		{ServiceAccount: "b@p.iam.gserviceaccount.com", Project: "p", KeyId: "k2", Created: now.AddDate(0, 0, -200),
+			Expires: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	want := [][]string{
+		{"b@p.iam.gserviceaccount.com", "p", "k2", "2023-11-14T00:00:00Z", "2025-01-01T00:00:00Z", "200", "true"},
+		{"a@p.iam.gserviceaccount.com", "p", "k1", "2024-05-22T00:00:00Z", "", "10", "false"},
+	}
+	if got := serviceAccountKeyRecords(keys, 90*24*time.Hour, now); !reflect.DeepEqual(got, want) {
+		t.Errorf("serviceAccountKeyRecords() = %q, want %q", got, want)
+	}
+	if got := serviceAccountKeyRecords(keys, 0, now); got[0][6] != "false" || got[1][6] != "false" {
+		t.Errorf("serviceAccountKeyRecords() with no max age = %q, want no stale keys", got)
+	}
+}
+
+func TestStaleKeysCondition(t *testing.T) {
+	resman := &resourceManager{orgId: "1", maxKeyAge: 90 * 24 * time.Hour}
+	if _, err := staleKeysCondition(nil, resman); err == nil {
+		t.Errorf("staleKeysCondition() without --service-account-keys succeeded, want an error")
+	}
+	resman.serviceAccountKeyList = []*serviceAccountKey{{KeyId: "k1", Created: time.Now().AddDate(-1, 0, 0)}}
+	found, err := staleKeysCondition(nil, resman)
+	if err != nil || found != "1 service account keys older than 2160h0m0s" {
+		t.Errorf("staleKeysCondition() = %q, %v", found, err)
+	}
+	failedConditions = nil
+	defer func() { failedConditions = nil }()
+	if err := checkFailConditions([]string{"stale-keys"}, nil, resman); err != nil {
+		t.Fatalf("checkFailConditions() = %v", err)
+	}
+	if err := failOnResult(); err == nil || err.Error() != "--fail-on: 1: 1 service account keys older than 2160h0m0s" {
+		t.Errorf("failOnResult() = %v", err)
+	}
+}
+
+func TestParseFailOn(t *testing.T) {
+	if got, err := parseFailOn(" stale-keys ,"); err != nil || !reflect.DeepEqual(got, []string{"stale-keys"}) {
+		t.Errorf("parseFailOn() = %q, %v", got, err)
+	}
+	if _, err := parseFailOn("nothing"); err == nil {
+		t.Errorf("parseFailOn(nothing) succeeded, want an error")
+	}
+}
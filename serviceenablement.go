@@ -0,0 +1,186 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"google.golang.org/api/option"
+	"google.golang.org/api/serviceusage/v1"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// serviceEnablementWorkers is how many projects' enabled services are listed at once.
+const serviceEnablementWorkers = 8
+
+// permissionServiceNames maps the permission prefixes whose API isn't <prefix>.googleapis.com.
+var permissionServiceNames = map[string]string{
+	"cloudsql":        "sqladmin.googleapis.com",
+	"resourcemanager": "cloudresourcemanager.googleapis.com",
+	"source":          "sourcerepo.googleapis.com",
+}
+
+// platformPermissionPrefixes are granted for services usable whether or not a project enabled
+// them, so the ServiceEnabled column leaves them empty.
+var platformPermissionPrefixes = map[string]bool{
+	"iam":             true,
+	"orgpolicy":       true,
+	"resourcemanager": true,
+	"serviceusage":    true,
+}
+
+// permissionPrefix is the service part of a permission, or of a --collapse-permissions summary.
+func permissionPrefix(permission string) string {
+	if i := strings.IndexAny(permission, ".:"); i >= 0 {
+		return permission[:i]
+	}
+	return permission
+}
+
+// permissionService is the API a permission belongs to, e.g. storage.googleapis.com.
+func permissionService(permission string) string {
+	prefix := permissionPrefix(permission)
+	if name, ok := permissionServiceNames[prefix]; ok {
+		return name
+	}
+	return prefix + ".googleapis.com"
+}
+
+// rowProject is the project a row's resource is or belongs to, or "" for orgs and folders.
+func rowProject(row *Row) string {
+	if row.Type == "project" {
+		return strings.TrimPrefix(row.Name, "projects/")
+	}
+	if strings.HasPrefix(row.Parent, "projects/") {
+		return strings.TrimPrefix(row.Parent, "projects/")
+	}
+	return ""
+}
+
+// EnabledServices lists the services enabled in a project from the Service Usage API.
+func (r *resourceManager) EnabledServices(project string) ([]string, error) {
+	var clientOptions []option.ClientOption
+	if r.client != nil {
+		clientOptions = append(clientOptions, option.WithHTTPClient(r.client))
+	}
+	service, err := serviceusage.NewService(r.ctx, clientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	services := make([]string, 0)
+	err = service.Services.List(fmt.Sprintf("projects/%s", project)).Filter("state:ENABLED").PageSize(200).
+		Fields("nextPageToken,services(config(name))").
+		Pages(r.ctx, func(page *serviceusage.ListServicesResponse) error {
+			for _, s := range page.Services {
+				if s.Config != nil {
+					services = append(services, s.Config.Name)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, classifyError(project, err)
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// ResolveEnabledServices lists the enabled services of every project rows are in.
+func (r *resourceManager) ResolveEnabledServices(rows []*Row) {
+	defer timeTrack("Listing enabled services")()
+	r.enabledServices = make(map[string]map[string]bool)
+	projects := make(map[string]bool)
+	for _, row := range rows {
+		if project := rowProject(row); project != "" {
+			projects[project] = true
+		}
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	work := make(chan string)
+	for i := 0; i < serviceEnablementWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for project := range work {
+				services, err := r.EnabledServices(project)
+				if err != nil {
+					logerr.Printf("Unable to list enabled services of project %s: %v\n", project, err)
+					continue
+				}
+				enabled := make(map[string]bool, len(services))
+				for _, s := range services {
+					enabled[s] = true
+				}
+				mu.Lock()
+				r.enabledServices[project] = enabled
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, project := range sortedKeys(projects) {
+		work <- project
+	}
+	close(work)
+	wg.Wait()
+	fmt.Printf("Listed enabled services of %d projects\n", len(r.enabledServices))
+}
+
+// serviceEnabledColumn reports whether rows carry the ServiceEnabled column.
+func (r *resourceManager) serviceEnabledColumn() bool {
+	return r.enabledServices != nil
+}
+
+// ServiceEnabled is the ServiceEnabled column: whether the API a permission belongs to is
+// enabled in the project of the row's resource. It is empty for orgs and folders, projects
+// whose services couldn't be listed, and services usable without enabling them.
+func (r *resourceManager) ServiceEnabled(row *Row, permission string) string {
+	enabled, ok := r.enabledServices[rowProject(row)]
+	if !ok || permission == "UNKNOWN" || platformPermissionPrefixes[permissionPrefix(permission)] {
+		return ""
+	}
+	return strconv.FormatBool(enabled[permissionService(permission)])
+}
+
+// serviceEnablementReport lists the services enabled in each project.
+func serviceEnablementReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Project", "EnabledServices", "Services"}
+	if !resman.serviceEnabledColumn() {
+		logerr.Printf("The service-enablement report needs --service-enablement\n")
+		return header, [][]string{}, nil
+	}
+	projects := make(map[string]bool, len(resman.enabledServices))
+	for project := range resman.enabledServices {
+		projects[project] = true
+	}
+	records := make([][]string, 0, len(projects))
+	for _, project := range sortedKeys(projects) {
+		services := sortedKeys(resman.enabledServices[project])
+		records = append(records, []string{project, strconv.Itoa(len(services)), strings.Join(services, " ")})
+	}
+	return header, records, nil
+}
+
+func init() {
+	registerEnricher("service-enablement", stageAnnotate, func(opts *Options) bool { return opts.ServiceEnablement },
+		func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+			resman.ResolveEnabledServices(rows)
+			return rows, nil
+		})
+	registerReport("service-enablement", serviceEnablementReport)
+	registerCollectorPermissions("service-enablement", "serviceusage.services.list")
+}
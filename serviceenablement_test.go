@@ -0,0 +1,43 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestServiceEnabled(t *testing.T) {
+	r := &resourceManager{enabledServices: map[string]map[string]bool{
+		"web": {"storage.googleapis.com": true, "sqladmin.googleapis.com": true},
+	}}
+	tests := []struct {
+		row        *Row
+		permission string
+		want       string
+	}{
+		{&Row{Type: "project", Name: "projects/web"}, "storage.buckets.get", "true"},
+		{&Row{Type: "project", Name: "projects/web"}, "compute.instances.get", "false"},
+		{&Row{Type: "project", Name: "projects/web"}, "cloudsql.instances.connect", "true"},
+		{&Row{Type: "project", Name: "projects/web"}, "storage: 4 permissions (incl. buckets.setIamPolicy)", "true"},
+		{&Row{Type: "project", Name: "projects/web"}, "resourcemanager.projects.get", ""},
+		{&Row{Type: "project", Name: "projects/web"}, "UNKNOWN", ""},
+		{&Row{Type: "bucket", Name: "//storage.googleapis.com/projects/_/buckets/logs", Parent: "projects/web"}, "storage.objects.get", "true"},
+		{&Row{Type: "project", Name: "projects/other"}, "storage.buckets.get", ""},
+		{&Row{Type: "folder", Name: "folders/2", Parent: "organizations/1"}, "storage.buckets.get", ""},
+	}
+	for _, test := range tests {
+		if got := r.ServiceEnabled(test.row, test.permission); got != test.want {
+			t.Errorf("ServiceEnabled(%s, %s) = %q, want %q", test.row.Name, test.permission, got, test.want)
+		}
+	}
+}
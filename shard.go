@@ -0,0 +1,92 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// shardKey picks the output file for a row. Rows of resources inside a project go with their
+// project, or when sharding by folder with the nearest folder above the project in tree, and
+// rows above the level sharded by (the org, and folders when sharding by project) share the
+// org's shard, orgKey, so nothing is dropped.
+func shardKey(row *Row, shardBy string, orgKey string, tree *resourceTree) string {
+	switch {
+	case row.Type == "project" && shardBy == "project":
+		if row.Name != "" {
+			return row.Name
+		}
+		return fmt.Sprintf("projects/%s", row.Resource)
+	case shardBy == "project" && strings.HasPrefix(row.Parent, "projects/") && row.Type != "project":
+		return row.Parent
+	case row.Type == "folder" && shardBy == "folder":
+		return row.Resource
+	case row.Type == "folder" || row.Type == "organization":
+		return orgKey
+	case shardBy == "folder" && row.Parent != "":
+		for _, name := range append([]string{row.Parent}, tree.ancestors(row.Parent)...) {
+			if strings.HasPrefix(name, "folders/") {
+				return name
+			}
+			if strings.HasPrefix(name, "organizations/") {
+				return orgKey
+			}
+		}
+		return row.Parent
+	}
+	return fmt.Sprintf("%ss/%s", row.Type, row.Resource)
+}
+
+func shardFilename(key string) string {
+	return unsafeFilenameChars.ReplaceAllString(key, "_") + ".csv"
+}
+
+func writeShards(dir string, shardBy string, rows []*Row, resman *resourceManager) error {
+	if !isGcsPath(dir) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return errors.New(fmt.Sprintf("Unable to create shard directory %s: %v", dir, err))
+		}
+	}
+	defer timeTrack(fmt.Sprintf("Printing CSV shards %s", dir))()
+	orgKey := fmt.Sprintf("organizations/%s", resman.orgId)
+	if resman.standaloneProject != "" {
+		orgKey = fmt.Sprintf("projects/%s", resman.standaloneProject)
+	}
+	tree := newResourceTree(rows, resman)
+	shards := make(map[string][]*Row)
+	for _, row := range rows {
+		key := shardKey(row, shardBy, orgKey, tree)
+		shards[key] = append(shards[key], row)
+	}
+	keys := make([]string, 0, len(shards))
+	for k := range shards {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := writeCsvFile(outputPath(dir, shardFilename(k)), shards[k], resman); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Wrote %d shards to %s\n", len(keys), dir)
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestShardKey(t *testing.T) {
+	const orgKey = "organizations/1"
+	tree := newResourceTree([]*Row{
+		{Name: "folders/2", Parent: "organizations/1", Type: "folder"},
+		{Name: "folders/3", Parent: "folders/2", Type: "folder"},
+		{Name: "projects/p1", Parent: "folders/3", Type: "project"},
+		{Name: "projects/top", Parent: "organizations/1", Type: "project"},
+	}, &resourceManager{hierarchy: map[string]hierarchyNode{
+		// a project without bindings of its own, known from the crawl
+		"projects/quiet": {Parent: "folders/2"},
+	}})
+	tests := []struct {
+		row     Row
+		shardBy string
+		want    string
+	}{
+		{Row{Type: "project", Resource: "Project One", Name: "projects/p1"}, "project", "projects/p1"},
+		{Row{Type: "project", Resource: "p1"}, "project", "projects/p1"},
+		{Row{Type: "bucket", Resource: "b", Parent: "projects/p1"}, "project", "projects/p1"},
+		{Row{Type: "folder", Resource: "folders/2", Parent: "organizations/1"}, "project", orgKey},
+		{Row{Type: "organization", Resource: "1"}, "project", orgKey},
+		{Row{Type: "folder", Resource: "folders/2", Parent: "organizations/1"}, "folder", "folders/2"},
+		{Row{Type: "folder", Resource: "folders/3", Parent: "folders/2"}, "folder", "folders/3"},
+		{Row{Type: "organization", Resource: "1"}, "folder", orgKey},
+		{Row{Type: "project", Resource: "p1", Name: "projects/p1", Parent: "folders/3"}, "folder", "folders/3"},
+		{Row{Type: "project", Resource: "top", Name: "projects/top", Parent: "organizations/1"}, "folder", orgKey},
+		{Row{Type: "project", Resource: "p9", Name: "projects/p9"}, "folder", "projects/p9"},
+		{Row{Type: "bucket", Resource: "b", Parent: "projects/p1"}, "folder", "folders/3"},
+		{Row{Type: "bucket", Resource: "q", Parent: "projects/quiet"}, "folder", "folders/2"},
+		{Row{Type: "bucket", Resource: "t", Parent: "projects/top"}, "folder", orgKey},
+		{Row{Type: "bucket", Resource: "b", Parent: "projects/elsewhere"}, "folder", "projects/elsewhere"},
+	}
+	for _, tt := range tests {
+		row := tt.row
+		if got := shardKey(&row, tt.shardBy, orgKey, tree); got != tt.want {
+			t.Errorf("shardKey(%s %s, %s) = %q, want %q", row.Type, row.Resource, tt.shardBy, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// hostProjectAttempts is how many crawled projects are tried for listing Shared VPC hosts,
+// which has to go through a project with the Compute Engine API enabled.
+const hostProjectAttempts = 5
+
+// xpnAttachment is a service project attached to a Shared VPC host project.
+type xpnAttachment struct {
+	Host           string
+	ServiceProject string
+}
+
+var numericProjectId = regexp.MustCompile(`^[0-9]+$`)
+
+func (r *resourceManager) computeService() (*compute.Service, error) {
+	if r.compute != nil {
+		return r.compute, nil
+	}
+	var clientOptions []option.ClientOption
+	if r.client != nil {
+		clientOptions = append(clientOptions, option.WithHTTPClient(r.client))
+	}
+	service, err := compute.NewService(r.ctx, clientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	r.compute = service
+	return service, nil
+}
+
+// sharedVpcHosts lists the Shared VPC host projects of the org, asking through the first
+// crawled projects until one answers.
+func (r *resourceManager) sharedVpcHosts(service *compute.Service) ([]string, error) {
+	projects := make([]string, 0, len(r.projectNumbers))
+	for id, number := range r.projectNumbers {
+		if number != "" {
+			projects = append(projects, id)
+		}
+	}
+	sort.Strings(projects)
+	if len(projects) > hostProjectAttempts {
+		projects = projects[:hostProjectAttempts]
+	}
+	err := errors.New("no project to list Shared VPC hosts through")
+	for _, project := range projects {
+		hosts := make([]string, 0)
+		err = service.Projects.ListXpnHosts(project, &compute.ProjectsListXpnHostsRequest{Organization: r.orgId}).
+			Pages(r.ctx, func(page *compute.XpnHostList) error {
+				for _, p := range page.Items {
+					hosts = append(hosts, p.Name)
+				}
+				return nil
+			})
+		if err == nil {
+			return hosts, nil
+		}
+	}
+	return nil, err
+}
+
+func (p *Policy) convertCompute(policy *compute.Policy) {
+	p.raw = policy
+	p.Etag = policy.Etag
+	p.Bindings = make([]*Binding, len(policy.Bindings))
+	for i, b := range policy.Bindings {
+		p.Bindings[i] = &Binding{Members: b.Members, Role: b.Role}
+		if b.Condition != nil {
+			p.Bindings[i].Condition = &Expr{
+				Description: b.Condition.Description,
+				Expression:  b.Condition.Expression,
+				Location:    b.Condition.Location,
+				Title:       b.Condition.Title,
+			}
+		}
+	}
+}
+
+// addSubnetPolicies adds the policies of a host project's subnets, where compute.networkUser
+// is usually granted to service projects instead of on the whole host.
+func (r *resourceManager) addSubnetPolicies(service *compute.Service, host string, rows *[]*Row) error {
+	return service.Subnetworks.AggregatedList(host).Fields("nextPageToken,items/*/subnetworks(name,region)").
+		Pages(r.ctx, func(page *compute.SubnetworkAggregatedList) error {
+			for _, scoped := range page.Items {
+				for _, subnet := range scoped.Subnetworks {
+					region := subnet.Region[strings.LastIndex(subnet.Region, "/")+1:]
+					base := Row{
+						Resource:    subnet.Name,
+						Type:        "subnetwork",
+						Parent:      fmt.Sprintf("projects/%s", host),
+						Name:        fmt.Sprintf("//compute.googleapis.com/projects/%s/regions/%s/subnetworks/%s", host, region, subnet.Name),
+						DisplayName: subnet.Name,
+					}
+					policy, err := service.Subnetworks.GetIamPolicy(host, region, subnet.Name).Context(r.ctx).Do()
+					if err != nil {
+						logerr.Printf("Unable to get policy of subnetwork %s in %s: %v\n", subnet.Name, host, err)
+						r.markUnreadable(base, classifyError(base.Name, err))
+						continue
+					}
+					p := &Policy{}
+					p.convertCompute(policy)
+					r.resourcesScanned++
+					r.addPolicy(p, rows, base)
+				}
+			}
+			return nil
+		})
+}
+
+// GetSharedVpcRows records which service projects are attached to each Shared VPC host in the
+// org, and collects the policies of the hosts' subnets; --resource-policies leaves them to it.
+func (r *resourceManager) GetSharedVpcRows() (*[]*Row, error) {
+	defer beginSpan("collector", "shared-vpc")()
+	rows := make([]*Row, 0)
+	service, err := r.computeService()
+	if err != nil {
+		return &rows, err
+	}
+	hosts, err := r.sharedVpcHosts(service)
+	if err != nil {
+		logerr.Printf("Unable to list Shared VPC host projects: %v\n", err)
+		return &rows, nil
+	}
+	tui.Start("shared-vpc", len(hosts))
+	for _, host := range hosts {
+		tui.Step("shared-vpc")
+		err := service.Projects.GetXpnResources(host).Pages(r.ctx, func(page *compute.ProjectsGetXpnResources) error {
+			for _, res := range page.Resources {
+				if res.Type != "PROJECT" {
+					continue
+				}
+				id := res.Id
+				if numericProjectId.MatchString(id) {
+					if resolved := r.ProjectIdForNumber(id); resolved != "" {
+						id = resolved
+					}
+				}
+				r.xpnAttachments = append(r.xpnAttachments, &xpnAttachment{Host: host, ServiceProject: id})
+			}
+			return nil
+		})
+		if err != nil {
+			logerr.Printf("Unable to list service projects of Shared VPC host %s: %v\n", host, err)
+		}
+		if err := r.addSubnetPolicies(service, host, &rows); err != nil {
+			logerr.Printf("Unable to list subnetworks of Shared VPC host %s: %v\n", host, err)
+		}
+	}
+	fmt.Printf("Found %d Shared VPC hosts with %d service projects\n", len(hosts), len(r.xpnAttachments))
+	return &rows, nil
+}
+
+// sharedVpcReport lists each service project attached to a Shared VPC host with the members
+// from that service project holding compute.networkUser on the host or one of its subnets, and
+// the members from nowhere attached (users, groups, other projects' service accounts) that can
+// use the host's networks from any of its service projects.
+func sharedVpcReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"HostProject", "ServiceProject", "NetworkUsers", "OtherNetworkUsers"}
+	if !resman.sharedVpc {
+		logerr.Printf("The shared-vpc report needs --shared-vpc\n")
+		return header, [][]string{}, nil
+	}
+	attached := make(map[string]map[string]bool)
+	for _, a := range resman.xpnAttachments {
+		if attached[a.Host] == nil {
+			attached[a.Host] = make(map[string]bool)
+		}
+		attached[a.Host][a.ServiceProject] = true
+	}
+	// host project to service project, "" for none attached, to members
+	users := make(map[string]map[string]map[string]bool)
+	for _, row := range rows {
+		if row.Role != "roles/compute.networkUser" {
+			continue
+		}
+		host := ""
+		switch {
+		case row.Type == "project":
+			host = strings.TrimPrefix(row.Name, "projects/")
+		case strings.HasPrefix(row.Parent, "projects/"):
+			host = strings.TrimPrefix(row.Parent, "projects/")
+		}
+		project := row.MemberProject
+		if !attached[host][project] {
+			project = ""
+		}
+		if users[host] == nil {
+			users[host] = make(map[string]map[string]bool)
+		}
+		if users[host][project] == nil {
+			users[host][project] = make(map[string]bool)
+		}
+		users[host][project][row.Member] = true
+	}
+	attachments := append([]*xpnAttachment{}, resman.xpnAttachments...)
+	sort.Slice(attachments, func(i, j int) bool {
+		if attachments[i].Host != attachments[j].Host {
+			return attachments[i].Host < attachments[j].Host
+		}
+		return attachments[i].ServiceProject < attachments[j].ServiceProject
+	})
+	records := make([][]string, len(attachments))
+	for i, a := range attachments {
+		records[i] = []string{a.Host, a.ServiceProject,
+			strings.Join(sortedKeys(users[a.Host][a.ServiceProject]), " "),
+			strings.Join(sortedKeys(users[a.Host][""]), " ")}
+	}
+	return header, records, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	registerReport("shared-vpc", sharedVpcReport)
+	registerCollectorPermissions("shared-vpc",
+		"compute.organizations.listHostProjects",
+		"compute.projects.get",
+		"compute.subnetworks.list",
+		"compute.subnetworks.getIamPolicy")
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// snapshotRolePermissions looks a row's role up in the role cache saved with a snapshot,
+// trying the same uris GetRole does.
+func snapshotRolePermissions(snap *Snapshot, row *Row) ([]string, bool) {
+	if row.Type == "project" || row.Type == "organization" {
+		if permissions, ok := snap.Roles[fmt.Sprintf("%ss/%s/%s", row.Type, row.Resource, row.Role)]; ok {
+			return permissions, true
+		}
+	}
+	permissions, ok := snap.Roles[row.Role]
+	return permissions, ok
+}
+
+// matchesResource accepts the resource the way it appears in any column: the canonical
+// name, the full resource name, or the legacy Resource value.
+func matchesResource(row *Row, resource string) bool {
+	resource = strings.TrimPrefix(resource, fullResourceNamePrefix)
+	return row.Name == resource || row.Resource == resource
+}
+
+// simulateRemoveBinding reports what a member would lose if one of their bindings were removed,
+// using the latest snapshot of the org (or the given one). A permission is kept when another binding
+// of the member grants it on the same resource or an ancestor, whose policies are inherited.
+// Access through groups the member belongs to is not visible in the policies and not considered.
+func simulateRemoveBinding(storeDir string, orgId string, snapshotId string, member string, role string, resource string) error {
+	if member == "" || role == "" || resource == "" {
+		return errors.New("--member, --role and --resource are all required")
+	}
+	snap, err := openSnapshot(storeDir, orgId, snapshotId)
+	if err != nil {
+		return err
+	}
+
+	parents := make(map[string]string)
+	for _, row := range snap.Rows {
+		if row.Name != "" && row.Parent != "" {
+			parents[row.Name] = row.Parent
+		}
+	}
+	var removed *Row
+	others := make([]*Row, 0)
+	for _, row := range snap.Rows {
+		if row.Member != member {
+			continue
+		}
+		if row.Role == role && matchesResource(row, resource) {
+			removed = row
+			continue
+		}
+		others = append(others, row)
+	}
+	if removed == nil {
+		return errors.New(fmt.Sprintf("%s has no binding to %s on %s in snapshot %s", member, role, resource, snap.Id))
+	}
+	permissions, ok := snapshotRolePermissions(snap, removed)
+	if !ok {
+		return errors.New(fmt.Sprintf("Permissions of %s are not in snapshot %s", role, snap.Id))
+	}
+
+	// the resource and everything above it
+	inherited := make(map[string]bool)
+	for name := removed.Name; name != ""; name = parents[name] {
+		inherited[name] = true
+	}
+	keptVia := make(map[string]string)
+	heldElsewhere := make(map[string]string)
+	for _, row := range others {
+		rolePermissions, ok := snapshotRolePermissions(snap, row)
+		if !ok {
+			logerr.Printf("Permissions of %s are not in snapshot %s, ignoring its binding on %s\n", row.Role, snap.Id, row.Name)
+			continue
+		}
+		via := fmt.Sprintf("%s on %s", row.Role, row.Name)
+		for _, p := range rolePermissions {
+			if inherited[row.Name] {
+				if _, ok := keptVia[p]; !ok {
+					keptVia[p] = via
+				}
+			} else if _, ok := heldElsewhere[p]; !ok {
+				heldElsewhere[p] = via
+			}
+		}
+	}
+
+	sort.Strings(permissions)
+	kept := make([]string, 0)
+	lostHere := make([]string, 0)
+	lost := make([]string, 0)
+	for _, p := range permissions {
+		switch {
+		case keptVia[p] != "":
+			kept = append(kept, fmt.Sprintf("%s (via %s)", p, keptVia[p]))
+		case heldElsewhere[p] != "":
+			lostHere = append(lostHere, fmt.Sprintf("%s (still held via %s)", p, heldElsewhere[p]))
+		default:
+			lost = append(lost, p)
+		}
+	}
+	fmt.Printf("Removing %s from %s on %s (snapshot %s) affects %d permissions\n",
+		role, member, removed.Name, snap.Id, len(permissions))
+	fmt.Printf("Lost entirely: %d\n", len(lost))
+	for _, p := range lost {
+		fmt.Printf("- %s\n", p)
+	}
+	fmt.Printf("Lost on %s, still held on other resources: %d\n", removed.Name, len(lostHere))
+	for _, p := range lostHere {
+		fmt.Printf("~ %s\n", p)
+	}
+	fmt.Printf("Kept through other bindings on %s or its ancestors: %d\n", removed.Name, len(kept))
+	for _, p := range kept {
+		fmt.Printf("= %s\n", p)
+	}
+	return nil
+}
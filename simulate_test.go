@@ -0,0 +1,92 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureStdout returns what f prints to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	out, err := ioutil.TempFile(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	saved := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = saved }()
+	f()
+	data, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSimulateRemoveBinding(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSnapshotStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	member := "user:a@example.com"
+	snap := &Snapshot{Id: "20200102T030405Z-1", Created: time.Now(), OrgId: "1",
+		Rows: []*Row{
+			{Resource: "1", Type: "organization", Name: "organizations/1", Member: "user:b@example.com", Role: "roles/owner"},
+			{Resource: "2", Type: "folder", Name: "folders/2", Parent: "organizations/1", Member: member, Role: "roles/viewer"},
+			{Resource: "p", Type: "project", Name: "projects/p", Parent: "folders/2", Member: member, Role: "roles/editor"},
+			{Resource: "q", Type: "project", Name: "projects/q", Parent: "organizations/1", Member: member, Role: "roles/custom"},
+		},
+		Roles: map[string][]string{
+			"roles/owner":             {"a.get", "a.set", "b.get", "c.get"},
+			"roles/viewer":            {"a.get"},
+			"roles/editor":            {"c.get", "a.set", "b.get", "a.get"},
+			"projects/q/roles/custom": {"b.get"},
+		},
+	}
+	if err := store.Save(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	var simErr error
+	out := captureStdout(t, func() {
+		simErr = simulateRemoveBinding(dir, "1", "", member, "roles/editor", "//cloudresourcemanager.googleapis.com/projects/p")
+	})
+	if simErr != nil {
+		t.Fatal(simErr)
+	}
+	for _, want := range []string{
+		"affects 4 permissions",
+		"Lost entirely: 2\n- a.set\n- c.get\n",
+		"~ b.get (still held via roles/custom on projects/q)\n",
+		"= a.get (via roles/viewer on folders/2)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("simulateRemoveBinding printed:\n%s\nwant it to contain %q", out, want)
+		}
+	}
+
+	if err := simulateRemoveBinding(dir, "1", "", member, "roles/owner", "p"); err == nil {
+		t.Errorf("simulating the removal of a binding the member doesn't have succeeded")
+	}
+	if err := simulateRemoveBinding(dir, "1", "", member, "", "p"); err == nil {
+		t.Errorf("simulating without --role succeeded")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupCollapsesConcurrentCalls(t *testing.T) {
+	var g flightGroup
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "viewer", nil
+	}
+	var wg sync.WaitGroup
+	results := make([]interface{}, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = g.Do("roles/viewer", fn)
+		}(i)
+	}
+	// like x/sync's tests, give every caller time to join the call in flight
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	for i, r := range results {
+		if r != "viewer" {
+			t.Errorf("result %d = %v, want viewer", i, r)
+		}
+	}
+	if c := atomic.LoadInt32(&calls); c != 1 {
+		t.Errorf("fn ran %d times, want 1", c)
+	}
+}
+
+func TestFlightGroupForgetsFinishedCalls(t *testing.T) {
+	var g flightGroup
+	failure := errors.New("denied")
+	if _, err := g.Do("k", func() (interface{}, error) { return nil, failure }); err != failure {
+		t.Errorf("Do() error = %v, want %v", err, failure)
+	}
+	val, err := g.Do("k", func() (interface{}, error) { return 1, nil })
+	if err != nil || val != 1 {
+		t.Errorf("Do() after a failed call = %v, %v, want 1", val, err)
+	}
+	if len(g.calls) != 0 {
+		t.Errorf("calls left in flight: %d", len(g.calls))
+	}
+}
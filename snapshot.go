@@ -0,0 +1,378 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const snapshotExt = ".json.gz"
+
+type Snapshot struct {
+	Id      string              `json:"id"`
+	Created time.Time           `json:"created"`
+	OrgId   string              `json:"orgId"`
+	Rows    []*Row              `json:"rows"`
+	Etags   map[string]string   `json:"etags,omitempty"`
+	Roles   map[string][]string `json:"roles,omitempty"`
+	// launch stage of the roles in Roles that are deprecated, disabled, or deleted (see
+	// roleStage), so the deprecated-roles report still works on roles reused by --incremental
+	RoleStages map[string]string `json:"roleStages"`
+}
+
+type snapshotStore struct {
+	dir string
+}
+
+func NewSnapshotStore(dir string) (*snapshotStore, error) {
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to find home directory for snapshot store: %v", err))
+		}
+		dir = filepath.Join(home, ".policygopher", "snapshots")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to create snapshot store %s: %v", dir, err))
+	}
+	return &snapshotStore{dir: dir}, nil
+}
+
+func (s *snapshotStore) path(id string) string {
+	return filepath.Join(s.dir, id+snapshotExt)
+}
+
+func (s *snapshotStore) Save(snap *Snapshot) error {
+	f, err := createAtomic(s.path(snap.Id), 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	zw := gzip.NewWriter(f)
+	if err := json.NewEncoder(zw).Encode(snap); err != nil {
+		return errors.New(fmt.Sprintf("Error encoding snapshot %s: %v", snap.Id, err))
+	}
+	if err := zw.Close(); err != nil {
+		return errors.New(fmt.Sprintf("Error compressing snapshot %s: %v", snap.Id, err))
+	}
+	return f.Commit()
+}
+
+// errUnknownSnapshot is returned by Load for an id that can't name a snapshot in the store, such
+// as one reaching outside it with a path separator or "..".
+var errUnknownSnapshot = errors.New("no such snapshot in the store")
+
+func (s *snapshotStore) Load(id string) (*Snapshot, error) {
+	if id == "" || strings.ContainsAny(id, `/\:`) || strings.Contains(id, "..") {
+		return nil, fmt.Errorf("%w: %q", errUnknownSnapshot, id)
+	}
+	f, err := os.Open(s.path(id))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to open snapshot %s: %v", id, err))
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to decompress snapshot %s: %v", id, err))
+	}
+	snap := &Snapshot{}
+	if err := json.NewDecoder(zr).Decode(snap); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to decode snapshot %s: %v", id, err))
+	}
+	return snap, nil
+}
+
+// List returns snapshot ids oldest first; ids are UTC timestamps so they sort chronologically.
+func (s *snapshotStore) List() ([]string, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0)
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, snapshotExt))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Has reports whether id is one of the snapshots List returns.
+func (s *snapshotStore) Has(id string) (bool, error) {
+	ids, err := s.List()
+	if err != nil {
+		return false, err
+	}
+	for _, stored := range ids {
+		if stored == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListForOrg returns the snapshot ids of one org, oldest first.
+func (s *snapshotStore) ListForOrg(orgId string) ([]string, error) {
+	ids, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	orgIds := make([]string, 0)
+	for _, id := range ids {
+		if snapshotOrgId(id) == orgId {
+			orgIds = append(orgIds, id)
+		}
+	}
+	return orgIds, nil
+}
+
+// LatestForOrg returns the most recent snapshot of an org, or nil if there is none.
+func (s *snapshotStore) LatestForOrg(orgId string) (*Snapshot, error) {
+	ids, err := s.ListForOrg(orgId)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return s.Load(ids[len(ids)-1])
+}
+
+// errSeveralOrgs is returned by LatestOf when no org is given and the store holds snapshots of more than one.
+var errSeveralOrgs = errors.New("snapshots of several orgs in the store")
+
+// LatestOf returns the most recent snapshot of orgId, or when orgId is empty of the only org the
+// store has snapshots of, so one org's snapshot is never taken for another's. It returns nil
+// when there is none.
+func (s *snapshotStore) LatestOf(orgId string) (*Snapshot, error) {
+	if orgId == "" {
+		ids, err := s.List()
+		if err != nil {
+			return nil, err
+		}
+		orgs := make(map[string]bool)
+		for _, id := range ids {
+			orgs[snapshotOrgId(id)] = true
+		}
+		if len(orgs) > 1 {
+			names := make([]string, 0, len(orgs))
+			for org := range orgs {
+				names = append(names, org)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("%w (%s), choose one with --org", errSeveralOrgs, strings.Join(names, ", "))
+		}
+		for org := range orgs {
+			orgId = org
+		}
+		if orgId == "" {
+			return nil, nil
+		}
+	}
+	return s.LatestForOrg(orgId)
+}
+
+// Snapshot ids are <UTC timestamp>-<org id>, or <UTC timestamp>-project-<project id> without an org.
+func newSnapshotId(orgId string) string {
+	return fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405Z"), orgId)
+}
+
+func snapshotOrgId(id string) string {
+	if i := strings.Index(id, "-"); i >= 0 {
+		return id[i+1:]
+	}
+	return ""
+}
+
+func newSnapshot(resman *resourceManager, rows []*Row) *Snapshot {
+	return &Snapshot{
+		Id:         newSnapshotId(resman.Scope()),
+		Created:    time.Now().UTC(),
+		OrgId:      resman.Scope(),
+		Rows:       rows,
+		Etags:      resman.etags,
+		Roles:      resman.RolePermissionsCache(),
+		RoleStages: resman.RoleStages(),
+	}
+}
+
+func useLatestSnapshot(store *snapshotStore, resman *resourceManager) error {
+	snap, err := store.LatestForOrg(resman.Scope())
+	if err != nil {
+		return err
+	}
+	if snap == nil {
+		fmt.Printf("No previous snapshot for org %s in %s, collecting everything\n", resman.Scope(), store.dir)
+		return nil
+	}
+	fmt.Printf("Using snapshot %s as the incremental baseline\n", snap.Id)
+	resman.SetBaseline(snap)
+	return nil
+}
+
+func saveSnapshot(opts *Options) error {
+	ctx := context.Background()
+	store, err := NewSnapshotStore(opts.StoreDir)
+	if err != nil {
+		return err
+	}
+	resman, err := newResourceManagerFromOptions(ctx, opts, nil)
+	if err != nil {
+		return err
+	}
+	if opts.Incremental {
+		if err := useLatestSnapshot(store, resman); err != nil {
+			return err
+		}
+	}
+	allow, err := loadAllowlist(opts.Allowlist)
+	if err != nil {
+		return err
+	}
+	if resman.riskWeights, err = loadRiskWeights(opts.RiskWeights); err != nil {
+		return err
+	}
+	allRows, err := resman.GetAllPolicyRows()
+	if err != nil {
+		return err
+	}
+	rows, err := enrichRows(opts, *allRows, resman)
+	if err != nil {
+		return err
+	}
+	snap, err := storeSnapshot(store, resman, rows, opts.NotifyWebhook, allow)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved snapshot %s with %d bindings to %s\n", snap.Id, len(snap.Rows), store.dir)
+	return nil
+}
+
+// storeSnapshot saves the rows as a new snapshot and, when a webhook is given, notifies it
+// of unapproved high-risk bindings added since the previous snapshot of the same org. A crawl
+// cut short by --max-api-calls isn't saved, as diffs against it would report false removals,
+// and neither is a second snapshot of the org within the same second, which would replace the
+// first under the same id.
+func storeSnapshot(store *snapshotStore, resman *resourceManager, rows []*Row, webhook string, allow *allowlist) (*Snapshot, error) {
+	if err := apiCalls.Exceeded(); err != nil {
+		return nil, err
+	}
+	prev, err := store.LatestForOrg(resman.Scope())
+	if err != nil {
+		return nil, err
+	}
+	snap := newSnapshot(resman, rows)
+	if _, err := os.Stat(store.path(snap.Id)); err == nil {
+		return nil, errors.New(fmt.Sprintf("Snapshot %s already exists, snapshots of an org must be saved at least a second apart", snap.Id))
+	}
+	if err := store.Save(snap); err != nil {
+		return nil, err
+	}
+	if webhook != "" {
+		if err := notifyNewBindings(webhook, prev, snap, allow); err != nil {
+			return snap, err
+		}
+	}
+	return snap, nil
+}
+
+func listSnapshots(storeDir string) error {
+	store, err := NewSnapshotStore(storeDir)
+	if err != nil {
+		return err
+	}
+	ids, err := store.List()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		snap, err := store.Load(id)
+		if err != nil {
+			logerr.Printf("%v\n", err)
+			continue
+		}
+		fmt.Printf("%s\torg %s\t%d bindings\n", snap.Id, snap.OrgId, len(snap.Rows))
+	}
+	return nil
+}
+
+// diffSnapshots compares two snapshots, defaulting to the two most recent of the latest snapshot's org.
+// Either can be the path of an export instead, as read by --input.
+// Added bindings the allowlist accepts are counted but not listed.
+func diffSnapshots(storeDir string, oldId string, newId string, allowlistFile string) error {
+	store, err := NewSnapshotStore(storeDir)
+	if err != nil {
+		return err
+	}
+	allow, err := loadAllowlist(allowlistFile)
+	if err != nil {
+		return err
+	}
+	if oldId == "" || newId == "" {
+		ids, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			if ids, err = store.ListForOrg(snapshotOrgId(ids[len(ids)-1])); err != nil {
+				return err
+			}
+		}
+		if len(ids) < 2 {
+			return errors.New(fmt.Sprintf("At least two snapshots are needed to diff, found %d in %s", len(ids), store.dir))
+		}
+		if oldId == "" {
+			oldId = ids[len(ids)-2]
+		}
+		if newId == "" {
+			newId = ids[len(ids)-1]
+		}
+	}
+	oldSnap, err := loadSnapshotOrExport(store, oldId)
+	if err != nil {
+		return err
+	}
+	newSnap, err := loadSnapshotOrExport(store, newId)
+	if err != nil {
+		return err
+	}
+	added, removed := DiffRows(oldSnap.Rows, newSnap.Rows)
+	unapproved := allow.Unapproved(added)
+	fmt.Printf("Diff %s -> %s: %d added, %d removed\n", oldId, newId, len(added), len(removed))
+	if len(unapproved) < len(added) {
+		fmt.Printf("%d added bindings are allowlisted and not shown\n", len(added)-len(unapproved))
+		added = unapproved
+	}
+	for _, row := range removed {
+		fmt.Printf("- %s\n", diffLine(row))
+	}
+	for _, row := range added {
+		fmt.Printf("+ %s\n", diffLine(row))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStoreSnapshotRefusesExceededBudget(t *testing.T) {
+	store, err := NewSnapshotStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := apiCalls
+	apiCalls = &apiCallCounter{counts: make(map[string]int), max: 10, total: 10, refused: 3}
+	defer func() { apiCalls = saved }()
+	resman := &resourceManager{orgId: "1"}
+	if _, err := storeSnapshot(store, resman, []*Row{{Resource: "1", Type: "organization"}}, "", nil); err == nil {
+		t.Errorf("storeSnapshot saved a crawl cut short by the API call budget")
+	}
+	if ids, _ := store.List(); len(ids) != 0 {
+		t.Errorf("store holds %v, want nothing", ids)
+	}
+}
+
+func TestStoreSnapshotRefusesSameId(t *testing.T) {
+	store, err := NewSnapshotStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resman := &resourceManager{orgId: "1"}
+	first, err := storeSnapshot(store, resman, []*Row{{Resource: "1", Type: "organization", Member: "user:a@example.com"}}, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a second save within the same second gets the same id and must not replace the first
+	second, err := storeSnapshot(store, resman, nil, "", nil)
+	if err == nil && second.Id == first.Id {
+		t.Fatalf("storeSnapshot replaced snapshot %s", first.Id)
+	}
+	if err != nil && !strings.Contains(err.Error(), "already exists") {
+		t.Fatal(err)
+	}
+	kept, err := store.Load(first.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept.Rows) != 1 {
+		t.Errorf("snapshot %s has %d rows after a second save, want 1", first.Id, len(kept.Rows))
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const defaultSortBy = "resource,member,role"
+
+// sortFields are the row fields --sort-by accepts. Permissions are always sorted within a binding.
+var sortFields = map[string]func(row *Row, resman *resourceManager) string{
+	"resource": func(row *Row, resman *resourceManager) string { return resman.ResourceColumn(row) },
+	"type":     func(row *Row, resman *resourceManager) string { return row.Type },
+	"member":   func(row *Row, resman *resourceManager) string { return row.Member },
+	"role":     func(row *Row, resman *resourceManager) string { return row.Role },
+}
+
+// parseSortBy returns the sort fields in order, or none when rows should keep the order
+// they were collected in.
+func parseSortBy(sortBy string) ([]string, error) {
+	if sortBy == "" || sortBy == "none" {
+		return nil, nil
+	}
+	fields := strings.Split(sortBy, ",")
+	for i, f := range fields {
+		fields[i] = strings.TrimSpace(f)
+		if _, ok := sortFields[fields[i]]; !ok {
+			return nil, errors.New(fmt.Sprintf("Unknown --sort-by field %s, expected resource, type, member, or role", fields[i]))
+		}
+	}
+	return fields, nil
+}
+
+// sortRows orders rows by the given fields, breaking ties on the row key so two exports
+// of an unchanged org come out identical.
+func sortRows(rows []*Row, fields []string, resman *resourceManager) {
+	if len(fields) == 0 {
+		return
+	}
+	less := rowLess(fields, resman)
+	sort.SliceStable(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+}
+
+// rowLess is the order sortRows puts rows in. With no fields no row comes before another.
+func rowLess(fields []string, resman *resourceManager) func(a *Row, b *Row) bool {
+	return func(a *Row, b *Row) bool {
+		if len(fields) == 0 {
+			return false
+		}
+		for _, f := range fields {
+			x, y := sortFields[f](a, resman), sortFields[f](b, resman)
+			if x != y {
+				return x < y
+			}
+		}
+		return a.Key() < b.Key()
+	}
+}
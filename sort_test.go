@@ -0,0 +1,98 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"google.golang.org/api/iam/v1"
+	"reflect"
+	"testing"
+)
+
+func TestParseSortBy(t *testing.T) {
+	for _, s := range []string{"", "none"} {
+		if fields, err := parseSortBy(s); err != nil || fields != nil {
+			t.Errorf("parseSortBy(%q) = %v, %v, want no fields", s, fields, err)
+		}
+	}
+	fields, err := parseSortBy("member, role")
+	if err != nil || !reflect.DeepEqual(fields, []string{"member", "role"}) {
+		t.Errorf("parseSortBy(\"member, role\") = %v, %v", fields, err)
+	}
+	if _, err := parseSortBy("resource,risk"); err == nil {
+		t.Errorf("parseSortBy accepted an unknown field")
+	}
+}
+
+func TestSortRows(t *testing.T) {
+	resman := &resourceManager{}
+	rows := []*Row{
+		{Resource: "q", Type: "project", Member: "user:a@example.com", Role: "roles/viewer"},
+		{Resource: "p", Type: "project", Member: "user:b@example.com", Role: "roles/owner"},
+		{Resource: "p", Type: "project", Member: "user:a@example.com", Role: "roles/viewer"},
+		{Resource: "p", Type: "project", Member: "user:a@example.com", Role: "roles/editor"},
+	}
+	keys := func(rows []*Row) []string {
+		keys := make([]string, len(rows))
+		for i, row := range rows {
+			keys[i] = row.Key()
+		}
+		return keys
+	}
+	unsorted := keys(rows)
+
+	sortRows(rows, nil, resman)
+	if !reflect.DeepEqual(keys(rows), unsorted) {
+		t.Errorf("sortRows without fields reordered rows to %v", keys(rows))
+	}
+	fields, _ := parseSortBy(defaultSortBy)
+	sortRows(rows, fields, resman)
+	want := []string{
+		"p,project,user:a@example.com,roles/editor",
+		"p,project,user:a@example.com,roles/viewer",
+		"p,project,user:b@example.com,roles/owner",
+		"q,project,user:a@example.com,roles/viewer",
+	}
+	if !reflect.DeepEqual(keys(rows), want) {
+		t.Errorf("sorted by %s:\n%v\nwant:\n%v", defaultSortBy, keys(rows), want)
+	}
+	// ties on member fall back to the row key
+	sortRows(rows, []string{"member"}, resman)
+	want = []string{
+		"p,project,user:a@example.com,roles/editor",
+		"p,project,user:a@example.com,roles/viewer",
+		"q,project,user:a@example.com,roles/viewer",
+		"p,project,user:b@example.com,roles/owner",
+	}
+	if !reflect.DeepEqual(keys(rows), want) {
+		t.Errorf("sorted by member:\n%v\nwant:\n%v", keys(rows), want)
+	}
+}
+
+func TestRowPermissionsSorted(t *testing.T) {
+	resman := &resourceManager{bindingRoles: make(map[string]*iam.Role)}
+	row := &Row{Resource: "p", Type: "project", Member: "user:a@example.com", Role: "roles/custom"}
+	role := &iam.Role{Name: row.Role, IncludedPermissions: []string{"b.get", "c.get", "a.get"}}
+	resman.bindingRoles[bindingRoleKey(row)] = role
+	permissions, err := resman.rowPermissions(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(permissions, []string{"a.get", "b.get", "c.get"}) {
+		t.Errorf("rowPermissions = %v, want them sorted", permissions)
+	}
+	if !reflect.DeepEqual(role.IncludedPermissions, []string{"b.get", "c.get", "a.get"}) {
+		t.Errorf("sorting changed the cached role to %v", role.IncludedPermissions)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// newRunId names one invocation of the export. Every org a config file lists shares it, so
+// rows from the per-org files can be concatenated and still traced back to the run.
+func newRunId() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%x", time.Now().UTC().Format("20060102T150405Z"), b)
+}
+
+// sourceColumns reports whether rows carry the RunId and Source columns of --source-columns.
+func (r *resourceManager) sourceColumns() bool {
+	return r.runId != ""
+}
+
+// bindingIndexColumn is the BindingIndex column, empty for rows read without their policy's etag.
+func bindingIndexColumn(row *Row) string {
+	if row.PolicyEtag == "" {
+		return ""
+	}
+	return strconv.Itoa(row.BindingIndex)
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"google.golang.org/api/iam/v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "provenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	resman := &resourceManager{orgId: "1", etags: make(map[string]string), bindingRoles: make(map[string]*iam.Role), provenance: true}
+	rows := make([]*Row, 0)
+	resman.addPolicy(&Policy{Etag: "BwXhqkd+/0A=", Bindings: []*Binding{
+		{Role: "roles/a", Members: []string{"user:x@example.com"}},
+		{Role: "roles/b", Members: []string{"user:x@example.com", "user:y@example.com"}},
+	}}, &rows, Row{Resource: "p", Type: "project", Name: "projects/p"})
+	indexes := make([]int, len(rows))
+	for i, row := range rows {
+		indexes[i] = row.BindingIndex
+		if row.PolicyEtag != "BwXhqkd+/0A=" {
+			t.Errorf("row %d PolicyEtag = %q", i, row.PolicyEtag)
+		}
+		resman.bindingRoles[bindingRoleKey(row)] = &iam.Role{Name: row.Role, IncludedPermissions: []string{"a.b.get"}}
+	}
+	if len(indexes) != 3 || indexes[0] != 0 || indexes[1] != 1 || indexes[2] != 1 {
+		t.Fatalf("binding indexes = %v, want [0 1 1]", indexes)
+	}
+	for _, format := range []string{"csv", "ndjson"} {
+		output := filepath.Join(dir, "export."+format)
+		if err := renderers[format](output, rows, resman); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format == "csv" && !strings.HasSuffix(strings.SplitN(string(data), "\n", 2)[0], ",PolicyEtag,BindingIndex") {
+			t.Errorf("csv header doesn't end with the provenance columns: %s", data)
+		}
+		snap, err := readExport(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, row := range snap.Rows {
+			if row.PolicyEtag != rows[i].PolicyEtag || row.BindingIndex != rows[i].BindingIndex {
+				t.Errorf("%s row %d read back with %q %d, want %q %d", format, i, row.PolicyEtag, row.BindingIndex,
+					rows[i].PolicyEtag, rows[i].BindingIndex)
+			}
+		}
+	}
+	if got := bindingIndexColumn(&Row{}); got != "" {
+		t.Errorf("bindingIndexColumn of a row without an etag = %q, want empty", got)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// spoolFormats are the formats --low-memory can render from its spool.
+var spoolFormats = map[string]bool{"csv": true, "json": true, "ndjson": true}
+
+// spoolRunRows is how many rows --low-memory keeps before sorting them into a run on disk.
+const spoolRunRows = 100000
+
+// spooledRow is a row as a spool run stores it, with the conditions --dedup merged into it.
+type spooledRow struct {
+	Row    *Row
+	Merged []*Expr
+}
+
+// rowSpool is the on-disk sort --low-memory renders from: rows are sorted in runs of
+// spoolRunRows written to a temporary directory, and Each merges the runs back in order.
+type rowSpool struct {
+	dir     string
+	less    func(a *Row, b *Row) bool
+	pending []*Row
+	runs    []string
+}
+
+func newRowSpool(less func(a *Row, b *Row) bool) (*rowSpool, error) {
+	dir, err := ioutil.TempDir("", "policygopher-spool-")
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to create a spool directory: %v", err))
+	}
+	return &rowSpool{dir: dir, less: less}, nil
+}
+
+// Add spools a row, writing a sorted run once spoolRunRows are pending.
+func (s *rowSpool) Add(row *Row) error {
+	s.pending = append(s.pending, row)
+	if len(s.pending) >= spoolRunRows {
+		return s.flush()
+	}
+	return nil
+}
+
+// flush sorts the pending rows and writes them as the next run.
+func (s *rowSpool) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	sort.SliceStable(s.pending, func(i, j int) bool { return s.less(s.pending[i], s.pending[j]) })
+	filename := filepath.Join(s.dir, fmt.Sprintf("run-%05d.gob", len(s.runs)))
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	encoder := gob.NewEncoder(w)
+	for _, row := range s.pending {
+		if err = encoder.Encode(&spooledRow{Row: row, Merged: row.merged}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error writing spool run %s: %v", filename, err))
+	}
+	s.runs = append(s.runs, filename)
+	s.pending = nil
+	return nil
+}
+
+// spoolCursor is a run being merged, with the next row it has to offer.
+type spoolCursor struct {
+	run     int
+	file    *os.File
+	decoder *gob.Decoder
+	row     *Row
+}
+
+func (c *spoolCursor) next() error {
+	record := &spooledRow{}
+	if err := c.decoder.Decode(record); err != nil {
+		c.row = nil
+		if err == io.EOF {
+			return nil
+		}
+		return errors.New(fmt.Sprintf("Error reading spool run %s: %v", c.file.Name(), err))
+	}
+	c.row = record.Row
+	c.row.merged = record.Merged
+	return nil
+}
+
+// spoolMerge is a heap of the runs' next rows; equal rows come from earlier runs first, so
+// rows that don't sort keep the order they were added in.
+type spoolMerge struct {
+	cursors []*spoolCursor
+	less    func(a *Row, b *Row) bool
+}
+
+func (m *spoolMerge) Len() int { return len(m.cursors) }
+func (m *spoolMerge) Less(i, j int) bool {
+	a, b := m.cursors[i], m.cursors[j]
+	if m.less(a.row, b.row) {
+		return true
+	}
+	if m.less(b.row, a.row) {
+		return false
+	}
+	return a.run < b.run
+}
+func (m *spoolMerge) Swap(i, j int)      { m.cursors[i], m.cursors[j] = m.cursors[j], m.cursors[i] }
+func (m *spoolMerge) Push(x interface{}) { m.cursors = append(m.cursors, x.(*spoolCursor)) }
+func (m *spoolMerge) Pop() interface{} {
+	last := m.cursors[len(m.cursors)-1]
+	m.cursors = m.cursors[:len(m.cursors)-1]
+	return last
+}
+
+// Each calls fn with every spooled row in order, reading one row of each run at a time.
+func (s *rowSpool) Each(fn func(row *Row) error) error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	merge := &spoolMerge{less: s.less}
+	defer func() {
+		for _, c := range merge.cursors {
+			c.file.Close()
+		}
+	}()
+	for i, run := range s.runs {
+		f, err := os.Open(run)
+		if err != nil {
+			return err
+		}
+		c := &spoolCursor{run: i, file: f, decoder: gob.NewDecoder(bufio.NewReader(f))}
+		if err := c.next(); err != nil {
+			f.Close()
+			return err
+		}
+		if c.row == nil {
+			f.Close()
+			continue
+		}
+		merge.cursors = append(merge.cursors, c)
+	}
+	heap.Init(merge)
+	for merge.Len() > 0 {
+		c := merge.cursors[0]
+		if err := fn(c.row); err != nil {
+			return err
+		}
+		if err := c.next(); err != nil {
+			return err
+		}
+		if c.row == nil {
+			heap.Pop(merge)
+			c.file.Close()
+		} else {
+			heap.Fix(merge, 0)
+		}
+	}
+	return nil
+}
+
+// Runs is how many sorted runs the spool wrote.
+func (s *rowSpool) Runs() int {
+	return len(s.runs)
+}
+
+// Close removes the spool's runs.
+func (s *rowSpool) Close() error {
+	return os.RemoveAll(s.dir)
+}
+
+// spoolRows writes rows to a new spool in sorted runs.
+func spoolRows(rows []*Row, less func(a *Row, b *Row) bool) (*rowSpool, error) {
+	defer timeTrack("Spooling rows")()
+	spool, err := newRowSpool(less)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err := spool.Add(row); err != nil {
+			spool.Close()
+			return nil, err
+		}
+	}
+	if err := spool.flush(); err != nil {
+		spool.Close()
+		return nil, err
+	}
+	fmt.Printf("Spooled %d rows in %d sorted runs\n", len(rows), spool.Runs())
+	return spool, nil
+}
+
+// writeSpooled renders a spool in one of spoolFormats, merging its runs as it goes.
+func writeSpooled(output string, format string, spool *rowSpool, resman *resourceManager) error {
+	switch format {
+	case "csv":
+		defer timeTrack(fmt.Sprintf("Printing CSV %s", output))()
+		fmt.Printf("Printing CSV %s\n", output)
+		return writeCsvRows(output, spool.Each, resman)
+	case "json", "ndjson":
+		return writeJSONRows(output, spool.Each, resman, format == "ndjson")
+	}
+	return errors.New(fmt.Sprintf("--low-memory doesn't support --format %s", format))
+}
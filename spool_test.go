@@ -0,0 +1,113 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"google.golang.org/api/iam/v1"
+	"reflect"
+	"testing"
+)
+
+func TestRowSpoolMergesSortedRuns(t *testing.T) {
+	resman := &resourceManager{}
+	spool, err := newRowSpool(rowLess([]string{"member"}, resman))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.Close()
+	members := []string{"user:d", "user:b", "user:e", "user:a", "user:c"}
+	for i, m := range members {
+		row := &Row{Resource: "p", Type: "project", Member: m, Role: "roles/viewer"}
+		if m == "user:b" {
+			row.merged = []*Expr{{Expression: "true"}}
+		}
+		if err := spool.Add(row); err != nil {
+			t.Fatal(err)
+		}
+		// write a run every two rows, as if spoolRunRows were 2
+		if i%2 == 1 {
+			if err := spool.flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	got := make([]string, 0)
+	err = spool.Each(func(row *Row) error {
+		got = append(got, row.Member)
+		if row.Member == "user:b" && len(row.merged) != 1 {
+			t.Errorf("merged conditions of %s were lost", row.Member)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"user:a", "user:b", "user:c", "user:d", "user:e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Each() = %q, want %q", got, want)
+	}
+	if spool.Runs() != 3 {
+		t.Errorf("Runs() = %d, want 3", spool.Runs())
+	}
+}
+
+func TestRowSpoolKeepsOrderWithoutSortFields(t *testing.T) {
+	spool, err := newRowSpool(rowLess(nil, &resourceManager{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.Close()
+	members := []string{"user:c", "user:a", "user:b"}
+	for _, m := range members {
+		spool.Add(&Row{Member: m})
+		spool.flush()
+	}
+	got := make([]string, 0)
+	spool.Each(func(row *Row) error {
+		got = append(got, row.Member)
+		return nil
+	})
+	if !reflect.DeepEqual(got, members) {
+		t.Errorf("Each() = %q, want %q", got, members)
+	}
+}
+
+func TestWriteJSONDocumentMatchesEncoder(t *testing.T) {
+	resman := &resourceManager{orgId: "1", offline: true, bindingRoles: make(map[string]*iam.Role)}
+	viewer := &Row{Resource: "p", Type: "project", Member: "user:a@example.com", Role: "roles/viewer"}
+	resman.bindingRoles[bindingRoleKey(viewer)] = &iam.Role{Name: "roles/viewer", IncludedPermissions: []string{"a.b.get", "a.b.list"}}
+	for _, rows := range [][]*Row{nil, {viewer}} {
+		var buf bytes.Buffer
+		if err := writeJSONDocument(&buf, sliceRows(rows), resman); err != nil {
+			t.Fatal(err)
+		}
+		doc := &exportDocument{}
+		if err := json.Unmarshal(buf.Bytes(), doc); err != nil {
+			t.Fatalf("writeJSONDocument() wrote invalid json %s: %v", buf.String(), err)
+		}
+		doc.Rows = make([]*permissionRecord, 0)
+		for _, row := range rows {
+			doc.Rows = append(doc.Rows, row.permissionRecords(resman)...)
+		}
+		var want bytes.Buffer
+		if err := json.NewEncoder(&want).Encode(doc); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want.String() {
+			t.Errorf("writeJSONDocument() = %s, want %s", buf.String(), want.String())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// errorCount counts lines written to logerr.
+var errorCount = &countingWriter{w: os.Stderr}
+
+type countingWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	lines int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	c.lines += bytes.Count(p, []byte("\n"))
+	c.mu.Unlock()
+	return c.w.Write(p)
+}
+
+func (c *countingWriter) Lines() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lines
+}
+
+type phaseDuration struct {
+	Name     string  `json:"name"`
+	Seconds  float64 `json:"seconds"`
+	ApiCalls int     `json:"apiCalls,omitempty"`
+	Retries  int     `json:"retries,omitempty"`
+}
+
+// phases collects every duration timeTrack logs, in the order they finish.
+var phases = struct {
+	sync.Mutex
+	list []phaseDuration
+}{}
+
+func recordPhase(run spanStats) {
+	phases.Lock()
+	phases.list = append(phases.list, phaseDuration{Name: run.Name, Seconds: run.Seconds, ApiCalls: run.ApiCalls, Retries: run.Retries})
+	phases.Unlock()
+}
+
+func phasesSince(i int) []phaseDuration {
+	phases.Lock()
+	defer phases.Unlock()
+	return append([]phaseDuration{}, phases.list[i:]...)
+}
+
+func phaseCount() int {
+	phases.Lock()
+	defer phases.Unlock()
+	return len(phases.list)
+}
+
+type exportStats struct {
+	OrgId           string         `json:"orgId"`
+	Organization    *orgMetadata   `json:"organization,omitempty"`
+	Started         time.Time      `json:"started"`
+	Finished        time.Time      `json:"finished"`
+	Tool            *buildMetadata `json:"tool"`
+	ProjectsScanned int            `json:"projectsScanned"`
+	FoldersScanned  int            `json:"foldersScanned"`
+	// resources inside projects, with --resource-policies
+	ResourcesScanned int             `json:"resourcesScanned,omitempty"`
+	Bindings         int             `json:"bindings"`
+	UniqueMembers    int             `json:"uniqueMembers"`
+	UniqueRoles      int             `json:"uniqueRoles"`
+	PermissionRows   int             `json:"permissionRows"`
+	Errors           int             `json:"errors"`
+	Phases           []phaseDuration `json:"phases"`
+	// collectors slowest first, see the timings report
+	Collectors []*spanStats `json:"collectors,omitempty"`
+	// listed but their policy couldn't be read, see the unreadable-policies report
+	UnreadablePolicies int `json:"unreadablePolicies,omitempty"`
+}
+
+// statsRecorder remembers where the error and phase counters stood when an export started,
+// so a run over several orgs gets stats for each org alone.
+type statsRecorder struct {
+	started time.Time
+	errors  int
+	phases  int
+}
+
+func newStatsRecorder() *statsRecorder {
+	return &statsRecorder{started: time.Now().UTC(), errors: errorCount.Lines(), phases: phaseCount()}
+}
+
+func (s *statsRecorder) Stats(resman *resourceManager, rows []*Row) *exportStats {
+	members := make(map[string]bool)
+	roles := make(map[string]bool)
+	for _, row := range rows {
+		members[row.Member] = true
+		roles[row.Role] = true
+	}
+	return &exportStats{
+		OrgId:              resman.orgId,
+		Organization:       resman.OrgMetadata(),
+		Started:            s.started,
+		Finished:           time.Now().UTC(),
+		Tool:               toolBuild,
+		ProjectsScanned:    resman.projectsScanned,
+		FoldersScanned:     resman.foldersScanned,
+		ResourcesScanned:   resman.resourcesScanned,
+		Bindings:           len(rows),
+		UniqueMembers:      len(members),
+		UniqueRoles:        len(roles),
+		PermissionRows:     resman.permissionRows,
+		Errors:             errorCount.Lines() - s.errors,
+		Phases:             phasesSince(s.phases),
+		Collectors:         resman.Collectors(),
+		UnreadablePolicies: len(resman.unreadablePolicies),
+	}
+}
+
+func writeStats(filename string, stats *exportStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error encoding stats: %v", err))
+	}
+	return writeOutputFile(filename, append(data, '\n'))
+}
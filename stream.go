@@ -0,0 +1,136 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// policies per --stream-to request
+const streamBatchSize = 100
+
+// streamedPolicy is a policy as --stream-to posts it, with the resource it is set on.
+type streamedPolicy struct {
+	Resource    string     `json:"resource"`
+	Type        string     `json:"type"`
+	Parent      string     `json:"parent,omitempty"`
+	DisplayName string     `json:"displayName,omitempty"`
+	Etag        string     `json:"etag,omitempty"`
+	Bindings    []*Binding `json:"bindings"`
+}
+
+// streamBatch is the body of one --stream-to request. Seq counts batches from 1 within a run,
+// and the last batch of a run is marked Final, possibly with no policies.
+type streamBatch struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	OrgId         string            `json:"orgId"`
+	Seq           int               `json:"seq"`
+	Final         bool              `json:"final,omitempty"`
+	Policies      []*streamedPolicy `json:"policies"`
+}
+
+// policyStreamer posts policies to --stream-to in batches while the crawl goes on. Posting
+// happens in the background, one batch at a time and in order, so a slow receiver delays
+// only the stream and not the crawl; failed batches are logged and dropped.
+type policyStreamer struct {
+	url     string
+	orgId   string
+	client  *http.Client
+	pending []*streamedPolicy
+	seq     int
+	batches chan *streamBatch
+	done    chan bool
+	sent    int
+	failed  int
+}
+
+func newPolicyStreamer(url string, orgId string) *policyStreamer {
+	s := &policyStreamer{
+		url:     url,
+		orgId:   orgId,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		batches: make(chan *streamBatch, 16),
+		done:    make(chan bool),
+	}
+	go s.run()
+	return s
+}
+
+func (s *policyStreamer) run() {
+	for batch := range s.batches {
+		if err := s.post(batch); err != nil {
+			s.failed++
+			logerr.Printf("%v\n", err)
+			continue
+		}
+		s.sent += len(batch.Policies)
+	}
+	close(s.done)
+}
+
+func (s *policyStreamer) post(batch *streamBatch) error {
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error streaming batch %d: %v", batch.Seq, err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New(fmt.Sprintf("Stream receiver returned %s for batch %d", resp.Status, batch.Seq))
+	}
+	return nil
+}
+
+// Add queues the policy of the resource base describes, sending a batch once enough are queued.
+// Policies are added from the collecting goroutine only.
+func (s *policyStreamer) Add(policy *Policy, base Row) {
+	bindings := policy.Bindings
+	if bindings == nil {
+		bindings = []*Binding{}
+	}
+	s.pending = append(s.pending, &streamedPolicy{
+		Resource:    base.Name,
+		Type:        base.Type,
+		Parent:      base.Parent,
+		DisplayName: base.DisplayName,
+		Etag:        policy.Etag,
+		Bindings:    bindings,
+	})
+	if len(s.pending) >= streamBatchSize {
+		s.flush(false)
+	}
+}
+
+func (s *policyStreamer) flush(final bool) {
+	s.seq++
+	s.batches <- &streamBatch{SchemaVersion: schemaVersion, OrgId: s.orgId, Seq: s.seq, Final: final, Policies: s.pending}
+	s.pending = nil
+}
+
+// Close sends what is left as the final batch and waits for every batch to be posted.
+func (s *policyStreamer) Close() {
+	s.flush(true)
+	close(s.batches)
+	<-s.done
+	fmt.Printf("Streamed %d policies in %d batches to %s, %d batches failed\n", s.sent, s.seq, s.url, s.failed)
+}
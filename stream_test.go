@@ -0,0 +1,61 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestPolicyStreamer(t *testing.T) {
+	var mu sync.Mutex
+	batches := make([]*streamBatch, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		batch := &streamBatch{}
+		if err := json.NewDecoder(req.Body).Decode(batch); err != nil {
+			t.Errorf("decoding batch: %v", err)
+		}
+		mu.Lock()
+		batches = append(batches, batch)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	s := newPolicyStreamer(server.URL, "123")
+	for i := 0; i < streamBatchSize+5; i++ {
+		s.Add(&Policy{Etag: "e"}, Row{Name: fmt.Sprintf("projects/p%d", i), Type: "project"})
+	}
+	s.Close()
+
+	if len(batches) != 2 {
+		t.Fatalf("got %d batches, want 2", len(batches))
+	}
+	if b := batches[0]; b.Seq != 1 || b.Final || len(b.Policies) != streamBatchSize || b.OrgId != "123" {
+		t.Errorf("first batch = seq %d, final %v, %d policies, org %s", b.Seq, b.Final, len(b.Policies), b.OrgId)
+	}
+	if b := batches[1]; b.Seq != 2 || !b.Final || len(b.Policies) != 5 {
+		t.Errorf("last batch = seq %d, final %v, %d policies", b.Seq, b.Final, len(b.Policies))
+	}
+	if p := batches[1].Policies[4]; p.Resource != fmt.Sprintf("projects/p%d", streamBatchSize+4) || p.Bindings == nil {
+		t.Errorf("last policy = %+v", p)
+	}
+	if s.sent != streamBatchSize+5 || s.failed != 0 {
+		t.Errorf("sent %d, failed %d", s.sent, s.failed)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// grantedMembers returns the members holding each permission on resource in snap, through
+// bindings on it or on the folders and org above it.
+func grantedMembers(snap *Snapshot, resource string) map[string]map[string]bool {
+	resource = strings.TrimPrefix(resource, fullResourceNamePrefix)
+	parents := make(map[string]string)
+	for _, row := range snap.Rows {
+		if row.Name != "" && row.Parent != "" {
+			parents[row.Name] = row.Parent
+		}
+	}
+	inherited := make(map[string]bool)
+	for name := resource; name != ""; name = parents[name] {
+		inherited[name] = true
+	}
+	granted := make(map[string]map[string]bool)
+	for _, row := range snap.Rows {
+		if !inherited[row.Name] && !matchesResource(row, resource) {
+			continue
+		}
+		permissions, ok := snapshotRolePermissions(snap, row)
+		if !ok {
+			logerr.Printf("Permissions of %s are not in snapshot %s, ignoring its binding on %s\n", row.Role, snap.Id, row.Name)
+			continue
+		}
+		for _, p := range permissions {
+			if granted[p] == nil {
+				granted[p] = make(map[string]bool)
+			}
+			granted[p][row.Member] = true
+		}
+	}
+	return granted
+}
+
+// printTestablePermissions lists every permission that can be granted on resource with the
+// number of members holding it there in the snapshot, and how many are held by nobody.
+// Members of groups holding a permission aren't counted, only the group.
+func printTestablePermissions(opts *Options, snapshotId string, resource string) error {
+	if resource == "" {
+		return errors.New("permissions testable needs a resource, e.g. projects/my-project")
+	}
+	snap, err := openSnapshot(opts.StoreDir, opts.OrgId, snapshotId)
+	if err != nil {
+		return err
+	}
+	resman, err := newRoleResolver(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+	permissions, err := resman.TestablePermissions(fullResourceName(&Row{Name: strings.TrimPrefix(resource, fullResourceNamePrefix)}))
+	if err != nil {
+		return err
+	}
+	sort.Strings(permissions)
+	granted := grantedMembers(snap, resource)
+	nobody := 0
+	for _, p := range permissions {
+		if len(granted[p]) == 0 {
+			nobody++
+		}
+	}
+	fmt.Printf("%s: %d testable permissions, %d granted to nobody in snapshot %s\n", resource, len(permissions), nobody, snap.Id)
+	for _, p := range permissions {
+		fmt.Printf("%6d  %s\n", len(granted[p]), p)
+	}
+	return nil
+}
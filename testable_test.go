@@ -0,0 +1,44 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGrantedMembers(t *testing.T) {
+	snap := &Snapshot{
+		Id: "test",
+		Rows: []*Row{
+			{Name: "organizations/1", Type: "organization", Resource: "1", Member: "group:admins@example.com", Role: "roles/viewer"},
+			{Name: "folders/2", Parent: "organizations/1", Type: "folder", Member: "user:a@example.com", Role: "roles/viewer"},
+			{Name: "projects/p", Parent: "folders/2", Type: "project", Resource: "p", Member: "user:b@example.com", Role: "roles/editor"},
+			{Name: "projects/q", Parent: "organizations/1", Type: "project", Resource: "q", Member: "user:c@example.com", Role: "roles/editor"},
+		},
+		Roles: map[string][]string{
+			"roles/viewer": {"resourcemanager.projects.get"},
+			"roles/editor": {"resourcemanager.projects.get", "compute.instances.create"},
+		},
+	}
+	got := grantedMembers(snap, "//cloudresourcemanager.googleapis.com/projects/p")
+	want := map[string]map[string]bool{
+		"resourcemanager.projects.get": {"group:admins@example.com": true, "user:a@example.com": true, "user:b@example.com": true},
+		"compute.instances.create":     {"user:b@example.com": true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("grantedMembers = %v, want %v", got, want)
+	}
+}
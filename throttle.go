@@ -0,0 +1,184 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	maxThrottleRetries = 8
+	maxBackoff         = 2 * time.Minute
+	// successes needed at the current limit before allowing one more request in flight
+	throttleRecovery = 20
+)
+
+var retryDelayDetail = regexp.MustCompile(`"retryDelay"\s*:\s*"([0-9.]+)s"`)
+var quotaErrorReason = regexp.MustCompile(`rateLimitExceeded|userRateLimitExceeded|RESOURCE_EXHAUSTED|quotaExceeded`)
+
+// apiLimiter bounds the requests in flight to one API, halving the bound whenever the API
+// throttles and growing it back by one after a run of successes.
+type apiLimiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	limit     int
+	max       int
+	inFlight  int
+	successes int
+}
+
+func newApiLimiter(max int) *apiLimiter {
+	l := &apiLimiter{limit: max, max: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *apiLimiter) acquire() {
+	l.mu.Lock()
+	for l.inFlight >= l.limit {
+		l.cond.Wait()
+	}
+	l.inFlight++
+	l.mu.Unlock()
+}
+
+func (l *apiLimiter) release(throttled bool) {
+	l.mu.Lock()
+	l.inFlight--
+	if throttled {
+		l.successes = 0
+		if l.limit > 1 {
+			l.limit /= 2
+		}
+	} else if l.limit < l.max {
+		l.successes++
+		if l.successes >= throttleRecovery {
+			l.successes = 0
+			l.limit++
+		}
+	}
+	l.cond.Broadcast()
+	l.mu.Unlock()
+}
+
+type throttlingTransport struct {
+	base           http.RoundTripper
+	maxConcurrency int
+	mu             sync.Mutex
+	limiters       map[string]*apiLimiter
+}
+
+func newThrottlingTransport(base http.RoundTripper, maxConcurrency int) *throttlingTransport {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	return &throttlingTransport{base: base, maxConcurrency: maxConcurrency, limiters: make(map[string]*apiLimiter)}
+}
+
+func (t *throttlingTransport) limiter(host string) *apiLimiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.limiters[host]
+	if !ok {
+		l = newApiLimiter(t.maxConcurrency)
+		t.limiters[host] = l
+	}
+	return l
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// throttled reports whether resp is a rate limit or quota error. The start of the body is
+// read to tell quota errors from permission errors, and put back for the caller.
+func throttled(resp *http.Response) (bool, []byte) {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusForbidden:
+	default:
+		return false, nil
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	resp.Body = readCloser{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if resp.StatusCode == http.StatusForbidden {
+		return quotaErrorReason.Match(body), body
+	}
+	return true, body
+}
+
+func retryAfter(resp *http.Response, body []byte, attempt int) time.Duration {
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if seconds, err := strconv.Atoi(s); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(s); err == nil {
+			return time.Until(at)
+		}
+	}
+	if m := retryDelayDetail.FindSubmatch(body); m != nil {
+		if seconds, err := strconv.ParseFloat(string(m[1]), 64); err == nil {
+			return time.Duration(seconds * float64(time.Second))
+		}
+	}
+	backoff := time.Second << uint(attempt)
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+func (t *throttlingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l := t.limiter(req.URL.Host)
+	for attempt := 0; ; attempt++ {
+		l.acquire()
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			l.release(false)
+			return nil, err
+		}
+		isThrottled, body := throttled(resp)
+		l.release(isThrottled)
+		canRetry := req.Body == nil || req.GetBody != nil
+		if !isThrottled || attempt == maxThrottleRetries || !canRetry {
+			return resp, nil
+		}
+		resp.Body.Close()
+		recordSpanRetry()
+		wait := retryAfter(resp, body, attempt)
+		logerr.Printf("%s throttled (%s), retrying in %s\n", req.URL.Host, resp.Status, wait.Round(time.Millisecond))
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+		if req.GetBody != nil {
+			newBody, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = newBody
+		}
+	}
+}
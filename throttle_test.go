@@ -0,0 +1,122 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApiLimiterBacksOffAndRecovers(t *testing.T) {
+	l := newApiLimiter(8)
+	l.acquire()
+	l.release(true)
+	l.acquire()
+	l.release(true)
+	if l.limit != 2 {
+		t.Fatalf("limit after two throttles = %d, want 2", l.limit)
+	}
+	for i := 0; i < throttleRecovery; i++ {
+		l.acquire()
+		l.release(false)
+	}
+	if l.limit != 3 {
+		t.Errorf("limit after %d successes = %d, want 3", throttleRecovery, l.limit)
+	}
+	for i := 0; i < 10; i++ {
+		l.acquire()
+		l.release(true)
+	}
+	if l.limit != 1 {
+		t.Errorf("limit after repeated throttles = %d, want 1", l.limit)
+	}
+}
+
+func TestThrottled(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{http.StatusOK, "", false},
+		{http.StatusTooManyRequests, "slow down", true},
+		{http.StatusServiceUnavailable, "", true},
+		{http.StatusForbidden, `{"error": {"errors": [{"reason": "rateLimitExceeded"}]}}`, true},
+		{http.StatusForbidden, `{"error": {"status": "PERMISSION_DENIED"}}`, false},
+	}
+	for _, c := range cases {
+		resp := &http.Response{StatusCode: c.status, Body: ioutil.NopCloser(strings.NewReader(c.body))}
+		if got, _ := throttled(resp); got != c.want {
+			t.Errorf("throttled(%d %q) = %v, want %v", c.status, c.body, got, c.want)
+		}
+		if body, _ := ioutil.ReadAll(resp.Body); string(body) != c.body {
+			t.Errorf("body after throttled(%d) = %q, want %q", c.status, body, c.body)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"7"}}}
+	if got := retryAfter(resp, nil, 0); got != 7*time.Second {
+		t.Errorf("retryAfter with Retry-After: 7 = %s, want 7s", got)
+	}
+	resp = &http.Response{Header: http.Header{}}
+	body := []byte(`{"details": [{"retryDelay": "1.5s"}]}`)
+	if got := retryAfter(resp, body, 0); got != 1500*time.Millisecond {
+		t.Errorf("retryAfter with retryDelay 1.5s = %s, want 1.5s", got)
+	}
+	for attempt := 0; attempt < 12; attempt++ {
+		backoff := time.Second << uint(attempt)
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		if got := retryAfter(resp, nil, attempt); got < backoff/2 || got > backoff {
+			t.Errorf("retryAfter attempt %d = %s, want between %s and %s", attempt, got, backoff/2, backoff)
+		}
+	}
+}
+
+func TestThrottlingTransportRetries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "request" {
+			t.Errorf("attempt %d sent body %q, want %q", calls, body, "request")
+		}
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newThrottlingTransport(server.Client().Transport, 4)}
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("request"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" || calls != 3 {
+		t.Errorf("got %s %q after %d calls, want 200 \"ok\" after 3", resp.Status, body, calls)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// timeBounds finds the request.time comparisons with a timestamp in a condition: the latest
+// time it starts granting access at and the earliest it stops at, zero when there is none.
+// timed tells whether the condition looks at request.time at all, which schedules such as
+// request.time.getHours() do without bounds. The bounds are looked for anywhere in the
+// expression, so one side of an || can make them look tighter than they are.
+func timeBounds(expr string) (notBefore time.Time, expires time.Time, timed bool) {
+	tokens, err := tokenizeCEL(expr)
+	if err != nil {
+		return
+	}
+	bound := func(op string, t time.Time) {
+		switch op {
+		case "<", "<=":
+			if expires.IsZero() || t.Before(expires) {
+				expires = t
+			}
+		case ">", ">=":
+			if t.After(notBefore) {
+				notBefore = t
+			}
+		}
+	}
+	flip := map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<="}
+	for i, token := range tokens {
+		if strings.HasPrefix(token, "request.time") {
+			timed = true
+		}
+		if token != "request.time" {
+			continue
+		}
+		// request.time < timestamp("...")
+		if i+5 < len(tokens) {
+			if t, ok := timestampCall(tokens[i+2:]); ok {
+				bound(tokens[i+1], t)
+			}
+		}
+		// timestamp("...") > request.time
+		if i >= 5 {
+			if t, ok := timestampCall(tokens[i-5:]); ok {
+				bound(flip[tokens[i-1]], t)
+			}
+		}
+	}
+	return
+}
+
+// timeBoxStatus tells where at is relative to the bounds.
+func timeBoxStatus(notBefore time.Time, expires time.Time, at time.Time) string {
+	switch {
+	case !expires.IsZero() && !at.Before(expires):
+		return "expired"
+	case !notBefore.IsZero() && at.Before(notBefore):
+		return "not-started"
+	case expires.IsZero() && notBefore.IsZero():
+		return "scheduled"
+	case expires.IsZero():
+		return "no-expiry"
+	}
+	return "active"
+}
+
+var timeBoxOrder = map[string]int{"expired": 0, "active": 1, "not-started": 2, "no-expiry": 3, "scheduled": 4}
+
+func formatBound(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// timeBoxedReport lists the bindings whose condition depends on request.time, such as
+// just-in-time or emergency grants, with when they start and expire. Expired ones come first:
+// they grant nothing anymore but were never removed. Statuses are at --evaluate-conditions-at,
+// or now.
+func timeBoxedReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Resource", "Member", "Role", "Title", "NotBefore", "Expires", "Status", "Expression"}
+	at := resman.conditionTime
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	type entry struct {
+		record  []string
+		expires time.Time
+	}
+	entries := make([]*entry, 0)
+	for _, row := range rows {
+		for _, c := range append([]*Expr{row.Condition}, row.merged...) {
+			if c == nil {
+				continue
+			}
+			notBefore, expires, timed := timeBounds(c.Expression)
+			if !timed {
+				continue
+			}
+			entries = append(entries, &entry{
+				record: []string{resman.ResourceColumn(row), row.Member, row.Role, c.Title, formatBound(notBefore),
+					formatBound(expires), timeBoxStatus(notBefore, expires, at), c.Expression},
+				expires: expires,
+			})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.record[6] != b.record[6] {
+			return timeBoxOrder[a.record[6]] < timeBoxOrder[b.record[6]]
+		}
+		return a.expires.Before(b.expires)
+	})
+	records := make([][]string, len(entries))
+	for i, e := range entries {
+		records[i] = e.record
+	}
+	return header, records, nil
+}
+
+func init() {
+	registerReport("time-boxed", timeBoxedReport)
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeBounds(t *testing.T) {
+	jan := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expr      string
+		notBefore time.Time
+		expires   time.Time
+		timed     bool
+	}{
+		{`request.time < timestamp("2020-02-01T00:00:00Z")`, time.Time{}, feb, true},
+		{`request.time > timestamp("2020-01-01T00:00:00Z") && request.time <= timestamp("2020-02-01T00:00:00Z")`, jan, feb, true},
+		{`timestamp("2020-02-01T00:00:00Z") > request.time && resource.name.startsWith("projects/_/buckets/x")`, time.Time{}, feb, true},
+		{`request.time < timestamp("2020-03-01T00:00:00Z") && request.time < timestamp("2020-02-01T00:00:00Z")`, time.Time{}, feb, true},
+		{`request.time.getHours("Europe/Berlin") >= 9`, time.Time{}, time.Time{}, true},
+		{`resource.name.startsWith("projects/_/buckets/x")`, time.Time{}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		notBefore, expires, timed := timeBounds(tt.expr)
+		if !notBefore.Equal(tt.notBefore) || !expires.Equal(tt.expires) || timed != tt.timed {
+			t.Errorf("timeBounds(%s) = %v, %v, %v, want %v, %v, %v", tt.expr, notBefore, expires, timed, tt.notBefore, tt.expires, tt.timed)
+		}
+	}
+}
+
+func TestTimeBoxStatus(t *testing.T) {
+	jan := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+	mid := jan.Add(24 * time.Hour)
+	tests := []struct {
+		notBefore, expires, at time.Time
+		want                   string
+	}{
+		{time.Time{}, feb, mid, "active"},
+		{time.Time{}, feb, feb, "expired"},
+		{feb, time.Time{}, mid, "not-started"},
+		{jan, time.Time{}, mid, "no-expiry"},
+		{time.Time{}, time.Time{}, mid, "scheduled"},
+	}
+	for _, tt := range tests {
+		if got := timeBoxStatus(tt.notBefore, tt.expires, tt.at); got != tt.want {
+			t.Errorf("timeBoxStatus(%v, %v, %v) = %s, want %s", tt.notBefore, tt.expires, tt.at, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	traceMu sync.Mutex
+	// --trace-api logger shared by every client of the run, nil until the first one is made
+	tracer *log.Logger
+)
+
+// apiTracer returns the logger --trace-api writes to, stderr or --trace-file, opening the file
+// the first time.
+func apiTracer(opts *Options) (*log.Logger, error) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if tracer != nil {
+		return tracer, nil
+	}
+	if opts.TraceFile == "" {
+		tracer = log.New(os.Stderr, "trace: ", log.LstdFlags|log.Lmicroseconds)
+		return tracer, nil
+	}
+	f, err := os.OpenFile(opts.TraceFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to open trace file %s: %v", opts.TraceFile, err))
+	}
+	tracer = log.New(f, "", log.LstdFlags|log.Lmicroseconds)
+	return tracer, nil
+}
+
+// tracingTransport logs every request that goes out, retries included, with its status and
+// latency. It sits under the throttling transport, so time spent waiting for a slot or before
+// a retry shows up as gaps between lines rather than in the latency.
+type tracingTransport struct {
+	base   http.RoundTripper
+	logger *log.Logger
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		t.logger.Printf("%s %s (%s) failed after %s: %v", req.Method, req.URL, apiMethod(req), elapsed, err)
+		return nil, err
+	}
+	t.logger.Printf("%s %s (%s) %s in %s", req.Method, req.URL, apiMethod(req), resp.Status, elapsed)
+	return resp, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTracingTransport(t *testing.T) {
+	var out bytes.Buffer
+	status := "429 Too Many Requests"
+	transport := &tracingTransport{
+		logger: log.New(&out, "", 0),
+		base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if status == "" {
+				return nil, errors.New("connection reset")
+			}
+			return &http.Response{Status: status}, nil
+		}),
+	}
+	req, _ := http.NewRequest("GET", "https://iam.googleapis.com/v1/roles/viewer", nil)
+	transport.RoundTrip(req)
+	status = ""
+	transport.RoundTrip(req)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d trace lines, want 2: %q", len(lines), out.String())
+	}
+	if !strings.HasPrefix(lines[0], "GET https://iam.googleapis.com/v1/roles/viewer (iam roles.get) 429 Too Many Requests in ") {
+		t.Errorf("trace line = %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "failed after") || !strings.HasSuffix(lines[1], ": connection reset") {
+		t.Errorf("trace line = %q", lines[1])
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// newHTTPClient builds the client every API call goes through. Transports that need to see
+// each request (throttling, caching, accounting, ...) are layered under the oauth2 transport.
+func newHTTPClient(ctx context.Context, opts *Options, ts oauth2.TokenSource) (*http.Client, error) {
+	return newHTTPClientWithBase(ctx, opts, ts, http.DefaultTransport)
+}
+
+// newHTTPClientWithBase is newHTTPClient sending requests through base instead of
+// http.DefaultTransport, for endpoints that need their own TLS settings.
+func newHTTPClientWithBase(ctx context.Context, opts *Options, ts oauth2.TokenSource, base http.RoundTripper) (*http.Client, error) {
+	if opts.Replay != "" && opts.Record != "" {
+		return nil, errors.New("--replay and --record can't be used together")
+	}
+	if (opts.Replay != "" || opts.Record != "") && opts.HttpCache != "" {
+		return nil, errors.New("--http-cache can't be combined with --replay or --record")
+	}
+	switch {
+	case opts.Input != "":
+		ts = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "offline"})
+		base = &offlineTransport{input: opts.Input}
+	case opts.Replay != "":
+		// replayed runs need no credentials at all
+		ts = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "replay"})
+		base = &replayTransport{dir: opts.Replay}
+	case opts.Record != "":
+		if err := os.MkdirAll(opts.Record, 0700); err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to create fixture directory %s: %v", opts.Record, err))
+		}
+		base = &recordingTransport{base: base, dir: opts.Record}
+	}
+	if ts == nil {
+		var err error
+		if ts, err = defaultTokenSource(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if opts.TraceApi {
+		logger, err := apiTracer(opts)
+		if err != nil {
+			return nil, err
+		}
+		base = &tracingTransport{base: base, logger: logger}
+	}
+	base = &accountingTransport{base: base, counter: apiCalls}
+	if opts.HttpCache != "" {
+		if err := os.MkdirAll(opts.HttpCache, 0700); err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to create http cache %s: %v", opts.HttpCache, err))
+		}
+		base = &cachingTransport{base: base, dir: opts.HttpCache}
+	}
+	base = newThrottlingTransport(base, opts.ApiConcurrency)
+	if otel != nil {
+		base = &otelTransport{base: base}
+	}
+	return &http.Client{Transport: &oauth2.Transport{Source: ts, Base: base}}, nil
+}
+
+func newResourceManagerFromOptions(ctx context.Context, opts *Options, ts oauth2.TokenSource) (*resourceManager, error) {
+	client, err := newHTTPClient(ctx, opts, ts)
+	if err != nil {
+		return nil, err
+	}
+	resman, err := newResourceManager(ctx, opts.CredentialsPath, opts.OrgId, opts.ProjectId, client)
+	if err != nil {
+		return nil, err
+	}
+	resman.resourcePolicies = opts.ResourcePolicies
+	resman.sharedVpc = opts.SharedVpc
+	resman.iap = opts.Iap
+	resman.kms = opts.Kms
+	resman.policyTags = opts.PolicyTags
+	resman.repos = opts.Repos
+	resman.bigQueryAcls = opts.BigQueryAcls
+	resman.bucketAcls = opts.BucketAcls
+	resman.collectedAssetTypes = make(map[string]bool)
+	if opts.SharedVpc {
+		resman.collectedAssetTypes["compute.googleapis.com/Subnetwork"] = true
+	}
+	if opts.Kms {
+		resman.collectedAssetTypes["cloudkms.googleapis.com/KeyRing"] = true
+	}
+	if opts.PolicyTags {
+		resman.collectedAssetTypes["datacatalog.googleapis.com/Taxonomy"] = true
+		resman.collectedAssetTypes["datacatalog.googleapis.com/PolicyTag"] = true
+	}
+	if opts.BigQueryAcls {
+		resman.collectedAssetTypes["bigquery.googleapis.com/Dataset"] = true
+	}
+	if opts.Repos {
+		resman.collectedAssetTypes["sourcerepo.googleapis.com/Repository"] = true
+		resman.collectedAssetTypes["artifactregistry.googleapis.com/Repository"] = true
+	}
+	if opts.RolesFile != "" {
+		if err := resman.UseRolesFile(opts.RolesFile); err != nil {
+			return nil, err
+		}
+	}
+	resman.normalizeMembers = !opts.KeepMemberSpelling
+	resman.offline = opts.Input != ""
+	resman.collapsePermissions = opts.CollapsePermissions
+	resman.permissionColumns = opts.PermissionColumns
+	resman.provenance = opts.Provenance
+	resman.bindingAgeDays = opts.BindingAgeDays
+	resman.permissionFilter = newPermissionFilter(opts.Service, opts.PermissionResource, opts.Verb)
+	resman.redactMode, resman.redactSalt = opts.RedactMembers, opts.RedactSalt
+	resman.serviceAccountStatus = opts.ServiceAccountStatus
+	resman.dormantDays = opts.DormantDays
+	if resman.maxKeyAge, err = parseKeyAge(opts.MaxKeyAge); err != nil {
+		return nil, err
+	}
+	if opts.UserStatus {
+		if resman.directory, err = newDirectoryClient(ctx, opts); err != nil {
+			return nil, err
+		}
+	}
+	if opts.SourceColumns {
+		resman.runId = opts.RunId
+	}
+	if opts.StreamTo != "" {
+		resman.stream = newPolicyStreamer(opts.StreamTo, resman.Scope())
+	}
+	if resman.conditionTime, err = parseConditionTime(opts.EvaluateConditions); err != nil {
+		return nil, err
+	}
+	return resman, nil
+}
+
+type cachedResponse struct {
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// cachingTransport keeps GET responses that carry an ETag on disk and revalidates them with
+// If-None-Match, so unchanged role definitions and list pages come back as a cheap 304.
+// Every request still reaches the API, which authorizes it with the caller's own credentials.
+type cachingTransport struct {
+	base http.RoundTripper
+	dir  string
+}
+
+func (t *cachingTransport) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String()))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *cachingTransport) load(path string) *cachedResponse {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	cached := &cachedResponse{}
+	if err := json.Unmarshal(data, cached); err != nil || cached.ETag == "" {
+		return nil
+	}
+	return cached
+}
+
+func (t *cachingTransport) store(path string, cached *cachedResponse) {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		logerr.Printf("Unable to write http cache entry: %v\n", err)
+	}
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+	path := t.path(req)
+	cached := t.load(path)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        cached.Header,
+			Body:          ioutil.NopCloser(bytes.NewReader(cached.Body)),
+			ContentLength: int64(len(cached.Body)),
+			Request:       req,
+		}, nil
+	}
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	t.store(path, &cachedResponse{ETag: etag, Header: resp.Header, Body: body})
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCachingTransport(t *testing.T) {
+	served := make(map[string]int)
+	revalidated := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/etag" {
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				revalidated[r.URL.Path]++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		} else if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("%s revalidated with %s though it has no ETag", r.URL.Path, r.Header.Get("If-None-Match"))
+		}
+		served[r.URL.Path]++
+		w.Write([]byte("body of " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := &http.Client{Transport: &cachingTransport{base: server.Client().Transport, dir: dir}}
+	get := func(path string) string {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s = %s, want 200 OK", path, resp.Status)
+		}
+		return string(body)
+	}
+
+	for i := 0; i < 3; i++ {
+		if body := get("/etag"); body != "body of /etag" {
+			t.Errorf("GET /etag #%d = %q, want %q", i+1, body, "body of /etag")
+		}
+		if body := get("/plain"); body != "body of /plain" {
+			t.Errorf("GET /plain #%d = %q, want %q", i+1, body, "body of /plain")
+		}
+	}
+	if served["/etag"] != 1 || revalidated["/etag"] != 2 {
+		t.Errorf("/etag served %d times and revalidated %d, want served once and revalidated twice",
+			served["/etag"], revalidated["/etag"])
+	}
+	if served["/plain"] != 3 {
+		t.Errorf("/plain served %d times, want every time", served["/plain"])
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("cache holds %d entries, want only the response with an ETag", len(entries))
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dashboardInterval = 500 * time.Millisecond
+	dashboardRecent   = 6
+	dashboardBarWidth = 30
+)
+
+// tui is the dashboard --tui shows while an export runs, nil without it. Its methods do
+// nothing on nil, so collectors report progress without checking for it.
+var tui *dashboard
+
+// collectorProgress is how far a collector has got, total being 0 until it knows how much
+// there is to do.
+type collectorProgress struct {
+	Name  string
+	Done  int
+	Total int
+}
+
+// dashboard redraws collector progress, the error count, the API call rate, and the latest
+// messages and findings in place on a terminal, instead of scrolling every message past.
+type dashboard struct {
+	mu         sync.Mutex
+	out        io.Writer
+	started    time.Time
+	collectors []*collectorProgress
+	recent     []string
+	// every error logged, printed again once the dashboard stops so none scroll away unseen
+	errors    []string
+	qps       float64
+	lastCalls int
+	lastTick  time.Time
+	drawn     int
+	stop      chan bool
+	stopped   chan bool
+	// closed once every line written to pipe has been read
+	read chan bool
+	// restored by Stop
+	stdout *os.File
+	pipe   *os.File
+}
+
+// isTerminal reports whether f is a character device, which a dashboard can be redrawn on.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// startDashboard takes over stdout, the log, and the error log, drawing the dashboard on out
+// every dashboardInterval until Stop.
+func startDashboard(out *os.File) (*dashboard, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	d := &dashboard{out: out, started: time.Now(), lastTick: time.Now(), stop: make(chan bool), stopped: make(chan bool), read: make(chan bool), stdout: os.Stdout, pipe: w}
+	os.Stdout = w
+	log.SetOutput(w)
+	errorCount.mu.Lock()
+	errorCount.w = &dashboardErrors{d: d, w: w}
+	errorCount.mu.Unlock()
+	go func() {
+		defer close(d.read)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			d.Message(scanner.Text())
+		}
+	}()
+	go func() {
+		defer close(d.stopped)
+		ticker := time.NewTicker(dashboardInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.stop:
+				d.draw(time.Now())
+				return
+			case now := <-ticker.C:
+				d.draw(now)
+			}
+		}
+	}()
+	return d, nil
+}
+
+// Stop draws the dashboard a last time, gives stdout and the logs back, and prints the
+// errors logged while it ran.
+func (d *dashboard) Stop() {
+	if d == nil {
+		return
+	}
+	os.Stdout = d.stdout
+	log.SetOutput(os.Stderr)
+	errorCount.mu.Lock()
+	errorCount.w = os.Stderr
+	errorCount.mu.Unlock()
+	d.pipe.Close()
+	<-d.read
+	close(d.stop)
+	<-d.stopped
+	for _, line := range d.errors {
+		fmt.Fprintln(d.out, line)
+	}
+}
+
+// dashboardErrors keeps the error log's lines for the dashboard.
+type dashboardErrors struct {
+	d *dashboard
+	w io.Writer
+}
+
+func (e *dashboardErrors) Write(p []byte) (int, error) {
+	e.d.mu.Lock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		e.d.errors = append(e.d.errors, line)
+	}
+	e.d.mu.Unlock()
+	return e.w.Write(p)
+}
+
+// Start adds a collector to the dashboard, or sets the total of one already on it.
+func (d *dashboard) Start(name string, total int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.collector(name).Total = total
+}
+
+// Step counts one more item of a collector done.
+func (d *dashboard) Step(name string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.collector(name).Done++
+}
+
+func (d *dashboard) collector(name string) *collectorProgress {
+	for _, c := range d.collectors {
+		if c.Name == name {
+			return c
+		}
+	}
+	c := &collectorProgress{Name: name}
+	d.collectors = append(d.collectors, c)
+	return c
+}
+
+// Message adds a line to the latest messages, dropping the oldest.
+func (d *dashboard) Message(line string) {
+	if d == nil || strings.TrimSpace(line) == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recent = append(d.recent, line)
+	if len(d.recent) > dashboardRecent {
+		d.recent = d.recent[len(d.recent)-dashboardRecent:]
+	}
+}
+
+// Finding adds something an export turned up to the latest messages.
+func (d *dashboard) Finding(format string, a ...interface{}) {
+	d.Message("finding: " + fmt.Sprintf(format, a...))
+}
+
+func (d *dashboard) draw(now time.Time) {
+	calls := apiCalls.Total()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if elapsed := now.Sub(d.lastTick).Seconds(); elapsed > 0 {
+		d.qps = float64(calls-d.lastCalls) / elapsed
+	}
+	d.lastCalls, d.lastTick = calls, now
+	lines := d.frame(now, calls, errorCount.Lines())
+	if d.drawn > 0 {
+		// back to the first line of the last frame and clear it
+		fmt.Fprintf(d.out, "\x1b[%dF\x1b[J", d.drawn)
+	}
+	for _, line := range lines {
+		fmt.Fprintln(d.out, line)
+	}
+	d.drawn = len(lines)
+}
+
+// frame is the dashboard's lines, given the API calls and errors so far.
+func (d *dashboard) frame(now time.Time, calls int, errs int) []string {
+	lines := []string{fmt.Sprintf("policygopher  elapsed %s  API calls %d (%.1f/s)  errors %d",
+		now.Sub(d.started).Round(time.Second), calls, d.qps, errs)}
+	width := 0
+	for _, c := range d.collectors {
+		if len(c.Name) > width {
+			width = len(c.Name)
+		}
+	}
+	for _, c := range d.collectors {
+		lines = append(lines, fmt.Sprintf("%-*s  %s", width, c.Name, progressBar(c.Done, c.Total)))
+	}
+	if len(d.recent) > 0 {
+		lines = append(lines, "recent:")
+		for _, line := range d.recent {
+			lines = append(lines, "  "+line)
+		}
+	}
+	return lines
+}
+
+// progressBar draws done of total, or just done while the total isn't known.
+func progressBar(done int, total int) string {
+	if total <= 0 {
+		return fmt.Sprintf("[%s] %d", strings.Repeat("?", dashboardBarWidth), done)
+	}
+	filled := done * dashboardBarWidth / total
+	if filled > dashboardBarWidth {
+		filled = dashboardBarWidth
+	}
+	return fmt.Sprintf("[%s%s] %d/%d", strings.Repeat("#", filled), strings.Repeat("-", dashboardBarWidth-filled), done, total)
+}
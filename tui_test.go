@@ -0,0 +1,77 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProgressBar(t *testing.T) {
+	cases := []struct {
+		done, total int
+		want        string
+	}{
+		{0, 10, "[------------------------------] 0/10"},
+		{5, 10, "[###############---------------] 5/10"},
+		{12, 10, "[##############################] 12/10"},
+		{3, 0, "[??????????????????????????????] 3"},
+	}
+	for _, c := range cases {
+		if got := progressBar(c.done, c.total); got != c.want {
+			t.Errorf("progressBar(%d, %d) = %q, want %q", c.done, c.total, got, c.want)
+		}
+	}
+}
+
+func TestDashboardFrame(t *testing.T) {
+	started := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := &dashboard{started: started, qps: 12.5}
+	d.Start("folders", 2)
+	d.Step("folders")
+	d.Start("projects", 4)
+	d.Step("projects")
+	d.Step("projects")
+	for i := 0; i < dashboardRecent+1; i++ {
+		d.Message(string(rune('a' + i)))
+	}
+	d.Message("  ")
+	d.Finding("allUsers holds %s on %s", "roles/viewer", "projects/p")
+	got := d.frame(started.Add(90*time.Second), 100, 3)
+	want := []string{
+		"policygopher  elapsed 1m30s  API calls 100 (12.5/s)  errors 3",
+		"folders   [###############---------------] 1/2",
+		"projects  [###############---------------] 2/4",
+		"recent:",
+		"  c",
+		"  d",
+		"  e",
+		"  f",
+		"  g",
+		"  finding: allUsers holds roles/viewer on projects/p",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("frame:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestNilDashboard(t *testing.T) {
+	var d *dashboard
+	d.Start("folders", 1)
+	d.Step("folders")
+	d.Finding("nothing %s", "recorded")
+	d.Stop()
+}
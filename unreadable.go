@@ -0,0 +1,63 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"sort"
+)
+
+// unreadablePolicy is a resource that was listed but whose policy couldn't be read. It has no
+// rows, and that must not be taken for nobody having access to it.
+type unreadablePolicy struct {
+	Row
+	Reason string
+}
+
+// unreadableReason names the class of the error a policy couldn't be read with.
+func unreadableReason(err error) string {
+	switch {
+	case errors.Is(err, ErrPermissionDenied):
+		return "permission-denied"
+	case errors.Is(err, ErrQuotaExceeded):
+		return "quota-exceeded"
+	case errors.Is(err, ErrNotFound):
+		return "not-found"
+	}
+	return "error"
+}
+
+// markUnreadable records that the policy of the resource base describes couldn't be read.
+// Collectors searching locations concurrently call it from several goroutines.
+func (r *resourceManager) markUnreadable(base Row, err error) {
+	r.unreadableMu.Lock()
+	defer r.unreadableMu.Unlock()
+	r.unreadablePolicies = append(r.unreadablePolicies, &unreadablePolicy{Row: base, Reason: unreadableReason(err)})
+	tui.Finding("policy of %s %s unreadable: %s", base.Type, base.Name, unreadableReason(err))
+}
+
+func unreadablePoliciesReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Resource", "Type", "Name", "Parent", "Reason"}
+	records := make([][]string, 0, len(resman.unreadablePolicies))
+	for _, u := range resman.unreadablePolicies {
+		records = append(records, []string{resman.ResourceColumn(&u.Row), u.Type, u.Name, u.Parent, u.Reason})
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i][2] < records[j][2] })
+	return header, records, nil
+}
+
+func init() {
+	registerReport("unreadable-policies", unreadablePoliciesReport)
+}
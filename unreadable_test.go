@@ -0,0 +1,66 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestUnreadableReason(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{classifyError("folders/1", &httpError{Code: http.StatusForbidden, Body: "denied"}), "permission-denied"},
+		{classifyError("folders/1", &httpError{Code: http.StatusTooManyRequests}), "quota-exceeded"},
+		{classifyError("folders/1", &httpError{Code: http.StatusNotFound}), "not-found"},
+		{fmt.Errorf("wrapped: %w", &apiError{Class: ErrPermissionDenied, Resource: "x", Err: errors.New("no")}), "permission-denied"},
+		{errors.New("connection reset"), "error"},
+	}
+	for _, c := range cases {
+		if got := unreadableReason(c.err); got != c.want {
+			t.Errorf("unreadableReason(%v) = %q, want %q", c.err, got, c.want)
+		}
+	}
+}
+
+func TestUnreadablePoliciesReport(t *testing.T) {
+	resman := &resourceManager{}
+	denied := &apiError{Class: ErrPermissionDenied, Resource: "x", Err: errors.New("no")}
+	resman.markUnreadable(Row{Resource: "proj", Type: "project", Parent: "folders/2", Name: "projects/proj"}, denied)
+	resman.markUnreadable(Row{Resource: "2", Type: "folder", Parent: "organizations/1", Name: "folders/2"}, denied)
+	resman.markUnreadable(Row{Resource: "ring", Type: "keyring", Parent: "projects/proj",
+		Name: "//cloudkms.googleapis.com/projects/proj/locations/global/keyRings/ring"}, errors.New("timeout"))
+	header, records, err := unreadablePoliciesReport(nil, resman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(header, []string{"Resource", "Type", "Name", "Parent", "Reason"}) {
+		t.Errorf("header = %v", header)
+	}
+	want := [][]string{
+		{"//cloudkms.googleapis.com/projects/proj/locations/global/keyRings/ring", "keyring",
+			"//cloudkms.googleapis.com/projects/proj/locations/global/keyRings/ring", "projects/proj", "error"},
+		{"2", "folder", "folders/2", "organizations/1", "permission-denied"},
+		{"proj", "project", "projects/proj", "folders/2", "permission-denied"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// bindingDrift is a difference between the desired bindings and the live ones: added when a
+// member holds a role nobody declared, removed when a declared one isn't held, and mismatch
+// when it is held with other conditions.
+type bindingDrift struct {
+	Change   string
+	Resource string
+	Role     string
+	Member   string
+	Desired  []string
+	Live     []string
+}
+
+// driftError ends a verify run that found drift, which exits with failOnExitCode like --fail-on.
+type driftError struct {
+	drift int
+}
+
+func (e *driftError) Error() string {
+	return fmt.Sprintf("%d bindings drifted from the desired state", e.drift)
+}
+
+// readDesired reads a desired-state document, in the yaml-tree shape for .yaml and .yml files
+// and as an export otherwise, with the resources it declares.
+func readDesired(filename string) ([]*Row, map[string]bool, error) {
+	var rows []*Row
+	var resources []string
+	switch filepath.Ext(filename) {
+	case ".yaml", ".yml":
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		if rows, resources, err = readYamlTree(f); err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("Unable to read desired state %s: %v", filename, err))
+		}
+	default:
+		snap, err := readExport(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = snap.Rows
+		for _, row := range rows {
+			resources = append(resources, graphResourceName(row))
+		}
+	}
+	return rows, stringSet(resources), nil
+}
+
+// driftBindings groups rows of the declared resources by resource, role, and member, with
+// the conditions each is held under; "" stands for none.
+func driftBindings(rows []*Row, declared map[string]bool) map[string]map[string]bool {
+	bindings := make(map[string]map[string]bool)
+	for _, row := range rows {
+		resource := graphResourceName(row)
+		if !declared[resource] {
+			continue
+		}
+		key := strings.Join([]string{resource, row.Role, normalizeMember(row.Member)}, "\n")
+		if bindings[key] == nil {
+			bindings[key] = make(map[string]bool)
+		}
+		bindings[key][conditionExpression(row.Condition)] = true
+	}
+	return bindings
+}
+
+// DiffDesired compares live bindings to desired ones on the resources the desired state
+// declares; the policies of others aren't its concern. The drift is ordered by resource,
+// role, and member.
+func DiffDesired(desired []*Row, live []*Row, declared map[string]bool) []*bindingDrift {
+	want := driftBindings(desired, declared)
+	have := driftBindings(live, declared)
+	keys := make(map[string]bool)
+	for key := range want {
+		keys[key] = true
+	}
+	for key := range have {
+		keys[key] = true
+	}
+	drift := make([]*bindingDrift, 0)
+	for _, key := range sortedKeys(keys) {
+		parts := strings.Split(key, "\n")
+		d := &bindingDrift{Resource: parts[0], Role: parts[1], Member: parts[2],
+			Desired: sortedKeys(want[key]), Live: sortedKeys(have[key])}
+		switch {
+		case len(d.Desired) == 0:
+			d.Change = "added"
+		case len(d.Live) == 0:
+			d.Change = "removed"
+		case strings.Join(d.Desired, "\n") != strings.Join(d.Live, "\n"):
+			d.Change = "mismatch"
+		default:
+			continue
+		}
+		drift = append(drift, d)
+	}
+	return drift
+}
+
+// liveRows collects the org's bindings, or reads them from --input.
+func liveRows(opts *Options) ([]*Row, error) {
+	if opts.Input != "" {
+		snap, err := readExport(opts.Input)
+		if err != nil {
+			return nil, err
+		}
+		return snap.Rows, nil
+	}
+	resman, err := newResourceManagerFromOptions(context.Background(), opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := resman.GetAllPolicyRows()
+	if err != nil {
+		return nil, err
+	}
+	return *rows, nil
+}
+
+// conditionList shows the conditions a binding is held under, none being unconditional.
+func conditionList(conditions []string) string {
+	shown := make([]string, len(conditions))
+	for i, c := range conditions {
+		if c == "" {
+			c = "unconditional"
+		}
+		shown[i] = c
+	}
+	return strings.Join(shown, " | ")
+}
+
+// verifyDesired prints how the live bindings drifted from the desired state in filename, and
+// fails with a driftError when they did.
+func verifyDesired(opts *Options, filename string) error {
+	if filename == "" {
+		return errors.New("verify needs --desired, a yaml-tree document or an export")
+	}
+	desired, declared, err := readDesired(filename)
+	if err != nil {
+		return err
+	}
+	live, err := liveRows(opts)
+	if err != nil {
+		return err
+	}
+	drift := DiffDesired(desired, live, declared)
+	counts := make(map[string]int)
+	for _, d := range drift {
+		counts[d.Change]++
+	}
+	fmt.Printf("Verified %d resources against %s: %d added, %d removed, %d mismatched\n",
+		len(declared), filename, counts["added"], counts["removed"], counts["mismatch"])
+	sort.SliceStable(drift, func(i, j int) bool { return drift[i].Change > drift[j].Change })
+	for _, d := range drift {
+		switch d.Change {
+		case "removed":
+			fmt.Printf("- %s %s %s\n", d.Resource, d.Role, d.Member)
+		case "added":
+			fmt.Printf("+ %s %s %s\n", d.Resource, d.Role, d.Member)
+		default:
+			fmt.Printf("~ %s %s %s: desired %s, live %s\n", d.Resource, d.Role, d.Member, conditionList(d.Desired), conditionList(d.Live))
+		}
+	}
+	if len(drift) > 0 {
+		return &driftError{drift: len(drift)}
+	}
+	return nil
+}
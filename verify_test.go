@@ -0,0 +1,48 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffDesired(t *testing.T) {
+	until := &Expr{Expression: `request.time < timestamp("2030-01-01T00:00:00Z")`}
+	desired := []*Row{
+		{Name: "projects/web", Type: "project", Member: "user:a@example.com", Role: "roles/viewer"},
+		{Name: "projects/web", Type: "project", Member: "user:b@example.com", Role: "roles/editor"},
+		{Name: "projects/web", Type: "project", Member: "user:c@example.com", Role: "roles/owner", Condition: until},
+	}
+	live := []*Row{
+		{Name: "projects/web", Type: "project", Member: "user:A@example.com", Role: "roles/viewer"},
+		{Name: "projects/web", Type: "project", Member: "user:c@example.com", Role: "roles/owner"},
+		{Name: "projects/web", Type: "project", Member: "user:d@example.com", Role: "roles/editor"},
+		{Name: "projects/other", Type: "project", Member: "user:e@example.com", Role: "roles/owner"},
+	}
+	drift := DiffDesired(desired, live, map[string]bool{"projects/web": true})
+	got := make([][]string, len(drift))
+	for i, d := range drift {
+		got[i] = []string{d.Change, d.Resource, d.Role, d.Member, conditionList(d.Desired), conditionList(d.Live)}
+	}
+	want := [][]string{
+		{"removed", "projects/web", "roles/editor", "user:b@example.com", "unconditional", ""},
+		{"added", "projects/web", "roles/editor", "user:d@example.com", "", "unconditional"},
+		{"mismatch", "projects/web", "roles/owner", "user:c@example.com", until.Expression, "unconditional"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("drift = %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"google.golang.org/api/accesscontextmanager/v1"
+	"google.golang.org/api/option"
+	"path"
+	"sort"
+	"strings"
+)
+
+// servicePerimeter is a VPC Service Controls perimeter of one of the org's access policies,
+// with its projects by id where they could be resolved.
+type servicePerimeter struct {
+	Policy             string
+	Name               string
+	Title              string
+	Type               string
+	Projects           []string
+	RestrictedServices []string
+	AccessLevels       []string
+}
+
+// regular tells a perimeter that restricts its projects from a bridge, which only lets
+// perimeters share projects.
+func (p *servicePerimeter) regular() bool {
+	return p.Type == "" || p.Type == "PERIMETER_TYPE_REGULAR"
+}
+
+func (r *resourceManager) accessContextManager() (*accesscontextmanager.Service, error) {
+	var clientOptions []option.ClientOption
+	if r.client != nil {
+		clientOptions = append(clientOptions, option.WithHTTPClient(r.client))
+	}
+	return accesscontextmanager.NewService(r.ctx, clientOptions...)
+}
+
+// ServicePerimeters lists the service perimeters of every access policy of the org.
+func (r *resourceManager) ServicePerimeters() ([]*servicePerimeter, error) {
+	service, err := r.accessContextManager()
+	if err != nil {
+		return nil, err
+	}
+	policies := make([]string, 0)
+	err = service.AccessPolicies.List().Parent(fmt.Sprintf("organizations/%s", r.orgId)).PageSize(100).
+		Fields("nextPageToken,accessPolicies(name)").
+		Pages(r.ctx, func(page *accesscontextmanager.ListAccessPoliciesResponse) error {
+			for _, p := range page.AccessPolicies {
+				policies = append(policies, p.Name)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, classifyError(fmt.Sprintf("organizations/%s", r.orgId), err)
+	}
+	perimeters := make([]*servicePerimeter, 0)
+	for _, policy := range policies {
+		err = service.AccessPolicies.ServicePerimeters.List(policy).PageSize(100).
+			Pages(r.ctx, func(page *accesscontextmanager.ListServicePerimetersResponse) error {
+				for _, p := range page.ServicePerimeters {
+					perimeter := &servicePerimeter{Policy: path.Base(policy), Name: path.Base(p.Name), Title: p.Title, Type: p.PerimeterType}
+					if p.Status != nil {
+						for _, resource := range p.Status.Resources {
+							number := strings.TrimPrefix(resource, "projects/")
+							if id := r.ProjectIdForNumber(number); id != "" {
+								perimeter.Projects = append(perimeter.Projects, id)
+							} else {
+								perimeter.Projects = append(perimeter.Projects, number)
+							}
+						}
+						perimeter.RestrictedServices = p.Status.RestrictedServices
+						for _, level := range p.Status.AccessLevels {
+							perimeter.AccessLevels = append(perimeter.AccessLevels, path.Base(level))
+						}
+					}
+					perimeters = append(perimeters, perimeter)
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, classifyError(policy, err)
+		}
+	}
+	return perimeters, nil
+}
+
+// perimeterIndex maps each project to the regular perimeters it is in.
+func perimeterIndex(perimeters []*servicePerimeter) map[string][]string {
+	index := make(map[string][]string)
+	for _, p := range perimeters {
+		if !p.regular() {
+			continue
+		}
+		for _, project := range p.Projects {
+			index[project] = append(index[project], p.Name)
+		}
+	}
+	for _, names := range index {
+		sort.Strings(names)
+	}
+	return index
+}
+
+// ResolveServicePerimeters reads the org's service perimeters for the Perimeter column.
+func (r *resourceManager) ResolveServicePerimeters() {
+	defer timeTrack("Listing service perimeters")()
+	perimeters, err := r.ServicePerimeters()
+	if err != nil {
+		logerr.Printf("Unable to list the service perimeters of organization %s: %v\n", r.orgId, err)
+	}
+	r.servicePerimeters = perimeters
+	r.projectPerimeters = perimeterIndex(perimeters)
+	fmt.Printf("Listed %d service perimeters covering %d projects\n", len(perimeters), len(r.projectPerimeters))
+}
+
+// perimeterColumn reports whether rows carry the Perimeter column.
+func (r *resourceManager) perimeterColumn() bool {
+	return r.projectPerimeters != nil
+}
+
+// Perimeter is the Perimeter column: the service perimeters the project of the row's resource
+// is in, empty outside of any and for org and folder bindings.
+func (r *resourceManager) Perimeter(row *Row) string {
+	return strings.Join(r.projectPerimeters[rowProject(row)], " ")
+}
+
+// servicePerimetersReport lists the org's service perimeters with what they contain and restrict.
+func servicePerimetersReport(rows []*Row, resman *resourceManager) ([]string, [][]string, error) {
+	header := []string{"Policy", "Perimeter", "Title", "Type", "Projects", "RestrictedServices", "AccessLevels"}
+	if !resman.perimeterColumn() {
+		logerr.Printf("The service-perimeters report needs --vpc-sc\n")
+		return header, [][]string{}, nil
+	}
+	records := make([][]string, 0, len(resman.servicePerimeters))
+	for _, p := range resman.servicePerimeters {
+		records = append(records, []string{p.Policy, p.Name, p.Title, p.Type, strings.Join(p.Projects, " "),
+			strings.Join(p.RestrictedServices, " "), strings.Join(p.AccessLevels, " ")})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i][0] != records[j][0] {
+			return records[i][0] < records[j][0]
+		}
+		return records[i][1] < records[j][1]
+	})
+	return header, records, nil
+}
+
+func init() {
+	registerEnricher("vpc-sc", stageAnnotate, func(opts *Options) bool { return opts.VpcSc },
+		func(rows []*Row, resman *resourceManager) ([]*Row, error) {
+			resman.ResolveServicePerimeters()
+			return rows, nil
+		})
+	registerReport("service-perimeters", servicePerimetersReport)
+	registerCollectorPermissions("vpc-sc", "accesscontextmanager.policies.list", "accesscontextmanager.servicePerimeters.list")
+}
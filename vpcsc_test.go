@@ -0,0 +1,48 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPerimeter(t *testing.T) {
+	perimeters := []*servicePerimeter{
+		{Name: "prod", Type: "PERIMETER_TYPE_REGULAR", Projects: []string{"web", "db"}},
+		{Name: "analytics", Projects: []string{"db"}},
+		{Name: "bridge", Type: "PERIMETER_TYPE_BRIDGE", Projects: []string{"web", "tools"}},
+	}
+	index := perimeterIndex(perimeters)
+	want := map[string][]string{"web": {"prod"}, "db": {"analytics", "prod"}}
+	if !reflect.DeepEqual(index, want) {
+		t.Errorf("perimeterIndex() = %v, want %v", index, want)
+	}
+	r := &resourceManager{projectPerimeters: index}
+	tests := []struct {
+		row  *Row
+		want string
+	}{
+		{&Row{Type: "project", Name: "projects/db"}, "analytics prod"},
+		{&Row{Type: "bucket", Parent: "projects/web"}, "prod"},
+		{&Row{Type: "project", Name: "projects/tools"}, ""},
+		{&Row{Type: "folder", Name: "folders/2", Parent: "organizations/1"}, ""},
+	}
+	for _, test := range tests {
+		if got := r.Perimeter(test.row); got != test.want {
+			t.Errorf("Perimeter(%s %s) = %q, want %q", test.row.Type, test.row.Name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// yamlLine is a line of a YAML document with its indentation, comments and blank lines left out.
+type yamlLine struct {
+	number  int
+	indent  int
+	content string
+}
+
+// yamlParser reads the subset of YAML the yaml-tree format is written in: block mappings and
+// sequences, plain, single-quoted, and double-quoted scalars, and empty [] and {}. That is
+// enough for the documents yaml-tree writes and hand-written ones shaped like them, without
+// a YAML library.
+type yamlParser struct {
+	lines []*yamlLine
+	pos   int
+}
+
+func newYamlParser(r io.Reader) (*yamlParser, error) {
+	p := &yamlParser{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, errors.New(fmt.Sprintf("line %d: tabs can't indent YAML", number))
+		}
+		p.lines = append(p.lines, &yamlLine{number: number, indent: len(text) - len(trimmed), content: trimmed})
+	}
+	return p, scanner.Err()
+}
+
+// parse returns the document as nested map[string]interface{}, []interface{}, and strings.
+func (p *yamlParser) parse() (interface{}, error) {
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+	value, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, errors.New(fmt.Sprintf("line %d: unexpected indentation", p.lines[p.pos].number))
+	}
+	return value, nil
+}
+
+func isSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// block parses the sequence or mapping whose lines start at indent.
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if isSequenceItem(p.lines[p.pos].content) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	items := make([]interface{}, 0)
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSequenceItem(p.lines[p.pos].content) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(strings.TrimPrefix(line.content, "-"), " ")
+		if rest == "" {
+			p.pos++
+			item, err := p.nested(indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+		if _, _, ok := splitYamlKey(rest); ok || isSequenceItem(rest) {
+			// the item's first line continues after the dash, at the column it starts in
+			p.lines[p.pos] = &yamlLine{number: line.number, indent: len(line.content) - len(rest) + indent, content: rest}
+			item, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+		p.pos++
+		item, err := yamlScalar(rest, line.number)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	values := make(map[string]interface{})
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && !isSequenceItem(p.lines[p.pos].content) {
+		line := p.lines[p.pos]
+		key, rest, ok := splitYamlKey(line.content)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("line %d: expected key: value, found %s", line.number, line.content))
+		}
+		p.pos++
+		var value interface{}
+		var err error
+		switch {
+		case rest != "":
+			value, err = yamlScalar(rest, line.number)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSequenceItem(p.lines[p.pos].content):
+			// a sequence may sit at its key's indentation
+			value, err = p.sequence(indent)
+		default:
+			value, err = p.nested(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// nested parses the block indented deeper than indent on the next line, or returns nil when
+// there is none.
+func (p *yamlParser) nested(indent int) (interface{}, error) {
+	if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+		return nil, nil
+	}
+	return p.block(p.lines[p.pos].indent)
+}
+
+// splitYamlKey splits "key: value" and "key:" lines.
+func splitYamlKey(content string) (string, string, bool) {
+	if strings.HasPrefix(content, "\"") || strings.HasPrefix(content, "'") {
+		return "", "", false
+	}
+	if strings.HasSuffix(content, ":") {
+		return content[:len(content)-1], "", true
+	}
+	if i := strings.Index(content, ": "); i > 0 {
+		return content[:i], strings.TrimSpace(content[i+2:]), true
+	}
+	return "", "", false
+}
+
+// yamlScalar reads a scalar, or an empty flow collection.
+func yamlScalar(s string, number int) (interface{}, error) {
+	switch {
+	case s == "[]":
+		return []interface{}{}, nil
+	case s == "{}":
+		return map[string]interface{}{}, nil
+	case strings.HasPrefix(s, "\""):
+		var value string
+		if err := json.Unmarshal([]byte(s), &value); err != nil {
+			return nil, errors.New(fmt.Sprintf("line %d: %v", number, err))
+		}
+		return value, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, errors.New(fmt.Sprintf("line %d: unterminated string %s", number, s))
+		}
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1), nil
+	case strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") || strings.HasPrefix(s, "|") || strings.HasPrefix(s, ">"):
+		return nil, errors.New(fmt.Sprintf("line %d: only block collections and single line scalars are read, found %s", number, s))
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s, nil
+}
+
+// readYamlTree reads a document shaped like --format yaml-tree back into one row per member
+// of each binding, with every resource it names, bindings or not.
+func readYamlTree(r io.Reader) ([]*Row, []string, error) {
+	p, err := newYamlParser(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := p.parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	top, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, nil, errors.New("expected a mapping with a resources list")
+	}
+	rows := make([]*Row, 0)
+	resources := make([]string, 0)
+	var walk func(nodes interface{}, parent string) error
+	walk = func(nodes interface{}, parent string) error {
+		if nodes == nil {
+			return nil
+		}
+		list, ok := nodes.([]interface{})
+		if !ok {
+			return errors.New(fmt.Sprintf("resources under %q aren't a list", parent))
+		}
+		for _, item := range list {
+			node, ok := item.(map[string]interface{})
+			if !ok {
+				return errors.New(fmt.Sprintf("a resource under %q isn't a mapping", parent))
+			}
+			name := yamlField(node, "name")
+			if name == "" {
+				return errors.New(fmt.Sprintf("a resource under %q has no name", parent))
+			}
+			resources = append(resources, name)
+			base := Row{
+				Resource:       name[strings.LastIndex(name, "/")+1:],
+				Type:           yamlField(node, "type"),
+				Parent:         parent,
+				Name:           name,
+				DisplayName:    yamlField(node, "displayName"),
+				LifecycleState: yamlField(node, "lifecycleState"),
+			}
+			bindings, _ := node["bindings"].([]interface{})
+			for _, item := range bindings {
+				b, ok := item.(map[string]interface{})
+				if !ok {
+					return errors.New(fmt.Sprintf("a binding of %s isn't a mapping", name))
+				}
+				var condition *Expr
+				if c, ok := b["condition"].(map[string]interface{}); ok {
+					condition = &Expr{Title: yamlField(c, "title"), Description: yamlField(c, "description"), Expression: yamlField(c, "expression")}
+				}
+				members, _ := b["members"].([]interface{})
+				for _, m := range members {
+					member, _ := m.(string)
+					row := base
+					row.Role = yamlField(b, "role")
+					row.Member = member
+					row.Condition = condition
+					rows = append(rows, &row)
+				}
+			}
+			if err := walk(node["children"], name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(top["resources"], ""); err != nil {
+		return nil, nil, err
+	}
+	return rows, resources, nil
+}
+
+func yamlField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadYamlTreeRoundTrip(t *testing.T) {
+	rows := []*Row{
+		{Name: "organizations/1", Type: "organization", DisplayName: "example.com", Member: "group:admins@example.com", Role: "roles/owner"},
+		{Name: "folders/2", Parent: "organizations/1", Type: "folder", DisplayName: "Prod \"eu\"", Member: "user:a@example.com", Role: "roles/editor"},
+		{Name: "projects/web", Parent: "folders/2", Type: "project", DisplayName: "web", LifecycleState: "ACTIVE", Member: "user:c@example.com", Role: "roles/viewer",
+			Condition: &Expr{Title: "until feb", Expression: `request.time < timestamp("2020-02-01T00:00:00Z")`}},
+	}
+	var buf bytes.Buffer
+	y := &yamlWriter{w: &buf}
+	y.line(0, "schemaVersion: 2")
+	y.line(0, "resources:")
+	for _, n := range buildTree(rows) {
+		y.node(0, n)
+	}
+	read, resources, err := readYamlTree(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resources, []string{"organizations/1", "folders/2", "projects/web"}) {
+		t.Errorf("resources = %v", resources)
+	}
+	if len(read) != len(rows) {
+		t.Fatalf("read %d rows, want %d", len(read), len(rows))
+	}
+	for i, row := range read {
+		want := rows[i]
+		if row.Name != want.Name || row.Parent != want.Parent || row.Type != want.Type || row.DisplayName != want.DisplayName ||
+			row.Member != want.Member || row.Role != want.Role || !reflect.DeepEqual(row.Condition, want.Condition) {
+			t.Errorf("row %d = %+v, want %+v", i, row, want)
+		}
+	}
+}
+
+func TestReadYamlTreeHandWritten(t *testing.T) {
+	doc := `# desired IAM for the web project
+resources:
+- name: projects/web
+  type: project
+  bindings:
+  - role: roles/viewer
+    members:
+    - user:a@example.com   # on call
+    - 'group:web''s-team@example.com'
+  - role: roles/editor
+    members: []
+  children:
+    - name: "//storage.googleapis.com/web-assets"
+      type: bucket
+      bindings: []
+`
+	rows, resources, err := readYamlTree(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resources, []string{"projects/web", "//storage.googleapis.com/web-assets"}) {
+		t.Errorf("resources = %v", resources)
+	}
+	members := make([]string, len(rows))
+	for i, row := range rows {
+		members[i] = row.Member
+		if row.Role != "roles/viewer" || row.Name != "projects/web" || row.Resource != "web" {
+			t.Errorf("row %d = %+v", i, row)
+		}
+	}
+	if !reflect.DeepEqual(members, []string{"user:a@example.com", "group:web's-team@example.com"}) {
+		t.Errorf("members = %v", members)
+	}
+}
+
+func TestReadYamlTreeErrors(t *testing.T) {
+	for _, doc := range []string{
+		"resources: [{name: a}]\n",
+		"resources:\n  - type: project\n",
+		"- a\n- b\n",
+		"resources:\n  - name: a\n bad: indent\n",
+	} {
+		if _, _, err := readYamlTree(strings.NewReader(doc)); err == nil {
+			t.Errorf("readYamlTree(%q) didn't fail", doc)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// treeNode is a resource in --format yaml-tree, with its bindings and the resources below it.
+type treeNode struct {
+	name           string
+	resourceType   string
+	displayName    string
+	lifecycleState string
+	bindings       []*Binding
+	children       []*treeNode
+}
+
+// buildTree nests the rows' resources under their parents, merging the rows of each resource
+// into bindings of one role and condition. Resources whose parent has no bindings, and so no
+// rows, become roots of their own.
+func buildTree(rows []*Row) []*treeNode {
+	nodes := make(map[string]*treeNode)
+	parents := make(map[string]string)
+	order := make([]string, 0)
+	bindings := make(map[string]*Binding)
+	for _, row := range rows {
+		name := graphResourceName(row)
+		node, ok := nodes[name]
+		if !ok {
+			node = &treeNode{name: name, resourceType: row.Type, displayName: row.DisplayName, lifecycleState: row.LifecycleState}
+			nodes[name] = node
+			order = append(order, name)
+			if row.Parent != "" {
+				parents[name] = row.Parent
+			}
+		}
+		key := fmt.Sprintf("%s|%s|%s", name, row.Role, conditionExpression(row.Condition))
+		b, ok := bindings[key]
+		if !ok {
+			b = &Binding{Role: row.Role, Condition: row.Condition}
+			bindings[key] = b
+			node.bindings = append(node.bindings, b)
+		}
+		b.Members = append(b.Members, row.Member)
+	}
+	roots := make([]*treeNode, 0)
+	for _, name := range order {
+		node := nodes[name]
+		if parent, ok := nodes[parents[name]]; ok && parent != node {
+			parent.children = append(parent.children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	var sortNodes func(nodes []*treeNode)
+	sortNodes = func(nodes []*treeNode) {
+		sort.SliceStable(nodes, func(i, j int) bool {
+			a, b := treeTypeOrder(nodes[i].resourceType), treeTypeOrder(nodes[j].resourceType)
+			if a != b {
+				return a < b
+			}
+			return nodes[i].name < nodes[j].name
+		})
+		for _, n := range nodes {
+			sort.SliceStable(n.bindings, func(i, j int) bool { return n.bindings[i].Role < n.bindings[j].Role })
+			for _, b := range n.bindings {
+				sort.Strings(b.Members)
+			}
+			sortNodes(n.children)
+		}
+	}
+	sortNodes(roots)
+	return roots
+}
+
+// treeTypeOrder puts folders before projects, and projects before the resources inside them.
+func treeTypeOrder(resourceType string) int {
+	switch resourceType {
+	case "organization":
+		return 0
+	case "folder":
+		return 1
+	case "project":
+		return 2
+	}
+	return 3
+}
+
+// yamlString quotes s as a YAML double-quoted scalar, which json strings are.
+func yamlString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+type yamlWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (y *yamlWriter) line(indent int, format string, args ...interface{}) {
+	if y.err != nil {
+		return
+	}
+	_, y.err = fmt.Fprintf(y.w, "%s%s\n", strings.Repeat("  ", indent), fmt.Sprintf(format, args...))
+}
+
+// node writes n as a list item at indent.
+func (y *yamlWriter) node(indent int, n *treeNode) {
+	y.line(indent, "- name: %s", yamlString(n.name))
+	indent++
+	y.line(indent, "type: %s", yamlString(n.resourceType))
+	if n.displayName != "" {
+		y.line(indent, "displayName: %s", yamlString(n.displayName))
+	}
+	if n.lifecycleState != "" {
+		y.line(indent, "lifecycleState: %s", yamlString(n.lifecycleState))
+	}
+	y.line(indent, "bindings:")
+	for _, b := range n.bindings {
+		y.line(indent+1, "- role: %s", yamlString(b.Role))
+		if c := b.Condition; c != nil {
+			y.line(indent+2, "condition:")
+			if c.Title != "" {
+				y.line(indent+3, "title: %s", yamlString(c.Title))
+			}
+			if c.Description != "" {
+				y.line(indent+3, "description: %s", yamlString(c.Description))
+			}
+			y.line(indent+3, "expression: %s", yamlString(c.Expression))
+		}
+		y.line(indent+2, "members:")
+		for _, m := range b.Members {
+			y.line(indent+3, "- %s", yamlString(m))
+		}
+	}
+	if len(n.children) > 0 {
+		y.line(indent, "children:")
+		for _, c := range n.children {
+			y.node(indent+1, c)
+		}
+	}
+}
+
+// writeYamlTree writes the org as one YAML document nesting folders, projects and the
+// resources inside them under their parents, with each one's bindings inline.
+func writeYamlTree(filename string, rows []*Row, resman *resourceManager) error {
+	f, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	defer timeTrack(fmt.Sprintf("Printing YAML %s", filename))()
+	fmt.Printf("Printing YAML %s\n", filename)
+	y := &yamlWriter{w: f}
+	y.line(0, "schemaVersion: %d", schemaVersion)
+	y.line(0, "orgId: %s", yamlString(resman.Scope()))
+	y.line(0, "created: %s", yamlString(time.Now().UTC().Format(time.RFC3339)))
+	roots := buildTree(rows)
+	if len(roots) == 0 {
+		y.line(0, "resources: []")
+	} else {
+		y.line(0, "resources:")
+	}
+	for _, n := range roots {
+		y.node(0, n)
+	}
+	if y.err != nil {
+		return errors.New(fmt.Sprintf("Error writing %s: %v", filename, y.err))
+	}
+	return f.Commit()
+}
+
+func init() {
+	registerRenderer("yaml-tree", writeYamlTree)
+}
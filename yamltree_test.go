@@ -0,0 +1,78 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//            http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestYamlTree(t *testing.T) {
+	rows := []*Row{
+		{Name: "projects/web", Parent: "folders/2", Type: "project", DisplayName: "web", LifecycleState: "ACTIVE", Member: "user:b@example.com", Role: "roles/viewer"},
+		{Name: "organizations/1", Type: "organization", DisplayName: "example.com", Member: "group:admins@example.com", Role: "roles/owner"},
+		{Name: "folders/2", Parent: "organizations/1", Type: "folder", DisplayName: "Prod \"eu\"", Member: "user:a@example.com", Role: "roles/editor"},
+		{Name: "projects/web", Parent: "folders/2", Type: "project", DisplayName: "web", LifecycleState: "ACTIVE", Member: "user:a@example.com", Role: "roles/viewer"},
+		{Name: "projects/web", Parent: "folders/2", Type: "project", DisplayName: "web", LifecycleState: "ACTIVE", Member: "user:c@example.com", Role: "roles/viewer",
+			Condition: &Expr{Title: "until feb", Expression: `request.time < timestamp("2020-02-01T00:00:00Z")`}},
+		{Name: "projects/orphan", Parent: "folders/9", Type: "project", Member: "user:d@example.com", Role: "roles/viewer"},
+	}
+	var buf bytes.Buffer
+	y := &yamlWriter{w: &buf}
+	for _, n := range buildTree(rows) {
+		y.node(0, n)
+	}
+	want := `- name: "organizations/1"
+  type: "organization"
+  displayName: "example.com"
+  bindings:
+    - role: "roles/owner"
+      members:
+        - "group:admins@example.com"
+  children:
+    - name: "folders/2"
+      type: "folder"
+      displayName: "Prod \"eu\""
+      bindings:
+        - role: "roles/editor"
+          members:
+            - "user:a@example.com"
+      children:
+        - name: "projects/web"
+          type: "project"
+          displayName: "web"
+          lifecycleState: "ACTIVE"
+          bindings:
+            - role: "roles/viewer"
+              members:
+                - "user:a@example.com"
+                - "user:b@example.com"
+            - role: "roles/viewer"
+              condition:
+                title: "until feb"
+                expression: "request.time < timestamp(\"2020-02-01T00:00:00Z\")"
+              members:
+                - "user:c@example.com"
+- name: "projects/orphan"
+  type: "project"
+  bindings:
+    - role: "roles/viewer"
+      members:
+        - "user:d@example.com"
+`
+	if y.err != nil || buf.String() != want {
+		t.Errorf("yaml tree =\n%s\nwant\n%s", buf.String(), want)
+	}
+}